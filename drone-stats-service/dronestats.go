@@ -2,16 +2,19 @@ package main
 
 import (
 	"context"
-	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"drone-stats-service/internal/audit"
 	"drone-stats-service/internal/backup"
 	"drone-stats-service/internal/config"
+	"drone-stats-service/internal/dao"
 	"drone-stats-service/internal/handler"
 	"drone-stats-service/internal/logic"
 	"drone-stats-service/internal/svc"
@@ -24,66 +27,90 @@ import (
 var configFile = flag.String("f", "etc/dronestats.yaml", "the config file")
 
 func main() {
+	// restore 子命令：从备份恢复后退出，不启动服务
+	if len(os.Args) > 1 && os.Args[1] == "restore" {
+		os.Exit(runRestore(os.Args[2:]))
+	}
 	flag.Parse()
 
 	var c config.Config
 	conf.MustLoad(*configFile, &c)
+	if c.BatteryConf.NominalCapacityAh <= 0 && len(c.BatteryConf.ModelNominalCapacityAh) == 0 {
+		fmt.Println("未配置电池标称容量：按 SOC 回退计算耗电时使用历史架次基准容量或 RM / SOC 推算值")
+	}
+	// 电压、电流采样缺失的架次按 SOC/RM 回退计算耗电，此时只能使用标称电压
+	if c.BatteryConf.NominalVoltageV <= 0 {
+		panic("BatteryConf.NominalVoltageV 必须大于 0（电池标称电压，单位 V）")
+	}
 
 	ctx := svc.NewServiceContext(c)
-	// 自动建表
-	if err := autoMigrate(ctx.MySQLDao.DB); err != nil {
-		panic(err)
+	// 自动建表（SQLite 在打开时建表）
+	if ctx.SQLDao.Dialect() == dao.DialectMySQL {
+		if err := dao.MigrateMySQL(ctx.SQLDao.DB); err != nil {
+			panic(err)
+		}
 	}
 
 	// 启动前同步重放本地队列，确保重启后老数据能被尽快写回
-	if ctx.MySQLDao != nil {
+	if ctx.SQLDao != nil {
 		fmt.Println("启动时检测并重放本地队列...")
-		ctx.MySQLDao.DrainQueueOnce()
+		ctx.SQLDao.DrainQueueOnce()
 	}
 
-	// 初始化备份管理器并启动定时备份（由服务管理）
-	// 备份配置：优先使用 etc 配置段 BackupConf，否则使用默认值
-	backupDir := "/droneMonitor/backups"
+	// 启动定时备份（备份管理器由 ServiceContext 创建，与备份管理 API 共用）
+	// 备份间隔：优先使用 etc 配置段 BackupConf，否则使用默认值
+	bm := ctx.Backup
 	intervalDays := 3
-	retention := 7
-	influxBucket := c.InfluxDBConfig.Bucket
-	if c.BackupConf.BackupDir != "" {
-		backupDir = c.BackupConf.BackupDir
-	}
 	if c.BackupConf.IntervalDays > 0 {
 		intervalDays = c.BackupConf.IntervalDays
 	}
-	if c.BackupConf.RetentionDays > 0 {
-		retention = c.BackupConf.RetentionDays
-	}
-	if c.BackupConf.InfluxBucket != "" {
-		influxBucket = c.BackupConf.InfluxBucket
-	}
-
-	// 确保备份目录存在
-	if err := os.MkdirAll(backupDir, 0755); err != nil {
-		fmt.Println("创建备份目录失败:", err)
-	}
-
-	bm := backup.NewManager(ctx.MySQLDao.DB, ctx.InfluxDao.Client, c.InfluxDBConfig.Org, influxBucket, backupDir, retention)
-	// 启动定期备份协程
-	go func() {
-		ticker := time.NewTicker(time.Duration(intervalDays) * 24 * time.Hour)
-		defer ticker.Stop()
-		fmt.Printf("备份管理器已启动：每 %d 天执行一次备份，备份目录=%s\n", intervalDays, backupDir)
-		for {
-			select {
-			case <-ticker.C:
-				ctx2 := context.Background()
-				fmt.Println("后台定时备份触发: ", time.Now())
-				if err := bm.BackupOnce(ctx2); err != nil {
-					fmt.Println("定时备份失败:", err)
-				} else {
-					fmt.Println("定时备份完成")
+	// 嵌入式存储（SQLite / 内存遥测）不支持备份
+	if bm.Supported() {
+		// 启动定期备份协程
+		go func() {
+			ticker := time.NewTicker(time.Duration(intervalDays) * 24 * time.Hour)
+			defer ticker.Stop()
+			fmt.Printf("备份管理器已启动：每 %d 天执行一次备份（每 %d 天一次完整备份，其余为增量），备份目录=%s\n", intervalDays, bm.FullIntervalDays, bm.BackupDir)
+			for {
+				select {
+				case <-ticker.C:
+					ctx2 := context.Background()
+					fmt.Println("后台定时备份触发: ", time.Now())
+					err := bm.BackupOnce(ctx2)
+					switch {
+					case errors.Is(err, backup.ErrBackupRunning):
+						fmt.Println("已有备份正在执行，跳过本次定时备份")
+					case err != nil:
+						fmt.Println("定时备份失败:", err)
+					default:
+						fmt.Println("定时备份完成")
+					}
 				}
 			}
+		}()
+
+		// 定期恢复演练：将最新备份链恢复到临时库并校验，结果写入该备份目录
+		if rt := c.BackupConf.RestoreTest; rt.IntervalDays > 0 && (rt.MySQLDataSource != "" || rt.InfluxBucket != "") {
+			go func() {
+				ticker := time.NewTicker(time.Duration(rt.IntervalDays) * 24 * time.Hour)
+				defer ticker.Stop()
+				fmt.Printf("恢复演练已启用：每 %d 天执行一次\n", rt.IntervalDays)
+				for range ticker.C {
+					res, err := bm.RestoreTest(context.Background(), rt.MySQLDataSource, rt.InfluxBucket)
+					switch {
+					case err != nil:
+						fmt.Println("恢复演练失败:", err)
+					case !res.OK:
+						fmt.Printf("恢复演练未通过，备份 %s 不可用: %s\n", res.Backup, strings.Join(res.Problems(), "; "))
+					default:
+						fmt.Printf("恢复演练通过，备份 %s 可用（备份链 %d 个备份）\n", res.Backup, len(res.Chain))
+					}
+				}
+			}()
 		}
-	}()
+	} else {
+		fmt.Println("当前存储后端不支持备份，定时备份与恢复演练未启动")
+	}
 
 	server := rest.MustNewServer(c.RestConf)
 	defer server.Stop()
@@ -93,18 +120,25 @@ func main() {
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
 		sig := <-sigCh
-		fmt.Println("收到退出信号：", sig, "，在退出前执行备份...")
-		ctx2 := context.Background()
-		if err := bm.BackupOnce(ctx2); err != nil {
-			fmt.Println("退出前备份失败:", err)
+		if bm.Supported() {
+			fmt.Println("收到退出信号：", sig, "，在退出前执行备份...")
+			ctx2 := context.Background()
+			// 有备份正在执行时等待其结束后再执行
+			if _, err := bm.Backup(ctx2, "", backup.TriggerShutdown); err != nil {
+				fmt.Println("退出前备份失败:", err)
+			} else {
+				fmt.Println("退出前备份完成")
+			}
 		} else {
-			fmt.Println("退出前备份完成")
+			fmt.Println("收到退出信号：", sig)
 		}
 		// 触发 server 停止
 		server.Stop()
 		os.Exit(0)
 	}()
 
+	// 记录请求发起人，供审计日志使用
+	server.Use(audit.NewMiddleware(c.Audit.TrustUserHeader))
 	handler.RegisterHandlers(server, ctx)
 
 	// 启动定时任务
@@ -114,6 +148,14 @@ func main() {
 		fmt.Println("开始拉取数据...")
 		for {
 			processAllUasData(ctx)
+			// 补算历史架次的电池指标，查询接口不再同步补算
+			n, err := logic.BackfillBatteryMetrics(ctx)
+			if err != nil {
+				fmt.Println("补算电池指标失败:", err)
+			}
+			if n > 0 {
+				fmt.Printf("已补算 %d 个架次的电池指标\n", n)
+			}
 			<-ticker.C
 		}
 	}()
@@ -124,14 +166,14 @@ func main() {
 }
 
 func processAllUasData(ctx *svc.ServiceContext) {
-	ids, err := ctx.InfluxDao.GetAllUasIDsAndFirstSeen()
+	ids, err := ctx.Telemetry.GetAllUasIDsAndFirstSeen()
 	if err != nil {
 		fmt.Println("拉取无人机ID失败:", err)
 		return
 	}
 	for id, regTime := range ids {
 		// 自动注册
-		if err := ctx.MySQLDao.RegisterSortiesIfNotExist(id, regTime); err != nil {
+		if err := ctx.Sorties.RegisterSortiesIfNotExist(id, regTime); err != nil {
 			fmt.Println("注册无人机失败:", id, err)
 		}
 
@@ -153,65 +195,3 @@ func processAllUasData(ctx *svc.ServiceContext) {
 		}
 	}
 }
-
-func autoMigrate(db *sql.DB) error {
-	// flight_sorties 表
-	_, err := db.Exec(`
-    CREATE TABLE IF NOT EXISTS flight_sorties (
-        id INT AUTO_INCREMENT PRIMARY KEY,
-        OrderID VARCHAR(128) NOT NULL UNIQUE,
-        register_time DATETIME,
-        model VARCHAR(64)
-    );`)
-	if err != nil {
-		return err
-	}
-
-	// flight_records 表
-	_, err = db.Exec(`
-    CREATE TABLE IF NOT EXISTS flight_records (
-        id INT AUTO_INCREMENT PRIMARY KEY,
-        OrderID VARCHAR(128) NOT NULL,
-		uasID VARCHAR(128) NOT NULL,
-        start_time DATETIME NOT NULL,
-        end_time DATETIME,
-        start_lat BIGINT,
-        start_lng BIGINT,
-        end_lat BIGINT,
-        end_lng BIGINT,
-        distance DOUBLE(10,2),
-        battery_used DOUBLE(10,6),
-        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		payload INT NOT NULL DEFAULT 0,
-		expressCount INT NOT NULL DEFAULT 0
-    );`)
-	if err != nil {
-		return err
-	}
-
-	// flight_track_points 表
-	_, err = db.Exec(`
-    CREATE TABLE IF NOT EXISTS flight_track_points (
-        id INT AUTO_INCREMENT PRIMARY KEY,
-        OrderID VARCHAR(128) NOT NULL,
-        flightStatus VARCHAR(16),
-        timeStamp DATETIME,
-        longitude BIGINT,
-        latitude BIGINT,
-        heightType INT,
-        height INT,
-        altitude INT,
-        VS INT,
-        GS INT,
-        course INT,
-        SOC INT,
-        RM INT,
-		voltage INT,
-		current INT,
-        windSpeed INT,
-        windDirect INT,
-        temperture INT,
-        humidity INT
-    );`)
-	return err
-}
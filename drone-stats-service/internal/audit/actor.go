@@ -0,0 +1,54 @@
+package audit
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// SystemActor 非请求触发（如后台任务）时的发起人
+const SystemActor = "system"
+
+type actorKey struct{}
+
+// RequestActor 返回请求发起人：默认为 TCP 对端地址；trustUserHeader 为 true（前置网关已认证并覆盖该请求头）时
+// 优先取 X-User-ID 请求头
+func RequestActor(r *http.Request, trustUserHeader bool) string {
+	if trustUserHeader {
+		if u := strings.TrimSpace(r.Header.Get("X-User-ID")); u != "" {
+			return u
+		}
+	}
+	return PeerAddr(r)
+}
+
+// PeerAddr 返回 TCP 对端地址（不含端口），不读取 X-User-ID / X-Forwarded-For 等客户端可伪造的请求头
+func PeerAddr(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// NewMiddleware 返回将请求发起人写入 context 的中间件，供 logic 层记录审计日志，发起人规则见 RequestActor
+func NewMiddleware(trustUserHeader bool) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			next(w, r.WithContext(WithActor(r.Context(), RequestActor(r, trustUserHeader))))
+		}
+	}
+}
+
+// WithActor 返回携带发起人的 context
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// Actor 返回 context 中的发起人，没有时为 SystemActor
+func Actor(ctx context.Context) string {
+	if a, ok := ctx.Value(actorKey{}).(string); ok && a != "" {
+		return a
+	}
+	return SystemActor
+}
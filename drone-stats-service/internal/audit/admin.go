@@ -0,0 +1,22 @@
+package audit
+
+import (
+	"crypto/subtle"
+	"errors"
+)
+
+var (
+	ErrAdminDisabled = errors.New("admin operations are disabled: Audit.AdminToken is not configured")
+	ErrAdminToken    = errors.New("invalid admin token")
+)
+
+// CheckAdminToken 校验 X-Admin-Token 请求头；未配置 Audit.AdminToken 时所有管理操作均被拒绝
+func CheckAdminToken(configured, given string) error {
+	if configured == "" {
+		return ErrAdminDisabled
+	}
+	if subtle.ConstantTimeCompare([]byte(given), []byte(configured)) != 1 {
+		return ErrAdminToken
+	}
+	return nil
+}
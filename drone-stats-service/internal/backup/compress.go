@@ -0,0 +1,107 @@
+package backup
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// 压缩方式
+const (
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+	CompressionNone = "none"
+)
+
+// compressionExt 各压缩方式的文件扩展名
+func compressionExt(c string) (string, error) {
+	switch c {
+	case CompressionGzip:
+		return ".gz", nil
+	case CompressionZstd:
+		return ".zst", nil
+	case CompressionNone:
+		return "", nil
+	}
+	return "", fmt.Errorf("不支持的压缩方式 %s", c)
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// outputFile 压缩写出的备份文件，同时计算压缩后内容的 SHA256 与大小
+type outputFile struct {
+	name string
+	f    *os.File
+	hash hash.Hash
+	size int64
+	zw   io.WriteCloser
+	bw   *bufio.Writer
+}
+
+// createOutput 在 dir 下创建 base + 压缩扩展名的文件
+func createOutput(dir, base, compression string) (*outputFile, error) {
+	ext, err := compressionExt(compression)
+	if err != nil {
+		return nil, err
+	}
+	o := &outputFile{name: base + ext, hash: sha256.New()}
+	if o.f, err = os.Create(filepath.Join(dir, o.name)); err != nil {
+		return nil, err
+	}
+	sink := io.MultiWriter(o.f, o.hash, counter{&o.size})
+	switch compression {
+	case CompressionGzip:
+		o.zw = gzip.NewWriter(sink)
+	case CompressionZstd:
+		if o.zw, err = zstd.NewWriter(sink); err != nil {
+			o.f.Close()
+			return nil, err
+		}
+	default:
+		o.zw = nopWriteCloser{sink}
+	}
+	o.bw = bufio.NewWriterSize(o.zw, 256<<10)
+	return o, nil
+}
+
+func (o *outputFile) Write(p []byte) (int, error) { return o.bw.Write(p) }
+
+func (o *outputFile) WriteString(s string) (int, error) { return o.bw.WriteString(s) }
+
+// Close 刷新并关闭文件，返回文件清单项
+func (o *outputFile) Close() (FileManifest, error) {
+	err := o.bw.Flush()
+	if cerr := o.zw.Close(); err == nil {
+		err = cerr
+	}
+	if serr := o.f.Sync(); err == nil {
+		err = serr
+	}
+	if cerr := o.f.Close(); err == nil {
+		err = cerr
+	}
+	return FileManifest{Name: o.name, Size: o.size, SHA256: hex.EncodeToString(o.hash.Sum(nil))}, err
+}
+
+// abort 放弃写出，关闭文件（由调用方删除目录）
+func (o *outputFile) abort() {
+	o.zw.Close()
+	o.f.Close()
+}
+
+type counter struct{ n *int64 }
+
+func (c counter) Write(p []byte) (int, error) {
+	*c.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,337 @@
+package backup
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lpPoint line-protocol 中的一个点：一行包含同一 measurement、tag 集合和时间戳下的全部字段
+type lpPoint struct {
+	Measurement string
+	Tags        map[string]string
+	Fields      map[string]interface{} // int64 | uint64 | float64 | string | bool
+	Time        time.Time
+}
+
+// fluxMetaColumns 查询结果中不属于 tag 或字段的列
+var fluxMetaColumns = map[string]bool{
+	"result":       true,
+	"table":        true,
+	"_start":       true,
+	"_stop":        true,
+	"_time":        true,
+	"_measurement": true,
+}
+
+// recordPoint 将按 _time 透视后的一行查询结果转为点：tagKeys 中的列为 tag，其余非元数据列为字段，
+// 空值（该时间点没有该字段或 tag）被忽略
+func recordPoint(measurement string, tagKeys map[string]bool, values map[string]interface{}, t time.Time) lpPoint {
+	p := lpPoint{Measurement: measurement, Tags: map[string]string{}, Fields: map[string]interface{}{}, Time: t}
+	for k, v := range values {
+		if v == nil || fluxMetaColumns[k] {
+			continue
+		}
+		if tagKeys[k] {
+			if s, ok := v.(string); ok && s != "" {
+				p.Tags[k] = s
+			}
+			continue
+		}
+		p.Fields[k] = v
+	}
+	return p
+}
+
+// appendLine 按 line-protocol 规范编码一个点（含结尾换行），tag 与字段按 key 排序。
+// 整数写为 i、无符号整数写为 u 后缀，浮点数不带后缀，保证导入后类型不变。
+// 无法表示的字段（NaN/Inf、不支持的类型）被丢弃，返回写入的字段数；key 或 tag 中含换行、或没有可写字段时返回错误
+func appendLine(buf []byte, p lpPoint) ([]byte, int, error) {
+	if p.Measurement == "" || strings.HasPrefix(p.Measurement, "#") {
+		return buf, 0, fmt.Errorf("measurement 无效: %q", p.Measurement)
+	}
+	if strings.ContainsAny(p.Measurement, "\r\n") {
+		return buf, 0, fmt.Errorf("measurement 含换行: %q", p.Measurement)
+	}
+	start := len(buf)
+	buf = appendEscaped(buf, p.Measurement, ", ")
+	for _, k := range sortedKeys(p.Tags) {
+		v := p.Tags[k]
+		if strings.ContainsAny(k+v, "\r\n") {
+			return buf[:start], 0, fmt.Errorf("tag %s 含换行", k)
+		}
+		buf = append(buf, ',')
+		buf = appendEscaped(buf, k, ",= ")
+		buf = append(buf, '=')
+		buf = appendEscaped(buf, v, ",= ")
+	}
+	n := 0
+	for _, k := range sortedKeys(p.Fields) {
+		if strings.ContainsAny(k, "\r\n") {
+			return buf[:start], 0, fmt.Errorf("字段 %s 含换行", k)
+		}
+		mark := len(buf)
+		if n == 0 {
+			buf = append(buf, ' ')
+		} else {
+			buf = append(buf, ',')
+		}
+		buf = appendEscaped(buf, k, ",= ")
+		buf = append(buf, '=')
+		var ok bool
+		if buf, ok = appendFieldValue(buf, p.Fields[k]); !ok {
+			buf = buf[:mark]
+			continue
+		}
+		n++
+	}
+	if n == 0 {
+		return buf[:start], 0, fmt.Errorf("没有可写入的字段")
+	}
+	buf = append(buf, ' ')
+	buf = strconv.AppendInt(buf, p.Time.UnixNano(), 10)
+	return append(buf, '\n'), n, nil
+}
+
+func appendFieldValue(buf []byte, v interface{}) ([]byte, bool) {
+	switch val := v.(type) {
+	case int64:
+		return append(strconv.AppendInt(buf, val, 10), 'i'), true
+	case uint64:
+		return append(strconv.AppendUint(buf, val, 10), 'u'), true
+	case float64:
+		if math.IsNaN(val) || math.IsInf(val, 0) {
+			return buf, false
+		}
+		return strconv.AppendFloat(buf, val, 'f', -1, 64), true
+	case bool:
+		return strconv.AppendBool(buf, val), true
+	case string:
+		buf = append(buf, '"')
+		for i := 0; i < len(val); i++ {
+			if val[i] == '"' || val[i] == '\\' {
+				buf = append(buf, '\\')
+			}
+			buf = append(buf, val[i])
+		}
+		return append(buf, '"'), true
+	}
+	return buf, false
+}
+
+// appendEscaped 以反斜杠转义 s 中属于 special 的字符
+func appendEscaped(buf []byte, s, special string) []byte {
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(special, s[i]) >= 0 {
+			buf = append(buf, '\\')
+		}
+		buf = append(buf, s[i])
+	}
+	return buf
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// scanLines 逐行读取 line-protocol，字符串字段值中的换行不视为行结束；跳过空行与注释行
+func scanLines(r io.Reader, fn func(line string) error) error {
+	br := bufio.NewReaderSize(r, 1<<20)
+	var (
+		line    []byte
+		section int // 0 measurement/tag，1 字段，2 时间戳
+		inStr   bool
+		escaped bool
+	)
+	emit := func() error {
+		s := strings.TrimRight(string(line), "\r")
+		line, section, inStr, escaped = line[:0], 0, false, false
+		if strings.TrimSpace(s) == "" || strings.HasPrefix(s, "#") {
+			return nil
+		}
+		return fn(s)
+	}
+	for {
+		c, err := br.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if c == '\n' && !inStr {
+			if err := emit(); err != nil {
+				return err
+			}
+			continue
+		}
+		line = append(line, c)
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case section == 1 && c == '"':
+			inStr = !inStr
+		case c == ' ' && !inStr && section < 2:
+			section++
+		}
+	}
+	if inStr {
+		return fmt.Errorf("line-protocol 不完整：字符串未闭合")
+	}
+	return emit()
+}
+
+// parseLine 解析一行 line-protocol，时间戳为纳秒
+func parseLine(line string) (lpPoint, error) {
+	p := lpPoint{Tags: map[string]string{}, Fields: map[string]interface{}{}}
+	series, rest, ok := cutUnescaped(line, ' ')
+	if !ok {
+		return p, fmt.Errorf("缺少字段")
+	}
+	parts := splitUnescaped(series, ',')
+	p.Measurement = unescapeLP(parts[0])
+	for _, t := range parts[1:] {
+		k, v, ok := cutUnescaped(t, '=')
+		if !ok {
+			return p, fmt.Errorf("tag 格式错误: %s", t)
+		}
+		p.Tags[unescapeLP(k)] = unescapeLP(v)
+	}
+
+	// 字段：key=value，以未转义的逗号分隔；字符串值中的逗号、空格不分隔
+	i := 0
+	for {
+		k, _, ok := cutUnescaped(rest[i:], '=')
+		if !ok {
+			return p, fmt.Errorf("字段格式错误")
+		}
+		i += len(k) + 1
+		var raw string
+		if i < len(rest) && rest[i] == '"' {
+			j := i + 1
+			for ; j < len(rest) && rest[j] != '"'; j++ {
+				if rest[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(rest) {
+				return p, fmt.Errorf("字符串字段未闭合")
+			}
+			raw = rest[i : j+1]
+			i = j + 1
+		} else {
+			j := strings.IndexAny(rest[i:], ", ")
+			if j < 0 {
+				j = len(rest) - i
+			}
+			raw = rest[i : i+j]
+			i += j
+		}
+		v, err := parseFieldValue(raw)
+		if err != nil {
+			return p, fmt.Errorf("字段 %s: %w", k, err)
+		}
+		p.Fields[unescapeLP(k)] = v
+		if i >= len(rest) || rest[i] == ' ' {
+			break
+		}
+		i++ // ','
+	}
+	if i >= len(rest) {
+		return p, fmt.Errorf("缺少时间戳")
+	}
+	ts, err := strconv.ParseInt(strings.TrimSpace(rest[i+1:]), 10, 64)
+	if err != nil {
+		return p, fmt.Errorf("时间戳无效: %s", rest[i+1:])
+	}
+	p.Time = time.Unix(0, ts)
+	return p, nil
+}
+
+func parseFieldValue(raw string) (interface{}, error) {
+	switch {
+	case raw == "":
+		return nil, fmt.Errorf("值为空")
+	case raw[0] == '"':
+		var b strings.Builder
+		for i := 1; i < len(raw)-1; i++ {
+			if raw[i] == '\\' && i+1 < len(raw)-1 && (raw[i+1] == '"' || raw[i+1] == '\\') {
+				i++
+			}
+			b.WriteByte(raw[i])
+		}
+		return b.String(), nil
+	case strings.HasSuffix(raw, "i"):
+		return strconv.ParseInt(raw[:len(raw)-1], 10, 64)
+	case strings.HasSuffix(raw, "u"):
+		return strconv.ParseUint(raw[:len(raw)-1], 10, 64)
+	}
+	switch raw {
+	case "t", "T", "true", "True", "TRUE":
+		return true, nil
+	case "f", "F", "false", "False", "FALSE":
+		return false, nil
+	}
+	return strconv.ParseFloat(raw, 64)
+}
+
+// unescapeLP 去掉 measurement、tag 与字段 key 中的反斜杠转义
+func unescapeLP(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && strings.IndexByte(",= ", s[i+1]) >= 0 {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// fieldKeys 返回点中每个字段值的唯一键（measurement + 排序后的 tag + 字段 + 时间戳），用于重叠区间去重。
+// 名称中不含换行（导出时已排除），以换行分隔各部分
+func (p lpPoint) fieldKeys() []string {
+	var b strings.Builder
+	b.WriteString(p.Measurement)
+	for _, k := range sortedKeys(p.Tags) {
+		b.WriteString("\n" + k + "\n" + p.Tags[k])
+	}
+	series, ts := b.String(), strconv.FormatInt(p.Time.UnixNano(), 10)
+	keys := make([]string, 0, len(p.Fields))
+	for k := range p.Fields {
+		keys = append(keys, series+"\n"+k+"\n"+ts)
+	}
+	return keys
+}
+
+// ReadLineProtocolFile 逐点读取 line-protocol 文件（按扩展名解压 .gz/.zst），如 Influx 备份文件
+func ReadLineProtocolFile(path string, fn func(measurement string, tags map[string]string, fields map[string]interface{}, t time.Time) error) error {
+	r, err := openBackupFile(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	n := 0
+	return scanLines(r, func(line string) error {
+		n++
+		p, err := parseLine(line)
+		if err != nil {
+			return fmt.Errorf("第 %d 个点: %w", n, err)
+		}
+		return fn(p.Measurement, p.Tags, p.Fields, p.Time)
+	})
+}
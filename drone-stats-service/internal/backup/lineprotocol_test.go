@@ -0,0 +1,190 @@
+package backup
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	protocol "github.com/influxdata/line-protocol"
+)
+
+var lpTestPoints = []lpPoint{
+	{
+		Measurement: "flight_telemetry",
+		Tags:        map[string]string{"drone_id": "D-001", "flight_id": "F1"},
+		Fields: map[string]interface{}{
+			"altitude":  float64(120.5),
+			"speed":     float64(12),
+			"battery":   int64(87),
+			"satellite": uint64(18),
+			"armed":     true,
+			"mode":      "AUTO",
+		},
+		Time: time.Unix(1700000000, 123456789),
+	},
+	{
+		// 需转义的 measurement、tag、字段 key 与字符串值
+		Measurement: "my measurement,v2",
+		Tags:        map[string]string{"tag key": "a=b,c d", "区域": "华东 1"},
+		Fields: map[string]interface{}{
+			"field,key=x": `say "hi" \ bye`,
+			"multi line":  "line1\nline2, with space",
+			"empty":       "",
+			"trailing":    `ends with \`,
+		},
+		Time: time.Unix(0, 1),
+	},
+	{
+		Measurement: "limits",
+		Tags:        map[string]string{},
+		Fields: map[string]interface{}{
+			"imin":   int64(math.MinInt64),
+			"imax":   int64(math.MaxInt64),
+			"umax":   uint64(math.MaxUint64),
+			"fsmall": 1e-300,
+			"fbig":   -1.7976931348623157e308,
+			"fint":   float64(3), // 不能被读成整数
+			"fzero":  float64(0),
+			"off":    false,
+		},
+		Time: time.Unix(-10, 0),
+	},
+}
+
+// TestLineProtocolRoundTrip 导出后重新导入（scanLines + parseLine，与恢复流程一致），比对 measurement、tag、字段类型与值
+func TestLineProtocolRoundTrip(t *testing.T) {
+	var buf []byte
+	wantFields := 0
+	for _, p := range lpTestPoints {
+		var n int
+		var err error
+		if buf, n, err = appendLine(buf, p); err != nil {
+			t.Fatalf("encode %s: %v", p.Measurement, err)
+		}
+		if n != len(p.Fields) {
+			t.Fatalf("encode %s: wrote %d fields, want %d", p.Measurement, n, len(p.Fields))
+		}
+		wantFields += n
+	}
+
+	var got []lpPoint
+	err := scanLines(bytes.NewReader(buf), func(line string) error {
+		p, err := parseLine(line)
+		if err != nil {
+			t.Errorf("parse %q: %v", line, err)
+			return nil
+		}
+		got = append(got, p)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(lpTestPoints) {
+		t.Fatalf("got %d points, want %d:\n%s", len(got), len(lpTestPoints), buf)
+	}
+	gotFields := 0
+	for i, want := range lpTestPoints {
+		g := got[i]
+		if g.Measurement != want.Measurement || !g.Time.Equal(want.Time) || !reflect.DeepEqual(g.Tags, want.Tags) {
+			t.Errorf("point %d: got %s %v %v, want %s %v %v", i, g.Measurement, g.Tags, g.Time, want.Measurement, want.Tags, want.Time)
+		}
+		if !reflect.DeepEqual(g.Fields, want.Fields) {
+			t.Errorf("point %d fields:\n got %#v\nwant %#v", i, g.Fields, want.Fields)
+		}
+		gotFields += len(g.fieldKeys())
+	}
+	if gotFields != wantFields {
+		t.Errorf("got %d field values, want %d", gotFields, wantFields)
+	}
+}
+
+// TestLineProtocolSpec 用 Influx 的 line-protocol 解析器读回导出结果，确认转义与类型后缀符合规范
+func TestLineProtocolSpec(t *testing.T) {
+	var buf []byte
+	for _, p := range lpTestPoints {
+		var err error
+		if buf, _, err = appendLine(buf, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	metrics, err := protocol.NewParser(protocol.NewMetricHandler()).Parse(buf)
+	if err != nil {
+		t.Fatalf("parse: %v\n%s", err, buf)
+	}
+	if len(metrics) != len(lpTestPoints) {
+		t.Fatalf("got %d metrics, want %d", len(metrics), len(lpTestPoints))
+	}
+	for i, want := range lpTestPoints {
+		m := metrics[i]
+		tags := map[string]string{}
+		for _, tag := range m.TagList() {
+			tags[tag.Key] = tag.Value
+		}
+		fields := map[string]interface{}{}
+		for _, f := range m.FieldList() {
+			fields[f.Key] = f.Value
+		}
+		if m.Name() != want.Measurement || !m.Time().Equal(want.Time) || !reflect.DeepEqual(tags, want.Tags) {
+			t.Errorf("metric %d: got %s %v %v", i, m.Name(), tags, m.Time())
+		}
+		if !reflect.DeepEqual(fields, want.Fields) {
+			t.Errorf("metric %d fields:\n got %#v\nwant %#v", i, fields, want.Fields)
+		}
+	}
+}
+
+func TestLineProtocolSkipsUnrepresentable(t *testing.T) {
+	p := lpPoint{
+		Measurement: "m",
+		Tags:        map[string]string{},
+		Fields:      map[string]interface{}{"nan": math.NaN(), "inf": math.Inf(1), "ok": int64(1)},
+		Time:        time.Unix(1, 0),
+	}
+	line, n, err := appendLine(nil, p)
+	if err != nil || n != 1 || string(line) != "m ok=1i 1000000000\n" {
+		t.Fatalf("got %q, %d, %v", line, n, err)
+	}
+
+	p.Fields = map[string]interface{}{"nan": math.NaN()}
+	if line, _, err := appendLine([]byte("prev\n"), p); err == nil || string(line) != "prev\n" {
+		t.Fatalf("point without fields: got %q, %v", line, err)
+	}
+	p.Fields = map[string]interface{}{"v": int64(1)}
+	p.Tags = map[string]string{"t": "a\nb"}
+	if _, _, err := appendLine(nil, p); err == nil {
+		t.Fatal("tag with newline accepted")
+	}
+}
+
+func TestRecordPoint(t *testing.T) {
+	values := map[string]interface{}{
+		"result":       "_result",
+		"table":        int64(0),
+		"_start":       time.Unix(0, 0),
+		"_stop":        time.Unix(10, 0),
+		"_time":        time.Unix(5, 0),
+		"_measurement": "m",
+		"drone_id":     "D1",
+		"region":       nil,  // 该序列没有此 tag
+		"status":       "ok", // 字符串字段，不是 tag
+		"speed":        float64(3),
+		"count":        int64(2),
+		"missing":      nil, // 该时间点没有此字段
+	}
+	p := recordPoint("m", map[string]bool{"drone_id": true, "region": true}, values, time.Unix(5, 0))
+	if !reflect.DeepEqual(p.Tags, map[string]string{"drone_id": "D1"}) {
+		t.Errorf("tags = %v", p.Tags)
+	}
+	want := map[string]interface{}{"status": "ok", "speed": float64(3), "count": int64(2)}
+	if !reflect.DeepEqual(p.Fields, want) {
+		t.Errorf("fields = %v", p.Fields)
+	}
+	line, _, err := appendLine(nil, p)
+	if err != nil || !strings.HasPrefix(string(line), "m,drone_id=D1 count=2i,speed=3,status=\"ok\" ") {
+		t.Errorf("line = %q, %v", line, err)
+	}
+}
@@ -7,17 +7,47 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
 	"github.com/influxdata/influxdb-client-go/v2/api"
+
+	"drone-stats-service/internal/model"
+)
+
+// 备份管理器：负责导出 MySQL 为 SQL 文件，以及导出 Influx 数据为 line-protocol 文件，输出按配置压缩。
+// 备份以链组织：每 FullIntervalDays 天一次完整备份，其间每次为增量备份（MySQL 按高水位导出新增行，
+// Influx 导出上一次备份之后的数据）。每个备份目录中的 manifest.json 记录导出范围、行数/点数及文件校验和。
+// 恢复时按链的顺序（完整备份在前）依次导入，导出的 MySQL 文件可以通过管道导入到 mysql 容器：
+// gunzip -c backup_..._full/mysql.sql.gz | docker exec -i drone-mysql sh -c 'mysql -uroot -proot123456'
+// 导出的 Influx 为 line-protocol 文件，解压后可以通过 influx write 导入：
+// docker cp influx.lp influxdb:/tmp/influx.lp && docker exec -i influxdb influx write --bucket <bucket> --file /tmp/influx.lp --org <org> --token <token>
+
+const (
+	defaultFullIntervalDays = 7
+	// influxOverlap 增量导出 Influx 时向前重叠的时长，覆盖延迟入库的点；重复写入相同的点是幂等的
+	influxOverlap = 10 * time.Minute
+	// defaultIDOverlap 增量导出按自增 id 向前重叠的 id 数：快照时尚未提交的事务可能持有更小的 id，
+	// 提交后落在上一次高水位之下；重叠部分以 REPLACE 写入，重复导入是幂等的
+	defaultIDOverlap = 10000
+	// sqlBatchSize 每条 INSERT/REPLACE 语句包含的行数
+	sqlBatchSize = 500
 )
 
-// 备份管理器：负责导出 MySQL 为 SQL 文件，以及导出 Influx 数据为 line-protocol 文件。
-// 导出的 MySQL 文件可以通过管道导入到 mysql 容器：
-// cat mysql_...sql | docker exec -i drone-mysql sh -c 'mysql -uroot -proot123456'
-// 导出的 Influx 为 line-protocol 文件，可以通过 influx write 导入：
-// docker cp backups/influx_... influxdb:/tmp/influx_lp && docker exec -i influxdb influx write --bucket <bucket> --file /tmp/influx_lp/points.lp --org <org> --token <token>
+// defaultRewriteTables 增量备份中默认整表重写的表：行会被原地更新或删除，且数据量较小
+var defaultRewriteTables = []string{
+	"maintenance_plans",
+	"report_schedules",
+	"report_runs",
+	"flight_battery_metrics",
+	"flight_track_cleaning",
+}
+
+// auditedTables 行被原地修改时会写入 audit_logs 的表及对应的审计实体，增量备份时据此重新导出高水位之前的已修改行
+var auditedTables = map[string]string{
+	"flight_records": model.AuditEntityFlightRecord,
+}
 
 type Manager struct {
 	MySQLDB       *sql.DB
@@ -27,152 +57,457 @@ type Manager struct {
 	InfluxBucket  string
 	BackupDir     string
 	RetentionDays int
+
+	FullIntervalDays int      // 完整备份间隔天数，其间执行增量备份，默认 7
+	Compression      string   // gzip | zstd | none，默认 gzip
+	RewriteTables    []string // 增量备份中整表重写的表，为空时使用 defaultRewriteTables
+	IDOverlap        int64    // 增量备份按自增 id 向前重叠的 id 数，为 0 时使用 defaultIDOverlap
+	Targets          []Target // 备份完成后上传到的存储目标，BackupDir 作为本地暂存及默认副本
+
+	runMu   sync.Mutex // 保证同一时间只有一个备份在执行（定时、手动、退出前备份及删除互斥）
+	stateMu sync.Mutex // 保护 current / last
+	current *RunStatus
+	last    *RunStatus
 }
 
-// NewManager 创建备份管理器
+// NewManager 创建备份管理器。mysqlDB 或 influxClient 为 nil（使用嵌入式存储）时不支持备份，见 Supported
 func NewManager(mysqlDB *sql.DB, influxClient influxdb2.Client, influxOrg, influxBucket, backupDir string, retention int) *Manager {
+	var query api.QueryAPI
+	if influxClient != nil {
+		query = influxClient.QueryAPI(influxOrg)
+	}
 	return &Manager{
-		MySQLDB:       mysqlDB,
-		InfluxClient:  influxClient,
-		InfluxQuery:   influxClient.QueryAPI(influxOrg),
-		InfluxOrg:     influxOrg,
-		InfluxBucket:  influxBucket,
-		BackupDir:     backupDir,
-		RetentionDays: retention,
+		MySQLDB:          mysqlDB,
+		InfluxClient:     influxClient,
+		InfluxQuery:      query,
+		InfluxOrg:        influxOrg,
+		InfluxBucket:     influxBucket,
+		BackupDir:        backupDir,
+		RetentionDays:    retention,
+		FullIntervalDays: defaultFullIntervalDays,
+		Compression:      CompressionGzip,
 	}
 }
 
-// BackupOnce 执行一次完整备份（MySQL SQL + Influx line-protocol）
+// Supported 是否可以备份：备份需同时导出 MySQL 与 InfluxDB
+func (m *Manager) Supported() bool {
+	return m.MySQLDB != nil && m.InfluxClient != nil
+}
+
+// BackupOnce 定时备份：距最近一次完整备份不足 FullIntervalDays 天时为增量备份，否则为完整备份；
+// 已有备份正在执行时直接返回 ErrBackupRunning
 func (m *Manager) BackupOnce(ctx context.Context) error {
-	ts := time.Now().Format("20060102_150405")
-	targetDir := filepath.Join(m.BackupDir, fmt.Sprintf("backup_%s", ts))
-	if err := os.MkdirAll(targetDir, 0755); err != nil {
-		return err
+	_, err := m.TryBackup(ctx, "", TriggerSchedule)
+	return err
+}
+
+// backup 执行一次指定类型的备份（full | incremental，为空时自动选择），返回备份清单。
+// 失败时删除本次备份目录，不影响已有的备份链。调用方需持有 runMu。
+func (m *Manager) backup(ctx context.Context, typ string, started func(mf *Manifest)) (*Manifest, error) {
+	if !m.Supported() {
+		return nil, fmt.Errorf("当前存储后端不支持备份，仅支持 MySQL + InfluxDB")
+	}
+	compression := m.Compression
+	if compression == "" {
+		compression = CompressionGzip
+	}
+	if _, err := compressionExt(compression); err != nil {
+		return nil, err
+	}
+	list, err := m.ListManifests()
+	if err != nil {
+		return nil, err
+	}
+	parent, base := m.chainTip(list)
+	fullInterval := m.FullIntervalDays
+	if fullInterval <= 0 {
+		fullInterval = defaultFullIntervalDays
+	}
+	switch typ {
+	case "":
+		typ = TypeFull
+		if parent != nil && time.Since(base.StartedAt) < time.Duration(fullInterval)*24*time.Hour {
+			typ = TypeIncremental
+		}
+	case TypeIncremental:
+		if parent == nil {
+			return nil, fmt.Errorf("没有可用的备份链，无法执行增量备份")
+		}
+	case TypeFull:
+	default:
+		return nil, fmt.Errorf("不支持的备份类型 %s", typ)
+	}
+	if typ == TypeFull {
+		parent = nil
+	}
+
+	start := time.Now()
+	suffix := "full"
+	if typ == TypeIncremental {
+		suffix = "incr"
+	}
+	mf := &Manifest{
+		ID:          fmt.Sprintf("backup_%s_%s", start.Format("20060102_150405"), suffix),
+		Type:        typ,
+		Compression: compression,
+		StartedAt:   start,
+	}
+	if parent != nil {
+		mf.Base, mf.Parent = parent.Base, parent.ID
+	} else {
+		mf.Base = mf.ID
 	}
+	if err := os.MkdirAll(m.BackupDir, 0755); err != nil {
+		return nil, err
+	}
+	targetDir := filepath.Join(m.BackupDir, mf.ID)
+	if err := os.Mkdir(targetDir, 0755); err != nil {
+		return nil, err
+	}
+	started(mf)
+	done := false
+	defer func() {
+		if !done {
+			_ = os.RemoveAll(targetDir)
+		}
+	}()
 
 	// 1) 导出 MySQL
-	sqlFile := filepath.Join(targetDir, fmt.Sprintf("mysql_%s.sql", ts))
-	if err := m.dumpMySQL(sqlFile); err != nil {
-		return fmt.Errorf("mysql dump failed: %w", err)
+	if err := m.dumpMySQL(ctx, targetDir, mf, parent); err != nil {
+		return nil, fmt.Errorf("mysql dump failed: %w", err)
 	}
 
 	// 2) 导出 Influx 为 line-protocol
-	lpFile := filepath.Join(targetDir, fmt.Sprintf("influx_%s.lp", ts))
-	if err := m.exportInfluxLP(ctx, lpFile); err != nil {
-		return fmt.Errorf("influx export failed: %w", err)
+	if err := m.exportInfluxLP(ctx, targetDir, mf, parent); err != nil {
+		return nil, fmt.Errorf("influx export failed: %w", err)
 	}
 
-	// 3) 清理过期备份
+	// 3) 写入清单，标记备份成功
+	mf.FinishedAt = time.Now()
+	if err := writeManifest(targetDir, mf); err != nil {
+		return nil, err
+	}
+	done = true
+
+	// 4) 清理过期备份
 	if err := m.cleanupOld(); err != nil {
-		return fmt.Errorf("cleanup failed: %w", err)
+		return mf, fmt.Errorf("cleanup failed: %w", err)
 	}
+	return mf, nil
+}
 
-	return nil
+// chainTip 返回最近一个备份链完整、且 bucket 与当前配置一致的备份及其所在链的完整备份
+func (m *Manager) chainTip(list []*Manifest) (*Manifest, *Manifest) {
+	for i := len(list) - 1; i >= 0; i-- {
+		if list[i].Influx.Bucket != m.InfluxBucket {
+			continue
+		}
+		chain, err := Chain(list, list[i].ID)
+		if err != nil {
+			continue
+		}
+		return list[i], chain[0]
+	}
+	return nil, nil
 }
 
-// dumpMySQL 导出当前数据库的 schema + data 为可直接导入的 SQL 文件
-func (m *Manager) dumpMySQL(outPath string) error {
-	f, err := os.Create(outPath)
+// dumpMySQL 导出当前数据库为可直接导入的 SQL 文件。完整备份包含 schema + 全部数据；
+// 增量备份只包含各表高水位之后的行，以 REPLACE 写入，可在上一个备份恢复后直接导入。
+// 所有表在同一个只读事务中读取，数据与高水位来自同一个一致性快照。
+func (m *Manager) dumpMySQL(ctx context.Context, dir string, mf *Manifest, parent *Manifest) error {
+	tx, err := m.MySQLDB.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	out, err := createOutput(dir, "mysql.sql", mf.Compression)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
+	closed := false
+	defer func() {
+		if !closed {
+			out.abort()
+		}
+	}()
 
 	// 基本 header
-	f.WriteString("SET FOREIGN_KEY_CHECKS=0;\n")
+	out.WriteString("SET FOREIGN_KEY_CHECKS=0;\n")
 
 	// 获取当前数据库名
 	var dbName sql.NullString
-	if err := m.MySQLDB.QueryRow("SELECT DATABASE()").Scan(&dbName); err != nil {
+	if err := tx.QueryRow("SELECT DATABASE()").Scan(&dbName); err != nil {
 		return err
 	}
-	db := ""
-	if dbName.Valid {
-		db = dbName.String
-	}
-	if db != "" {
-		f.WriteString(fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s`;\nUSE `%s`;\n", db, db))
+	mf.MySQL.Database = dbName.String
+	if dbName.String != "" {
+		out.WriteString(fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s`;\nUSE `%s`;\n", dbName.String, dbName.String))
 	}
 
 	// 获取表列表
-	rows, err := m.MySQLDB.Query("SELECT TABLE_NAME FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = DATABASE()")
+	rows, err := tx.Query("SELECT TABLE_NAME FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_TYPE = 'BASE TABLE' ORDER BY TABLE_NAME")
 	if err != nil {
 		return err
 	}
-	defer rows.Close()
 	var tables []string
 	for rows.Next() {
 		var t string
-		if err := rows.Scan(&t); err == nil {
-			tables = append(tables, t)
+		if err := rows.Scan(&t); err != nil {
+			rows.Close()
+			return err
 		}
+		tables = append(tables, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
 	}
 
-	// 导出每个表的 CREATE 和 INSERT
-	for _, t := range tables {
-		// SHOW CREATE TABLE
-		var table string
-		var createSQL string
-		if err := m.MySQLDB.QueryRow(fmt.Sprintf("SHOW CREATE TABLE `%s`", t)).Scan(&table, &createSQL); err != nil {
-			return err
+	// audit_logs 在本次备份覆盖的 id 范围（与行一样向前重叠），用于找出高水位之前被原地修改的行
+	var audit auditRange
+	if parent != nil {
+		if t, ok := parent.Table("audit_logs"); ok && t.Watermark == WatermarkID {
+			audit.from = overlapFrom(t.ToID, m.idOverlap())
+		}
+		for _, t := range tables {
+			if t == "audit_logs" {
+				if err := tx.QueryRow("SELECT IFNULL(MAX(id), 0) FROM audit_logs").Scan(&audit.to); err != nil {
+					return err
+				}
+				audit.valid = true
+			}
 		}
-		f.WriteString(fmt.Sprintf("DROP TABLE IF EXISTS `%s`;\n", t))
-		f.WriteString(createSQL + ";\n\n")
+	}
+	rewrite := make(map[string]bool)
+	rewriteTables := m.RewriteTables
+	if len(rewriteTables) == 0 {
+		rewriteTables = defaultRewriteTables
+	}
+	for _, t := range rewriteTables {
+		rewrite[t] = true
+	}
 
-		// 导出数据（批量 INSERT）
-		q := fmt.Sprintf("SELECT * FROM `%s`", t)
-		r, err := m.MySQLDB.Query(q)
-		if err != nil {
-			return err
+	for _, t := range tables {
+		var prev *TableManifest
+		if parent != nil {
+			if pt, ok := parent.Table(t); ok {
+				prev = &pt
+			}
 		}
-		cols, err := r.Columns()
+		tm, err := m.dumpTable(tx, out, t, parent != nil, rewrite[t], prev, audit)
 		if err != nil {
-			r.Close()
-			return err
+			return fmt.Errorf("table %s: %w", t, err)
 		}
-		colCount := len(cols)
-		batchSize := 500
-		vals := make([]interface{}, colCount)
-		ptrs := make([]interface{}, colCount)
-		for i := range vals {
-			ptrs[i] = &vals[i]
-		}
-		inserts := 0
-		var valueBuf strings.Builder
-		for r.Next() {
-			if err := r.Scan(ptrs...); err != nil {
-				r.Close()
-				return err
-			}
-			// build value tuple
-			if inserts%batchSize == 0 {
-				if inserts != 0 {
-					// flush previous
-					f.WriteString(fmt.Sprintf("INSERT INTO `%s` (%s) VALUES %s;\n", t, strings.Join(cols, ", "), valueBuf.String()))
-					valueBuf.Reset()
+		mf.MySQL.Tables = append(mf.MySQL.Tables, tm)
+	}
+
+	out.WriteString("SET FOREIGN_KEY_CHECKS=1;\n")
+	closed = true
+	fm, err := out.Close()
+	if err != nil {
+		return err
+	}
+	mf.MySQL.File = fm.Name
+	mf.Files = append(mf.Files, fm)
+	return nil
+}
+
+// auditRange audit_logs 的 id 范围 (from, to]
+type auditRange struct {
+	valid    bool
+	from, to int64
+}
+
+// dumpTable 导出单个表。incremental 为 false 时输出 DROP + CREATE + 全部数据；
+// 为 true 时输出 CREATE TABLE IF NOT EXISTS，之后整表重写或按 prev 的高水位导出新增行（自增 id 向前重叠 IDOverlap）
+func (m *Manager) dumpTable(tx *sql.Tx, out *outputFile, table string, incremental, rewrite bool, prev *TableManifest, audit auditRange) (TableManifest, error) {
+	tm := TableManifest{Name: table}
+	var createSQL string
+	if err := tx.QueryRow(fmt.Sprintf("SHOW CREATE TABLE `%s`", table)).Scan(&table, &createSQL); err != nil {
+		return tm, err
+	}
+	watermark, err := tableWatermark(tx, table)
+	if err != nil {
+		return tm, err
+	}
+
+	// 本次快照中的高水位，完整备份与增量备份都只导出不超过该值的行
+	var (
+		cond  string
+		args  []interface{}
+		toID  int64
+		toT   sql.NullTime
+		where string
+	)
+	switch watermark {
+	case WatermarkID:
+		if err := tx.QueryRow(fmt.Sprintf("SELECT IFNULL(MAX(id), 0) FROM `%s`", table)).Scan(&toID); err != nil {
+			return tm, err
+		}
+		tm.Watermark, tm.ToID = watermark, toID
+		where, args = "id <= ?", []interface{}{toID}
+	case WatermarkCreatedAt:
+		if err := tx.QueryRow(fmt.Sprintf("SELECT MAX(created_at) FROM `%s`", table)).Scan(&toT); err != nil {
+			return tm, err
+		}
+		tm.Watermark = watermark
+		if toT.Valid {
+			tm.ToTime = &toT.Time
+			where, args = "created_at <= ?", []interface{}{toT.Time}
+		}
+	}
+
+	if !incremental {
+		tm.Mode = TableModeFull
+		out.WriteString(fmt.Sprintf("DROP TABLE IF EXISTS `%s`;\n", table))
+		out.WriteString(createSQL + ";\n\n")
+		tm.Rows, err = writeRows(tx, out, table, "INSERT", where, args)
+		return tm, err
+	}
+
+	out.WriteString(strings.Replace(createSQL, "CREATE TABLE", "CREATE TABLE IF NOT EXISTS", 1) + ";\n\n")
+	if rewrite || watermark == "" {
+		tm.Mode = TableModeRewrite
+		out.WriteString(fmt.Sprintf("DELETE FROM `%s`;\n", table))
+		tm.Rows, err = writeRows(tx, out, table, "INSERT", where, args)
+		return tm, err
+	}
+
+	// 上一个备份没有该表或高水位列不同时，导出全部行
+	tm.Mode = TableModeIncremental
+	cond = where
+	if prev != nil && prev.Watermark == watermark {
+		switch {
+		case watermark == WatermarkID && prev.ToID > 0 && prev.ToID <= toID:
+			tm.FromID = overlapFrom(prev.ToID, m.idOverlap())
+			cond, args = "id > ? AND id <= ?", []interface{}{tm.FromID, toID}
+		case watermark == WatermarkCreatedAt && prev.ToTime != nil && toT.Valid:
+			tm.FromTime = prev.ToTime
+			cond, args = "created_at > ? AND created_at <= ?", []interface{}{*prev.ToTime, toT.Time}
+		}
+	}
+	if tm.Rows, err = writeRows(tx, out, table, "REPLACE", cond, args); err != nil {
+		return tm, err
+	}
+
+	// 高水位之前、之后被原地修改过的行（依据 audit_logs）
+	if entity, ok := auditedTables[table]; ok && audit.valid && tm.FromID > 0 && audit.to > audit.from {
+		tm.UpdatedRows, err = writeRows(tx, out, table, "REPLACE",
+			"id <= ? AND id IN (SELECT CAST(entity_id AS UNSIGNED) FROM audit_logs WHERE entity = ? AND id > ? AND id <= ?)",
+			[]interface{}{tm.FromID, entity, audit.from, audit.to})
+	}
+	// 撤销修改时原审计条目的 reverted_by 被原地更新，其值为新写入的撤销条目 id
+	if table == "audit_logs" && tm.FromID > 0 && err == nil {
+		var n int64
+		n, err = writeRows(tx, out, table, "REPLACE", "id <= ? AND reverted_by > ?", []interface{}{tm.FromID, tm.FromID})
+		tm.UpdatedRows += n
+	}
+	return tm, err
+}
+
+func (m *Manager) idOverlap() int64 {
+	if m.IDOverlap > 0 {
+		return m.IDOverlap
+	}
+	return defaultIDOverlap
+}
+
+// overlapFrom 上一次高水位向前重叠 overlap 后的起点（不含），不小于 0
+func overlapFrom(toID, overlap int64) int64 {
+	if toID > overlap {
+		return toID - overlap
+	}
+	return 0
+}
+
+// tableWatermark 返回表的高水位列：自增 id 优先，其次 created_at，都没有时为空
+func tableWatermark(tx *sql.Tx, table string) (string, error) {
+	rows, err := tx.Query(`SELECT COLUMN_NAME, EXTRA FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?`, table)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	watermark := ""
+	for rows.Next() {
+		var col, extra string
+		if err := rows.Scan(&col, &extra); err != nil {
+			return "", err
+		}
+		switch {
+		case col == "id" && strings.Contains(strings.ToLower(extra), "auto_increment"):
+			watermark = WatermarkID
+		case col == "created_at" && watermark == "":
+			watermark = WatermarkCreatedAt
+		}
+	}
+	return watermark, rows.Err()
+}
+
+// writeRows 以 verb（INSERT | REPLACE）批量语句写出满足 cond 的行，返回行数
+func writeRows(tx *sql.Tx, out *outputFile, table, verb, cond string, args []interface{}) (int64, error) {
+	q := fmt.Sprintf("SELECT * FROM `%s`", table)
+	if cond != "" {
+		q += " WHERE " + cond
+	}
+	r, err := tx.Query(q, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+	cols, err := r.Columns()
+	if err != nil {
+		return 0, err
+	}
+	quoted := make([]string, len(cols))
+	for i, c := range cols {
+		quoted[i] = "`" + c + "`"
+	}
+	head := fmt.Sprintf("%s INTO `%s` (%s) VALUES ", verb, table, strings.Join(quoted, ", "))
+	vals := make([]interface{}, len(cols))
+	ptrs := make([]interface{}, len(cols))
+	for i := range vals {
+		ptrs[i] = &vals[i]
+	}
+	var (
+		n        int64
+		valueBuf strings.Builder
+	)
+	for r.Next() {
+		if err := r.Scan(ptrs...); err != nil {
+			return n, err
+		}
+		if n%sqlBatchSize == 0 {
+			if n != 0 {
+				// flush previous
+				if _, err := out.WriteString(head + valueBuf.String() + ";\n"); err != nil {
+					return n, err
 				}
-			} else {
-				valueBuf.WriteString(",")
+				valueBuf.Reset()
 			}
-			valueBuf.WriteString("(")
-			for i := 0; i < colCount; i++ {
-				if i > 0 {
-					valueBuf.WriteString(",")
-				}
-				valueBuf.WriteString(escapeSQLValue(vals[i]))
+		} else {
+			valueBuf.WriteString(",")
+		}
+		valueBuf.WriteString("(")
+		for i := range vals {
+			if i > 0 {
+				valueBuf.WriteString(",")
 			}
-			valueBuf.WriteString(")")
-			inserts++
+			valueBuf.WriteString(escapeSQLValue(vals[i]))
 		}
-		if inserts > 0 {
-			// flush remaining
-			f.WriteString(fmt.Sprintf("INSERT INTO `%s` (%s) VALUES %s;\n\n", t, strings.Join(cols, ", "), valueBuf.String()))
+		valueBuf.WriteString(")")
+		n++
+	}
+	if err := r.Err(); err != nil {
+		return n, err
+	}
+	if n > 0 {
+		// flush remaining
+		if _, err := out.WriteString(head + valueBuf.String() + ";\n\n"); err != nil {
+			return n, err
 		}
-		r.Close()
 	}
-
-	f.WriteString("SET FOREIGN_KEY_CHECKS=1;\n")
-	return nil
+	return n, nil
 }
 
 // escapeSQLValue 将单元格值转换为 SQL 字面量
@@ -213,16 +548,69 @@ func quoteStringForSQL(s string) string {
 	return fmt.Sprintf("'%s'", s)
 }
 
-// exportInfluxLP 导出 Influx 数据为 line-protocol 文件
-func (m *Manager) exportInfluxLP(ctx context.Context, outPath string) error {
-	f, err := os.Create(outPath)
+// exportInfluxLP 导出 Influx 数据为 line-protocol 文件：完整备份从最早数据开始，
+// 增量备份从上一个备份的截止时间（向前重叠 influxOverlap）开始，截止到本次备份开始时刻
+func (m *Manager) exportInfluxLP(ctx context.Context, dir string, mf *Manifest, parent *Manifest) error {
+	mf.Influx.Bucket = m.InfluxBucket
+	mf.Influx.Start = time.Unix(0, 0).UTC()
+	if parent != nil {
+		mf.Influx.Start = parent.Influx.Stop.Add(-influxOverlap).UTC()
+	}
+	mf.Influx.Stop = mf.StartedAt.UTC()
+
+	out, err := createOutput(dir, "influx.lp", mf.Compression)
+	if err != nil {
+		return err
+	}
+	closed := false
+	defer func() {
+		if !closed {
+			out.abort()
+		}
+	}()
+
+	measurements, err := m.influxSchema(ctx, "measurements", mf.Influx, "")
+	if err != nil {
+		return fmt.Errorf("查询 measurement 失败: %w", err)
+	}
+	var buf []byte
+	for _, measurement := range measurements {
+		if err := m.exportMeasurement(ctx, out, &buf, mf, measurement); err != nil {
+			return fmt.Errorf("导出 %s 失败: %w", measurement, err)
+		}
+	}
+	closed = true
+	fm, err := out.Close()
 	if err != nil {
 		return err
 	}
-	defer f.Close()
+	mf.Influx.File = fm.Name
+	mf.Files = append(mf.Files, fm)
+	return nil
+}
 
-	// flux 查询：从最早数据开始导出 bucket 全量数据
-	flux := fmt.Sprintf(`from(bucket: "%s") |> range(start: 0)`, m.InfluxBucket)
+// exportMeasurement 按 tag key 元数据区分 tag 与字段：以 measurement 和全部 tag 分组、
+// 按 _time 透视，使同一时间点的全部字段写在一行
+func (m *Manager) exportMeasurement(ctx context.Context, out *outputFile, buf *[]byte, mf *Manifest, measurement string) error {
+	keys, err := m.influxSchema(ctx, "measurementTagKeys", mf.Influx, measurement)
+	if err != nil {
+		return fmt.Errorf("查询 tag key 失败: %w", err)
+	}
+	tagKeys := make(map[string]bool)
+	group := []string{fluxString("_measurement")}
+	for _, k := range keys {
+		if !strings.HasPrefix(k, "_") {
+			tagKeys[k] = true
+			group = append(group, fluxString(k))
+		}
+	}
+	flux := fmt.Sprintf(`from(bucket: %s)
+  |> range(start: %s, stop: %s)
+  |> filter(fn: (r) => r._measurement == %s)
+  |> group(columns: [%s])
+  |> pivot(rowKey: ["_time"], columnKey: ["_field"], valueColumn: "_value")`,
+		fluxString(m.InfluxBucket), mf.Influx.Start.Format(time.RFC3339Nano), mf.Influx.Stop.Format(time.RFC3339Nano),
+		fluxString(measurement), strings.Join(group, ", "))
 	result, err := m.InfluxQuery.Query(ctx, flux)
 	if err != nil {
 		return err
@@ -231,93 +619,73 @@ func (m *Manager) exportInfluxLP(ctx context.Context, outPath string) error {
 
 	for result.Next() {
 		rec := result.Record()
-		// 构造 line-protocol：measurement,tags field=value timestamp
-		measurement := rec.Measurement()
-		if measurement == "" {
-			measurement = "measurement"
-		}
-		// tags: record.Values() 包含标签字段，排除内部字段
-		vals := rec.Values()
-		// 收集 tags
-		tags := make([]string, 0)
-		for k, v := range vals {
-			if strings.HasPrefix(k, "_") || k == "result" || k == "table" || k == "_value" || k == "_field" || k == "_time" {
-				continue
-			}
-			// 仅当值为 string 时作为 tag
-			if sv, ok := v.(string); ok {
-				tags = append(tags, fmt.Sprintf("%s=%s", sanitizeTag(k), sanitizeTag(sv)))
-			}
+		line, fields, err := appendLine((*buf)[:0], recordPoint(measurement, tagKeys, rec.Values(), rec.Time()))
+		*buf = line
+		if err != nil {
+			mf.Influx.Skipped++
+			continue
 		}
-		tagStr := ""
-		if len(tags) > 0 {
-			tagStr = "," + strings.Join(tags, ",")
-		}
-		// field
-		field := rec.Field()
-		value := rec.Value()
-		fieldVal := formatLPField(value)
-		ts := rec.Time().UnixNano()
-		line := fmt.Sprintf("%s%s %s=%s %d\n", measurement, tagStr, sanitizeField(field), fieldVal, ts)
-		if _, err := f.WriteString(line); err != nil {
+		if _, err := out.Write(line); err != nil {
 			return err
 		}
+		mf.Influx.Points++
+		mf.Influx.Fields += int64(fields)
 	}
-	if result.Err() != nil {
-		return result.Err()
-	}
-	return nil
+	return result.Err()
 }
 
-func sanitizeTag(s string) string {
-	s = strings.ReplaceAll(s, ",", "\\,")
-	s = strings.ReplaceAll(s, "=", "\\=")
-	s = strings.ReplaceAll(s, " ", "\\ ")
-	return s
-}
-
-func sanitizeField(s string) string {
-	s = strings.ReplaceAll(s, " ", "\\ ")
-	return s
-}
-
-func formatLPField(v interface{}) string {
-	switch val := v.(type) {
-	case string:
-		// string fields must be quoted
-		esc := strings.ReplaceAll(val, "\"", "\\\"")
-		return fmt.Sprintf("\"%s\"", esc)
-	case int64, int32, int, int8, int16:
-		return fmt.Sprintf("%di", val)
-	case uint64, uint32, uint, uint8, uint16:
-		return fmt.Sprintf("%di", val)
-	case float64, float32:
-		return fmt.Sprintf("%v", val)
-	case bool:
-		if val {
-			return "true"
+// influxSchema 调用 schema.measurements / schema.measurementTagKeys 返回 [Start, Stop) 内的名称列表
+func (m *Manager) influxSchema(ctx context.Context, fn string, im InfluxManifest, measurement string) ([]string, error) {
+	args := fmt.Sprintf("bucket: %s, start: %s, stop: %s", fluxString(m.InfluxBucket),
+		im.Start.Format(time.RFC3339Nano), im.Stop.Format(time.RFC3339Nano))
+	if measurement != "" {
+		args += ", measurement: " + fluxString(measurement)
+	}
+	result, err := m.InfluxQuery.Query(ctx, fmt.Sprintf("import \"influxdata/influxdb/schema\"\nschema.%s(%s)", fn, args))
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+	var names []string
+	for result.Next() {
+		if s, ok := result.Record().Value().(string); ok && s != "" {
+			names = append(names, s)
 		}
-		return "false"
-	default:
-		// fallback to quoted string
-		s := fmt.Sprintf("%v", val)
-		esc := strings.ReplaceAll(s, "\"", "\\\"")
-		return fmt.Sprintf("\"%s\"", esc)
 	}
+	return names, result.Err()
+}
+
+// fluxString 将 s 写为 Flux 字符串字面量
+func fluxString(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "${", `\${`)
+	return `"` + r.Replace(s) + `"`
 }
 
-// cleanupOld 删除超过保留期的备份目录
+// cleanupOld 按备份链清理过期备份：链中最新的备份也超过保留期时删除整条链，最新备份所在的链始终保留；
+// 没有清单的目录（旧格式或未完成的备份）按修改时间清理
 func (m *Manager) cleanupOld() error {
 	if m.RetentionDays <= 0 {
 		return nil
 	}
 	cutoff := time.Now().AddDate(0, 0, -m.RetentionDays)
+	list, err := m.ListManifests()
+	if err != nil {
+		return err
+	}
+	known := make(map[string]bool, len(list))
+	for _, mf := range list {
+		known[mf.ID] = true
+	}
+	for _, id := range expiredBackups(list, cutoff) {
+		_ = os.RemoveAll(filepath.Join(m.BackupDir, id))
+	}
+
 	entries, err := os.ReadDir(m.BackupDir)
 	if err != nil {
 		return err
 	}
 	for _, e := range entries {
-		if !e.IsDir() {
+		if !e.IsDir() || known[e.Name()] {
 			continue
 		}
 		info, err := e.Info()
@@ -330,3 +698,26 @@ func (m *Manager) cleanupOld() error {
 	}
 	return nil
 }
+
+// expiredBackups 返回已过期的备份链中的全部备份：链中最新的备份也早于 cutoff 时整条链过期，
+// 最新备份所在的链始终保留。list 需按开始时间升序。
+func expiredBackups(list []*Manifest, cutoff time.Time) []string {
+	chains := make(map[string][]*Manifest)
+	for _, mf := range list {
+		chains[mf.Base] = append(chains[mf.Base], mf)
+	}
+	latestBase := ""
+	if len(list) > 0 {
+		latestBase = list[len(list)-1].Base
+	}
+	var ids []string
+	for base, members := range chains {
+		if base == latestBase || !members[len(members)-1].FinishedAt.Before(cutoff) {
+			continue
+		}
+		for _, mf := range members {
+			ids = append(ids, mf.ID)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,178 @@
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// 备份类型
+const (
+	TypeFull        = "full"        // 完整备份，作为一条备份链的起点
+	TypeIncremental = "incremental" // 增量备份，依赖上一个备份
+)
+
+// MySQL 表的导出方式
+const (
+	TableModeFull        = "full"        // 完整备份：DROP + CREATE + INSERT
+	TableModeIncremental = "incremental" // 增量：高水位之后的行（及按 audit_logs 找出的已修改行），以 REPLACE 写入
+	TableModeRewrite     = "rewrite"     // 增量备份中整表重写：DELETE + INSERT，用于行会被原地修改或删除的小表及没有高水位列的表
+)
+
+// 高水位列
+const (
+	WatermarkID        = "id"         // 自增 id
+	WatermarkCreatedAt = "created_at" // 表没有自增 id 时使用
+)
+
+// manifestName 每个备份目录中的清单文件，最后写入，存在即表示备份成功
+const manifestName = "manifest.json"
+
+// Manifest 单次备份的清单
+type Manifest struct {
+	ID          string         `json:"id"`               // 备份目录名
+	Type        string         `json:"type"`             // full | incremental
+	Base        string         `json:"base"`             // 所在备份链的完整备份 ID，完整备份为自身
+	Parent      string         `json:"parent,omitempty"` // 增量备份的上一个备份
+	Compression string         `json:"compression"`
+	StartedAt   time.Time      `json:"startedAt"`
+	FinishedAt  time.Time      `json:"finishedAt"`
+	MySQL       MySQLManifest  `json:"mysql"`
+	Influx      InfluxManifest `json:"influx"`
+	Files       []FileManifest `json:"files"`
+}
+
+// MySQLManifest MySQL 导出信息
+type MySQLManifest struct {
+	Database string          `json:"database"`
+	File     string          `json:"file"`
+	Tables   []TableManifest `json:"tables"`
+}
+
+// TableManifest 单表导出范围及行数。FromID/FromTime 不含，ToID/ToTime 含，作为下一次增量的高水位；
+// 增量导出的 FromID 为上一次 ToID 向前重叠 Manager.IDOverlap 后的值
+type TableManifest struct {
+	Name        string     `json:"name"`
+	Mode        string     `json:"mode"`
+	Watermark   string     `json:"watermark,omitempty"` // id | created_at，为空表示没有高水位
+	FromID      int64      `json:"fromID,omitempty"`
+	ToID        int64      `json:"toID,omitempty"`
+	FromTime    *time.Time `json:"fromTime,omitempty"`
+	ToTime      *time.Time `json:"toTime,omitempty"`
+	Rows        int64      `json:"rows"`
+	UpdatedRows int64      `json:"updatedRows,omitempty"` // 高水位之前、按 audit_logs 重新导出的已修改行（audit_logs 自身为被撤销的条目）
+}
+
+// InfluxManifest Influx 导出范围及点数，时间范围为 [Start, Stop)。
+// 每个点（line-protocol 的一行）包含该时间戳下的全部字段，Fields 为字段值总数
+type InfluxManifest struct {
+	Bucket  string    `json:"bucket"`
+	File    string    `json:"file"`
+	Start   time.Time `json:"start"`
+	Stop    time.Time `json:"stop"`
+	Points  int64     `json:"points"`
+	Fields  int64     `json:"fields,omitempty"`  // 旧备份每行一个字段，未记录
+	Skipped int64     `json:"skipped,omitempty"` // 无法以 line-protocol 表示而未导出的点（如 tag 中含换行）
+}
+
+// FileManifest 备份文件大小及 SHA256（针对压缩后的文件）
+type FileManifest struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Table 返回指定表的导出信息
+func (mf *Manifest) Table(name string) (TableManifest, bool) {
+	for _, t := range mf.MySQL.Tables {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return TableManifest{}, false
+}
+
+// writeManifest 原子写入清单文件
+func writeManifest(dir string, mf *Manifest) error {
+	data, err := json.MarshalIndent(mf, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, manifestName+".tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, manifestName))
+}
+
+// ReadManifest 读取备份目录中的清单
+func ReadManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, manifestName))
+	if err != nil {
+		return nil, err
+	}
+	var mf Manifest
+	if err := json.Unmarshal(data, &mf); err != nil {
+		return nil, err
+	}
+	return &mf, nil
+}
+
+// ListManifests 返回备份目录下所有成功完成的备份，按开始时间升序；没有清单的目录（旧格式或未完成）被忽略
+func (m *Manager) ListManifests() ([]*Manifest, error) {
+	return listManifests(m.BackupDir)
+}
+
+func listManifests(backupDir string) ([]*Manifest, error) {
+	entries, err := os.ReadDir(backupDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []*Manifest
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		mf, err := ReadManifest(filepath.Join(backupDir, e.Name()))
+		if err != nil {
+			continue
+		}
+		mf.ID = e.Name()
+		list = append(list, mf)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].StartedAt.Before(list[j].StartedAt) })
+	return list, nil
+}
+
+// Chain 返回以 id 结尾的备份链（从完整备份到 id，按恢复顺序）；链中任一备份缺失时返回错误
+func Chain(list []*Manifest, id string) ([]*Manifest, error) {
+	byID := make(map[string]*Manifest, len(list))
+	for _, mf := range list {
+		byID[mf.ID] = mf
+	}
+	var chain []*Manifest
+	for cur := id; ; {
+		mf, ok := byID[cur]
+		if !ok {
+			return nil, errors.New("备份链不完整，缺少 " + cur)
+		}
+		chain = append(chain, mf)
+		if mf.Type == TypeFull {
+			break
+		}
+		if mf.Parent == "" || len(chain) > len(list) {
+			return nil, errors.New("备份链不完整：" + mf.ID + " 缺少上一个备份")
+		}
+		cur = mf.Parent
+	}
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain, nil
+}
@@ -0,0 +1,591 @@
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/klauspost/compress/zstd"
+)
+
+// restoreTimeColumns 按时间范围恢复时各表用于过滤的时间列；未列出的表不按时间过滤，整表恢复
+var restoreTimeColumns = map[string]timeColumn{
+	"flight_records":         {name: "start_time"},
+	"flight_track_points":    {name: "timeStamp", utc: true},
+	"flight_battery_metrics": {name: "start_time"},
+	"flight_track_cleaning":  {name: "start_time"},
+	"flight_sorties":         {name: "register_time"},
+	"maintenance_records":    {name: "performed_at"},
+	"export_downloads":       {name: "downloaded_at"},
+	"audit_logs":             {name: "created_at"},
+	"report_runs":            {name: "started_at"},
+}
+
+type timeColumn struct {
+	name string
+	utc  bool // 以 UTC 墙上时间存储（轨迹点）
+}
+
+// restoreBatchLines 每次写入 Influx 的行数
+const restoreBatchLines = 5000
+
+// restoreResultName 恢复演练结果文件，写在被演练的备份目录中
+const restoreResultName = "restore_test.json"
+
+// RestoreOptions 恢复目标及范围
+type RestoreOptions struct {
+	MySQL        *sql.DB          // 目标库（DSN 中指定的数据库），nil 时不恢复 MySQL
+	InfluxClient influxdb2.Client // 为 nil 或 InfluxBucket 为空时不恢复 Influx
+	InfluxOrg    string
+	InfluxBucket string
+	// From/To 只恢复该时间范围内的数据（零值表示不限）。指定时以合并方式恢复：
+	// 不删除、不重建目标中已有的表，行以 REPLACE 写入；没有时间列的表整表合并
+	From, To time.Time
+	Location *time.Location // 备份中 DATETIME 值的时区（与源库 DSN 一致），默认 time.Local
+	Single   bool           // 只恢复指定的备份，不恢复其所在备份链中之前的备份
+}
+
+func (o RestoreOptions) filtered() bool { return !o.From.IsZero() || !o.To.IsZero() }
+
+func (o RestoreOptions) inRange(t time.Time) bool {
+	return (o.From.IsZero() || !t.Before(o.From)) && (o.To.IsZero() || t.Before(o.To))
+}
+
+// RestoreResult 恢复及校验结果
+type RestoreResult struct {
+	Backup     string          `json:"backup"`
+	Chain      []string        `json:"chain"`
+	From       *time.Time      `json:"from,omitempty"`
+	To         *time.Time      `json:"to,omitempty"`
+	StartedAt  time.Time       `json:"startedAt"`
+	FinishedAt time.Time       `json:"finishedAt"`
+	OK         bool            `json:"ok"`
+	Errors     []string        `json:"errors,omitempty"`
+	Tables     []TableRestore  `json:"tables,omitempty"`
+	Influx     *InfluxRestore  `json:"influx,omitempty"`
+	Files      []FileIntegrity `json:"files"`
+}
+
+// FileIntegrity 备份文件校验结果
+type FileIntegrity struct {
+	Backup string `json:"backup"`
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Error  string `json:"error,omitempty"`
+}
+
+// TableRestore 单表恢复结果。Read 为从备份文件读出的行数，与清单中的行数比对；Restored 为按时间过滤后
+// 写入（只校验时为将写入）的行数；未按时间过滤时恢复后目标表行数应等于 Expected
+type TableRestore struct {
+	Name     string `json:"name"`
+	Read     int64  `json:"read"`
+	Manifest int64  `json:"manifest"`
+	Restored int64  `json:"restored"`
+	Expected int64  `json:"expected,omitempty"`
+	Target   int64  `json:"target,omitempty"`
+	OK       bool   `json:"ok"`
+	Message  string `json:"message,omitempty"`
+}
+
+// InfluxRestore Influx 恢复结果。Distinct 为去掉增量备份重叠区间重复点后的点数，
+// 目标 bucket 恢复前在该时间范围内为空且未按时间过滤时，恢复后点数应等于 Distinct
+type InfluxRestore struct {
+	Bucket   string    `json:"bucket"`
+	Start    time.Time `json:"start"`
+	Stop     time.Time `json:"stop"`
+	Read     int64     `json:"read"`
+	Manifest int64     `json:"manifest"`
+	Restored int64     `json:"restored"`
+	Distinct int64     `json:"distinct"`
+	Before   int64     `json:"before"`
+	Target   int64     `json:"target"`
+	OK       bool      `json:"ok"`
+	Message  string    `json:"message,omitempty"`
+}
+
+// Restore 将 dir 指向的备份（默认连同其所在备份链中之前的备份，按顺序）恢复到目标库，并按清单校验。
+// 恢复前校验所有文件的大小与 SHA256，任一文件损坏时不写入目标。
+// 目标均为 nil 时只做文件及行数/点数校验。
+func Restore(ctx context.Context, dir string, opts RestoreOptions) (*RestoreResult, error) {
+	dir = filepath.Clean(dir)
+	mf, err := ReadManifest(dir)
+	if err != nil {
+		return nil, fmt.Errorf("读取备份清单失败: %w", err)
+	}
+	mf.ID = filepath.Base(dir)
+	chain := []*Manifest{mf}
+	if !opts.Single {
+		list, err := listManifests(filepath.Dir(dir))
+		if err != nil {
+			return nil, err
+		}
+		if chain, err = Chain(list, mf.ID); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Location == nil {
+		opts.Location = time.Local
+	}
+
+	res := &RestoreResult{Backup: mf.ID, StartedAt: time.Now()}
+	if !opts.From.IsZero() {
+		res.From = &opts.From
+	}
+	if !opts.To.IsZero() {
+		res.To = &opts.To
+	}
+	root := filepath.Dir(dir)
+	for _, b := range chain {
+		res.Chain = append(res.Chain, b.ID)
+		for _, f := range b.Files {
+			fi := FileIntegrity{Backup: b.ID, Name: f.Name, OK: true}
+			if err := verifyFile(filepath.Join(root, b.ID, f.Name), f); err != nil {
+				fi.OK, fi.Error = false, err.Error()
+				res.Errors = append(res.Errors, fmt.Sprintf("%s/%s: %v", b.ID, f.Name, err))
+			}
+			res.Files = append(res.Files, fi)
+		}
+	}
+	if len(res.Errors) > 0 {
+		res.FinishedAt = time.Now()
+		return res, nil
+	}
+
+	if err := restoreMySQL(ctx, root, chain, opts, res); err != nil {
+		res.Errors = append(res.Errors, "mysql: "+err.Error())
+	}
+	if err := restoreInflux(ctx, root, chain, opts, res); err != nil {
+		res.Errors = append(res.Errors, "influx: "+err.Error())
+	}
+	res.OK = len(res.Errors) == 0
+	for _, t := range res.Tables {
+		res.OK = res.OK && t.OK
+	}
+	if res.Influx != nil {
+		res.OK = res.OK && res.Influx.OK
+	}
+	res.FinishedAt = time.Now()
+	return res, nil
+}
+
+// verifyFile 校验文件大小及 SHA256
+func verifyFile(path string, fm FileManifest) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return err
+	}
+	if n != fm.Size {
+		return fmt.Errorf("大小 %d 与清单 %d 不一致", n, fm.Size)
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != fm.SHA256 {
+		return fmt.Errorf("SHA256 与清单不一致")
+	}
+	return nil
+}
+
+// openBackupFile 按扩展名解压读取备份文件
+func openBackupFile(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	var r io.Reader
+	closeFn := func() {}
+	switch filepath.Ext(path) {
+	case ".gz":
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		r = zr
+	case ".zst":
+		zr, err := zstd.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		r, closeFn = zr, zr.Close
+	default:
+		r = f
+	}
+	return readCloser{r, func() error { closeFn(); return f.Close() }}, nil
+}
+
+type readCloser struct {
+	io.Reader
+	close func() error
+}
+
+func (rc readCloser) Close() error { return rc.close() }
+
+// restoreMySQL 依次执行备份链中的 SQL 文件。所有语句在同一连接上执行（SET FOREIGN_KEY_CHECKS 为会话变量）；
+// CREATE DATABASE / USE 被跳过，数据恢复到目标 DSN 指定的数据库
+func restoreMySQL(ctx context.Context, root string, chain []*Manifest, opts RestoreOptions, res *RestoreResult) error {
+	var conn *sql.Conn
+	if opts.MySQL != nil {
+		var err error
+		if conn, err = opts.MySQL.Conn(ctx); err != nil {
+			return err
+		}
+		defer conn.Close()
+	}
+	merge := opts.filtered()
+	tables := map[string]*TableRestore{}
+	var order []string
+	table := func(name string) *TableRestore {
+		t, ok := tables[name]
+		if !ok {
+			t = &TableRestore{Name: name}
+			tables[name] = t
+			order = append(order, name)
+		}
+		return t
+	}
+
+	for _, b := range chain {
+		if b.MySQL.File == "" {
+			continue
+		}
+		read := map[string]int64{}
+		for _, tm := range b.MySQL.Tables {
+			t := table(tm.Name)
+			t.Manifest += tm.Rows + tm.UpdatedRows
+			// 期望行数：完整导出与整表重写以本次为准，增量导出累加新增行（已修改行为覆盖写入）
+			if tm.Mode == TableModeIncremental {
+				t.Expected += tm.Rows
+			} else {
+				t.Expected = tm.Rows
+			}
+		}
+		exec := func(stmt string) error {
+			if conn == nil {
+				return nil
+			}
+			_, err := conn.ExecContext(ctx, stmt)
+			return err
+		}
+		r, err := openBackupFile(filepath.Join(root, b.ID, b.MySQL.File))
+		if err != nil {
+			return err
+		}
+		err = scanStatements(r, func(stmt string) error {
+			upper := strings.ToUpper(stmt[:min(len(stmt), 32)])
+			switch {
+			case strings.HasPrefix(upper, "CREATE DATABASE"), strings.HasPrefix(upper, "USE "):
+				return nil
+			case strings.HasPrefix(upper, "DROP TABLE"), strings.HasPrefix(upper, "DELETE FROM"):
+				if merge {
+					return nil
+				}
+				return exec(stmt)
+			case strings.HasPrefix(upper, "CREATE TABLE"):
+				if merge && !strings.HasPrefix(upper, "CREATE TABLE IF NOT EXISTS") {
+					stmt = "CREATE TABLE IF NOT EXISTS" + stmt[len("CREATE TABLE"):]
+				}
+				return exec(stmt)
+			case strings.HasPrefix(upper, "INSERT "), strings.HasPrefix(upper, "REPLACE "):
+				ins, err := parseInsert(stmt)
+				if err != nil {
+					return fmt.Errorf("%s: %w", b.ID, err)
+				}
+				read[ins.table] += int64(len(ins.tuples))
+				keep := filterTuples(ins, opts)
+				table(ins.table).Restored += int64(len(keep))
+				if len(keep) == 0 {
+					return nil
+				}
+				verb := ins.verb
+				if merge {
+					verb = "REPLACE"
+				}
+				if len(keep) == len(ins.tuples) && verb == ins.verb {
+					return exec(stmt)
+				}
+				return exec(ins.build(verb, keep))
+			default:
+				return exec(stmt)
+			}
+		})
+		r.Close()
+		if err != nil {
+			return err
+		}
+		for name, n := range read {
+			table(name).Read += n
+		}
+	}
+
+	for _, name := range order {
+		t := tables[name]
+		t.OK = t.Read == t.Manifest
+		if !t.OK {
+			t.Message = fmt.Sprintf("备份文件中读出 %d 行，清单为 %d 行", t.Read, t.Manifest)
+		} else if conn != nil && !merge {
+			if err := conn.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM `%s`", name)).Scan(&t.Target); err != nil {
+				return err
+			}
+			if t.Target != t.Expected {
+				t.OK = false
+				t.Message = fmt.Sprintf("恢复后 %d 行，期望 %d 行", t.Target, t.Expected)
+			}
+		}
+		if merge {
+			t.Expected = 0
+		}
+		res.Tables = append(res.Tables, *t)
+	}
+	return nil
+}
+
+// filterTuples 返回时间范围内的行下标；表没有时间列或值为 NULL 时保留
+func filterTuples(ins *insertStmt, opts RestoreOptions) []int {
+	keep := make([]int, 0, len(ins.tuples))
+	col := -1
+	tc, ok := restoreTimeColumns[ins.table]
+	if ok && opts.filtered() {
+		for i, c := range ins.columns {
+			if c == tc.name {
+				col = i
+			}
+		}
+	}
+	loc := opts.Location
+	if tc.utc {
+		loc = time.UTC
+	}
+	for i, vals := range ins.values {
+		if col >= 0 {
+			if v, ok := unquoteSQL(vals[col]); ok {
+				if t, err := time.ParseInLocation("2006-01-02 15:04:05", v, loc); err == nil && !opts.inRange(t) {
+					continue
+				}
+			}
+		}
+		keep = append(keep, i)
+	}
+	return keep
+}
+
+// restoreInflux 依次写入备份链中的 line-protocol 文件
+func restoreInflux(ctx context.Context, root string, chain []*Manifest, opts RestoreOptions, res *RestoreResult) error {
+	var files []*Manifest
+	for _, b := range chain {
+		if b.Influx.File != "" {
+			files = append(files, b)
+		}
+	}
+	if len(files) == 0 {
+		return nil
+	}
+	ir := &InfluxRestore{Bucket: opts.InfluxBucket, Start: files[0].Influx.Start, Stop: files[len(files)-1].Influx.Stop}
+	res.Influx = ir
+	if !opts.From.IsZero() && opts.From.After(ir.Start) {
+		ir.Start = opts.From
+	}
+	if !opts.To.IsZero() && opts.To.Before(ir.Stop) {
+		ir.Stop = opts.To
+	}
+	write := opts.InfluxClient != nil && opts.InfluxBucket != ""
+	if write {
+		var err error
+		if ir.Before, err = countInflux(ctx, opts, ir.Start, ir.Stop); err != nil {
+			return err
+		}
+	}
+
+	// 增量备份与上一个备份在 [Start, 上一个 Stop) 区间重叠，记录上一个文件中该区间的字段值用于去重计数。
+	// Influx 按字段值计数，Distinct 与 Target 均为字段值个数，Read 与 Manifest 为点（行）数
+	var tail map[string]struct{}
+	for i, b := range files {
+		var overlapUntil, keepFrom int64
+		if i > 0 {
+			overlapUntil = files[i-1].Influx.Stop.UnixNano()
+		}
+		if i+1 < len(files) {
+			keepFrom = files[i+1].Influx.Start.UnixNano()
+		}
+		next := map[string]struct{}{}
+		var read int64
+		err := func() error {
+			r, err := openBackupFile(filepath.Join(root, b.ID, b.Influx.File))
+			if err != nil {
+				return err
+			}
+			defer r.Close()
+			var (
+				wapi  = opts.InfluxClient
+				batch []string
+			)
+			flush := func() error {
+				if len(batch) == 0 {
+					return nil
+				}
+				if write {
+					if err := wapi.WriteAPIBlocking(opts.InfluxOrg, opts.InfluxBucket).WriteRecord(ctx, batch...); err != nil {
+						return err
+					}
+				}
+				ir.Restored += int64(len(batch))
+				batch = batch[:0]
+				return nil
+			}
+			err = scanLines(r, func(line string) error {
+				read++
+				p, err := parseLine(line)
+				if err != nil {
+					return fmt.Errorf("%s 第 %d 个点: %w", b.ID, read, err)
+				}
+				ts := p.Time.UnixNano()
+				keys := p.fieldKeys()
+				if i+1 < len(files) && ts >= keepFrom {
+					for _, key := range keys {
+						next[key] = struct{}{}
+					}
+				}
+				if !opts.inRange(p.Time) {
+					return nil
+				}
+				for _, key := range keys {
+					if _, dup := tail[key]; !(ts < overlapUntil && dup) {
+						ir.Distinct++
+					}
+				}
+				batch = append(batch, line)
+				if len(batch) >= restoreBatchLines {
+					return flush()
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			return flush()
+		}()
+		if err != nil {
+			return err
+		}
+		ir.Read += read
+		ir.Manifest += b.Influx.Points
+		tail = next
+	}
+
+	ir.OK = ir.Read == ir.Manifest
+	if !ir.OK {
+		ir.Message = fmt.Sprintf("备份文件中读出 %d 个点，清单为 %d 个", ir.Read, ir.Manifest)
+		return nil
+	}
+	if !write {
+		return nil
+	}
+	var err error
+	if ir.Target, err = countInflux(ctx, opts, ir.Start, ir.Stop); err != nil {
+		return err
+	}
+	switch {
+	case ir.Before > 0:
+		ir.Message = "目标 bucket 恢复前已有数据，未比对点数"
+	case ir.Target != ir.Distinct:
+		ir.OK = false
+		ir.Message = fmt.Sprintf("恢复后 %d 个字段值，期望 %d 个", ir.Target, ir.Distinct)
+	}
+	return nil
+}
+
+// countInflux 统计目标 bucket 在 [start, stop) 内的字段值个数
+func countInflux(ctx context.Context, opts RestoreOptions, start, stop time.Time) (int64, error) {
+	flux := fmt.Sprintf(`from(bucket: "%s") |> range(start: %s, stop: %s) |> count() |> group() |> sum()`,
+		opts.InfluxBucket, start.UTC().Format(time.RFC3339Nano), stop.UTC().Format(time.RFC3339Nano))
+	result, err := opts.InfluxClient.QueryAPI(opts.InfluxOrg).Query(ctx, flux)
+	if err != nil {
+		return 0, err
+	}
+	defer result.Close()
+	var n int64
+	for result.Next() {
+		switch v := result.Record().Value().(type) {
+		case int64:
+			n += v
+		case float64:
+			n += int64(v)
+		}
+	}
+	return n, result.Err()
+}
+
+// cutUnescaped 在第一个未被反斜杠转义的 sep 处切分
+func cutUnescaped(s string, sep byte) (string, string, bool) {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case sep:
+			return s[:i], s[i+1:], true
+		}
+	}
+	return s, "", false
+}
+
+func splitUnescaped(s string, sep byte) []string {
+	var parts []string
+	for {
+		before, after, ok := cutUnescaped(s, sep)
+		parts = append(parts, before)
+		if !ok {
+			return parts
+		}
+		s = after
+	}
+}
+
+// WriteRestoreResult 将恢复演练结果写入备份目录
+func WriteRestoreResult(dir string, res *RestoreResult) error {
+	data, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, restoreResultName), data, 0644)
+}
+
+// ReadRestoreResult 读取备份目录中最近一次恢复演练结果，未演练时返回 os.ErrNotExist
+func ReadRestoreResult(dir string) (*RestoreResult, error) {
+	data, err := os.ReadFile(filepath.Join(dir, restoreResultName))
+	if err != nil {
+		return nil, err
+	}
+	var res RestoreResult
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Problems 汇总未通过的校验项
+func (r *RestoreResult) Problems() []string {
+	problems := append([]string(nil), r.Errors...)
+	for _, t := range r.Tables {
+		if !t.OK {
+			problems = append(problems, t.Name+": "+t.Message)
+		}
+	}
+	if r.Influx != nil && !r.Influx.OK {
+		problems = append(problems, "influx: "+r.Influx.Message)
+	}
+	return problems
+}
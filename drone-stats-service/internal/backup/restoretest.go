@@ -0,0 +1,109 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// RestoreTest 恢复演练：将最新的备份链恢复到临时库（mysqlDSN 指定的数据库及 influxBucket），按清单校验后
+// 将结果写入被演练备份目录的 restore_test.json。临时库中原有的表和数据会被清空，因此不允许与生产库相同。
+func (m *Manager) RestoreTest(ctx context.Context, mysqlDSN, influxBucket string) (*RestoreResult, error) {
+	if !m.Supported() {
+		return nil, fmt.Errorf("当前存储后端不支持备份，仅支持 MySQL + InfluxDB")
+	}
+	list, err := m.ListManifests()
+	if err != nil {
+		return nil, err
+	}
+	tip, _ := m.chainTip(list)
+	if tip == nil {
+		return nil, fmt.Errorf("没有可用的备份")
+	}
+	opts := RestoreOptions{}
+	if mysqlDSN != "" {
+		db, err := sql.Open("mysql", mysqlDSN)
+		if err != nil {
+			return nil, err
+		}
+		defer db.Close()
+		if err := m.resetScratchMySQL(ctx, db); err != nil {
+			return nil, err
+		}
+		opts.MySQL = db
+	}
+	if influxBucket != "" {
+		if influxBucket == m.InfluxBucket {
+			return nil, fmt.Errorf("恢复演练的 bucket 不能与备份的 bucket 相同")
+		}
+		// 清空临时 bucket
+		if err := m.InfluxClient.DeleteAPI().DeleteWithName(ctx, m.InfluxOrg, influxBucket, time.Unix(0, 0), time.Now().Add(24*time.Hour), ""); err != nil {
+			return nil, fmt.Errorf("清空临时 bucket 失败: %w", err)
+		}
+		opts.InfluxClient, opts.InfluxOrg, opts.InfluxBucket = m.InfluxClient, m.InfluxOrg, influxBucket
+	}
+	dir := filepath.Join(m.BackupDir, tip.ID)
+	res, err := Restore(ctx, dir, opts)
+	if err != nil {
+		return nil, err
+	}
+	return res, WriteRestoreResult(dir, res)
+}
+
+// resetScratchMySQL 确认临时库不是生产库后删除其中所有表
+func (m *Manager) resetScratchMySQL(ctx context.Context, db *sql.DB) error {
+	identity := func(db *sql.DB) (string, error) {
+		var name sql.NullString
+		var host string
+		var port int
+		err := db.QueryRowContext(ctx, "SELECT DATABASE(), @@hostname, @@port").Scan(&name, &host, &port)
+		return fmt.Sprintf("%s:%d/%s", host, port, name.String), err
+	}
+	src, err := identity(m.MySQLDB)
+	if err != nil {
+		return err
+	}
+	dst, err := identity(db)
+	if err != nil {
+		return fmt.Errorf("连接临时库失败: %w", err)
+	}
+	if src == dst {
+		return fmt.Errorf("恢复演练的数据库不能与生产库相同")
+	}
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	rows, err := conn.QueryContext(ctx, "SELECT TABLE_NAME FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = DATABASE()")
+	if err != nil {
+		return err
+	}
+	var tables []string
+	for rows.Next() {
+		var t string
+		if err := rows.Scan(&t); err != nil {
+			rows.Close()
+			return err
+		}
+		tables = append(tables, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if _, err := conn.ExecContext(ctx, "SET FOREIGN_KEY_CHECKS=0"); err != nil {
+		return err
+	}
+	for _, t := range tables {
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf("DROP TABLE IF EXISTS `%s`", t)); err != nil {
+			return err
+		}
+	}
+	_, err = conn.ExecContext(ctx, "SET FOREIGN_KEY_CHECKS=1")
+	return err
+}
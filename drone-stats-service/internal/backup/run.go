@@ -0,0 +1,366 @@
+package backup
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 备份触发方式
+const (
+	TriggerSchedule = "schedule" // 定时备份
+	TriggerManual   = "manual"   // 通过 API 手动触发
+	TriggerShutdown = "shutdown" // 服务退出前
+)
+
+// targetDeleteTimeout 删除备份时从全部存储目标删除副本的超时
+const targetDeleteTimeout = 5 * time.Minute
+
+var (
+	ErrBackupRunning       = errors.New("已有备份正在执行")
+	ErrBackupNotFound      = errors.New("备份不存在")
+	ErrBackupHasDependents = errors.New("备份被后续增量备份依赖")
+)
+
+// RunStatus 一次备份执行的状态
+type RunStatus struct {
+	Trigger    string     `json:"trigger"`
+	Type       string     `json:"type,omitempty"` // 自动选择类型时在备份开始后确定
+	BackupID   string     `json:"backupID,omitempty"`
+	Running    bool       `json:"running"`
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	// UploadErrors 上传到存储目标失败的信息，未上传的备份在下一次备份后继续上传
+	UploadErrors []string `json:"uploadErrors,omitempty"`
+}
+
+// BackupInfo 备份目录信息；Manifest 为空表示旧格式、未完成或正在执行的备份
+type BackupInfo struct {
+	ID          string
+	Size        int64
+	ModTime     time.Time
+	Running     bool
+	Manifest    *Manifest
+	RestoreTest *RestoreResult
+}
+
+// Backup 执行一次备份，已有备份正在执行时等待其结束（用于退出前备份）
+func (m *Manager) Backup(ctx context.Context, typ, trigger string) (*Manifest, error) {
+	m.runMu.Lock()
+	return m.run(ctx, typ, trigger)
+}
+
+// TryBackup 执行一次备份，已有备份正在执行时返回 ErrBackupRunning
+func (m *Manager) TryBackup(ctx context.Context, typ, trigger string) (*Manifest, error) {
+	if !m.runMu.TryLock() {
+		return nil, ErrBackupRunning
+	}
+	return m.run(ctx, typ, trigger)
+}
+
+// StartBackup 在后台执行一次备份并立即返回，进度通过 Status 查询；已有备份正在执行时返回 ErrBackupRunning
+func (m *Manager) StartBackup(typ, trigger string) (RunStatus, error) {
+	if typ != "" && typ != TypeFull && typ != TypeIncremental {
+		return RunStatus{}, fmt.Errorf("不支持的备份类型 %s", typ)
+	}
+	if !m.runMu.TryLock() {
+		return RunStatus{}, ErrBackupRunning
+	}
+	st := m.begin(typ, trigger)
+	go m.runLocked(context.Background(), typ, st)
+	return *st, nil
+}
+
+// Status 返回正在执行及最近一次结束的备份状态，没有时为 nil
+func (m *Manager) Status() (current, last *RunStatus) {
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	if m.current != nil {
+		c := *m.current
+		current = &c
+	}
+	if m.last != nil {
+		l := *m.last
+		last = &l
+	}
+	return current, last
+}
+
+// run 在已持有 runMu 的情况下执行备份并记录状态，结束时释放 runMu
+func (m *Manager) run(ctx context.Context, typ, trigger string) (*Manifest, error) {
+	return m.runLocked(ctx, typ, m.begin(typ, trigger))
+}
+
+func (m *Manager) begin(typ, trigger string) *RunStatus {
+	st := &RunStatus{Trigger: trigger, Type: typ, Running: true, StartedAt: time.Now()}
+	m.stateMu.Lock()
+	m.current = st
+	m.stateMu.Unlock()
+	return st
+}
+
+func (m *Manager) runLocked(ctx context.Context, typ string, st *RunStatus) (*Manifest, error) {
+	defer m.runMu.Unlock()
+	mf, err := m.backup(ctx, typ, func(mf *Manifest) {
+		m.stateMu.Lock()
+		st.Type, st.BackupID = mf.Type, mf.ID
+		m.stateMu.Unlock()
+	})
+	// 本次备份失败时仍上传之前未上传完成的备份
+	uploadErrs := m.SyncTargets(ctx)
+	now := time.Now()
+	m.stateMu.Lock()
+	st.Running, st.FinishedAt = false, &now
+	if err != nil {
+		st.Error = err.Error()
+	}
+	for _, e := range uploadErrs {
+		st.UploadErrors = append(st.UploadErrors, e.Error())
+	}
+	m.current, m.last = nil, st
+	m.stateMu.Unlock()
+	return mf, err
+}
+
+// ListBackups 返回备份目录下的所有备份（含没有清单的目录），按时间倒序
+func (m *Manager) ListBackups() ([]BackupInfo, error) {
+	entries, err := os.ReadDir(m.BackupDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	running := ""
+	if cur, _ := m.Status(); cur != nil {
+		running = cur.BackupID
+	}
+	var list []BackupInfo
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		dir := filepath.Join(m.BackupDir, e.Name())
+		b := BackupInfo{ID: e.Name(), Running: e.Name() == running}
+		if info, err := e.Info(); err == nil {
+			b.ModTime = info.ModTime()
+		}
+		b.Size, _ = dirSize(dir)
+		if mf, err := ReadManifest(dir); err == nil {
+			mf.ID = e.Name()
+			b.Manifest = mf
+			b.ModTime = mf.StartedAt
+		}
+		if res, err := ReadRestoreResult(dir); err == nil {
+			b.RestoreTest = res
+		}
+		list = append(list, b)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ModTime.After(list[j].ModTime) })
+	return list, nil
+}
+
+// Delete 删除备份，包括本地目录及各存储目标上的副本。备份被后续增量备份依赖时，cascade 为 false 返回 ErrBackupHasDependents，
+// 为 true 时一并删除依赖它的备份。备份执行期间不允许删除。返回被删除的备份 ID。
+func (m *Manager) Delete(id string, cascade bool) ([]string, error) {
+	if err := validBackupID(id); err != nil {
+		return nil, err
+	}
+	if !m.runMu.TryLock() {
+		return nil, ErrBackupRunning
+	}
+	defer m.runMu.Unlock()
+
+	if _, err := os.Stat(filepath.Join(m.BackupDir, id)); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrBackupNotFound
+		}
+		return nil, err
+	}
+	list, err := m.ListManifests()
+	if err != nil {
+		return nil, err
+	}
+	var dependents []string
+	for _, mf := range list {
+		if mf.ID != id && dependsOn(list, mf, id) {
+			dependents = append(dependents, mf.ID)
+		}
+	}
+	if len(dependents) > 0 && !cascade {
+		return nil, fmt.Errorf("%w：%s", ErrBackupHasDependents, strings.Join(dependents, ", "))
+	}
+	deleted := append(dependents, id)
+	// 先删除各存储目标上的副本：任一目标失败时保留本地备份以便重试，避免目标上留下无法再通过接口删除的备份
+	ctx, cancel := context.WithTimeout(context.Background(), targetDeleteTimeout)
+	defer cancel()
+	for _, t := range m.Targets {
+		for _, d := range deleted {
+			if err := t.Storage.DeleteBackup(ctx, d); err != nil {
+				return nil, fmt.Errorf("从 %s 删除 %s 失败: %w", t.Storage.Name(), d, err)
+			}
+		}
+	}
+	for _, d := range deleted {
+		if err := os.RemoveAll(filepath.Join(m.BackupDir, d)); err != nil {
+			return nil, err
+		}
+	}
+	return deleted, nil
+}
+
+// dependsOn 判断 mf 的备份链中是否包含 id
+func dependsOn(list []*Manifest, mf *Manifest, id string) bool {
+	byID := make(map[string]*Manifest, len(list))
+	for _, x := range list {
+		byID[x.ID] = x
+	}
+	for cur, n := mf, 0; cur != nil && n <= len(list); n++ {
+		if cur.Parent == id {
+			return true
+		}
+		cur = byID[cur.Parent]
+	}
+	return false
+}
+
+// Archive 待下载的备份打包，文件在创建时全部打开，之后备份被清理也能完整输出
+type Archive struct {
+	Name  string // 下载文件名
+	files []archiveFile
+}
+
+type archiveFile struct {
+	name string
+	f    *os.File
+	info os.FileInfo
+}
+
+// OpenArchive 打开备份 id 的全部文件；withChain 为 true 时包含恢复该备份所需的整条备份链
+func (m *Manager) OpenArchive(id string, withChain bool) (*Archive, error) {
+	if err := validBackupID(id); err != nil {
+		return nil, err
+	}
+	if cur, _ := m.Status(); cur != nil && cur.BackupID == id {
+		return nil, ErrBackupRunning
+	}
+	ids := []string{id}
+	a := &Archive{Name: id + ".tar"}
+	if withChain {
+		list, err := m.ListManifests()
+		if err != nil {
+			return nil, err
+		}
+		chain, err := Chain(list, id)
+		if err != nil {
+			return nil, err
+		}
+		ids = ids[:0]
+		for _, mf := range chain {
+			ids = append(ids, mf.ID)
+		}
+		a.Name = id + "_chain.tar"
+	}
+	for _, d := range ids {
+		dir := filepath.Join(m.BackupDir, d)
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			a.Close()
+			if errors.Is(err, os.ErrNotExist) {
+				return nil, ErrBackupNotFound
+			}
+			return nil, err
+		}
+		for _, e := range entries {
+			if !e.Type().IsRegular() {
+				continue
+			}
+			f, err := os.Open(filepath.Join(dir, e.Name()))
+			if err != nil {
+				a.Close()
+				return nil, err
+			}
+			info, err := f.Stat()
+			if err != nil {
+				f.Close()
+				a.Close()
+				return nil, err
+			}
+			a.files = append(a.files, archiveFile{name: d + "/" + e.Name(), f: f, info: info})
+		}
+	}
+	return a, nil
+}
+
+// WriteTo 以 tar 格式输出，备份文件已压缩，tar 本身不再压缩
+func (a *Archive) WriteTo(w io.Writer) (int64, error) {
+	cw := &countWriter{w: w}
+	tw := tar.NewWriter(cw)
+	for _, af := range a.files {
+		hdr, err := tar.FileInfoHeader(af.info, "")
+		if err != nil {
+			return cw.n, err
+		}
+		hdr.Name = af.name
+		if err := tw.WriteHeader(hdr); err != nil {
+			return cw.n, err
+		}
+		if _, err := io.Copy(tw, af.f); err != nil {
+			return cw.n, err
+		}
+	}
+	err := tw.Close()
+	return cw.n, err
+}
+
+// Close 关闭已打开的文件
+func (a *Archive) Close() {
+	for _, af := range a.files {
+		af.f.Close()
+	}
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// validBackupID 备份 ID 只能是备份目录下的目录名
+func validBackupID(id string) error {
+	if id == "" || id == "." || id == ".." || strings.ContainsAny(id, `/\`) {
+		return fmt.Errorf("非法的备份 ID %q", id)
+	}
+	return nil
+}
+
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(_ string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
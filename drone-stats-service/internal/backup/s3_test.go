@@ -0,0 +1,208 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// newTestS3Storage 连接 BACKUP_TEST_S3_ENDPOINT 指定的 S3 兼容服务（如本地 MinIO），未设置时跳过。
+// 每次运行使用独立的前缀，结束后删除其下的对象。
+//
+//	docker run -p 9000:9000 minio/minio server /data
+//	BACKUP_TEST_S3_ENDPOINT=127.0.0.1:9000 go test ./internal/backup -run S3
+func newTestS3Storage(t *testing.T) *S3Storage {
+	t.Helper()
+	endpoint := os.Getenv("BACKUP_TEST_S3_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("未设置 BACKUP_TEST_S3_ENDPOINT，跳过 S3 集成测试")
+	}
+	o := S3Options{
+		Name:      "s3",
+		Endpoint:  endpoint,
+		Bucket:    envOr("BACKUP_TEST_S3_BUCKET", "drone-stats-backup-test"),
+		Prefix:    fmt.Sprintf("test-%d", time.Now().UnixNano()),
+		AccessKey: envOr("BACKUP_TEST_S3_ACCESS_KEY", "minioadmin"),
+		SecretKey: envOr("BACKUP_TEST_S3_SECRET_KEY", "minioadmin"),
+		UseSSL:    os.Getenv("BACKUP_TEST_S3_SSL") == "true",
+	}
+	s, err := NewS3Storage(context.Background(), o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		ctx := context.Background()
+		for _, id := range []string{"B1", "B2"} {
+			_ = s.DeleteBackup(ctx, id)
+		}
+	})
+	return s
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// putTestPart 向未完成的分段上传写入一个分段
+func putTestPart(t *testing.T, s *S3Storage, object, uploadID string, partNum int, data []byte) {
+	t.Helper()
+	sum := md5.Sum(data)
+	if _, err := s.core.PutObjectPart(context.Background(), s.bucket, object, uploadID, partNum, bytes.NewReader(data), int64(len(data)),
+		minio.PutObjectPartOptions{Md5Base64: base64.StdEncoding.EncodeToString(sum[:])}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readTestObject 读取已上传的对象
+func readTestObject(t *testing.T, s *S3Storage, key string) []byte {
+	t.Helper()
+	rc, err := s.Open(context.Background(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	got, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func TestS3UploadResume(t *testing.T) {
+	s := newTestS3Storage(t)
+	ctx := context.Background()
+	// 3 个分段，最后一个不足分段大小
+	data := make([]byte, 2*s3PartSize+1024)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	localPath, fm := writeLocalFile(t, data)
+	key := "B1/mysql.sql"
+	object := s.prefix + key
+
+	// 模拟中断的上传：分段 1 已完整上传，分段 2 大小相同但内容损坏（MD5 不一致需重传），分段 3 未上传
+	uploadID, err := s.core.NewMultipartUpload(ctx, s.bucket, object, minio.PutObjectOptions{UserMetadata: map[string]string{s3MetaSHA256: fm.SHA256}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	putTestPart(t, s, object, uploadID, 1, data[:s3PartSize])
+	putTestPart(t, s, object, uploadID, 2, make([]byte, s3PartSize))
+
+	resumed, parts, err := s.resumableUpload(ctx, object)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumed != uploadID || len(parts) != 2 {
+		t.Fatalf("resumable upload = %s with %d parts, want %s with 2", resumed, len(parts), uploadID)
+	}
+
+	if err := s.Upload(ctx, key, localPath, fm); err != nil {
+		t.Fatal(err)
+	}
+	if got := readTestObject(t, s, key); !bytes.Equal(got, data) {
+		t.Errorf("uploaded object (%d bytes) differs from local file (%d bytes)", len(got), len(data))
+	}
+	if id, _, err := s.resumableUpload(ctx, object); err != nil || id != "" {
+		t.Errorf("upload %q left after completion: %v", id, err)
+	}
+	stat, err := s.core.StatObject(ctx, s.bucket, object, minio.StatObjectOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := stat.Metadata.Get("X-Amz-Meta-" + s3MetaSHA256); got != fm.SHA256 {
+		t.Errorf("sha256 metadata = %q, want %q", got, fm.SHA256)
+	}
+
+	// 对象已存在且大小、SHA256 一致时跳过
+	if err := s.Upload(ctx, key, localPath, fm); err != nil {
+		t.Fatal(err)
+	}
+	again, err := s.core.StatObject(ctx, s.bucket, object, minio.StatObjectOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ETag != stat.ETag || !again.LastModified.Equal(stat.LastModified) {
+		t.Errorf("unchanged object re-uploaded: %s -> %s", stat.ETag, again.ETag)
+	}
+}
+
+func TestS3UploadMismatch(t *testing.T) {
+	s := newTestS3Storage(t)
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		size int
+	}{
+		{name: "单次上传", size: 1024},
+		{name: "分段上传", size: s3PartSize + 1024},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			localPath, fm := writeLocalFile(t, bytes.Repeat([]byte{'x'}, tt.size))
+			fm.SHA256 = fmt.Sprintf("%064x", 0) // 本地文件与清单不一致
+			key := "B2/mysql.sql"
+			if err := s.Upload(ctx, key, localPath, fm); err == nil {
+				t.Fatal("upload with mismatched sha256 succeeded")
+			}
+			if _, err := s.core.StatObject(ctx, s.bucket, s.prefix+key, minio.StatObjectOptions{}); err == nil {
+				t.Error("mismatched object stored")
+			}
+			// 分段上传被中止，不留下未完成的上传
+			if id, _, err := s.resumableUpload(ctx, s.prefix+key); err != nil || id != "" {
+				t.Errorf("upload %q left after mismatch: %v", id, err)
+			}
+		})
+	}
+}
+
+func TestS3DeleteBackup(t *testing.T) {
+	s := newTestS3Storage(t)
+	ctx := context.Background()
+	for _, id := range []string{"B1", "B2"} {
+		localPath, fm := writeLocalFile(t, []byte("-- "+id+"\n"))
+		if err := s.Upload(ctx, id+"/mysql.sql", localPath, fm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// B1 还有一个未完成的分段上传
+	pending := s.prefix + "B1/influx.lp"
+	uploadID, err := s.core.NewMultipartUpload(ctx, s.bucket, pending, minio.PutObjectOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	putTestPart(t, s, pending, uploadID, 1, []byte("partial"))
+
+	if err := s.DeleteBackup(ctx, "B1"); err != nil {
+		t.Fatal(err)
+	}
+	objects, err := s.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 1 || objects[0].Key != "B2/mysql.sql" {
+		t.Errorf("objects after delete = %+v, want only B2/mysql.sql", objects)
+	}
+	// 不支持按前缀列出分段上传的实现不会中止，依赖 bucket 生命周期规则清理
+	if _, err := s.core.ListMultipartUploads(ctx, s.bucket, s.prefix+"B1/", "", "", "", 1000); err != nil {
+		t.Logf("按前缀列出分段上传不受支持，跳过未完成上传的检查: %v", err)
+		_ = s.core.AbortMultipartUpload(ctx, s.bucket, pending, uploadID)
+		return
+	}
+	if id, _, err := s.resumableUpload(ctx, pending); err != nil || id != "" {
+		t.Errorf("incomplete upload %q not aborted: %v", id, err)
+	}
+	if err := s.DeleteBackup(ctx, ".."); err == nil {
+		t.Error("delete of invalid id succeeded")
+	}
+}
@@ -0,0 +1,234 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// SFTPOptions SFTP 存储目标配置。主机密钥必须通过 KnownHostsFile 或 HostKeyFingerprint 校验
+type SFTPOptions struct {
+	Name               string
+	Host               string // host:port，端口缺省 22
+	User               string
+	Password           string
+	PrivateKeyFile     string
+	KnownHostsFile     string
+	HostKeyFingerprint string // ssh-keygen -l 输出的 SHA256:... 格式
+	Path               string // 远端目录
+}
+
+// SFTPStorage SFTP 存储目标。文件先写入 .part，中断后从已写入的长度继续，
+// 读回校验 SHA256 后重命名为正式文件。连接在首次使用时建立，出错后下次使用时重连。
+type SFTPStorage struct {
+	name string
+	root string
+	addr string
+	conf *ssh.ClientConfig
+
+	mu     sync.Mutex
+	conn   *ssh.Client
+	client *sftp.Client
+}
+
+// NewSFTPStorage 创建 SFTP 存储目标（不立即连接）
+func NewSFTPStorage(o SFTPOptions) (*SFTPStorage, error) {
+	if o.Host == "" || o.User == "" || o.Path == "" {
+		return nil, fmt.Errorf("SFTP 存储目标未配置 Host、User 或 Path")
+	}
+	var auth []ssh.AuthMethod
+	if o.PrivateKeyFile != "" {
+		key, err := os.ReadFile(o.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("解析私钥失败: %w", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if o.Password != "" {
+		auth = append(auth, ssh.Password(o.Password))
+	}
+	if len(auth) == 0 {
+		return nil, fmt.Errorf("SFTP 存储目标未配置 Password 或 PrivateKeyFile")
+	}
+	var hostKey ssh.HostKeyCallback
+	switch {
+	case o.KnownHostsFile != "":
+		cb, err := knownhosts.New(o.KnownHostsFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取 known_hosts 失败: %w", err)
+		}
+		hostKey = cb
+	case o.HostKeyFingerprint != "":
+		want := o.HostKeyFingerprint
+		hostKey = func(_ string, _ net.Addr, key ssh.PublicKey) error {
+			if got := ssh.FingerprintSHA256(key); got != want {
+				return fmt.Errorf("主机密钥指纹 %s 与配置不一致", got)
+			}
+			return nil
+		}
+	default:
+		return nil, fmt.Errorf("SFTP 存储目标需配置 KnownHostsFile 或 HostKeyFingerprint")
+	}
+	addr := o.Host
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "22")
+	}
+	return &SFTPStorage{
+		name: o.Name,
+		root: o.Path,
+		addr: addr,
+		conf: &ssh.ClientConfig{User: o.User, Auth: auth, HostKeyCallback: hostKey, Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (s *SFTPStorage) Name() string { return s.name }
+
+// sftpClient 返回可用的连接，未连接或已断开时重新连接
+func (s *SFTPStorage) sftpClient() (*sftp.Client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.client != nil {
+		if _, err := s.client.Getwd(); err == nil {
+			return s.client, nil
+		}
+		s.closeLocked()
+	}
+	conn, err := ssh.Dial("tcp", s.addr, s.conf)
+	if err != nil {
+		return nil, fmt.Errorf("连接 %s 失败: %w", s.addr, err)
+	}
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	s.conn, s.client = conn, client
+	return client, nil
+}
+
+func (s *SFTPStorage) Upload(ctx context.Context, key, localPath string, fm FileManifest) error {
+	if err := validStorageKey(key); err != nil {
+		return err
+	}
+	c, err := s.sftpClient()
+	if err != nil {
+		return err
+	}
+	dst := path.Join(s.root, key)
+	if info, err := c.Stat(dst); err == nil && info.Size() == fm.Size {
+		if sum, err := s.remoteSHA256(c, dst); err == nil && sum == fm.SHA256 {
+			return nil
+		}
+	}
+	if err := c.MkdirAll(path.Dir(dst)); err != nil {
+		return err
+	}
+	part := dst + partSuffix
+	f, err := c.OpenFile(part, os.O_WRONLY|os.O_CREATE)
+	if err != nil {
+		return err
+	}
+	if err := resumeCopy(ctx, f, localPath, fm.Size); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	sum, err := s.remoteSHA256(c, part)
+	if err != nil {
+		return err
+	}
+	if sum != fm.SHA256 {
+		_ = c.Remove(part)
+		return fmt.Errorf("%s 上传后 SHA256 与清单不一致", key)
+	}
+	return c.PosixRename(part, dst)
+}
+
+// remoteSHA256 读回远端文件计算 SHA256
+func (s *SFTPStorage) remoteSHA256(c *sftp.Client, p string) (string, error) {
+	f, err := c.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return hashReader(f)
+}
+
+func (s *SFTPStorage) List(ctx context.Context) ([]StoredObject, error) {
+	c, err := s.sftpClient()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.Stat(s.root); os.IsNotExist(err) {
+		return nil, nil
+	}
+	var out []StoredObject
+	w := c.Walk(s.root)
+	for w.Step() {
+		if err := w.Err(); err != nil {
+			return nil, err
+		}
+		info := w.Stat()
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		key := strings.TrimPrefix(strings.TrimPrefix(w.Path(), s.root), "/")
+		out = append(out, StoredObject{Key: key, Size: info.Size(), ModTime: info.ModTime()})
+	}
+	return out, nil
+}
+
+func (s *SFTPStorage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := validStorageKey(key); err != nil {
+		return nil, err
+	}
+	c, err := s.sftpClient()
+	if err != nil {
+		return nil, err
+	}
+	return c.Open(path.Join(s.root, key))
+}
+
+func (s *SFTPStorage) DeleteBackup(ctx context.Context, id string) error {
+	if err := validBackupID(id); err != nil {
+		return err
+	}
+	c, err := s.sftpClient()
+	if err != nil {
+		return err
+	}
+	return c.RemoveAll(path.Join(s.root, id))
+}
+
+func (s *SFTPStorage) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeLocked()
+	return nil
+}
+
+func (s *SFTPStorage) closeLocked() {
+	if s.client != nil {
+		s.client.Close()
+	}
+	if s.conn != nil {
+		s.conn.Close()
+	}
+	s.conn, s.client = nil, nil
+}
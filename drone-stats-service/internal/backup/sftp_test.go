@@ -0,0 +1,175 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/sftp"
+)
+
+// pipeConn 将一对管道组合为 sftp.Server 使用的连接
+type pipeConn struct {
+	io.Reader
+	io.WriteCloser
+}
+
+// newTestSFTPStorage 通过内存管道连接进程内 SFTP 服务端，远端目录为临时目录
+func newTestSFTPStorage(t *testing.T) (*SFTPStorage, string) {
+	t.Helper()
+	sr, cw := io.Pipe()
+	cr, sw := io.Pipe()
+	server, err := sftp.NewServer(pipeConn{Reader: sr, WriteCloser: sw})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve()
+	client, err := sftp.NewClientPipe(cr, cw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	s := &SFTPStorage{name: "sftp", root: root, client: client}
+	// 先关闭服务端，客户端读到 EOF 后才能退出
+	t.Cleanup(func() {
+		server.Close()
+		s.Close()
+	})
+	return s, root
+}
+
+// writeLocalFile 写入待上传的本地文件并返回其清单
+func writeLocalFile(t *testing.T, data []byte) (string, FileManifest) {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "mysql.sql")
+	if err := os.WriteFile(p, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	fm, err := hashFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, fm
+}
+
+func TestSFTPUploadResume(t *testing.T) {
+	data := bytes.Repeat([]byte("INSERT INTO flight_records VALUES (1);\n"), 1000)
+	tests := []struct {
+		name string
+		part []byte // 上次中断遗留的 .part 内容，nil 表示不存在
+	}{
+		{name: "全新上传"},
+		{name: "从已写入的长度继续", part: data[:len(data)/3]},
+		{name: ".part 比源文件长时从头重写", part: append(append([]byte{}, data...), "garbage"...)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, root := newTestSFTPStorage(t)
+			localPath, fm := writeLocalFile(t, data)
+			dst := filepath.Join(root, "B1", "mysql.sql")
+			if tt.part != nil {
+				if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(dst+partSuffix, tt.part, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := s.Upload(context.Background(), "B1/mysql.sql", localPath, fm); err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(dst)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("uploaded %d bytes differ from local %d bytes", len(got), len(data))
+			}
+			if _, err := os.Stat(dst + partSuffix); !os.IsNotExist(err) {
+				t.Errorf(".part still exists: %v", err)
+			}
+		})
+	}
+}
+
+func TestSFTPUploadVerifiesSHA256(t *testing.T) {
+	s, root := newTestSFTPStorage(t)
+	data := []byte("-- B1\n")
+	localPath, fm := writeLocalFile(t, data)
+	dst := filepath.Join(root, "B1", "mysql.sql")
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// 遗留的 .part 长度与源文件相同但内容已损坏，续传不写入新数据，读回校验失败
+	if err := os.WriteFile(dst+partSuffix, []byte("-- XX\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Upload(context.Background(), "B1/mysql.sql", localPath, fm); err == nil {
+		t.Fatal("upload of corrupted .part succeeded")
+	}
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Errorf("corrupted file renamed to %s: %v", dst, err)
+	}
+	if _, err := os.Stat(dst + partSuffix); !os.IsNotExist(err) {
+		t.Errorf("corrupted .part not removed: %v", err)
+	}
+
+	// 损坏的 .part 删除后重试从头上传
+	if err := s.Upload(context.Background(), "B1/mysql.sql", localPath, fm); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(dst); !bytes.Equal(got, data) {
+		t.Errorf("retried upload = %q, want %q", got, data)
+	}
+
+	// 远端已有内容一致的文件时跳过；内容不一致时重新上传
+	if err := os.WriteFile(dst, []byte("-- YY\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Upload(context.Background(), "B1/mysql.sql", localPath, fm); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(dst); !bytes.Equal(got, data) {
+		t.Errorf("re-uploaded = %q, want %q", got, data)
+	}
+}
+
+func TestSFTPListAndDeleteBackup(t *testing.T) {
+	s, root := newTestSFTPStorage(t)
+	for _, id := range []string{"B1", "B2"} {
+		localPath, fm := writeLocalFile(t, []byte("-- "+id+"\n"))
+		if err := s.Upload(context.Background(), id+"/mysql.sql", localPath, fm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// B1 还有一个未完成的上传
+	if err := os.WriteFile(filepath.Join(root, "B1", "influx.lp"+partSuffix), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := storedBackupIDs(t, s); len(got) != 0 {
+		t.Errorf("backups without manifest listed as complete: %v", got)
+	}
+	objects, err := s.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 3 {
+		t.Errorf("objects = %+v, want 3", objects)
+	}
+
+	if err := s.DeleteBackup(context.Background(), "B1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "B1")); !os.IsNotExist(err) {
+		t.Errorf("B1 still exists: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "B2", "mysql.sql")); err != nil {
+		t.Errorf("B2 removed: %v", err)
+	}
+	if err := s.DeleteBackup(context.Background(), ".."); err == nil {
+		t.Error("delete of invalid id succeeded")
+	}
+}
@@ -0,0 +1,191 @@
+package backup
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// scanStatements 逐条读取 SQL 文件中以 ; 结尾的语句（忽略引号内的 ;），去掉首尾空白后交给 fn
+func scanStatements(r io.Reader, fn func(stmt string) error) error {
+	br := bufio.NewReaderSize(r, 1<<20)
+	var (
+		buf     strings.Builder
+		quote   rune // 当前所在的引号，0 表示不在引号内
+		escaped bool
+	)
+	for {
+		c, _, err := br.ReadRune()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		switch {
+		case escaped:
+			escaped = false
+		case quote != 0 && c == '\\' && quote != '`':
+			escaped = true
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '\'' || c == '"' || c == '`'):
+			quote = c
+		case quote == 0 && c == ';':
+			if stmt := strings.TrimSpace(buf.String()); stmt != "" {
+				if err := fn(stmt); err != nil {
+					return err
+				}
+			}
+			buf.Reset()
+			continue
+		}
+		buf.WriteRune(c)
+	}
+	if quote != 0 {
+		return fmt.Errorf("SQL 文件不完整：引号未闭合")
+	}
+	if stmt := strings.TrimSpace(buf.String()); stmt != "" {
+		return fn(stmt)
+	}
+	return nil
+}
+
+// insertStmt 解析后的 INSERT/REPLACE 语句
+type insertStmt struct {
+	verb    string // INSERT | REPLACE
+	table   string
+	columns []string
+	tuples  []string // 每行 "(...)" 的原文
+	values  [][]string
+}
+
+// parseInsert 解析备份中由 writeRows 生成的 INSERT/REPLACE 语句：
+// VERB INTO `table` (`col`, ...) VALUES (v, ...),(v, ...)；值为 NULL、数字或单引号字符串
+func parseInsert(stmt string) (*insertStmt, error) {
+	s := &insertStmt{}
+	sp := strings.IndexByte(stmt, ' ')
+	if sp < 0 {
+		return nil, fmt.Errorf("无法解析的语句")
+	}
+	s.verb = strings.ToUpper(stmt[:sp])
+	rest := strings.TrimSpace(stmt[sp+1:])
+	if !strings.HasPrefix(strings.ToUpper(rest), "INTO ") {
+		return nil, fmt.Errorf("无法解析的语句")
+	}
+	rest = strings.TrimSpace(rest[5:])
+	open := strings.IndexByte(rest, '(')
+	if open < 0 {
+		return nil, fmt.Errorf("缺少列名")
+	}
+	s.table = strings.Trim(strings.TrimSpace(rest[:open]), "`")
+	closeIdx := strings.IndexByte(rest[open:], ')')
+	if closeIdx < 0 {
+		return nil, fmt.Errorf("缺少列名")
+	}
+	for _, c := range strings.Split(rest[open+1:open+closeIdx], ",") {
+		s.columns = append(s.columns, strings.Trim(strings.TrimSpace(c), "`"))
+	}
+	rest = strings.TrimSpace(rest[open+closeIdx+1:])
+	if !strings.HasPrefix(strings.ToUpper(rest), "VALUES") {
+		return nil, fmt.Errorf("缺少 VALUES")
+	}
+	rest = strings.TrimSpace(rest[len("VALUES"):])
+
+	// 按字符扫描各行，跟踪单引号字符串及转义
+	i := 0
+	for i < len(rest) {
+		for i < len(rest) && (rest[i] == ',' || rest[i] == ' ' || rest[i] == '\n' || rest[i] == '\r' || rest[i] == '\t') {
+			i++
+		}
+		if i >= len(rest) {
+			break
+		}
+		if rest[i] != '(' {
+			return nil, fmt.Errorf("第 %d 行格式错误", len(s.tuples)+1)
+		}
+		start := i
+		i++
+		var (
+			vals []string
+			tok  strings.Builder
+		)
+		inStr, escaped, done := false, false, false
+		for ; i < len(rest) && !done; i++ {
+			c := rest[i]
+			switch {
+			case escaped:
+				escaped = false
+				tok.WriteByte(c)
+			case inStr && c == '\\':
+				escaped = true
+				tok.WriteByte(c)
+			case inStr && c == '\'':
+				inStr = false
+				tok.WriteByte(c)
+			case inStr:
+				tok.WriteByte(c)
+			case c == '\'':
+				inStr = true
+				tok.WriteByte(c)
+			case c == ',':
+				vals = append(vals, strings.TrimSpace(tok.String()))
+				tok.Reset()
+			case c == ')':
+				vals = append(vals, strings.TrimSpace(tok.String()))
+				done = true
+			default:
+				tok.WriteByte(c)
+			}
+		}
+		if !done {
+			return nil, fmt.Errorf("第 %d 行未闭合", len(s.tuples)+1)
+		}
+		if len(vals) != len(s.columns) {
+			return nil, fmt.Errorf("第 %d 行有 %d 个值，应为 %d 个", len(s.tuples)+1, len(vals), len(s.columns))
+		}
+		s.tuples = append(s.tuples, rest[start:i])
+		s.values = append(s.values, vals)
+	}
+	return s, nil
+}
+
+// build 以 verb 及保留的行重新生成语句
+func (s *insertStmt) build(verb string, keep []int) string {
+	quoted := make([]string, len(s.columns))
+	for i, c := range s.columns {
+		quoted[i] = "`" + c + "`"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s INTO `%s` (%s) VALUES ", verb, s.table, strings.Join(quoted, ", "))
+	for i, k := range keep {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(s.tuples[k])
+	}
+	return b.String()
+}
+
+// unquoteSQL 还原 quoteStringForSQL 生成的字符串字面量，NULL 返回 ok=false
+func unquoteSQL(v string) (string, bool) {
+	if len(v) < 2 || v[0] != '\'' || v[len(v)-1] != '\'' {
+		if strings.EqualFold(v, "NULL") {
+			return "", false
+		}
+		return v, true
+	}
+	v = v[1 : len(v)-1]
+	if !strings.Contains(v, "\\") {
+		return v, true
+	}
+	var b strings.Builder
+	for i := 0; i < len(v); i++ {
+		if v[i] == '\\' && i+1 < len(v) {
+			i++
+		}
+		b.WriteByte(v[i])
+	}
+	return b.String(), true
+}
@@ -0,0 +1,176 @@
+package backup
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqlTestRows 需转义的字符串、NULL 与时间值；nil 表示 NULL
+var sqlTestRows = [][]interface{}{
+	{int64(1), "D-001", "普通文本", time.Date(2025, 6, 20, 8, 0, 0, 0, time.UTC), 12.5},
+	{int64(2), "O'Brien", `C:\data\`, time.Date(2025, 6, 20, 8, 30, 0, 0, time.UTC), nil},
+	{int64(3), `\'`, "a,(b);c\nd", nil, -1.25},
+	{int64(4), "", "NULL", time.Date(2025, 6, 19, 23, 59, 59, 0, time.UTC), 0.0},
+	{int64(5), nil, "'", time.Date(2025, 6, 20, 9, 0, 0, 0, time.UTC), 3.0},
+}
+
+// dumpTestRows 将 sqlTestRows 写入 SQLite 的 flight_track_points 表，再由 writeRows 导出为 SQL 文件，返回其中的语句
+func dumpTestRows(t *testing.T) []string {
+	t.Helper()
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "src.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE flight_track_points (id INTEGER PRIMARY KEY, uasID TEXT, note TEXT, timeStamp DATETIME, height REAL)"); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range sqlTestRows {
+		if _, err := db.Exec("INSERT INTO flight_track_points VALUES (?, ?, ?, ?, ?)", r...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dir := t.TempDir()
+	out, err := createOutput(dir, "mysql.sql", "gzip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if n, err := writeRows(tx, out, "flight_track_points", "INSERT", "", nil); err != nil || n != int64(len(sqlTestRows)) {
+		t.Fatalf("writeRows = %d, %v", n, err)
+	}
+	if _, err := out.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := openBackupFile(filepath.Join(dir, out.name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var stmts []string
+	if err := scanStatements(r, func(stmt string) error {
+		stmts = append(stmts, stmt)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return stmts
+}
+
+// cellText 单元格期望还原出的文本，NULL 返回 ok=false
+func cellText(v interface{}) (string, bool) {
+	switch val := v.(type) {
+	case nil:
+		return "", false
+	case time.Time:
+		return val.Format("2006-01-02 15:04:05"), true
+	case string:
+		return val, true
+	default:
+		return fmt.Sprint(val), true
+	}
+}
+
+func TestSQLRoundTrip(t *testing.T) {
+	stmts := dumpTestRows(t)
+	if len(stmts) != 1 {
+		t.Fatalf("statements = %d, want 1", len(stmts))
+	}
+	ins, err := parseInsert(stmts[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ins.verb != "INSERT" || ins.table != "flight_track_points" {
+		t.Fatalf("verb/table = %s/%s", ins.verb, ins.table)
+	}
+	if want := []string{"id", "uasID", "note", "timeStamp", "height"}; !reflect.DeepEqual(ins.columns, want) {
+		t.Fatalf("columns = %v, want %v", ins.columns, want)
+	}
+	check := func(t *testing.T, ins *insertStmt) {
+		t.Helper()
+		if len(ins.values) != len(sqlTestRows) {
+			t.Fatalf("rows = %d, want %d", len(ins.values), len(sqlTestRows))
+		}
+		for i, row := range sqlTestRows {
+			for j, cell := range row {
+				want, wantOK := cellText(cell)
+				got, ok := unquoteSQL(ins.values[i][j])
+				if ok != wantOK || got != want {
+					t.Errorf("row %d col %s = %q (%v), want %q (%v)", i+1, ins.columns[j], got, ok, want, wantOK)
+				}
+			}
+		}
+	}
+	check(t, ins)
+
+	// build 重新生成的语句应能再次解析出相同的值
+	all := make([]int, len(ins.tuples))
+	for i := range all {
+		all[i] = i
+	}
+	rebuilt, err := parseInsert(ins.build("REPLACE", all))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rebuilt.verb != "REPLACE" || rebuilt.table != ins.table || !reflect.DeepEqual(rebuilt.columns, ins.columns) {
+		t.Fatalf("rebuilt verb/table/columns = %s/%s/%v", rebuilt.verb, rebuilt.table, rebuilt.columns)
+	}
+	check(t, rebuilt)
+
+	// 只保留部分行时，其余行的原文不受转义字符影响
+	part, err := parseInsert(ins.build("INSERT", []int{1, 4}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(part.values) != 2 || part.values[0][0] != "2" || part.values[1][0] != "5" {
+		t.Fatalf("partial rows = %v", part.values)
+	}
+}
+
+func TestFilterTuplesUTC(t *testing.T) {
+	ins, err := parseInsert(dumpTestRows(t)[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	shanghai := time.FixedZone("CST", 8*3600)
+	tests := []struct {
+		name string
+		opts RestoreOptions
+		keep []int
+	}{
+		{
+			// 轨迹点 timeStamp 为 UTC 墙上时间，不受 Location 影响；NULL 时间的行保留
+			name: "UTC 时间范围",
+			opts: RestoreOptions{From: time.Date(2025, 6, 20, 8, 0, 0, 0, time.UTC), To: time.Date(2025, 6, 20, 9, 0, 0, 0, time.UTC), Location: shanghai},
+			keep: []int{0, 1, 2},
+		},
+		{
+			// 以东八区表示的同一时间范围结果相同
+			name: "东八区表示的范围",
+			opts: RestoreOptions{From: time.Date(2025, 6, 20, 16, 0, 0, 0, shanghai), To: time.Date(2025, 6, 20, 17, 0, 0, 0, shanghai), Location: shanghai},
+			keep: []int{0, 1, 2},
+		},
+		{
+			name: "不限时间",
+			opts: RestoreOptions{Location: shanghai},
+			keep: []int{0, 1, 2, 3, 4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := filterTuples(ins, tt.opts); !reflect.DeepEqual(got, tt.keep) {
+				t.Errorf("keep = %v, want %v", got, tt.keep)
+			}
+		})
+	}
+}
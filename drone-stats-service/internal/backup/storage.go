@@ -0,0 +1,369 @@
+package backup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 存储目标类型
+const (
+	StorageLocal = "local"
+	StorageS3    = "s3"
+	StorageSFTP  = "sftp"
+)
+
+// partSuffix 本地/SFTP 上传中的临时文件后缀，上传并校验完成后重命名为正式文件
+const partSuffix = ".part"
+
+// Storage 备份存储目标。备份先写入本地 BackupDir，成功后上传到各存储目标，
+// 对象按 "<备份 ID>/<文件名>" 组织；manifest.json 最后上传，存在即表示该备份在目标上完整。
+type Storage interface {
+	Name() string
+	// Upload 将本地文件上传为 key：目标上已有相同内容时直接返回；上次中断时从已上传的位置继续；
+	// 上传完成后按 fm.SHA256 校验，不一致时删除已上传的内容并返回错误
+	Upload(ctx context.Context, key, localPath string, fm FileManifest) error
+	// List 返回目标上的全部对象（含未完成的上传）
+	List(ctx context.Context) ([]StoredObject, error)
+	// Open 读取 key 的内容
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// DeleteBackup 删除备份 id 下的全部对象及未完成的上传
+	DeleteBackup(ctx context.Context, id string) error
+	Close() error
+}
+
+// StoredObject 存储目标上的对象
+type StoredObject struct {
+	Key     string // <备份 ID>/<文件名>
+	Size    int64
+	ModTime time.Time
+}
+
+// Target 存储目标及其保留天数，各目标独立清理
+type Target struct {
+	Storage       Storage
+	RetentionDays int // 为 0 时使用 Manager.RetentionDays
+}
+
+// SyncTargets 将本地已完成、目标上尚不完整的备份按时间顺序上传到各存储目标，然后按目标的保留期清理。
+// 某个目标失败不影响其它目标，返回各目标的错误。调用方需持有 runMu。
+func (m *Manager) SyncTargets(ctx context.Context) []error {
+	if len(m.Targets) == 0 {
+		return nil
+	}
+	list, err := m.ListManifests()
+	if err != nil {
+		return []error{err}
+	}
+	var errs []error
+	for _, t := range m.Targets {
+		if err := m.syncTarget(ctx, t, list); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", t.Storage.Name(), err))
+		}
+	}
+	return errs
+}
+
+func (m *Manager) syncTarget(ctx context.Context, t Target, local []*Manifest) error {
+	objects, err := t.Storage.List(ctx)
+	if err != nil {
+		return fmt.Errorf("列出备份失败: %w", err)
+	}
+	retention := t.RetentionDays
+	if retention <= 0 {
+		retention = m.RetentionDays
+	}
+	// 目标保留期短于本地时，已超出目标保留期的备份不再上传，避免上传后又被清理
+	var cutoff time.Time
+	skip := map[string]bool{}
+	if retention > 0 {
+		cutoff = time.Now().AddDate(0, 0, -retention)
+		for _, id := range expiredBackups(local, cutoff) {
+			skip[id] = true
+		}
+	}
+	remote := groupStoredObjects(objects)
+	for _, mf := range local {
+		if remote[mf.ID].complete || skip[mf.ID] {
+			continue
+		}
+		if err := uploadBackup(ctx, t.Storage, filepath.Join(m.BackupDir, mf.ID), mf); err != nil {
+			return fmt.Errorf("上传 %s 失败: %w", mf.ID, err)
+		}
+	}
+	if cutoff.IsZero() {
+		return nil
+	}
+	return cleanupTarget(ctx, t.Storage, cutoff)
+}
+
+// uploadBackup 上传备份目录中的全部文件，清单最后上传
+func uploadBackup(ctx context.Context, s Storage, dir string, mf *Manifest) error {
+	for _, fm := range mf.Files {
+		if err := s.Upload(ctx, mf.ID+"/"+fm.Name, filepath.Join(dir, fm.Name), fm); err != nil {
+			return err
+		}
+	}
+	manifestPath := filepath.Join(dir, manifestName)
+	fm, err := hashFile(manifestPath)
+	if err != nil {
+		return err
+	}
+	fm.Name = manifestName
+	return s.Upload(ctx, mf.ID+"/"+manifestName, manifestPath, fm)
+}
+
+// cleanupTarget 按备份链清理目标上的过期备份，规则与本地相同；没有清单的（未上传完成的）备份按最后修改时间清理
+func cleanupTarget(ctx context.Context, s Storage, cutoff time.Time) error {
+	objects, err := s.List(ctx)
+	if err != nil {
+		return err
+	}
+	var list []*Manifest
+	for id, b := range groupStoredObjects(objects) {
+		if !b.complete {
+			if b.modTime.Before(cutoff) {
+				if err := s.DeleteBackup(ctx, id); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		mf, err := readStoredManifest(ctx, s, id)
+		if err != nil {
+			return fmt.Errorf("读取 %s 的清单失败: %w", id, err)
+		}
+		list = append(list, mf)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].StartedAt.Before(list[j].StartedAt) })
+	for _, id := range expiredBackups(list, cutoff) {
+		if err := s.DeleteBackup(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readStoredManifest(ctx context.Context, s Storage, id string) (*Manifest, error) {
+	rc, err := s.Open(ctx, id+"/"+manifestName)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	var mf Manifest
+	if err := json.NewDecoder(rc).Decode(&mf); err != nil {
+		return nil, err
+	}
+	mf.ID = id
+	return &mf, nil
+}
+
+type storedBackup struct {
+	complete bool
+	modTime  time.Time // 目录下最新对象的修改时间
+}
+
+// groupStoredObjects 按备份 ID 汇总对象
+func groupStoredObjects(objects []StoredObject) map[string]storedBackup {
+	out := make(map[string]storedBackup)
+	for _, o := range objects {
+		id, name, ok := strings.Cut(o.Key, "/")
+		if !ok {
+			continue
+		}
+		b := out[id]
+		if name == manifestName {
+			b.complete = true
+		}
+		if o.ModTime.After(b.modTime) {
+			b.modTime = o.ModTime
+		}
+		out[id] = b
+	}
+	return out
+}
+
+// hashFile 计算本地文件的大小及 SHA256
+func hashFile(p string) (FileManifest, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return FileManifest{}, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return FileManifest{}, err
+	}
+	return FileManifest{Name: filepath.Base(p), Size: n, SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// hashReader 计算 r 的 SHA256
+func hashReader(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// validStorageKey 对象 key 只能是 "<备份 ID>/<文件名>"
+func validStorageKey(key string) error {
+	id, name, ok := strings.Cut(key, "/")
+	if !ok || validBackupID(id) != nil || validBackupID(name) != nil {
+		return fmt.Errorf("非法的对象 key %q", key)
+	}
+	return nil
+}
+
+// LocalStorage 本地文件系统存储目标，如挂载的 NAS 或另一块磁盘
+type LocalStorage struct {
+	name string
+	root string
+}
+
+// NewLocalStorage 创建本地存储目标，root 不存在时创建
+func NewLocalStorage(name, root string) (*LocalStorage, error) {
+	if root == "" {
+		return nil, fmt.Errorf("本地存储目标未配置目录")
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+	return &LocalStorage{name: name, root: root}, nil
+}
+
+func (s *LocalStorage) Name() string { return s.name }
+
+func (s *LocalStorage) Upload(ctx context.Context, key, localPath string, fm FileManifest) error {
+	if err := validStorageKey(key); err != nil {
+		return err
+	}
+	dst := filepath.Join(s.root, filepath.FromSlash(key))
+	if info, err := os.Stat(dst); err == nil && info.Size() == fm.Size {
+		if got, err := hashFile(dst); err == nil && got.SHA256 == fm.SHA256 {
+			return nil
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	part := dst + partSuffix
+	f, err := os.OpenFile(part, os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if err := resumeCopy(ctx, f, localPath, fm.Size); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	got, err := hashFile(part)
+	if err != nil {
+		return err
+	}
+	if got.SHA256 != fm.SHA256 {
+		_ = os.Remove(part)
+		return fmt.Errorf("%s 上传后 SHA256 与清单不一致", key)
+	}
+	return os.Rename(part, dst)
+}
+
+func (s *LocalStorage) List(ctx context.Context) ([]StoredObject, error) {
+	var out []StoredObject
+	err := filepath.WalkDir(s.root, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(s.root, p)
+		if err != nil {
+			return err
+		}
+		out = append(out, StoredObject{Key: filepath.ToSlash(rel), Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	return out, err
+}
+
+func (s *LocalStorage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := validStorageKey(key); err != nil {
+		return nil, err
+	}
+	return os.Open(filepath.Join(s.root, filepath.FromSlash(key)))
+}
+
+func (s *LocalStorage) DeleteBackup(ctx context.Context, id string) error {
+	if err := validBackupID(id); err != nil {
+		return err
+	}
+	return os.RemoveAll(filepath.Join(s.root, id))
+}
+
+func (s *LocalStorage) Close() error { return nil }
+
+// seekWriter 可定位写入的文件（os.File / sftp.File）
+type seekWriter interface {
+	io.Writer
+	io.Seeker
+	Truncate(size int64) error
+}
+
+// resumeCopy 将本地文件从 dst 当前长度处继续写入 dst；dst 长度超过源文件时从头重写
+func resumeCopy(ctx context.Context, dst seekWriter, localPath string, size int64) error {
+	off, err := dst.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if off > size {
+		if err := dst.Truncate(0); err != nil {
+			return err
+		}
+		if off, err = dst.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+	}
+	src, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	if _, err := src.Seek(off, io.SeekStart); err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, ctxReader{ctx: ctx, r: src})
+	return err
+}
+
+// ctxReader 在 context 取消后停止读取，使长时间的上传可以被中断
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c ctxReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
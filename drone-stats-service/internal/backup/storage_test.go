@@ -0,0 +1,121 @@
+package backup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// writeTestBackup 在 dir 下创建只含一个数据文件的已完成备份
+func writeTestBackup(t *testing.T, dir string, mf *Manifest) {
+	t.Helper()
+	bdir := filepath.Join(dir, mf.ID)
+	if err := os.MkdirAll(bdir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(bdir, "mysql.sql"), []byte("-- "+mf.ID+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fm, err := hashFile(filepath.Join(bdir, "mysql.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fm.Name = "mysql.sql"
+	mf.Files = []FileManifest{fm}
+	if err := writeManifest(bdir, mf); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// storedBackupIDs 目标上已完整上传的备份
+func storedBackupIDs(t *testing.T, s Storage) []string {
+	t.Helper()
+	objects, err := s.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for id, b := range groupStoredObjects(objects) {
+		if b.complete {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// recordingStorage 记录上传过的备份
+type recordingStorage struct {
+	Storage
+	uploaded map[string]bool
+}
+
+func (s *recordingStorage) Upload(ctx context.Context, key, localPath string, fm FileManifest) error {
+	s.uploaded[filepath.Dir(key)] = true
+	return s.Storage.Upload(ctx, key, localPath, fm)
+}
+
+func TestSyncTargetsAndDelete(t *testing.T) {
+	local := t.TempDir()
+	now := time.Now()
+	day := 24 * time.Hour
+	// A 为 20 天前的完整备份；B1 为 3 天前的完整备份，B2 为其后 1 天前的增量备份
+	for _, mf := range []*Manifest{
+		{ID: "A", Type: TypeFull, Base: "A", StartedAt: now.Add(-20 * day), FinishedAt: now.Add(-20 * day)},
+		{ID: "B1", Type: TypeFull, Base: "B1", StartedAt: now.Add(-3 * day), FinishedAt: now.Add(-3 * day)},
+		{ID: "B2", Type: TypeIncremental, Base: "B1", Parent: "B1", StartedAt: now.Add(-day), FinishedAt: now.Add(-day)},
+	} {
+		writeTestBackup(t, local, mf)
+	}
+	shortLocal, err := NewLocalStorage("short", t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	short := &recordingStorage{Storage: shortLocal, uploaded: map[string]bool{}}
+	long, err := NewLocalStorage("long", t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &Manager{
+		BackupDir:     local,
+		RetentionDays: 30,
+		Targets:       []Target{{Storage: short, RetentionDays: 7}, {Storage: long}},
+	}
+
+	if errs := m.SyncTargets(context.Background()); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	// 已超出 short 保留期的 A 不再上传
+	if short.uploaded["A"] {
+		t.Error("expired backup A uploaded to short")
+	}
+	if got, want := storedBackupIDs(t, short), []string{"B1", "B2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("short = %v, want %v", got, want)
+	}
+	if got, want := storedBackupIDs(t, long), []string{"A", "B1", "B2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("long = %v, want %v", got, want)
+	}
+
+	deleted, err := m.Delete("B1", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"B2", "B1"}; !reflect.DeepEqual(deleted, want) {
+		t.Errorf("deleted = %v, want %v", deleted, want)
+	}
+	if got := storedBackupIDs(t, short); len(got) != 0 {
+		t.Errorf("short after delete = %v, want empty", got)
+	}
+	if got, want := storedBackupIDs(t, long), []string{"A"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("long after delete = %v, want %v", got, want)
+	}
+	for _, id := range deleted {
+		if _, err := os.Stat(filepath.Join(local, id)); !os.IsNotExist(err) {
+			t.Errorf("local %s still exists: %v", id, err)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package battery
+
+import (
+	"math"
+
+	"drone-stats-service/internal/model"
+)
+
+// 单架次容量估算方式
+const (
+	CapacityMethodCurrent = "current" // 电流对时间积分
+	CapacityMethodRM      = "rm"      // 剩余容量（RM）差值
+	CapacityMethodNone    = "none"    // 无可用数据
+)
+
+const (
+	// minSOCDrop SOC 降幅低于该值（百分点）时不估算有效容量，避免小分母放大误差
+	minSOCDrop = 5
+	// maxIntegrateGap 相邻采样间隔超过该值（秒）时不做电流积分，视为数据缺失
+	maxIntegrateGap = 30
+	// minRegressionPoints 估算内阻所需的最少有效采样点数
+	minRegressionPoints = 10
+	// minCurrentSpreadA 电流跨度（A）不足时无法从电压跌落估算内阻
+	minCurrentSpreadA = 2
+)
+
+// FlightMetrics 单架次电池指标
+type FlightMetrics struct {
+	SOCStart            int
+	SOCEnd              int
+	SOCUsed             float64 // 百分点
+	CapacityUsedAh      float64
+	CapacityMethod      string
+	EffectiveCapacityAh float64 // CapacityUsedAh / (SOCUsed/100)，数据不足时为 0
+	ResistanceMOhm      float64 // 电压-电流线性回归斜率的相反数，数据不足时为 0
+	MinVoltage          int     // mV
+	MaxCurrent          int     // mA
+}
+
+// Analyze 根据轨迹点（需按时间升序）估算单架次的电池容量使用与内阻。
+// 轨迹点中 SOC/RM/电压/电流为 0 表示无数据，按协议约定跳过。
+func Analyze(points []model.FlightTrackPoint) FlightMetrics {
+	m := FlightMetrics{CapacityMethod: CapacityMethodNone}
+
+	first, last := -1, -1
+	for i, p := range points {
+		if p.SOC > 0 {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+		if p.Voltage > 0 && (m.MinVoltage == 0 || p.Voltage < m.MinVoltage) {
+			m.MinVoltage = p.Voltage
+		}
+		if p.Current > m.MaxCurrent {
+			m.MaxCurrent = p.Current
+		}
+	}
+	if first >= 0 {
+		m.SOCStart = points[first].SOC
+		m.SOCEnd = points[last].SOC
+		m.SOCUsed = math.Max(float64(m.SOCStart-m.SOCEnd), 0)
+	}
+
+	if ah, ok := integrateCurrentAh(points); ok {
+		m.CapacityUsedAh = ah
+		m.CapacityMethod = CapacityMethodCurrent
+	} else if ah, ok := rmDeltaAh(points); ok {
+		m.CapacityUsedAh = ah
+		m.CapacityMethod = CapacityMethodRM
+	}
+	if m.CapacityUsedAh > 0 && m.SOCUsed >= minSOCDrop {
+		m.EffectiveCapacityAh = m.CapacityUsedAh / (m.SOCUsed / 100)
+	}
+	m.ResistanceMOhm = estimateResistanceMOhm(points)
+	return m
+}
+
+// integrateCurrentAh 对电流做梯形积分（A·h），任一端无电流或间隔过大的区间跳过
+func integrateCurrentAh(points []model.FlightTrackPoint) (float64, bool) {
+	var ah float64
+	used := false
+	for i := 1; i < len(points); i++ {
+		a, b := points[i-1], points[i]
+		if a.Current <= 0 || b.Current <= 0 {
+			continue
+		}
+		dt := b.TimeStamp.Sub(a.TimeStamp).Seconds()
+		if dt <= 0 || dt > maxIntegrateGap {
+			continue
+		}
+		ah += (float64(a.Current) + float64(b.Current)) / 2 / 1000 * dt / 3600
+		used = true
+	}
+	return ah, used
+}
+
+// rmDeltaAh 取首尾有效 RM 之差（A·h）
+func rmDeltaAh(points []model.FlightTrackPoint) (float64, bool) {
+	first, last := -1, -1
+	for i, p := range points {
+		if p.RM > 0 {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+	if first < 0 || first == last {
+		return 0, false
+	}
+	delta := float64(points[first].RM - points[last].RM)
+	if delta <= 0 {
+		return 0, false
+	}
+	return delta, true
+}
+
+// estimateResistanceMOhm 以 V = V0 - R·I 对有效采样点做最小二乘拟合，返回 R（毫欧）
+func estimateResistanceMOhm(points []model.FlightTrackPoint) float64 {
+	var n, sumI, sumV, sumII, sumIV float64
+	minI, maxI := math.Inf(1), math.Inf(-1)
+	for _, p := range points {
+		if p.Voltage <= 0 || p.Current <= 0 {
+			continue
+		}
+		i := float64(p.Current) / 1000
+		v := float64(p.Voltage) / 1000
+		n++
+		sumI += i
+		sumV += v
+		sumII += i * i
+		sumIV += i * v
+		minI = math.Min(minI, i)
+		maxI = math.Max(maxI, i)
+	}
+	if n < minRegressionPoints || maxI-minI < minCurrentSpreadA {
+		return 0
+	}
+	denom := n*sumII - sumI*sumI
+	if denom == 0 {
+		return 0
+	}
+	slope := (n*sumIV - sumI*sumV) / denom
+	if slope >= 0 {
+		// 电压未随电流下降，无法得到有意义的内阻
+		return 0
+	}
+	return -slope * 1000
+}
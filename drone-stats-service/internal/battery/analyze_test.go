@@ -0,0 +1,139 @@
+package battery
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"drone-stats-service/internal/model"
+)
+
+var analyzeBase = time.Date(2025, 6, 20, 8, 0, 0, 0, time.UTC)
+
+// samplePoints 以 step 秒为间隔生成 n 个轨迹点，fill 按序号填充电池字段
+func samplePoints(n int, step time.Duration, fill func(i int, p *model.FlightTrackPoint)) []model.FlightTrackPoint {
+	points := make([]model.FlightTrackPoint, n)
+	for i := range points {
+		points[i].TimeStamp = analyzeBase.Add(time.Duration(i) * step)
+		fill(i, &points[i])
+	}
+	return points
+}
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		name       string
+		points     []model.FlightTrackPoint
+		method     string
+		socStart   int
+		socEnd     int
+		usedAh     float64
+		effAh      float64
+		resistance float64
+		minVoltage int
+		maxCurrent int
+	}{
+		{
+			name:   "无轨迹点",
+			method: CapacityMethodNone,
+		},
+		{
+			// 10A 恒流 1 小时，SOC 90 -> 40：耗 10Ah，有效容量 20Ah；电流无跨度不估算内阻
+			name: "电流积分",
+			points: samplePoints(361, 10*time.Second, func(i int, p *model.FlightTrackPoint) {
+				p.SOC = 90 - i*50/360
+				p.Voltage = 48000
+				p.Current = 10000
+			}),
+			method:     CapacityMethodCurrent,
+			socStart:   90,
+			socEnd:     40,
+			usedAh:     10,
+			effAh:      20,
+			minVoltage: 48000,
+			maxCurrent: 10000,
+		},
+		{
+			// 无电流时回退 RM 首尾差值
+			name: "RM 回退",
+			points: samplePoints(11, 10*time.Second, func(i int, p *model.FlightTrackPoint) {
+				p.SOC = 80 - i*2
+				p.RM = 16 - i
+			}),
+			method:   CapacityMethodRM,
+			socStart: 80,
+			socEnd:   60,
+			usedAh:   10,
+			effAh:    50,
+		},
+		{
+			// 采样间隔超过 maxIntegrateGap 的区间不积分，且无 RM，视为无数据
+			name: "间隔过大",
+			points: samplePoints(5, time.Minute, func(i int, p *model.FlightTrackPoint) {
+				p.SOC = 90 - i*10
+				p.Current = 10000
+			}),
+			method:     CapacityMethodNone,
+			socStart:   90,
+			socEnd:     50,
+			maxCurrent: 10000,
+		},
+		{
+			// SOC 为 0 的采样按无数据跳过；降幅不足 minSOCDrop 时不估算有效容量
+			name: "SOC 降幅过小",
+			points: samplePoints(13, 10*time.Second, func(i int, p *model.FlightTrackPoint) {
+				if i > 0 && i < 12 {
+					p.SOC = 70 - i/4
+				}
+				p.RM = 20 - i/6
+			}),
+			method:   CapacityMethodRM,
+			socStart: 70,
+			socEnd:   68,
+			usedAh:   2,
+		},
+		{
+			// V = 50V - 0.025Ω × I，电流 5A~16A：内阻 25 毫欧
+			name: "内阻回归",
+			points: samplePoints(12, 10*time.Second, func(i int, p *model.FlightTrackPoint) {
+				p.Current = (5 + i) * 1000
+				p.Voltage = 50000 - 25*(5+i)
+			}),
+			method:     CapacityMethodCurrent,
+			usedAh:     (5.0 + 16.0) / 2 * 110 / 3600,
+			resistance: 25,
+			minVoltage: 50000 - 25*16,
+			maxCurrent: 16000,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := Analyze(tt.points)
+			if m.CapacityMethod != tt.method {
+				t.Errorf("method = %s, want %s", m.CapacityMethod, tt.method)
+			}
+			if m.SOCStart != tt.socStart || m.SOCEnd != tt.socEnd {
+				t.Errorf("SOC = %d -> %d, want %d -> %d", m.SOCStart, m.SOCEnd, tt.socStart, tt.socEnd)
+			}
+			if want := math.Max(float64(tt.socStart-tt.socEnd), 0); m.SOCUsed != want {
+				t.Errorf("SOCUsed = %v, want %v", m.SOCUsed, want)
+			}
+			if !approx(m.CapacityUsedAh, tt.usedAh) {
+				t.Errorf("CapacityUsedAh = %v, want %v", m.CapacityUsedAh, tt.usedAh)
+			}
+			if !approx(m.EffectiveCapacityAh, tt.effAh) {
+				t.Errorf("EffectiveCapacityAh = %v, want %v", m.EffectiveCapacityAh, tt.effAh)
+			}
+			if !approx(m.ResistanceMOhm, tt.resistance) {
+				t.Errorf("ResistanceMOhm = %v, want %v", m.ResistanceMOhm, tt.resistance)
+			}
+			if m.MinVoltage != tt.minVoltage || m.MaxCurrent != tt.maxCurrent {
+				t.Errorf("MinVoltage/MaxCurrent = %d/%d, want %d/%d", m.MinVoltage, m.MaxCurrent, tt.minVoltage, tt.maxCurrent)
+			}
+		})
+	}
+}
+
+func approx(got, want float64) bool {
+	return math.Abs(got-want) <= 1e-6*math.Max(1, math.Abs(want))
+}
@@ -0,0 +1,139 @@
+package battery
+
+import (
+	"math"
+	"sort"
+
+	"drone-stats-service/internal/model"
+)
+
+// 单架次耗电计算方式（flight_records.energy_method）
+const (
+	EnergyMethodPower = "power" // 功率对真实时间戳积分
+	EnergyMethodSOC   = "soc"   // SOC 降幅 × 标称容量 × 电压
+	EnergyMethodRM    = "rm"    // 剩余容量（RM）差值 × 电压
+	EnergyMethodNone  = "none"  // 无可用数据
+)
+
+const (
+	// maxInterpolateGap 相邻有效功率采样间隔不超过该值（秒）时按线性插值（梯形）积分
+	maxInterpolateGap = 30
+	// minPowerCoverage 有效功率采样覆盖的时长占飞行时长的比例低于该值时不采用功率积分
+	minPowerCoverage = 0.5
+)
+
+// EnergyOptions 回退计算所需的电池参数，0 表示未知：
+// 电压未知时取该架次有效电压采样的均值，容量未知时由同一采样的 RM / SOC 推算
+type EnergyOptions struct {
+	NominalCapacityAh float64
+	NominalVoltageV   float64
+}
+
+// EnergyResult 单架次耗电
+type EnergyResult struct {
+	KWh         float64
+	Method      string
+	GapSeconds  float64 // 超过插值上限、以平均功率补齐的时长（秒）
+	CoveredRate float64 // 有效功率采样覆盖的时长占飞行时长的比例
+}
+
+// Energy 计算单架次耗电（kWh），points 需按时间升序。
+// 电压、电流同时有效的采样点按真实时间戳做梯形积分：间隔不超过 maxInterpolateGap 的区间线性插值，
+// 更长的间隔以已测区间的平均功率补齐。有效采样覆盖不足时回退到 SOC × 标称容量，再回退到 RM 差值。
+func Energy(points []model.FlightTrackPoint, opts EnergyOptions) EnergyResult {
+	res := EnergyResult{Method: EnergyMethodNone}
+	if len(points) < 2 {
+		return res
+	}
+	flightSeconds := points[len(points)-1].TimeStamp.Sub(points[0].TimeStamp).Seconds()
+
+	var (
+		wattSeconds, measured, gap float64
+		prev                       *model.FlightTrackPoint
+	)
+	for i := range points {
+		p := &points[i]
+		if p.Voltage <= 0 || p.Current <= 0 {
+			continue
+		}
+		if prev != nil {
+			dt := p.TimeStamp.Sub(prev.TimeStamp).Seconds()
+			switch {
+			case dt <= 0:
+			case dt <= maxInterpolateGap:
+				wattSeconds += (powerW(*prev) + powerW(*p)) / 2 * dt
+				measured += dt
+			default:
+				gap += dt
+			}
+		}
+		prev = p
+	}
+	if measured > 0 && flightSeconds > 0 {
+		res.CoveredRate = math.Min(measured/flightSeconds, 1)
+	}
+	if res.CoveredRate >= minPowerCoverage {
+		// 长间隔按已测区间平均功率补齐
+		wattSeconds += wattSeconds / measured * gap
+		res.KWh = wattSeconds / 3600 / 1000
+		res.Method = EnergyMethodPower
+		res.GapSeconds = gap
+		return res
+	}
+
+	voltage := opts.NominalVoltageV
+	if voltage <= 0 {
+		voltage = meanVoltageV(points)
+	}
+	if voltage <= 0 {
+		return res
+	}
+	capacity := opts.NominalCapacityAh
+	if capacity <= 0 {
+		capacity = packCapacityAh(points)
+	}
+	if m := Analyze(points); m.SOCUsed > 0 && capacity > 0 {
+		res.KWh = m.SOCUsed / 100 * capacity * voltage / 1000
+		res.Method = EnergyMethodSOC
+		return res
+	}
+	if ah, ok := rmDeltaAh(points); ok {
+		res.KWh = ah * voltage / 1000
+		res.Method = EnergyMethodRM
+	}
+	return res
+}
+
+func powerW(p model.FlightTrackPoint) float64 {
+	return float64(p.Voltage) / 1000 * float64(p.Current) / 1000
+}
+
+// packCapacityAh 由 RM、SOC 同时有效的采样推算电池满电容量（RM / SOC × 100，A·h），取中位数；无此类采样时返回 0
+func packCapacityAh(points []model.FlightTrackPoint) float64 {
+	var caps []float64
+	for _, p := range points {
+		if p.RM > 0 && p.SOC > 0 {
+			caps = append(caps, float64(p.RM)*100/float64(p.SOC))
+		}
+	}
+	if len(caps) == 0 {
+		return 0
+	}
+	sort.Float64s(caps)
+	return caps[len(caps)/2]
+}
+
+// meanVoltageV 有效电压采样的平均值（V）
+func meanVoltageV(points []model.FlightTrackPoint) float64 {
+	var sum, n float64
+	for _, p := range points {
+		if p.Voltage > 0 {
+			sum += float64(p.Voltage) / 1000
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / n
+}
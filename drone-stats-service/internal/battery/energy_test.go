@@ -0,0 +1,114 @@
+package battery
+
+import (
+	"testing"
+	"time"
+
+	"drone-stats-service/internal/model"
+)
+
+func TestEnergy(t *testing.T) {
+	// SOC 90 -> 40，每 10 秒一个点
+	socDrop := func(i int, p *model.FlightTrackPoint) { p.SOC = 90 - i*5 }
+	tests := []struct {
+		name   string
+		points []model.FlightTrackPoint
+		opts   EnergyOptions
+		method string
+		kwh    float64
+	}{
+		{
+			// 48V × 10A 恒定 100 秒
+			name: "功率积分",
+			points: samplePoints(11, 10*time.Second, func(i int, p *model.FlightTrackPoint) {
+				p.Voltage = 48000
+				p.Current = 10000
+			}),
+			method: EnergyMethodPower,
+			kwh:    48.0 * 10 * 100 / 3600 / 1000,
+		},
+		{
+			// 无电流：SOC 降 50% × 20Ah × 48V
+			name:   "SOC 回退，使用配置",
+			points: samplePoints(11, 10*time.Second, socDrop),
+			opts:   EnergyOptions{NominalCapacityAh: 20, NominalVoltageV: 48},
+			method: EnergyMethodSOC,
+			kwh:    0.48,
+		},
+		{
+			// 未配置容量与电压：容量由 RM / SOC 推算为 20Ah，电压取采样均值 48V
+			name: "SOC 回退，由采样推算",
+			points: samplePoints(11, 10*time.Second, func(i int, p *model.FlightTrackPoint) {
+				socDrop(i, p)
+				p.RM = p.SOC / 5
+				p.Voltage = 47000 + 200*i
+			}),
+			method: EnergyMethodSOC,
+			kwh:    0.48,
+		},
+		{
+			// 电压、电流均为 0（不可用）：容量由 RM / SOC 推算为 20Ah，SOC 降 50% × 20Ah × 标称 44.4V
+			name: "电压电流不可用，使用标称电压",
+			points: samplePoints(11, 10*time.Second, func(i int, p *model.FlightTrackPoint) {
+				socDrop(i, p)
+				p.RM = p.SOC / 5
+				p.Voltage = 0
+				p.Current = 0
+			}),
+			opts:   EnergyOptions{NominalVoltageV: 44.4},
+			method: EnergyMethodSOC,
+			kwh:    0.444,
+		},
+		{
+			// 未配置容量且无 RM 时无法按 SOC 回退
+			name: "SOC 回退，容量未知",
+			points: samplePoints(11, 10*time.Second, func(i int, p *model.FlightTrackPoint) {
+				socDrop(i, p)
+				p.Voltage = 48000
+			}),
+			method: EnergyMethodNone,
+		},
+		{
+			// 无 SOC：RM 差值 10Ah × 48V
+			name: "RM 回退",
+			points: samplePoints(11, 10*time.Second, func(i int, p *model.FlightTrackPoint) {
+				p.RM = 18 - i
+			}),
+			opts:   EnergyOptions{NominalVoltageV: 48},
+			method: EnergyMethodRM,
+			kwh:    0.48,
+		},
+		{
+			// 无电压采样且未配置标称电压
+			name:   "电压未知",
+			points: samplePoints(11, 10*time.Second, socDrop),
+			opts:   EnergyOptions{NominalCapacityAh: 20},
+			method: EnergyMethodNone,
+		},
+		{
+			// 功率采样仅覆盖前 20 秒（不足一半），回退到 SOC
+			name: "功率覆盖不足",
+			points: samplePoints(11, 10*time.Second, func(i int, p *model.FlightTrackPoint) {
+				socDrop(i, p)
+				if i <= 2 {
+					p.Voltage = 48000
+					p.Current = 10000
+				}
+			}),
+			opts:   EnergyOptions{NominalCapacityAh: 20},
+			method: EnergyMethodSOC,
+			kwh:    0.48,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := Energy(tt.points, tt.opts)
+			if res.Method != tt.method {
+				t.Errorf("method = %s, want %s", res.Method, tt.method)
+			}
+			if !approx(res.KWh, tt.kwh) {
+				t.Errorf("kWh = %v, want %v", res.KWh, tt.kwh)
+			}
+		})
+	}
+}
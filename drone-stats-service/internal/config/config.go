@@ -4,9 +4,28 @@ import "github.com/zeromicro/go-zero/rest"
 
 type Config struct {
 	rest.RestConf
-	InfluxDBConfig InfluxDB
-	MySQL          MySQLConf
+	InfluxDBConfig InfluxDB  `json:",optional"` // Storage.Telemetry 为 memory 时可省略
+	MySQL          MySQLConf `json:",optional"` // Storage.Driver 为 sqlite 时仅使用其中的重试与队列参数
 	BackupConf     BackupConf
+	Storage        StorageConf      `json:",optional"`
+	BatteryConf    BatteryConf      `json:",optional"`
+	Maintenance    MaintenanceConf  `json:",optional"`
+	TrackClean     TrackCleanConf   `json:",optional"`
+	TrackCache     TrackCacheConf   `json:",optional"`
+	Export         ExportConf       `json:",optional"`
+	Report         ReportConf       `json:",optional"`
+	FlightReport   FlightReportConf `json:",optional"`
+	Audit          AuditConf        `json:",optional"`
+	Downsample     DownsampleConf   `json:",optional"`
+}
+
+// StorageConf 存储后端。默认 MySQL + InfluxDB；sqlite + memory 可在无外部依赖的单机、演示或测试环境运行
+type StorageConf struct {
+	Driver        string `json:",optional"` // mysql | sqlite，默认 mysql
+	SQLitePath    string `json:",optional"` // SQLite 数据库文件，默认 ./data/drone_stats.db，:memory: 表示内存库
+	Timezone      string `json:",optional"` // SQLite 中 DATETIME 的时区（对应 MySQL DSN 的 loc），默认 UTC
+	Telemetry     string `json:",optional"` // influx | memory，默认 influx
+	TelemetryFile string `json:",optional"` // memory 时启动导入的 line-protocol 文件（支持 .gz/.zst，如 Influx 备份文件）
 }
 
 type InfluxDB struct {
@@ -27,10 +46,11 @@ type InfluxDB struct {
 type MySQLConf struct {
 	DataSource string
 	// 以下为可配置的重试与队列参数
-	RetryMaxAttempts    int    `json:"retryMaxAttempts"`    // 最大重试次数
-	RetryBaseDelayMs    int    `json:"retryBaseDelayMs"`    // 指数退避基准延迟（毫秒）
-	ReplayerIntervalSec int    `json:"replayerIntervalSec"` // 后台重放间隔（秒）
-	QueuePath           string `json:"queuePath"`           // 本地队列文件路径
+	RetryMaxAttempts    int    `json:"retryMaxAttempts"`          // 最大重试次数
+	RetryBaseDelayMs    int    `json:"retryBaseDelayMs"`          // 指数退避基准延迟（毫秒）
+	ReplayerIntervalSec int    `json:"replayerIntervalSec"`       // 后台重放间隔（秒）
+	QueuePath           string `json:"queuePath"`                 // 本地队列文件路径
+	QueueMaxAttempts    int    `json:"queueMaxAttempts,optional"` // 批次被 MySQL 拒绝多少次后移入死信，默认 10
 }
 
 type BackupConf struct {
@@ -38,4 +58,137 @@ type BackupConf struct {
 	IntervalDays  int    `json:"intervalDays"`  // 定期备份间隔（天）
 	RetentionDays int    `json:"retentionDays"` // 备份保留天数
 	InfluxBucket  string `json:"influxBucket"`  // 要导出的 InfluxDB bucket 名称
+	// 以下为增量备份与压缩参数
+	FullIntervalDays int             `json:"fullIntervalDays,optional"` // 完整备份间隔（天），其间每次备份为增量备份，默认 7
+	Compression      string          `json:"compression,optional"`      // gzip | zstd | none，默认 gzip
+	RewriteTables    []string        `json:"rewriteTables,optional"`    // 增量备份中整表重写的表（行会被原地修改或删除），为空时使用内置列表
+	IDOverlap        int64           `json:"idOverlap,optional"`        // 增量备份按自增 id 向前重叠的 id 数（覆盖快照时未提交、之后才提交的行），默认 10000
+	RestoreTest      RestoreTestConf `json:"restoreTest,optional"`
+	// Targets 备份存储目标，每次备份完成后将 BackupDir 中尚未上传的备份上传到各目标，各目标按自身 RetentionDays 清理
+	Targets []StorageTargetConf `json:"targets,optional"`
+}
+
+// StorageTargetConf 备份存储目标
+type StorageTargetConf struct {
+	Name          string `json:",optional"` // 日志与状态中显示的名称，默认为 Type
+	Type          string // local | s3 | sftp
+	RetentionDays int    `json:",optional"` // 该目标的保留天数，为 0 时与 BackupConf.RetentionDays 相同
+	Path          string `json:",optional"` // local：目标目录（如挂载的 NAS）；sftp：远端目录
+	// s3：S3 兼容对象存储，如 MinIO
+	Endpoint  string `json:",optional"` // host:port
+	Bucket    string `json:",optional"` // 不存在时自动创建
+	Prefix    string `json:",optional"` // 对象 key 前缀
+	Region    string `json:",optional"`
+	AccessKey string `json:",optional"`
+	SecretKey string `json:",optional"`
+	UseSSL    bool   `json:",optional"`
+	// sftp
+	Host               string `json:",optional"` // host:port，端口缺省 22
+	User               string `json:",optional"`
+	Password           string `json:",optional"`
+	PrivateKeyFile     string `json:",optional"`
+	KnownHostsFile     string `json:",optional"` // 与 HostKeyFingerprint 至少配置一个
+	HostKeyFingerprint string `json:",optional"` // SHA256:... 格式
+}
+
+// RestoreTestConf 定期恢复演练：将最新备份链恢复到临时库并按清单校验
+type RestoreTestConf struct {
+	IntervalDays    int    `json:",optional"` // 演练间隔（天），0 表示不启用
+	MySQLDataSource string `json:",optional"` // 临时库 DSN，其中的表会被清空，不能与生产库相同；为空时不演练 MySQL
+	InfluxBucket    string `json:",optional"` // 临时 bucket，其中的数据会被清空，不能与备份的 bucket 相同；为空时不演练 Influx
+}
+
+type BatteryConf struct {
+	NominalCapacityAh      float64            `json:",optional"` // 电池标称容量（A.h），为 0 时以该无人机最早若干架次的有效容量作为基准；按 SOC 回退计算耗电时，无基准再由轨迹点 RM / SOC 推算
+	ModelNominalCapacityAh map[string]float64 `json:",optional"` // 按机型覆盖标称容量（键为 flight_sorties.model）
+	WarnCapacityPercent    float64            `json:",optional"` // 有效容量低于标称容量该百分比时告警，默认 80
+	TrendWindow            int                `json:",optional"` // 计算当前有效容量/内阻时取最近多少个架次的均值，默认 10
+	NominalVoltageV        float64            `json:",optional"` // 电池标称电压（V），必填，按 SOC/RM 回退计算耗电时使用
+}
+
+type MaintenanceConf struct {
+	DueSoonPercent float64 `json:",optional"` // 剩余量低于间隔的该百分比时视为即将到期，默认 10
+	BlockOverdue   bool    `json:",optional"` // 为 true 时超期未维保的无人机状态返回 blocked=true。仅为提示，本服务不拦截起飞，需由调度侧据此禁飞
+}
+
+type TrackCleanConf struct {
+	MaxSpeedMps     float64 `json:",optional"` // 相邻点隐含水平速度上限（m/s），默认 40
+	MaxAccelMps2    float64 `json:",optional"` // 相邻区间隐含加速度上限（m/s²），默认 20
+	FrozenMinGS     float64 `json:",optional"` // 上报地速（m/s）不低于该值而坐标不变时视为位置冻结，默认 1
+	GapSeconds      float64 `json:",optional"` // 相邻点间隔超过该值（秒）时记为缺口，默认 10
+	Smoothing       string  `json:",optional"` // none | moving_average | kalman，默认 none
+	SmoothingWindow int     `json:",optional"` // 滑动平均窗口（点数），默认 5
+	KalmanNoiseM    float64 `json:",optional"` // 卡尔曼滤波观测噪声标准差（m），默认 5
+	KalmanAccelMps2 float64 `json:",optional"` // 卡尔曼滤波过程噪声（m/s²），默认 3
+}
+
+type TrackCacheConf struct {
+	ExpireSeconds int `json:",optional"` // 简化轨迹缓存过期时间（秒），默认 600
+	Limit         int `json:",optional"` // 最多缓存的轨迹数，默认 1000
+}
+
+type ExportConf struct {
+	Dir          string `json:",optional"` // 异步导出任务目录，为空时使用系统临时目录下 drone_export_tasks
+	Workers      int    `json:",optional"` // 并行执行导出任务的 worker 数，默认 2
+	PerUserLimit int    `json:",optional"` // 每个用户同时执行的任务数上限，默认 1，超出的任务继续排队
+	// TrustUserHeader 为 true 时按 X-User-ID 请求头区分用户（仅在前置网关完成认证并覆盖该请求头时开启），
+	// 默认按 TCP 对端地址区分，避免客户端伪造请求头绕过并发限制
+	TrustUserHeader bool `json:",optional"`
+	RetainDays      int  `json:",optional"` // 已结束任务及产物保留天数，默认 7
+	// SigningKey 下载链接 HMAC 签名密钥，为空时在任务目录生成并持久化随机密钥
+	SigningKey     string `json:",optional"`
+	LinkTTLSeconds int    `json:",optional"` // 下载链接有效期（秒），默认 3600
+}
+
+type ReportConf struct {
+	Dir         string `json:",optional"` // 定时报表存档目录，默认 /app/reports
+	DeliveryDir string `json:",optional"` // local 投递的根目录，计划中的 deliveryTo 为其下子目录；为空时不支持 local 投递
+	Timezone    string `json:",optional"` // cron 表达式的时区，默认 Asia/Shanghai
+	RetainDays  int    `json:",optional"` // 报表存档默认保留天数（计划未指定时），默认 30
+	// WebhookSecret 非空时 webhook 请求携带 X-Report-Signature（meta 字段的 HMAC-SHA256）
+	WebhookSecret         string `json:",optional"`
+	WebhookTimeoutSeconds int    `json:",optional"` // webhook 请求超时（秒），默认 60
+	// WebhookAllowedHosts webhook 投递允许的目标主机名（精确匹配，不含端口），为空时不支持 webhook 投递，防止借投递访问内网地址
+	WebhookAllowedHosts []string `json:",optional"`
+	SMTP                SMTPConf `json:",optional"`
+}
+
+// FlightReportConf 单架次 PDF 报告的告警阈值
+type FlightReportConf struct {
+	LowSOCPercent float64 `json:",optional"` // 最低 SOC 低于该百分比时告警，默认 20
+	HighWindMps   float64 `json:",optional"` // 风速超过该值（m/s）时告警，默认 10
+	MaxHeightM    float64 `json:",optional"` // 真高超过该值（m）时告警，默认 120
+}
+
+type AuditConf struct {
+	// AdminToken 撤销修改、查看全部导出任务、备份管理等操作需在 X-Admin-Token 请求头中提供，为空时不允许这些操作
+	AdminToken string `json:",optional"`
+	// TrustUserHeader 为 true 时以 X-User-ID 请求头作为审计日志的发起人（仅在前置网关完成认证并覆盖该请求头时开启），
+	// 默认记录 TCP 对端地址，避免客户端伪造发起人
+	TrustUserHeader bool `json:",optional"`
+}
+
+// DownsampleConf 原始遥测的降采样与保留策略，由服务内任务执行
+type DownsampleConf struct {
+	Enabled          bool             `json:",optional"`
+	RawRetentionDays int              `json:",optional"` // 原始数据保留天数，0 表示永久保留；尚未完成降采样的数据不会删除
+	Tiers            []DownsampleTier `json:",optional"` // 降采样级别，为空时使用 10s（保留 30 天）与 1m（保留 365 天）
+	IntervalSeconds  int              `json:",optional"` // 执行间隔（秒），默认 60
+	LagSeconds       int              `json:",optional"` // 只聚合早于当前时刻该秒数的窗口，等待迟到数据，默认 60
+	MaxPoints        int              `json:",optional"` // 自动选择分辨率时单条序列的目标点数上限，默认 2000
+}
+
+type DownsampleTier struct {
+	Every         string // 窗口，如 10s、1m，须能整除 24h
+	Bucket        string `json:",optional"` // 聚合结果 bucket，不存在时自动创建，默认 <原始 bucket>_<Every>
+	RetentionDays int    `json:",optional"` // 聚合结果保留天数，0 表示永久保留
+}
+
+type SMTPConf struct {
+	Host            string `json:",optional"`
+	Port            int    `json:",optional"` // 默认 25；465 时使用隐式 TLS，其余端口在服务器支持时使用 STARTTLS
+	Username        string `json:",optional"`
+	Password        string `json:",optional"`
+	From            string `json:",optional"`
+	MaxAttachmentMB int    `json:",optional"` // 附件大小上限（MB），默认 20
 }
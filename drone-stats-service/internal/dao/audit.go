@@ -0,0 +1,236 @@
+package dao
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"drone-stats-service/internal/model"
+)
+
+var (
+	ErrAuditNotRevertible = errors.New("该审计条目不支持撤销")
+	ErrAuditReverted      = errors.New("该修改已被撤销")
+	ErrAuditStale         = errors.New("字段在该修改之后已被再次修改")
+	ErrAuditRecordGone    = errors.New("飞行记录已不存在")
+)
+
+// flightAuditColumns 可审计、可撤销的飞行记录字段及对应列名
+var flightAuditColumns = map[string]string{
+	"payload":      "payload",
+	"expressCount": "expressCount",
+}
+
+// FlightAuditRevertible 飞行记录字段的修改是否可撤销（系统写入的超期维保标记等不可撤销）
+func FlightAuditRevertible(field string) bool {
+	_, ok := flightAuditColumns[field]
+	return ok
+}
+
+// AuditMeta 修改的发起人与来源
+type AuditMeta struct {
+	Actor   string
+	Source  string // model.AuditSource*
+	BatchID string
+}
+
+// AuditFilter 审计日志查询条件，空值不过滤
+type AuditFilter struct {
+	Entity   string
+	EntityID string
+	OrderID  string
+	Actor    string
+	Source   string
+	BatchID  string
+	Limit    int
+}
+
+// SaveAuditLog 写入一条审计日志，CreatedAt 为零值时取当前时间
+func (d *SQLDao) SaveAuditLog(e model.AuditLog) error {
+	_, err := insertAuditLog(d.DB, e)
+	return err
+}
+
+// GetAuditLogs 按条件查询审计日志，按时间倒序
+func (d *SQLDao) GetAuditLogs(f AuditFilter) ([]model.AuditLog, error) {
+	query := `SELECT ` + auditColumns + ` FROM audit_logs WHERE 1=1`
+	args := []interface{}{}
+	for _, c := range []struct{ col, val string }{
+		{"entity", f.Entity}, {"entity_id", f.EntityID}, {"order_id", f.OrderID},
+		{"actor", f.Actor}, {"source", f.Source}, {"batch_id", f.BatchID},
+	} {
+		if c.val != "" {
+			query += " AND " + c.col + " = ?"
+			args = append(args, c.val)
+		}
+	}
+	query += " ORDER BY created_at DESC, id DESC LIMIT ?"
+	args = append(args, f.Limit)
+	rows, err := d.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []model.AuditLog
+	for rows.Next() {
+		e, err := scanAuditLog(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, e)
+	}
+	return list, rows.Err()
+}
+
+// RevertFlightAudit 将飞行记录字段恢复为审计条目中的旧值，并写入一条 revert 来源的审计日志。
+// 条目不存在时返回 sql.ErrNoRows；字段当前值与条目的新值不一致（之后又被修改过）时返回 ErrAuditStale。
+func (d *SQLDao) RevertFlightAudit(id int64, meta AuditMeta) (model.AuditLog, error) {
+	tx, err := d.DB.Begin()
+	if err != nil {
+		return model.AuditLog{}, err
+	}
+	defer tx.Rollback()
+	orig, err := scanAuditLog(tx.QueryRow(`SELECT `+auditColumns+` FROM audit_logs WHERE id = ?`+d.forUpdate(), id))
+	if err != nil {
+		return model.AuditLog{}, err
+	}
+	col, ok := flightAuditColumns[orig.Field]
+	if orig.Entity != model.AuditEntityFlightRecord || !ok {
+		return model.AuditLog{}, ErrAuditNotRevertible
+	}
+	if orig.RevertedBy != 0 {
+		return model.AuditLog{}, ErrAuditReverted
+	}
+	oldVal, err := strconv.Atoi(orig.OldValue)
+	if err != nil {
+		return model.AuditLog{}, ErrAuditNotRevertible
+	}
+	var cur int
+	err = tx.QueryRow(`SELECT `+col+` FROM flight_records WHERE id = ?`+d.forUpdate(), orig.EntityID).Scan(&cur)
+	if err == sql.ErrNoRows {
+		return model.AuditLog{}, ErrAuditRecordGone
+	}
+	if err != nil {
+		return model.AuditLog{}, err
+	}
+	if strconv.Itoa(cur) != orig.NewValue {
+		return model.AuditLog{}, ErrAuditStale
+	}
+	if _, err := tx.Exec(`UPDATE flight_records SET `+col+` = ? WHERE id = ?`, oldVal, orig.EntityID); err != nil {
+		return model.AuditLog{}, err
+	}
+	e := model.AuditLog{
+		Entity:    orig.Entity,
+		EntityID:  orig.EntityID,
+		OrderID:   orig.OrderID,
+		Field:     orig.Field,
+		OldValue:  strconv.Itoa(cur),
+		NewValue:  orig.OldValue,
+		Actor:     meta.Actor,
+		Source:    model.AuditSourceRevert,
+		RevertOf:  orig.ID,
+		CreatedAt: time.Now(),
+	}
+	if e.ID, err = insertAuditLog(tx, e); err != nil {
+		return model.AuditLog{}, err
+	}
+	if _, err := tx.Exec(`UPDATE audit_logs SET reverted_by = ? WHERE id = ?`, e.ID, orig.ID); err != nil {
+		return model.AuditLog{}, err
+	}
+	return e, tx.Commit()
+}
+
+// setFlightPayloadTx 在事务内更新一条飞行记录的载货量、票数（nil 为不修改），并为实际变化的字段写入审计日志
+func (d *SQLDao) setFlightPayloadTx(tx *sql.Tx, id int, payload, expressCount *int, meta AuditMeta) error {
+	var (
+		orderID    string
+		oldPayload int
+		oldCount   int
+	)
+	err := tx.QueryRow(`SELECT OrderID, payload, expressCount FROM flight_records WHERE id = ?`+d.forUpdate(), id).Scan(&orderID, &oldPayload, &oldCount)
+	if err == sql.ErrNoRows {
+		// 记录已被删除，与直接 UPDATE 一致视为无需更新
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	var logs []model.AuditLog
+	change := func(field string, old int, v *int) int {
+		if v == nil || *v == old {
+			return old
+		}
+		logs = append(logs, model.AuditLog{
+			Entity:    model.AuditEntityFlightRecord,
+			EntityID:  strconv.Itoa(id),
+			OrderID:   orderID,
+			Field:     field,
+			OldValue:  strconv.Itoa(old),
+			NewValue:  strconv.Itoa(*v),
+			Actor:     meta.Actor,
+			Source:    meta.Source,
+			BatchID:   meta.BatchID,
+			CreatedAt: now,
+		})
+		return *v
+	}
+	newPayload := change("payload", oldPayload, payload)
+	newCount := change("expressCount", oldCount, expressCount)
+	if len(logs) == 0 {
+		return nil
+	}
+	if _, err := tx.Exec(`UPDATE flight_records SET payload = ?, expressCount = ? WHERE id = ?`, newPayload, newCount, id); err != nil {
+		return err
+	}
+	for _, e := range logs {
+		if _, err := insertAuditLog(tx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const auditColumns = `id, entity, entity_id, IFNULL(order_id, ''), field, IFNULL(old_value, ''), IFNULL(new_value, ''),
+	actor, source, IFNULL(batch_id, ''), IFNULL(revert_of, 0), IFNULL(reverted_by, 0), created_at`
+
+// execer 为 *sql.DB 与 *sql.Tx 的公共方法
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+func insertAuditLog(db execer, e model.AuditLog) (int64, error) {
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	res, err := db.Exec(`INSERT INTO audit_logs (entity, entity_id, order_id, field, old_value, new_value, actor, source, batch_id, revert_of, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.Entity, e.EntityID, emptyToNull(e.OrderID), e.Field, emptyToNull(e.OldValue), emptyToNull(e.NewValue),
+		e.Actor, e.Source, emptyToNull(e.BatchID), zeroIDToNull(e.RevertOf), e.CreatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func scanAuditLog(r rowScanner) (model.AuditLog, error) {
+	var e model.AuditLog
+	err := r.Scan(&e.ID, &e.Entity, &e.EntityID, &e.OrderID, &e.Field, &e.OldValue, &e.NewValue,
+		&e.Actor, &e.Source, &e.BatchID, &e.RevertOf, &e.RevertedBy, &e.CreatedAt)
+	return e, err
+}
+
+func emptyToNull(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func zeroIDToNull(id int64) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
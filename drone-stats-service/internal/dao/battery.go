@@ -0,0 +1,117 @@
+package dao
+
+import (
+	"database/sql"
+	"time"
+
+	"drone-stats-service/internal/model"
+)
+
+// SaveBatteryFlightMetrics 写入单架次电池指标，同一架次（OrderID + start_time）重复写入时覆盖
+func (d *SQLDao) SaveBatteryFlightMetrics(m model.BatteryFlightMetrics) error {
+	_, err := d.DB.Exec(`INSERT INTO flight_battery_metrics
+		(OrderID, uasID, start_time, soc_start, soc_end, soc_used, capacity_used_ah, capacity_method, effective_capacity_ah, resistance_mohm, min_voltage, max_current)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`+
+		d.onDuplicateUpdate("OrderID, start_time", "uasID", "soc_start", "soc_end", "soc_used",
+			"capacity_used_ah", "capacity_method", "effective_capacity_ah", "resistance_mohm", "min_voltage", "max_current"),
+		m.OrderID, m.UasID, m.StartTime, m.SOCStart, m.SOCEnd, m.SOCUsed, m.CapacityUsedAh, m.CapacityMethod,
+		m.EffectiveCapacityAh, m.ResistanceMOhm, m.MinVoltage, m.MaxCurrent)
+	return err
+}
+
+// GetBatteryFlightMetrics 查询电池指标，按起飞时间升序；uasID 为空时返回全部无人机
+func (d *SQLDao) GetBatteryFlightMetrics(uasID string, start, end time.Time) ([]model.BatteryFlightMetrics, error) {
+	query := `SELECT id, OrderID, uasID, start_time, soc_start, soc_end, soc_used, capacity_used_ah, capacity_method, effective_capacity_ah, resistance_mohm, min_voltage, max_current
+		FROM flight_battery_metrics WHERE 1=1`
+	args := []interface{}{}
+	if uasID != "" {
+		query += " AND uasID = ?"
+		args = append(args, uasID)
+	}
+	if !start.IsZero() {
+		query += " AND start_time >= ?"
+		args = append(args, start)
+	}
+	if !end.IsZero() {
+		query += " AND start_time < ?"
+		args = append(args, end)
+	}
+	query += " ORDER BY uasID, start_time ASC"
+	rows, err := d.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []model.BatteryFlightMetrics
+	for rows.Next() {
+		var m model.BatteryFlightMetrics
+		if err := rows.Scan(&m.ID, &m.OrderID, &m.UasID, &m.StartTime, &m.SOCStart, &m.SOCEnd, &m.SOCUsed, &m.CapacityUsedAh, &m.CapacityMethod, &m.EffectiveCapacityAh, &m.ResistanceMOhm, &m.MinVoltage, &m.MaxCurrent); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+// ListRecordsWithoutBatteryMetrics 返回尚未计算电池指标的飞行记录（uasID 为空时不限无人机）
+func (d *SQLDao) ListRecordsWithoutBatteryMetrics(uasID string, limit int) ([]model.FlightRecord, error) {
+	query := `SELECT r.id, r.OrderID, r.uasID, r.start_time FROM flight_records r
+		LEFT JOIN flight_battery_metrics b ON b.OrderID = r.OrderID AND b.start_time = r.start_time
+		WHERE b.id IS NULL`
+	args := []interface{}{}
+	if uasID != "" {
+		query += " AND r.uasID = ?"
+		args = append(args, uasID)
+	}
+	query += " ORDER BY r.start_time ASC LIMIT ?"
+	args = append(args, limit)
+	rows, err := d.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []model.FlightRecord
+	for rows.Next() {
+		var r model.FlightRecord
+		if err := rows.Scan(&r.ID, &r.OrderID, &r.UasID, &r.StartTime); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// GetUasModel 返回无人机最近一个已登记机型的架次的机型，未登记时返回空字符串
+func (d *SQLDao) GetUasModel(uasID string) (string, error) {
+	var m sql.NullString
+	err := d.DB.QueryRow(`SELECT s.model FROM flight_records r
+		JOIN flight_sorties s ON s.OrderID = r.OrderID
+		WHERE r.uasID = ? AND s.model IS NOT NULL AND s.model <> ''
+		ORDER BY r.start_time DESC LIMIT 1`, uasID).Scan(&m)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return m.String, err
+}
+
+// GetUasModels 一次查询全部无人机最近一个已登记机型的架次的机型，键为 uasID；未登记机型的无人机不出现在结果中
+func (d *SQLDao) GetUasModels() (map[string]string, error) {
+	rows, err := d.DB.Query(`SELECT r.uasID, s.model FROM flight_records r
+		JOIN flight_sorties s ON s.OrderID = r.OrderID
+		WHERE s.model IS NOT NULL AND s.model <> ''
+		ORDER BY r.uasID, r.start_time ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make(map[string]string)
+	for rows.Next() {
+		var uasID, m string
+		if err := rows.Scan(&uasID, &m); err != nil {
+			return nil, err
+		}
+		// 按起飞时间升序，后写入的即最近一次
+		out[uasID] = m
+	}
+	return out, rows.Err()
+}
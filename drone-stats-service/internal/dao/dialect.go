@@ -0,0 +1,82 @@
+package dao
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 关系库方言。SQL 以 MySQL 语法为主，两种库语法不同的片段由以下方法生成
+const (
+	DialectMySQL  = "mysql"
+	DialectSQLite = "sqlite"
+)
+
+// Dialect 返回当前关系库方言
+func (d *SQLDao) Dialect() string {
+	return d.dialect
+}
+
+// dateFormat 按 MySQL DATE_FORMAT 格式（仅支持 %Y %m %d %H %i %s）格式化 DATETIME 表达式
+func (d *SQLDao) dateFormat(expr, layout string) string {
+	if d.dialect == DialectSQLite {
+		layout = strings.NewReplacer("%i", "%M", "%s", "%S").Replace(layout)
+		return fmt.Sprintf("strftime('%s', %s)", layout, expr)
+	}
+	return fmt.Sprintf("DATE_FORMAT(%s, '%s')", expr, layout)
+}
+
+// secondsBetween 返回 end - start 的整秒数，任一为 NULL 时为 NULL
+func (d *SQLDao) secondsBetween(start, end string) string {
+	if d.dialect == DialectSQLite {
+		return fmt.Sprintf("(CAST(strftime('%%s', %s) AS INTEGER) - CAST(strftime('%%s', %s) AS INTEGER))", end, start)
+	}
+	return fmt.Sprintf("TIMESTAMPDIFF(SECOND, %s, %s)", start, end)
+}
+
+// greatest 返回参数中的最大值，任一为 NULL 时为 NULL
+func (d *SQLDao) greatest(args ...string) string {
+	if d.dialect == DialectSQLite {
+		return "MAX(" + strings.Join(args, ", ") + ")"
+	}
+	return "GREATEST(" + strings.Join(args, ", ") + ")"
+}
+
+// minuteSlot 返回 DATETIME 表达式所在的 minutes 分钟桶起点，格式 yyyy-MM-dd HH:mm
+func (d *SQLDao) minuteSlot(expr string, minutes int) string {
+	if d.dialect == DialectSQLite {
+		return fmt.Sprintf("strftime('%%Y-%%m-%%d %%H:', %s) || printf('%%02d', CAST(strftime('%%M', %s) AS INTEGER) / %d * %d)", expr, expr, minutes, minutes)
+	}
+	return fmt.Sprintf("CONCAT(DATE_FORMAT(%s, '%%Y-%%m-%%d %%H:'), LPAD(FLOOR(MINUTE(%s) / %d) * %d, 2, '0'))", expr, expr, minutes, minutes)
+}
+
+// subDays 返回 DATETIME 表达式减去 days（整数表达式）天
+func (d *SQLDao) subDays(expr, days string) string {
+	if d.dialect == DialectSQLite {
+		return fmt.Sprintf("datetime(%s, '-' || (%s) || ' days')", expr, days)
+	}
+	return fmt.Sprintf("DATE_SUB(%s, INTERVAL (%s) DAY)", expr, days)
+}
+
+// forUpdate 事务内读取待修改行时追加的锁定子句；SQLite 的写事务本身是串行的
+func (d *SQLDao) forUpdate() string {
+	if d.dialect == DialectSQLite {
+		return ""
+	}
+	return " FOR UPDATE"
+}
+
+// onDuplicateUpdate 插入时唯一键 key（列名，逗号分隔）冲突则以新值覆盖 cols
+func (d *SQLDao) onDuplicateUpdate(key string, cols ...string) string {
+	sets := make([]string, len(cols))
+	for i, c := range cols {
+		if d.dialect == DialectSQLite {
+			sets[i] = fmt.Sprintf("%s=excluded.%s", c, c)
+		} else {
+			sets[i] = fmt.Sprintf("%s=VALUES(%s)", c, c)
+		}
+	}
+	if d.dialect == DialectSQLite {
+		return fmt.Sprintf(" ON CONFLICT(%s) DO UPDATE SET %s", key, strings.Join(sets, ", "))
+	}
+	return " ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", ")
+}
@@ -0,0 +1,44 @@
+package dao
+
+import (
+	"drone-stats-service/internal/model"
+)
+
+// SaveExportDownload 记录一次导出结果下载
+func (d *SQLDao) SaveExportDownload(r model.ExportDownload) error {
+	_, err := d.DB.Exec(`INSERT INTO export_downloads (task_id, file_name, user, remote_addr, user_agent, range_header, status, bytes_sent, downloaded_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.TaskID, r.FileName, r.User, r.RemoteAddr, r.UserAgent, r.RangeHeader, r.Status, r.BytesSent, r.DownloadedAt)
+	return err
+}
+
+// GetExportDownloads 查询下载记录，taskID/user 为空时不过滤，按下载时间倒序
+func (d *SQLDao) GetExportDownloads(taskID, user string, limit int) ([]model.ExportDownload, error) {
+	query := `SELECT id, task_id, file_name, user, remote_addr, IFNULL(user_agent, ''), IFNULL(range_header, ''), status, bytes_sent, downloaded_at
+		FROM export_downloads WHERE 1=1`
+	args := []interface{}{}
+	if taskID != "" {
+		query += " AND task_id = ?"
+		args = append(args, taskID)
+	}
+	if user != "" {
+		query += " AND user = ?"
+		args = append(args, user)
+	}
+	query += " ORDER BY downloaded_at DESC, id DESC LIMIT ?"
+	args = append(args, limit)
+	rows, err := d.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []model.ExportDownload
+	for rows.Next() {
+		var r model.ExportDownload
+		if err := rows.Scan(&r.ID, &r.TaskID, &r.FileName, &r.User, &r.RemoteAddr, &r.UserAgent, &r.RangeHeader, &r.Status, &r.BytesSent, &r.DownloadedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, r)
+	}
+	return list, rows.Err()
+}
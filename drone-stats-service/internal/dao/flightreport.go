@@ -0,0 +1,47 @@
+package dao
+
+import (
+	"database/sql"
+
+	"drone-stats-service/internal/model"
+)
+
+// FlightReportRecord 单架次报告所需的飞行记录及维保超期标记
+type FlightReportRecord struct {
+	model.FlightRecord
+	HasEndTime              bool   // end_time 为空时 EndTime 取起飞时间
+	MaintenanceOverdue      bool   // 起飞时存在超期未做的维保项
+	MaintenanceOverdueItems string // 超期维保项，逗号分隔
+}
+
+// GetFlightReportRecord 按 id 查询飞行记录；id 为 0 时取 orderID 最近一次架次。不存在时返回 sql.ErrNoRows
+func (d *SQLDao) GetFlightReportRecord(id int, orderID string) (FlightReportRecord, error) {
+	query := `SELECT id, OrderID, IFNULL(uasID, ''), start_time, end_time, IFNULL(start_lat,0), IFNULL(start_lng,0), IFNULL(end_lat,0), IFNULL(end_lng,0),
+		IFNULL(distance,0), IFNULL(battery_used,0), IFNULL(energy_method,''), created_at, IFNULL(payload,0), IFNULL(expressCount,0),
+		maintenance_overdue, IFNULL(maintenance_overdue_items, '')
+		FROM flight_records`
+	var args []interface{}
+	if id > 0 {
+		query += " WHERE id = ?"
+		args = append(args, id)
+	} else {
+		query += " WHERE OrderID = ? ORDER BY start_time DESC, id DESC LIMIT 1"
+		args = append(args, orderID)
+	}
+	var (
+		r                  FlightReportRecord
+		endTime, createdAt sql.NullTime
+	)
+	err := d.DB.QueryRow(query, args...).Scan(&r.ID, &r.OrderID, &r.UasID, &r.StartTime, &endTime, &r.StartLat, &r.StartLng, &r.EndLat, &r.EndLng,
+		&r.Distance, &r.BatteryUsed, &r.EnergyMethod, &createdAt, &r.Payload, &r.ExpressCount, &r.MaintenanceOverdue, &r.MaintenanceOverdueItems)
+	if err != nil {
+		return r, err
+	}
+	r.HasEndTime = endTime.Valid
+	r.EndTime = r.StartTime
+	if endTime.Valid {
+		r.EndTime = endTime.Time
+	}
+	r.CreatedAt = createdAt.Time
+	return r, nil
+}
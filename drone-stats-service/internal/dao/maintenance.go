@@ -0,0 +1,185 @@
+package dao
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	"drone-stats-service/internal/model"
+)
+
+// DefaultMaintenanceModel 未登记机型或机型无专属计划时使用的计划
+const DefaultMaintenanceModel = "default"
+
+// ReplaceMaintenancePlan 以给定维保项整体替换某机型的维保计划
+func (d *SQLDao) ReplaceMaintenancePlan(uasModel string, items []model.MaintenancePlan) error {
+	tx, err := d.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`DELETE FROM maintenance_plans WHERE model = ?`, uasModel); err != nil {
+		return err
+	}
+	for _, it := range items {
+		if _, err := tx.Exec(`INSERT INTO maintenance_plans (model, item, interval_hours, interval_cycles, description) VALUES (?, ?, ?, ?, ?)`,
+			uasModel, it.Item, it.IntervalHours, it.IntervalCycles, it.Description); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetMaintenancePlans 查询维保计划，uasModel 为空时返回全部机型，按机型、维保项排序
+func (d *SQLDao) GetMaintenancePlans(uasModel string) ([]model.MaintenancePlan, error) {
+	query := `SELECT id, model, item, interval_hours, interval_cycles, IFNULL(description, '') FROM maintenance_plans`
+	args := []interface{}{}
+	if uasModel != "" {
+		query += " WHERE model = ?"
+		args = append(args, uasModel)
+	}
+	query += " ORDER BY model, item"
+	rows, err := d.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []model.MaintenancePlan
+	for rows.Next() {
+		var p model.MaintenancePlan
+		if err := rows.Scan(&p.ID, &p.Model, &p.Item, &p.IntervalHours, &p.IntervalCycles, &p.Description); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// SaveMaintenanceRecord 写入维保完成记录，返回自增 ID
+func (d *SQLDao) SaveMaintenanceRecord(r model.MaintenanceRecord) (int, error) {
+	res, err := d.DB.Exec(`INSERT INTO maintenance_records (uasID, item, performed_at, flight_hours, cycles, technician, note) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		r.UasID, r.Item, r.PerformedAt, r.FlightHours, r.Cycles, r.Technician, r.Note)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+// GetLatestMaintenanceRecords 返回某架无人机每个维保项在 before 之前（before 为零值时不限）最近一次的完成记录，键为维保项
+func (d *SQLDao) GetLatestMaintenanceRecords(uasID string, before time.Time) (map[string]model.MaintenanceRecord, error) {
+	all, err := d.latestMaintenanceRecords(uasID, before)
+	if err != nil {
+		return nil, err
+	}
+	if out, ok := all[uasID]; ok {
+		return out, nil
+	}
+	return make(map[string]model.MaintenanceRecord), nil
+}
+
+// GetAllLatestMaintenanceRecords 一次查询全部无人机每个维保项在 before 之前最近一次的完成记录，键为 uasID、维保项
+func (d *SQLDao) GetAllLatestMaintenanceRecords(before time.Time) (map[string]map[string]model.MaintenanceRecord, error) {
+	return d.latestMaintenanceRecords("", before)
+}
+
+// latestMaintenanceRecords uasID 为空时不限无人机
+func (d *SQLDao) latestMaintenanceRecords(uasID string, before time.Time) (map[string]map[string]model.MaintenanceRecord, error) {
+	query := `SELECT id, uasID, item, performed_at, flight_hours, cycles, IFNULL(technician, ''), IFNULL(note, '')
+		FROM maintenance_records WHERE 1=1`
+	args := []interface{}{}
+	if uasID != "" {
+		query += " AND uasID = ?"
+		args = append(args, uasID)
+	}
+	if !before.IsZero() {
+		query += " AND performed_at < ?"
+		args = append(args, before)
+	}
+	rows, err := d.DB.Query(query+" ORDER BY performed_at ASC, id ASC", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make(map[string]map[string]model.MaintenanceRecord)
+	for rows.Next() {
+		var r model.MaintenanceRecord
+		if err := rows.Scan(&r.ID, &r.UasID, &r.Item, &r.PerformedAt, &r.FlightHours, &r.Cycles, &r.Technician, &r.Note); err != nil {
+			return nil, err
+		}
+		if out[r.UasID] == nil {
+			out[r.UasID] = make(map[string]model.MaintenanceRecord)
+		}
+		out[r.UasID][r.Item] = r
+	}
+	return out, rows.Err()
+}
+
+// ListUasIDs 返回有飞行记录的全部无人机编号
+func (d *SQLDao) ListUasIDs() ([]string, error) {
+	rows, err := d.DB.Query(`SELECT DISTINCT uasID FROM flight_records ORDER BY uasID`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out = append(out, id)
+	}
+	return out, rows.Err()
+}
+
+// FlagMaintenanceOverdue 标记某架次为超期维保飞行，并写入 system 来源的审计日志
+// （增量备份依据 audit_logs 重新导出被原地修改的飞行记录）
+func (d *SQLDao) FlagMaintenanceOverdue(orderID string, startTime time.Time, items string) error {
+	tx, err := d.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var (
+		id       int
+		overdue  bool
+		oldItems sql.NullString
+	)
+	err = tx.QueryRow(`SELECT id, maintenance_overdue, maintenance_overdue_items FROM flight_records WHERE OrderID = ? AND start_time = ?`+d.forUpdate(),
+		orderID, startTime).Scan(&id, &overdue, &oldItems)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if overdue && oldItems.String == items {
+		return nil
+	}
+	if _, err := tx.Exec(`UPDATE flight_records SET maintenance_overdue = 1, maintenance_overdue_items = ? WHERE id = ?`, items, id); err != nil {
+		return err
+	}
+	if _, err := insertAuditLog(tx, model.AuditLog{
+		Entity:    model.AuditEntityFlightRecord,
+		EntityID:  strconv.Itoa(id),
+		OrderID:   orderID,
+		Field:     "maintenance_overdue_items",
+		OldValue:  oldItems.String,
+		NewValue:  items,
+		Actor:     model.AuditSourceSystem,
+		Source:    model.AuditSourceSystem,
+		CreatedAt: time.Now(),
+	}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CountMaintenanceOverdueFlights 统计某架无人机在 since 之后（含）被标记为超期维保的架次数
+func (d *SQLDao) CountMaintenanceOverdueFlights(uasID string, since time.Time) (int, error) {
+	var cnt sql.NullInt64
+	err := d.DB.QueryRow(`SELECT COUNT(*) FROM flight_records WHERE uasID = ? AND maintenance_overdue = 1 AND start_time >= ?`,
+		uasID, since).Scan(&cnt)
+	return int(cnt.Int64), err
+}
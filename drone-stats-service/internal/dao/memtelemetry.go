@@ -0,0 +1,148 @@
+package dao
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"drone-stats-service/internal/backup"
+)
+
+// telemetryMeasurement 原始遥测的 measurement
+const telemetryMeasurement = "drone_status"
+
+// MemoryTelemetry 内存中的遥测数据源，可替代 InfluxDB 用于测试、演示与单机部署。
+// 数据按 measurement + tag 集合分序列保存，同一时间戳的字段合并为一行（等同 Influx 的 pivot）
+type MemoryTelemetry struct {
+	mu     sync.RWMutex
+	series map[string]*memSeries
+}
+
+type memSeries struct {
+	measurement string
+	tags        map[string]string
+	rows        map[int64]map[string]interface{} // 纳秒时间戳 -> 字段
+}
+
+func NewMemoryTelemetry() *MemoryTelemetry {
+	return &MemoryTelemetry{series: map[string]*memSeries{}}
+}
+
+// Write 写入一个点，已存在的同名字段被覆盖
+func (m *MemoryTelemetry) Write(measurement string, tags map[string]string, fields map[string]interface{}, t time.Time) {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString(measurement)
+	for _, k := range keys {
+		b.WriteString("\n" + k + "\n" + tags[k])
+	}
+	key := b.String()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.series[key]
+	if !ok {
+		s = &memSeries{measurement: measurement, tags: map[string]string{}, rows: map[int64]map[string]interface{}{}}
+		for k, v := range tags {
+			s.tags[k] = v
+		}
+		m.series[key] = s
+	}
+	ts := t.UnixNano()
+	row, ok := s.rows[ts]
+	if !ok {
+		row = map[string]interface{}{}
+		s.rows[ts] = row
+	}
+	for k, v := range fields {
+		row[k] = v
+	}
+}
+
+// LoadFile 导入 line-protocol 文件（支持 .gz/.zst，如 Influx 备份文件），返回导入的点数
+func (m *MemoryTelemetry) LoadFile(path string) (int, error) {
+	n := 0
+	err := backup.ReadLineProtocolFile(path, func(measurement string, tags map[string]string, fields map[string]interface{}, t time.Time) error {
+		m.Write(measurement, tags, fields, t)
+		n++
+		return nil
+	})
+	return n, err
+}
+
+// query 返回 measurement 在 [start, end) 内满足 match 的行，按时间升序；行格式与 Influx 查询结果的 Values() 一致
+func (m *MemoryTelemetry) query(measurement string, start, end time.Time, match func(row map[string]interface{}) bool) []map[string]interface{} {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var out []map[string]interface{}
+	for _, s := range m.series {
+		if s.measurement != measurement {
+			continue
+		}
+		for ts, fields := range s.rows {
+			t := time.Unix(0, ts).UTC()
+			if t.Before(start) || !t.Before(end) {
+				continue
+			}
+			row := map[string]interface{}{
+				"_start":       start.UTC(),
+				"_stop":        end.UTC(),
+				"_time":        t,
+				"_measurement": s.measurement,
+			}
+			for k, v := range s.tags {
+				row[k] = v
+			}
+			for k, v := range fields {
+				row[k] = v
+			}
+			if match == nil || match(row) {
+				out = append(out, row)
+			}
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i]["_time"].(time.Time).Before(out[j]["_time"].(time.Time))
+	})
+	return out
+}
+
+// 查询指定架次在时间范围内的飞行数据
+func (m *MemoryTelemetry) QueryFlightRecords(orderID string, start, end time.Time) ([]map[string]interface{}, error) {
+	return m.query(telemetryMeasurement, start, end, func(row map[string]interface{}) bool {
+		return row["orderID"] == orderID
+	}), nil
+}
+
+// 查询所有架次 ID 及首次出现时间
+func (m *MemoryTelemetry) GetAllUasIDsAndFirstSeen() (map[string]time.Time, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	ids := make(map[string]time.Time)
+	for _, s := range m.series {
+		if s.measurement != telemetryMeasurement {
+			continue
+		}
+		for ts, fields := range s.rows {
+			id, ok := fields["orderID"].(string)
+			if !ok {
+				continue
+			}
+			t := time.Unix(0, ts).UTC()
+			if first, exists := ids[id]; !exists || t.Before(first) {
+				ids[id] = t
+			}
+		}
+	}
+	return ids, nil
+}
+
+// 拉取指定时间范围的飞行数据
+func (m *MemoryTelemetry) GetFlightDate(start, end time.Time) ([]map[string]interface{}, error) {
+	return m.query(telemetryMeasurement, start, end, nil), nil
+}
@@ -2,10 +2,12 @@ package dao
 
 import (
 	"bufio"
+	"context"
 	"database/sql"
 	"drone-stats-service/internal/config"
 	"drone-stats-service/internal/model"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -13,27 +15,39 @@ import (
 	"strings"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 	"github.com/xuri/excelize/v2"
 )
 
-type MySQLDao struct {
+// SQLDao 关系库访问，支持 MySQL 与嵌入式 SQLite（见 NewSQLiteDao），方言差异见 dialect.go
+type SQLDao struct {
 	DB               *sql.DB
+	dialect          string
 	q                *Queue
 	retryAttempts    int
 	retryBaseDelay   time.Duration
 	replayerInterval time.Duration
 	peekLimit        int
 	queuePath        string
+	loc              *time.Location // DSN 中配置的时区，DATETIME 字段按该时区读写
 }
 
-func NewMySQLDao(conf config.MySQLConf) (*MySQLDao, error) {
+func NewMySQLDao(conf config.MySQLConf) (*SQLDao, error) {
 	db, err := sql.Open("mysql", conf.DataSource)
 	if err != nil {
 		return nil, err
 	}
 	db.SetMaxOpenConns(20) // 适当调大
 	db.SetMaxIdleConns(10)
+	loc := time.UTC
+	if cfg, err := mysql.ParseDSN(conf.DataSource); err == nil && cfg.Loc != nil {
+		loc = cfg.Loc
+	}
+	return newSQLDao(db, DialectMySQL, loc, conf)
+}
+
+// newSQLDao 按 conf 中的重试与队列参数创建本地重放队列，并启动后台重放协程
+func newSQLDao(db *sql.DB, dialect string, loc *time.Location, conf config.MySQLConf) (*SQLDao, error) {
 	// 从配置读取可调参数（带默认值）
 	retryAttempts := conf.RetryMaxAttempts
 	if retryAttempts <= 0 {
@@ -64,18 +78,24 @@ func NewMySQLDao(conf config.MySQLConf) (*MySQLDao, error) {
 		}
 	}
 
-	q, err := NewQueue(queuePath)
+	maxAttempts := conf.QueueMaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 10
+	}
+	q, err := NewQueue(queuePath, maxAttempts)
 	if err != nil {
 		return nil, err
 	}
-	dao := &MySQLDao{
+	dao := &SQLDao{
 		DB:               db,
+		dialect:          dialect,
 		q:                q,
 		retryAttempts:    retryAttempts,
 		retryBaseDelay:   time.Duration(baseMs) * time.Millisecond,
 		replayerInterval: time.Duration(replayerSec) * time.Second,
 		peekLimit:        20,
 		queuePath:        queuePath,
+		loc:              loc,
 	}
 	// 启动后台重放协程
 	go dao.startReplayer()
@@ -83,7 +103,7 @@ func NewMySQLDao(conf config.MySQLConf) (*MySQLDao, error) {
 }
 
 // 保存飞行记录
-func (d *MySQLDao) SaveFlightRecord(orderID, uasID string, startTime, endTime time.Time, start_lat, start_lng, end_lat, end_lng int64, distance, batteryUsed float64) error {
+func (d *SQLDao) SaveFlightRecord(orderID, uasID string, startTime, endTime time.Time, start_lat, start_lng, end_lat, end_lng int64, distance, batteryUsed float64) error {
 	_, err := d.DB.Exec(
 		`INSERT INTO flight_records (
 			orderID,
@@ -95,18 +115,37 @@ func (d *MySQLDao) SaveFlightRecord(orderID, uasID string, startTime, endTime ti
 			end_lat,
 			end_lng,
 			distance,
-			battery_used
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		orderID, uasID, startTime, endTime, start_lat, start_lng, end_lat, end_lng, distance, batteryUsed)
+			battery_used,
+			sort_end_time,
+			duration_sec
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		orderID, uasID, startTime, endTime, start_lat, start_lng, end_lat, end_lng, distance, batteryUsed, sortEndTime(startTime, endTime), durationSeconds(startTime, endTime))
 	return err
 }
 
+// sortEndTime 与 durationSeconds 计算 flight_records 中用于排序的冗余列：
+// sort_end_time 为降落时间（未降落时为起飞时间），duration_sec 为飞行秒数（未降落时为 0）
+func sortEndTime(start, end time.Time) time.Time {
+	if end.IsZero() {
+		return start
+	}
+	return end
+}
+
+func durationSeconds(start, end time.Time) int64 {
+	if end.IsZero() {
+		return 0
+	}
+	return int64(end.Sub(start).Seconds())
+}
+
 // 保存主表并返回orderID（飞行架次唯一编号）
-func (d *MySQLDao) SaveFlightRecordAndGetOrderID(fr model.FlightRecord) (string, error) {
+func (d *SQLDao) SaveFlightRecordAndGetOrderID(fr model.FlightRecord) (string, error) {
 	_, err := d.DB.Exec(`INSERT INTO flight_records 
-		(orderID, uasID, start_time, end_time, start_lat, start_lng, end_lat, end_lng, distance, battery_used, payload) 
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		fr.OrderID, fr.UasID, fr.StartTime, fr.EndTime, fr.StartLat, fr.StartLng, fr.EndLat, fr.EndLng, fr.Distance, fr.BatteryUsed, fr.Payload)
+		(orderID, uasID, start_time, end_time, start_lat, start_lng, end_lat, end_lng, distance, battery_used, energy_method, payload, sort_end_time, duration_sec) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		fr.OrderID, fr.UasID, fr.StartTime, fr.EndTime, fr.StartLat, fr.StartLng, fr.EndLat, fr.EndLng, fr.Distance, fr.BatteryUsed, fr.EnergyMethod, fr.Payload,
+		sortEndTime(fr.StartTime, fr.EndTime), durationSeconds(fr.StartTime, fr.EndTime))
 	if err != nil {
 		fmt.Println("MySQL主表写入错误:", err)
 		return "", err
@@ -115,7 +154,7 @@ func (d *MySQLDao) SaveFlightRecordAndGetOrderID(fr model.FlightRecord) (string,
 }
 
 // 保存轨迹点
-func (d *MySQLDao) SaveTrackPoints(points []model.FlightTrackPoint) error {
+func (d *SQLDao) SaveTrackPoints(points []model.FlightTrackPoint) error {
 	if len(points) == 0 {
 		return nil
 	}
@@ -150,7 +189,7 @@ func (d *MySQLDao) SaveTrackPoints(points []model.FlightTrackPoint) error {
 
 // 执行轨迹点
 // execInsertTrackPoints 执行实际的批量插入（不包含入队逻辑）
-func (d *MySQLDao) execInsertTrackPoints(points []model.FlightTrackPoint) error {
+func (d *SQLDao) execInsertTrackPoints(points []model.FlightTrackPoint) error {
 	if len(points) == 0 {
 		return nil
 	}
@@ -192,7 +231,7 @@ func (d *MySQLDao) execInsertTrackPoints(points []model.FlightTrackPoint) error
 }
 
 // startReplayer 在后台运行，定期重放队列中的批次并尝试写回 MySQL
-func (d *MySQLDao) startReplayer() {
+func (d *SQLDao) startReplayer() {
 	ticker := time.NewTicker(d.replayerInterval)
 	defer ticker.Stop()
 	for range ticker.C {
@@ -204,28 +243,12 @@ func (d *MySQLDao) startReplayer() {
 			fmt.Println("队列读取失败:", err)
 			continue
 		}
-		if len(batches) == 0 {
-			continue
-		}
-		var successKeys []string
-		for k, pts := range batches {
-			if err := d.execInsertTrackPoints(pts); err != nil {
-				fmt.Println("重放批次写入失败:", k, err)
-				// if failed, skip deleting so it will be retried later
-				continue
-			}
-			successKeys = append(successKeys, k)
-		}
-		if len(successKeys) > 0 {
-			if err := d.q.DeleteKeys(successKeys); err != nil {
-				fmt.Println("删除已成功重放的队列键失败:", err)
-			}
-		}
+		d.replayBatches(batches, "")
 	}
 }
 
 // DrainQueueOnce 尝试同步重放队列中的批次，直到队列为空或一轮没有进展
-func (d *MySQLDao) DrainQueueOnce() {
+func (d *SQLDao) DrainQueueOnce() {
 	if d.q == nil {
 		return
 	}
@@ -239,22 +262,7 @@ func (d *MySQLDao) DrainQueueOnce() {
 			// empty
 			return
 		}
-		progress := 0
-		var successKeys []string
-		for k, pts := range batches {
-			if err := d.execInsertTrackPoints(pts); err != nil {
-				fmt.Println("DrainQueueOnce: 重放批次写入失败:", k, err)
-				continue
-			}
-			successKeys = append(successKeys, k)
-			progress++
-		}
-		if len(successKeys) > 0 {
-			if err := d.q.DeleteKeys(successKeys); err != nil {
-				fmt.Println("DrainQueueOnce: 删除已成功重放的队列键失败:", err)
-			}
-		}
-		if progress == 0 {
+		if d.replayBatches(batches, "DrainQueueOnce: ") == 0 {
 			// couldn't make progress, return to avoid busy loop
 			return
 		}
@@ -262,22 +270,85 @@ func (d *MySQLDao) DrainQueueOnce() {
 	}
 }
 
+// replayBatches 重放一组批次并删除成功的批次，返回成功的批次数。
+// 被 MySQL 拒绝的批次累计重放次数，达到 QueueMaxAttempts 后移入死信；连接失败等临时错误不计入，
+// 以免 MySQL 长时间不可用时把正常批次移入死信
+func (d *SQLDao) replayBatches(batches map[string][]model.FlightTrackPoint, logPrefix string) int {
+	var successKeys []string
+	for k, pts := range batches {
+		err := d.execInsertTrackPoints(pts)
+		if err == nil {
+			successKeys = append(successKeys, k)
+			continue
+		}
+		fmt.Println(logPrefix+"重放批次写入失败:", k, err)
+		if !d.isRejected(err) {
+			continue
+		}
+		dead, qerr := d.q.RecordFailure(k, err)
+		switch {
+		case qerr != nil:
+			fmt.Println(logPrefix+"记录重放失败次数失败:", k, qerr)
+		case dead:
+			fmt.Printf("%s批次 %s 已被拒绝 %d 次，移入死信队列\n", logPrefix, k, d.q.maxAttempts)
+		}
+	}
+	if len(successKeys) > 0 {
+		if err := d.q.DeleteKeys(successKeys); err != nil {
+			fmt.Println(logPrefix+"删除已成功重放的队列键失败:", err)
+		}
+	}
+	return len(successKeys)
+}
+
+// isRejected 判断写入是否被数据库拒绝，见 isRejectedByMySQL / isRejectedBySQLite
+func (d *SQLDao) isRejected(err error) bool {
+	if d.dialect == DialectSQLite {
+		return isRejectedBySQLite(err)
+	}
+	return isRejectedByMySQL(err)
+}
+
+// isRejectedByMySQL 判断写入是否被 MySQL 拒绝（数据或语句问题，重试也不会成功）。
+// 连接错误不是 MySQLError；锁等待、死锁、连接数过多、只读等服务端临时错误同样不计入
+func isRejectedByMySQL(err error) bool {
+	var me *mysql.MySQLError
+	if !errors.As(err, &me) {
+		return false
+	}
+	switch me.Number {
+	case 1040, 1053, 1205, 1213, 1290, 1792, 1836:
+		return false
+	}
+	return true
+}
+
+// Queue 返回本地重放队列
+func (d *SQLDao) Queue() *Queue {
+	return d.q
+}
+
+// Ping 检查关系库连接
+func (d *SQLDao) Ping(ctx context.Context) error {
+	return d.DB.PingContext(ctx)
+}
+
 // 查询总无人机数
-func (d *MySQLDao) CountTotalSorties() (int, error) {
+func (d *SQLDao) CountTotalSorties() (int, error) {
 	var total int
 	err := d.DB.QueryRow("SELECT COUNT(*) FROM flight_sorties").Scan(&total)
 	return total, err
 }
 
 // 查询在线无人机数（假设status=1为在线）
-func (d *MySQLDao) CountOnlineSorties() (int, error) {
+func (d *SQLDao) CountOnlineSorties() (int, error) {
 	var online int
 	err := d.DB.QueryRow("SELECT COUNT(*) FROM flight_sorties WHERE status=1").Scan(&online)
 	return online, err
 }
 
 // 注册新架次
-func (d *MySQLDao) RegisterSortiesIfNotExist(orderID string, regTime time.Time) error {
+func (d *SQLDao) RegisterSortiesIfNotExist(orderID string, regTime time.Time) error {
 	var exists int
 	err := d.DB.QueryRow("SELECT COUNT(*) FROM flight_sorties WHERE OrderID=?", orderID).Scan(&exists)
 	if err != nil {
@@ -291,7 +362,7 @@ func (d *MySQLDao) RegisterSortiesIfNotExist(orderID string, regTime time.Time)
 }
 
 // 判断飞行架次是否已存在
-func (d *MySQLDao) FlightRecordExists(orderID string, startTime, endTime time.Time) (bool, error) {
+func (d *SQLDao) FlightRecordExists(orderID string, startTime, endTime time.Time) (bool, error) {
 	var cnt int
 	err := d.DB.QueryRow(
 		"SELECT COUNT(*) FROM flight_records WHERE orderID=? AND start_time=? AND end_time=?",
@@ -300,69 +371,8 @@ func (d *MySQLDao) FlightRecordExists(orderID string, startTime, endTime time.Ti
 	return cnt > 0, err
 }
 
-// 查询飞行记录（支持条件筛选）
-func (d *MySQLDao) QueryFlightRecords(orderID, uasID, startTime, endTime string) ([]map[string]interface{}, error) {
-	query := `SELECT id, OrderID, uasID, start_time, end_time, start_lat, start_lng, end_lat, end_lng, distance, battery_used, created_at, payload, expressCount
-        FROM flight_records WHERE 1=1`
-	args := []interface{}{}
-	if orderID != "" {
-		query += " AND OrderID=?"
-		args = append(args, orderID)
-	}
-	if uasID != "" {
-		query += " AND uasID=? AND start_lat < 228000000 AND start_lng > 1139430000"
-		args = append(args, uasID)
-	}
-	if startTime != "" {
-		query += " AND start_time >= ?"
-		args = append(args, startTime)
-	}
-	if endTime != "" {
-		query += " AND end_time <= ?"
-		args = append(args, endTime)
-	}
-	query += " ORDER BY start_time DESC LIMIT 100"
-	rows, err := d.DB.Query(query, args...)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var records []map[string]interface{}
-	for rows.Next() {
-		var (
-			id, payload, expressCount          int
-			orderID, uasID                     string
-			startTime, endTime, createdAt      sql.NullTime
-			startLat, startLng, endLat, endLng sql.NullInt64
-			distance, batteryUsed              sql.NullFloat64
-		)
-		err := rows.Scan(&id, &orderID, &uasID, &startTime, &endTime, &startLat, &startLng, &endLat, &endLng, &distance, &batteryUsed, &createdAt, &payload, &expressCount)
-		if err != nil {
-			continue
-		}
-		record := map[string]interface{}{
-			"id":           id,
-			"OrderID":      orderID,
-			"uasID":        uasID,
-			"start_time":   startTime.Time.Format("2006-01-02 15:04:05"),
-			"end_time":     endTime.Time.Format("2006-01-02 15:04:05"),
-			"start_lat":    startLat.Int64,
-			"start_lng":    startLng.Int64,
-			"end_lat":      endLat.Int64,
-			"end_lng":      endLng.Int64,
-			"distance":     distance.Float64,
-			"battery_used": batteryUsed.Float64,
-			"created_at":   createdAt.Time.Format("2006-01-02 15:04:05"),
-			"payload":      payload,
-			"expressCount": expressCount,
-		}
-		records = append(records, record)
-	}
-	return records, nil
-}
-
 // 统计总飞行架次、总航程、总飞行时长（单位：秒）
-func (d *MySQLDao) GetFlightStats() (totalCount int, totalDistance float64, totalTime int64, err error) {
+func (d *SQLDao) GetFlightStats() (totalCount int, totalDistance float64, totalTime int64, err error) {
 	rows, err := d.DB.Query(`
         SELECT start_time, end_time, distance FROM flight_records
     `)
@@ -393,9 +403,9 @@ func (d *MySQLDao) GetFlightStats() (totalCount int, totalDistance float64, tota
 }
 
 // 按年、月、日统计飞行架次
-func (d *MySQLDao) GetFlightRecordsStats() (yearStats, monthStats, dayStats []map[string]interface{}, err error) {
+func (d *SQLDao) GetFlightRecordsStats() (yearStats, monthStats, dayStats []map[string]interface{}, err error) {
 	// 年统计
-	rows, err := d.DB.Query(`SELECT DATE_FORMAT(start_time, '%Y') as date, COUNT(*) as count FROM flight_records GROUP BY date ORDER BY date`)
+	rows, err := d.DB.Query(`SELECT ` + d.dateFormat("start_time", "%Y") + ` as date, COUNT(*) as count FROM flight_records GROUP BY date ORDER BY date`)
 	if err != nil {
 		return
 	}
@@ -409,7 +419,7 @@ func (d *MySQLDao) GetFlightRecordsStats() (yearStats, monthStats, dayStats []ma
 	}
 
 	// 月统计
-	rows2, err := d.DB.Query(`SELECT DATE_FORMAT(start_time, '%Y-%m') as date, COUNT(*) as count FROM flight_records GROUP BY date ORDER BY date`)
+	rows2, err := d.DB.Query(`SELECT ` + d.dateFormat("start_time", "%Y-%m") + ` as date, COUNT(*) as count FROM flight_records GROUP BY date ORDER BY date`)
 	if err != nil {
 		return
 	}
@@ -423,7 +433,7 @@ func (d *MySQLDao) GetFlightRecordsStats() (yearStats, monthStats, dayStats []ma
 	}
 
 	// 日统计
-	rows3, err := d.DB.Query(`SELECT DATE_FORMAT(start_time, '%Y-%m-%d') as date, COUNT(*) as count FROM flight_records GROUP BY date ORDER BY date`)
+	rows3, err := d.DB.Query(`SELECT ` + d.dateFormat("start_time", "%Y-%m-%d") + ` as date, COUNT(*) as count FROM flight_records GROUP BY date ORDER BY date`)
 	if err != nil {
 		return
 	}
@@ -439,10 +449,10 @@ func (d *MySQLDao) GetFlightRecordsStats() (yearStats, monthStats, dayStats []ma
 }
 
 // 按年、月、日统计净电能（battery_used总和）
-func (d *MySQLDao) GetSOCUsageStats() (yearStats, monthStats, dayStats []map[string]interface{}, err error) {
+func (d *SQLDao) GetSOCUsageStats() (yearStats, monthStats, dayStats []map[string]interface{}, err error) {
 	// 年统计
 	rows, err := d.DB.Query(`
-        SELECT DATE_FORMAT(start_time, '%Y') as date, 
+        SELECT ` + d.dateFormat("start_time", "%Y") + ` as date, 
         SUM(battery_used) as total 
         FROM flight_records 
         GROUP BY date ORDER BY date`)
@@ -460,7 +470,7 @@ func (d *MySQLDao) GetSOCUsageStats() (yearStats, monthStats, dayStats []map[str
 
 	// 月统计
 	rows2, err := d.DB.Query(`
-        SELECT DATE_FORMAT(start_time, '%Y-%m') as date, 
+        SELECT ` + d.dateFormat("start_time", "%Y-%m") + ` as date, 
         SUM(battery_used) as total 
         FROM flight_records 
         GROUP BY date ORDER BY date`)
@@ -478,7 +488,7 @@ func (d *MySQLDao) GetSOCUsageStats() (yearStats, monthStats, dayStats []map[str
 
 	// 日统计
 	rows3, err := d.DB.Query(`
-        SELECT DATE_FORMAT(start_time, '%Y-%m-%d') as date, 
+        SELECT ` + d.dateFormat("start_time", "%Y-%m-%d") + ` as date, 
         SUM(battery_used) as total 
         FROM flight_records 
         GROUP BY date ORDER BY date`)
@@ -497,14 +507,14 @@ func (d *MySQLDao) GetSOCUsageStats() (yearStats, monthStats, dayStats []map[str
 }
 
 // 按年、月、日统计总电能/总距离/总载重（distance或payload为0时正常处理，为null按0处理）
-func (d *MySQLDao) GetAvgSOCPerDistancePayloadStats() (yearStats, monthStats, dayStats []map[string]interface{}, err error) {
+func (d *SQLDao) GetAvgSOCPerDistancePayloadStats() (yearStats, monthStats, dayStats []map[string]interface{}, err error) {
 	// 年统计
 	rows, err := d.DB.Query(`
         SELECT 
-            DATE_FORMAT(start_time, '%Y') as date,
+            ` + d.dateFormat("start_time", "%Y") + ` as date,
             SUM(battery_used) as total_battery,
-            SUM(IFNULL(distance,0)/1000) as total_distance,
-            SUM(IFNULL(payload,0)/10) as total_payload
+            SUM(IFNULL(distance,0)/1000.0) as total_distance,
+            SUM(IFNULL(payload,0)/10.0) as total_payload
         FROM flight_records
         GROUP BY date ORDER BY date`)
 	if err != nil {
@@ -526,10 +536,10 @@ func (d *MySQLDao) GetAvgSOCPerDistancePayloadStats() (yearStats, monthStats, da
 	// 月统计
 	rows2, err := d.DB.Query(`
         SELECT 
-            DATE_FORMAT(start_time, '%Y-%m') as date,
+            ` + d.dateFormat("start_time", "%Y-%m") + ` as date,
             SUM(battery_used) as total_battery,
-            SUM(IFNULL(distance,0)/1000) as total_distance,
-            SUM(IFNULL(payload,0)/10) as total_payload
+            SUM(IFNULL(distance,0)/1000.0) as total_distance,
+            SUM(IFNULL(payload,0)/10.0) as total_payload
         FROM flight_records
         GROUP BY date ORDER BY date`)
 	if err != nil {
@@ -551,10 +561,10 @@ func (d *MySQLDao) GetAvgSOCPerDistancePayloadStats() (yearStats, monthStats, da
 	// 日统计
 	rows3, err := d.DB.Query(`
         SELECT 
-            DATE_FORMAT(start_time, '%Y-%m-%d') as date,
+            ` + d.dateFormat("start_time", "%Y-%m-%d") + ` as date,
             SUM(battery_used) as total_battery,
-            SUM(IFNULL(distance,0)/1000) as total_distance,
-            SUM(IFNULL(payload,0)/10) as total_payload
+            SUM(IFNULL(distance,0)/1000.0) as total_distance,
+            SUM(IFNULL(payload,0)/10.0) as total_payload
         FROM flight_records
         GROUP BY date ORDER BY date`)
 	if err != nil {
@@ -576,9 +586,9 @@ func (d *MySQLDao) GetAvgSOCPerDistancePayloadStats() (yearStats, monthStats, da
 }
 
 // 按年、月、日统计运输货量
-func (d *MySQLDao) GetPayloadStats() (yearStats, monthStats, dayStats []map[string]interface{}, err error) {
+func (d *SQLDao) GetPayloadStats() (yearStats, monthStats, dayStats []map[string]interface{}, err error) {
 	// 年统计
-	rows, err := d.DB.Query(`SELECT DATE_FORMAT(start_time, '%Y') as date, SUM(payload/10) as payload FROM flight_records GROUP BY date ORDER BY date`)
+	rows, err := d.DB.Query(`SELECT ` + d.dateFormat("start_time", "%Y") + ` as date, SUM(payload/10.0) as payload FROM flight_records GROUP BY date ORDER BY date`)
 	if err != nil {
 		return
 	}
@@ -592,7 +602,7 @@ func (d *MySQLDao) GetPayloadStats() (yearStats, monthStats, dayStats []map[stri
 	}
 
 	// 月统计
-	rows2, err := d.DB.Query(`SELECT DATE_FORMAT(start_time, '%Y-%m') as date, SUM(payload/10) as payload FROM flight_records GROUP BY date ORDER BY date`)
+	rows2, err := d.DB.Query(`SELECT ` + d.dateFormat("start_time", "%Y-%m") + ` as date, SUM(payload/10.0) as payload FROM flight_records GROUP BY date ORDER BY date`)
 	if err != nil {
 		return
 	}
@@ -606,7 +616,7 @@ func (d *MySQLDao) GetPayloadStats() (yearStats, monthStats, dayStats []map[stri
 	}
 
 	// 日统计
-	rows3, err := d.DB.Query(`SELECT DATE_FORMAT(start_time, '%Y-%m-%d') as date, SUM(payload/10) as payload FROM flight_records GROUP BY date ORDER BY date`)
+	rows3, err := d.DB.Query(`SELECT ` + d.dateFormat("start_time", "%Y-%m-%d") + ` as date, SUM(payload/10.0) as payload FROM flight_records GROUP BY date ORDER BY date`)
 	if err != nil {
 		return
 	}
@@ -622,14 +632,14 @@ func (d *MySQLDao) GetPayloadStats() (yearStats, monthStats, dayStats []map[stri
 }
 
 // 统计平均飞行时长（秒）、平均耗电量、平均载货量、平均速度
-func (d *MySQLDao) GetAvgStats() (avgTime float64, avgSOC float64, avgPayload float64, avgGS float64, err error) {
+func (d *SQLDao) GetAvgStats() (avgTime float64, avgSOC float64, avgPayload float64, avgGS float64, err error) {
 	var avgTimeNull, avgEnergyNull, avgPayloadNull, avgGSNull sql.NullFloat64
 	row := d.DB.QueryRow(`
         SELECT 
-            AVG(TIMESTAMPDIFF(SECOND, start_time, end_time)) as avg_time,
+            AVG(` + d.secondsBetween("start_time", "end_time") + `) as avg_time,
             AVG(battery_used) as avg_battery,
-            AVG(CASE WHEN payload=0 OR payload IS NULL THEN NULL ELSE payload/10 END) as avg_payload,
-            (SELECT AVG(gs/10) FROM flight_track_points WHERE gs IS NOT NULL) as avg_gs
+            AVG(CASE WHEN payload=0 OR payload IS NULL THEN NULL ELSE payload/10.0 END) as avg_payload,
+            (SELECT AVG(gs/10.0) FROM flight_track_points WHERE gs IS NOT NULL) as avg_gs
         FROM flight_records
         WHERE end_time IS NOT NULL AND battery_used IS NOT NULL
     `)
@@ -650,7 +660,7 @@ func (d *MySQLDao) GetAvgStats() (avgTime float64, avgSOC float64, avgPayload fl
 }
 
 // 查询某条飞行记录的所有轨迹点
-func (d *MySQLDao) GetTrackPointsByRecordId(orderID string) ([]map[string]interface{}, error) {
+func (d *SQLDao) GetTrackPointsByRecordId(orderID string) ([]map[string]interface{}, error) {
 	rows, err := d.DB.Query(`
         SELECT id, orderID, flightStatus, timeStamp, longitude, latitude, heightType, height, altitude, VS, GS, course, SOC, RM, voltage, current, windSpeed, windDirect, temperture, humidity
         FROM flight_track_points
@@ -700,8 +710,38 @@ func (d *MySQLDao) GetTrackPointsByRecordId(orderID string) ([]map[string]interf
 	return points, nil
 }
 
+// TrackPointsVersion 返回某 OrderID 轨迹点的数量与最大 ID，用于判断缓存的简化轨迹是否过期
+func (d *SQLDao) TrackPointsVersion(orderID string) (count int, maxID int64, err error) {
+	var m sql.NullInt64
+	err = d.DB.QueryRow(`SELECT COUNT(*), MAX(id) FROM flight_track_points WHERE orderID = ?`, orderID).Scan(&count, &m)
+	return count, m.Int64, err
+}
+
+// GetTrackPoints 查询某架次的全部轨迹点（按时间升序）
+func (d *SQLDao) GetTrackPoints(orderID string) ([]model.FlightTrackPoint, error) {
+	rows, err := d.DB.Query(`
+        SELECT id, orderID, flightStatus, timeStamp, longitude, latitude, heightType, height, altitude, VS, GS, course, SOC, RM, voltage, current, windSpeed, windDirect, temperture, humidity
+        FROM flight_track_points
+        WHERE orderID = ?
+        ORDER BY timeStamp ASC, id ASC
+    `, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var points []model.FlightTrackPoint
+	for rows.Next() {
+		var p model.FlightTrackPoint
+		if err := rows.Scan(&p.ID, &p.OrderID, &p.FlightStatus, &p.TimeStamp, &p.Longitude, &p.Latitude, &p.HeightType, &p.Height, &p.Altitude, &p.VS, &p.GS, &p.Course, &p.SOC, &p.RM, &p.Voltage, &p.Current, &p.WindSpeed, &p.WindDirect, &p.Temperture, &p.Humidity); err != nil {
+			return nil, err
+		}
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
+
 // ExportFlightRecordsToExcelStream 使用流式写入将 MySQL 中的 flight_records 导出为 xlsx 文件，减少内存占用
-func (d *MySQLDao) ExportFlightRecordsToExcelStream(orderID, uasID, startTime, endTime, filePath string) error {
+func (d *SQLDao) ExportFlightRecordsToExcelStream(ctx context.Context, orderID, uasID, startTime, endTime, filePath string, onRow func()) error {
 	f := excelize.NewFile()
 	sheet := "Sheet1"
 	// 使用流式写入器
@@ -735,7 +775,7 @@ func (d *MySQLDao) ExportFlightRecordsToExcelStream(orderID, uasID, startTime, e
 	}
 	query += " ORDER BY start_time DESC"
 
-	rows, err := d.DB.Query(query, args...)
+	rows, err := d.DB.QueryContext(ctx, query, args...)
 	if err != nil {
 		return err
 	}
@@ -804,7 +844,7 @@ func (d *MySQLDao) ExportFlightRecordsToExcelStream(orderID, uasID, startTime, e
 
 		// 查询该架次的轨迹点聚合：平均风向、平均风速、平均湿度、平均温度
 		var avgWindDir, avgWindSpeed, avgHumidity, avgTemp sql.NullFloat64
-		_ = d.DB.QueryRow("SELECT AVG(windDirect), AVG(windSpeed), AVG(temperture), AVG(humidity) FROM flight_track_points WHERE orderID = ?", orderIDs).Scan(&avgWindDir, &avgWindSpeed, &avgHumidity, &avgTemp)
+		_ = d.DB.QueryRowContext(ctx, "SELECT AVG(windDirect), AVG(windSpeed), AVG(temperture), AVG(humidity) FROM flight_track_points WHERE orderID = ?", orderIDs).Scan(&avgWindDir, &avgWindSpeed, &avgHumidity, &avgTemp)
 
 		vals := []interface{}{
 			id,
@@ -833,6 +873,12 @@ func (d *MySQLDao) ExportFlightRecordsToExcelStream(orderID, uasID, startTime, e
 			return err
 		}
 		rowIdx++
+		if onRow != nil {
+			onRow()
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
 	}
 	if err := w.Flush(); err != nil {
 		return err
@@ -841,7 +887,7 @@ func (d *MySQLDao) ExportFlightRecordsToExcelStream(orderID, uasID, startTime, e
 }
 
 // ExportTrackPointsToExcelStream 使用流式写入将 flight_track_points 导出为 xlsx 文件
-func (d *MySQLDao) ExportTrackPointsToExcelStream(startTime, endTime, orderID, uasID, filePath string) error {
+func (d *SQLDao) ExportTrackPointsToExcelStream(ctx context.Context, startTime, endTime, orderID, uasID, filePath string, onRow func()) error {
 	f := excelize.NewFile()
 	sheet := "Sheet1"
 	w, err := f.NewStreamWriter(sheet)
@@ -879,7 +925,7 @@ func (d *MySQLDao) ExportTrackPointsToExcelStream(startTime, endTime, orderID, u
 			recArgs = append(recArgs, uasID)
 		}
 		recQuery += " ORDER BY start_time ASC"
-		rowsRec, err := d.DB.Query(recQuery, recArgs...)
+		rowsRec, err := d.DB.QueryContext(ctx, recQuery, recArgs...)
 		if err != nil {
 			return err
 		}
@@ -908,7 +954,7 @@ func (d *MySQLDao) ExportTrackPointsToExcelStream(startTime, endTime, orderID, u
 		query += " ORDER BY timeStamp ASC"
 	}
 
-	rows, err := d.DB.Query(query, args...)
+	rows, err := d.DB.QueryContext(ctx, query, args...)
 	if err != nil {
 		return err
 	}
@@ -980,6 +1026,12 @@ func (d *MySQLDao) ExportTrackPointsToExcelStream(startTime, endTime, orderID, u
 			return err
 		}
 		rowIdx++
+		if onRow != nil {
+			onRow()
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
 	}
 	if err := w.Flush(); err != nil {
 		return err
@@ -988,7 +1040,7 @@ func (d *MySQLDao) ExportTrackPointsToExcelStream(startTime, endTime, orderID, u
 }
 
 // ExportFlightRecordsToCSVStream 使用流式写入将 MySQL 中的 flight_records 导出为 csv 文件，减少内存占用
-func (d *MySQLDao) ExportFlightRecordsToCSVStream(orderID, uasID, startTime, endTime, filePath string) error {
+func (d *SQLDao) ExportFlightRecordsToCSVStream(ctx context.Context, orderID, uasID, startTime, endTime, filePath string, onRow func()) error {
 	f, err := os.Create(filePath)
 	if err != nil {
 		return err
@@ -1025,7 +1077,7 @@ func (d *MySQLDao) ExportFlightRecordsToCSVStream(orderID, uasID, startTime, end
 	}
 	query += " ORDER BY start_time DESC"
 
-	rows, err := d.DB.Query(query, args...)
+	rows, err := d.DB.QueryContext(ctx, query, args...)
 	if err != nil {
 		return err
 	}
@@ -1091,7 +1143,7 @@ func (d *MySQLDao) ExportFlightRecordsToCSVStream(orderID, uasID, startTime, end
 
 		// 查询该架次的轨迹点聚合：平均风向、平均风速、平均湿度、平均温度
 		var avgWindDir, avgWindSpeed, avgHumidity, avgTemp sql.NullFloat64
-		_ = d.DB.QueryRow("SELECT AVG(windDirect), AVG(windSpeed), AVG(temperture), AVG(humidity) FROM flight_track_points WHERE orderID = ?", orderIDs).Scan(&avgWindDir, &avgWindSpeed, &avgHumidity, &avgTemp)
+		_ = d.DB.QueryRowContext(ctx, "SELECT AVG(windDirect), AVG(windSpeed), AVG(temperture), AVG(humidity) FROM flight_track_points WHERE orderID = ?", orderIDs).Scan(&avgWindDir, &avgWindSpeed, &avgHumidity, &avgTemp)
 
 		row := []string{
 			strconv.Itoa(id),
@@ -1118,12 +1170,15 @@ func (d *MySQLDao) ExportFlightRecordsToCSVStream(orderID, uasID, startTime, end
 		if err := w.Write(row); err != nil {
 			return err
 		}
+		if onRow != nil {
+			onRow()
+		}
 	}
-	return nil
+	return rows.Err()
 }
 
 // ExportTrackPointsToCSVStream 使用流式写入将 flight_track_points 导出为 csv 文件
-func (d *MySQLDao) ExportTrackPointsToCSVStream(startTime, endTime, orderID, uasID, filePath string) error {
+func (d *SQLDao) ExportTrackPointsToCSVStream(ctx context.Context, startTime, endTime, orderID, uasID, filePath string, onRow func()) error {
 	f, err := os.Create(filePath)
 	if err != nil {
 		return err
@@ -1163,7 +1218,7 @@ func (d *MySQLDao) ExportTrackPointsToCSVStream(startTime, endTime, orderID, uas
 			recArgs = append(recArgs, uasID)
 		}
 		recQuery += " ORDER BY start_time ASC"
-		rowsRec, err := d.DB.Query(recQuery, recArgs...)
+		rowsRec, err := d.DB.QueryContext(ctx, recQuery, recArgs...)
 		if err != nil {
 			return err
 		}
@@ -1189,7 +1244,7 @@ func (d *MySQLDao) ExportTrackPointsToCSVStream(startTime, endTime, orderID, uas
 		query += " ORDER BY timeStamp ASC"
 	}
 
-	rows, err := d.DB.Query(query, args...)
+	rows, err := d.DB.QueryContext(ctx, query, args...)
 	if err != nil {
 		return err
 	}
@@ -1254,8 +1309,11 @@ func (d *MySQLDao) ExportTrackPointsToCSVStream(startTime, endTime, orderID, uas
 		if err := w.Write(row); err != nil {
 			return err
 		}
+		if onRow != nil {
+			onRow()
+		}
 	}
-	return nil
+	return rows.Err()
 }
 
 // helpers to convert nullable types used above to string
@@ -1303,7 +1361,7 @@ func nullableFloat64(n sql.NullFloat64) interface{} {
 }
 
 // QueryTrackPoints 按时间范围或 orderID 查询轨迹点
-func (d *MySQLDao) QueryTrackPoints(startTime, endTime, orderID string) ([]map[string]interface{}, error) {
+func (d *SQLDao) QueryTrackPoints(startTime, endTime, orderID string) ([]map[string]interface{}, error) {
 	query := `SELECT id, orderID, flightStatus, timeStamp, longitude, latitude, heightType, height, altitude, VS, GS, course, SOC, RM, voltage, current, windSpeed, windDirect, temperture, humidity
 		FROM flight_track_points WHERE 1=1`
 	args := []interface{}{}
@@ -1365,8 +1423,79 @@ func (d *MySQLDao) QueryTrackPoints(startTime, endTime, orderID string) ([]map[s
 	return points, nil
 }
 
-// 更新指定架次的载货量
-func (d *MySQLDao) UpdateFlightPayload(orderID string, payload, expressCount int) error {
-	_, err := d.DB.Exec("UPDATE flight_records SET payload=?, expressCount=? WHERE OrderID=?", payload, expressCount, orderID)
-	return err
+// 更新指定架次的载货量，并为实际变化的字段写入审计日志
+func (d *SQLDao) UpdateFlightPayload(orderID string, payload, expressCount int, meta AuditMeta) error {
+	tx, err := d.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	rows, err := tx.Query("SELECT id FROM flight_records WHERE OrderID=?"+d.forUpdate(), orderID)
+	if err != nil {
+		return err
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := d.setFlightPayloadTx(tx, id, &payload, &expressCount, meta); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetTrackPointsInRange 查询 [start, end] 内的轨迹点，orderIDs 为空时不限，按 OrderID、时间升序。
+// 轨迹点 timeStamp 以 UTC 墙上时间写入，读出后统一转换为 UTC 时刻。
+func (d *SQLDao) GetTrackPointsInRange(orderIDs []string, start, end time.Time) ([]model.FlightTrackPoint, error) {
+	query := `
+        SELECT id, orderID, flightStatus, timeStamp, longitude, latitude, heightType, height, altitude, VS, GS, course, SOC, RM, voltage, current, windSpeed, windDirect, temperture, humidity
+        FROM flight_track_points
+        WHERE timeStamp >= ? AND timeStamp <= ?`
+	args := []interface{}{start.UTC().Format("2006-01-02 15:04:05"), end.UTC().Format("2006-01-02 15:04:05")}
+	if len(orderIDs) > 0 {
+		query += " AND orderID IN (?" + strings.Repeat(", ?", len(orderIDs)-1) + ")"
+		for _, id := range orderIDs {
+			args = append(args, id)
+		}
+	}
+	query += " ORDER BY orderID, timeStamp ASC, id ASC"
+	rows, err := d.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var points []model.FlightTrackPoint
+	for rows.Next() {
+		var p model.FlightTrackPoint
+		if err := rows.Scan(&p.ID, &p.OrderID, &p.FlightStatus, &p.TimeStamp, &p.Longitude, &p.Latitude, &p.HeightType, &p.Height, &p.Altitude, &p.VS, &p.GS, &p.Course, &p.SOC, &p.RM, &p.Voltage, &p.Current, &p.WindSpeed, &p.WindDirect, &p.Temperture, &p.Humidity); err != nil {
+			return nil, err
+		}
+		p.TimeStamp = utcWallClock(p.TimeStamp)
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
+
+// GetFlightWindow 返回某 OrderID 的一次飞行的起降时间：at 非零时取包含 at 的架次，否则取最近一次架次
+func (d *SQLDao) GetFlightWindow(orderID string, at time.Time) (start, end time.Time, err error) {
+	query := `SELECT start_time, IFNULL(end_time, start_time) FROM flight_records WHERE OrderID = ?`
+	args := []interface{}{orderID}
+	if !at.IsZero() {
+		query += " AND start_time <= ? AND (end_time IS NULL OR end_time >= ?)"
+		args = append(args, at, at)
+	}
+	landed := nullDateTime{loc: d.loc}
+	err = d.DB.QueryRow(query+" ORDER BY start_time DESC LIMIT 1", args...).Scan(&start, &landed)
+	return start, landed.Time, err
 }
@@ -0,0 +1,276 @@
+package dao
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// mysqlSchema MySQL 建表语句，已存在的表缺少的列由 mysqlAddedColumns 补充，索引另见 mysqlIndexes。
+// 修改时需同步 sqliteSchema，两者的表与列由 schema_test 校验一致
+var mysqlSchema = []string{
+	// flight_sorties 表
+	`CREATE TABLE IF NOT EXISTS flight_sorties (
+        id INT AUTO_INCREMENT PRIMARY KEY,
+        OrderID VARCHAR(128) NOT NULL UNIQUE,
+        register_time DATETIME,
+        model VARCHAR(64),
+        manufacturer VARCHAR(64)
+    )`,
+	// flight_records 表
+	`CREATE TABLE IF NOT EXISTS flight_records (
+        id INT AUTO_INCREMENT PRIMARY KEY,
+        OrderID VARCHAR(128) NOT NULL,
+		uasID VARCHAR(128) NOT NULL,
+        start_time DATETIME NOT NULL,
+        end_time DATETIME,
+        start_lat BIGINT,
+        start_lng BIGINT,
+        end_lat BIGINT,
+        end_lng BIGINT,
+        distance DOUBLE(10,2),
+        battery_used DOUBLE(10,6),
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		payload INT NOT NULL DEFAULT 0,
+		expressCount INT NOT NULL DEFAULT 0
+    )`,
+	// flight_track_points 表
+	`CREATE TABLE IF NOT EXISTS flight_track_points (
+        id INT AUTO_INCREMENT PRIMARY KEY,
+        OrderID VARCHAR(128) NOT NULL,
+        flightStatus VARCHAR(16),
+        timeStamp DATETIME,
+        longitude BIGINT,
+        latitude BIGINT,
+        heightType INT,
+        height INT,
+        altitude INT,
+        VS INT,
+        GS INT,
+        course INT,
+        SOC INT,
+        RM INT,
+		voltage INT,
+		current INT,
+        windSpeed INT,
+        windDirect INT,
+        temperture INT,
+        humidity INT
+    )`,
+	// flight_battery_metrics 表：单架次电池指标
+	`CREATE TABLE IF NOT EXISTS flight_battery_metrics (
+        id INT AUTO_INCREMENT PRIMARY KEY,
+        OrderID VARCHAR(128) NOT NULL,
+        uasID VARCHAR(128) NOT NULL,
+        start_time DATETIME NOT NULL,
+        soc_start INT NOT NULL DEFAULT 0,
+        soc_end INT NOT NULL DEFAULT 0,
+        soc_used DOUBLE NOT NULL DEFAULT 0,
+        capacity_used_ah DOUBLE NOT NULL DEFAULT 0,
+        capacity_method VARCHAR(16) NOT NULL DEFAULT 'none',
+        effective_capacity_ah DOUBLE NOT NULL DEFAULT 0,
+        resistance_mohm DOUBLE NOT NULL DEFAULT 0,
+        min_voltage INT NOT NULL DEFAULT 0,
+        max_current INT NOT NULL DEFAULT 0,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        UNIQUE KEY uk_battery_order_start (OrderID, start_time),
+        KEY idx_battery_uas_start (uasID, start_time)
+    )`,
+	// flight_track_cleaning 表：单架次轨迹清洗统计
+	`CREATE TABLE IF NOT EXISTS flight_track_cleaning (
+        id INT AUTO_INCREMENT PRIMARY KEY,
+        OrderID VARCHAR(128) NOT NULL,
+        uasID VARCHAR(128) NOT NULL,
+        start_time DATETIME NOT NULL,
+        points_total INT NOT NULL DEFAULT 0,
+        points_kept INT NOT NULL DEFAULT 0,
+        dropped_zero INT NOT NULL DEFAULT 0,
+        dropped_time INT NOT NULL DEFAULT 0,
+        dropped_speed INT NOT NULL DEFAULT 0,
+        dropped_accel INT NOT NULL DEFAULT 0,
+        dropped_frozen INT NOT NULL DEFAULT 0,
+        gap_count INT NOT NULL DEFAULT 0,
+        gap_seconds DOUBLE NOT NULL DEFAULT 0,
+        gaps TEXT,
+        smoothing VARCHAR(16) NOT NULL DEFAULT 'none',
+        raw_distance DOUBLE NOT NULL DEFAULT 0,
+        clean_distance DOUBLE NOT NULL DEFAULT 0,
+        raw_energy_kwh DOUBLE NOT NULL DEFAULT 0,
+        clean_energy_kwh DOUBLE NOT NULL DEFAULT 0,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        UNIQUE KEY uk_cleaning_order_start (OrderID, start_time)
+    )`,
+	// maintenance_plans 表：按机型的维保计划
+	`CREATE TABLE IF NOT EXISTS maintenance_plans (
+        id INT AUTO_INCREMENT PRIMARY KEY,
+        model VARCHAR(64) NOT NULL,
+        item VARCHAR(64) NOT NULL,
+        interval_hours DOUBLE NOT NULL DEFAULT 0,
+        interval_cycles INT NOT NULL DEFAULT 0,
+        description VARCHAR(255),
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        UNIQUE KEY uk_plan_model_item (model, item)
+    )`,
+	// maintenance_records 表：维保完成记录
+	`CREATE TABLE IF NOT EXISTS maintenance_records (
+        id INT AUTO_INCREMENT PRIMARY KEY,
+        uasID VARCHAR(128) NOT NULL,
+        item VARCHAR(64) NOT NULL,
+        performed_at DATETIME NOT NULL,
+        flight_hours DOUBLE NOT NULL DEFAULT 0,
+        cycles INT NOT NULL DEFAULT 0,
+        technician VARCHAR(64),
+        note VARCHAR(512),
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        KEY idx_maint_uas_item (uasID, item, performed_at)
+    )`,
+	// export_downloads 表：导出结果下载记录
+	`CREATE TABLE IF NOT EXISTS export_downloads (
+        id BIGINT AUTO_INCREMENT PRIMARY KEY,
+        task_id VARCHAR(64) NOT NULL,
+        file_name VARCHAR(255) NOT NULL,
+        user VARCHAR(128) NOT NULL,
+        remote_addr VARCHAR(64) NOT NULL,
+        user_agent VARCHAR(255),
+        range_header VARCHAR(128),
+        status INT NOT NULL,
+        bytes_sent BIGINT NOT NULL DEFAULT 0,
+        downloaded_at DATETIME NOT NULL,
+        KEY idx_download_task (task_id, downloaded_at),
+        KEY idx_download_user (user, downloaded_at)
+    )`,
+	// audit_logs 表：飞行记录、维保、报表计划等手工修改的审计日志
+	`CREATE TABLE IF NOT EXISTS audit_logs (
+        id BIGINT AUTO_INCREMENT PRIMARY KEY,
+        entity VARCHAR(32) NOT NULL,
+        entity_id VARCHAR(64) NOT NULL,
+        order_id VARCHAR(64),
+        field VARCHAR(64) NOT NULL,
+        old_value TEXT,
+        new_value TEXT,
+        actor VARCHAR(128) NOT NULL,
+        source VARCHAR(16) NOT NULL,
+        batch_id VARCHAR(64),
+        revert_of BIGINT,
+        reverted_by BIGINT,
+        created_at DATETIME NOT NULL,
+        KEY idx_audit_entity (entity, entity_id, created_at),
+        KEY idx_audit_order (order_id, created_at),
+        KEY idx_audit_actor (actor, created_at),
+        KEY idx_audit_batch (batch_id)
+    )`,
+	// report_schedules 表：定时报表计划
+	`CREATE TABLE IF NOT EXISTS report_schedules (
+        id BIGINT AUTO_INCREMENT PRIMARY KEY,
+        name VARCHAR(128) NOT NULL,
+        cron VARCHAR(64) NOT NULL,
+        target VARCHAR(16) NOT NULL,
+        format VARCHAR(16) NOT NULL,
+        partition_by VARCHAR(16) NOT NULL DEFAULT '',
+        uasID VARCHAR(128),
+        range_hours INT NOT NULL DEFAULT 24,
+        delivery VARCHAR(16) NOT NULL,
+        delivery_to VARCHAR(1024) NOT NULL DEFAULT '',
+        retain_days INT NOT NULL DEFAULT 0,
+        enabled TINYINT NOT NULL DEFAULT 1,
+        last_run_at DATETIME NULL,
+        next_run_at DATETIME NULL,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    )`,
+	// report_runs 表：定时报表执行记录
+	`CREATE TABLE IF NOT EXISTS report_runs (
+        id BIGINT AUTO_INCREMENT PRIMARY KEY,
+        schedule_id BIGINT NOT NULL,
+        task_id VARCHAR(64),
+        trigger_type VARCHAR(16) NOT NULL,
+        period_start DATETIME NOT NULL,
+        period_end DATETIME NOT NULL,
+        status VARCHAR(16) NOT NULL,
+        file_path VARCHAR(512),
+        file_size BIGINT NOT NULL DEFAULT 0,
+        error VARCHAR(1024),
+        started_at DATETIME NOT NULL,
+        finished_at DATETIME NULL,
+        KEY idx_report_runs_schedule (schedule_id, started_at),
+        KEY idx_report_runs_status (status)
+    )`,
+}
+
+// mysqlAddedColumns 建表后新增的列，为已存在的表补充
+var mysqlAddedColumns = []struct {
+	table, column, definition string
+	sortKey                   bool // 按降落时间/时长排序所用的冗余列，新增时为已有记录回填
+}{
+	{table: "flight_sorties", column: "manufacturer", definition: "VARCHAR(64)"},
+	{table: "flight_records", column: "energy_method", definition: "VARCHAR(16)"},
+	{table: "flight_records", column: "maintenance_overdue", definition: "TINYINT NOT NULL DEFAULT 0"},
+	{table: "flight_records", column: "maintenance_overdue_items", definition: "VARCHAR(255)"},
+	{table: "flight_records", column: "sort_end_time", definition: "DATETIME", sortKey: true},
+	{table: "flight_records", column: "duration_sec", definition: "INT NOT NULL DEFAULT 0", sortKey: true},
+}
+
+// mysqlIndexes 查询/分页所需索引（建表语句中未包含的）
+var mysqlIndexes = []struct{ table, name, cols string }{
+	{"flight_records", "idx_records_start_time", "start_time, id"},
+	{"flight_records", "idx_records_uas_start", "uasID, start_time"},
+	{"flight_records", "idx_records_order", "OrderID"},
+	{"flight_records", "idx_records_end_time", "sort_end_time, id"},
+	{"flight_records", "idx_records_duration", "duration_sec, id"},
+	{"flight_track_points", "idx_points_order_time", "orderID, timeStamp"},
+}
+
+// MigrateMySQL 创建缺失的表、列与索引（SQLite 在 NewSQLiteDao 中建表）
+func MigrateMySQL(db *sql.DB) error {
+	for _, stmt := range mysqlSchema {
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	backfill := false
+	for _, c := range mysqlAddedColumns {
+		added, err := ensureColumn(db, c.table, c.column, c.definition)
+		if err != nil {
+			return err
+		}
+		backfill = backfill || (added && c.sortKey)
+	}
+	if backfill {
+		if _, err := db.Exec(`UPDATE flight_records SET sort_end_time = COALESCE(end_time, start_time),
+            duration_sec = COALESCE(TIMESTAMPDIFF(SECOND, start_time, end_time), 0)`); err != nil {
+			return err
+		}
+	}
+	for _, idx := range mysqlIndexes {
+		if err := ensureIndex(db, idx.table, idx.name, idx.cols); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureColumn 为已存在的表补充新增列，返回本次是否新增
+func ensureColumn(db *sql.DB, table, column, definition string) (bool, error) {
+	var cnt int
+	err := db.QueryRow(
+		"SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?",
+		table, column,
+	).Scan(&cnt)
+	if err != nil || cnt > 0 {
+		return false, err
+	}
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err == nil, err
+}
+
+// ensureIndex 索引不存在时创建（MySQL 不支持 CREATE INDEX IF NOT EXISTS）
+func ensureIndex(db *sql.DB, table, name, cols string) error {
+	var cnt int
+	err := db.QueryRow(
+		"SELECT COUNT(*) FROM INFORMATION_SCHEMA.STATISTICS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND INDEX_NAME = ?",
+		table, name,
+	).Scan(&cnt)
+	if err != nil || cnt > 0 {
+		return err
+	}
+	_, err = db.Exec(fmt.Sprintf("CREATE INDEX %s ON %s (%s)", name, table, cols))
+	return err
+}
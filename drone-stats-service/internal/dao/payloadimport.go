@@ -0,0 +1,96 @@
+package dao
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// payloadImportBatch 按 OrderID 批量查询时每批的参数个数
+const payloadImportBatch = 500
+
+// PayloadCandidate 载货量导入时用于匹配的飞行记录
+type PayloadCandidate struct {
+	ID           int
+	OrderID      string
+	UasID        string
+	StartTime    time.Time
+	EndTime      time.Time // 未记录降落时间时为零值
+	Payload      int       // 千克乘 10，0 表示未录入
+	ExpressCount int
+}
+
+// PayloadUpdate 单条记录的载货量/票数更新，字段为 nil 时保持原值
+type PayloadUpdate struct {
+	ID           int
+	Payload      *int
+	ExpressCount *int
+}
+
+const payloadCandidateColumns = `id, OrderID, uasID, start_time, end_time, payload, expressCount`
+
+func scanPayloadCandidates(rows *sql.Rows) ([]PayloadCandidate, error) {
+	defer rows.Close()
+	var list []PayloadCandidate
+	for rows.Next() {
+		var (
+			c   PayloadCandidate
+			end sql.NullTime
+		)
+		if err := rows.Scan(&c.ID, &c.OrderID, &c.UasID, &c.StartTime, &end, &c.Payload, &c.ExpressCount); err != nil {
+			return nil, err
+		}
+		c.EndTime = end.Time
+		list = append(list, c)
+	}
+	return list, rows.Err()
+}
+
+// FindPayloadCandidatesByOrderIDs 查询指定 OrderID 的全部飞行记录，按 OrderID、起飞时间升序
+func (d *SQLDao) FindPayloadCandidatesByOrderIDs(orderIDs []string) ([]PayloadCandidate, error) {
+	var out []PayloadCandidate
+	for i := 0; i < len(orderIDs); i += payloadImportBatch {
+		batch := orderIDs[i:min(i+payloadImportBatch, len(orderIDs))]
+		args := make([]interface{}, len(batch))
+		for j, id := range batch {
+			args[j] = id
+		}
+		rows, err := d.DB.Query(`SELECT `+payloadCandidateColumns+` FROM flight_records WHERE OrderID IN (?`+
+			strings.Repeat(", ?", len(batch)-1)+`) ORDER BY OrderID, start_time`, args...)
+		if err != nil {
+			return nil, err
+		}
+		list, err := scanPayloadCandidates(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, list...)
+	}
+	return out, nil
+}
+
+// FindPayloadCandidatesByUas 查询某无人机起飞时间在 [from, to] 内、或在 from 时仍在飞行的记录，按起飞时间升序
+func (d *SQLDao) FindPayloadCandidatesByUas(uasID string, from, to time.Time) ([]PayloadCandidate, error) {
+	rows, err := d.DB.Query(`SELECT `+payloadCandidateColumns+` FROM flight_records
+		WHERE uasID = ? AND start_time <= ? AND (start_time >= ? OR end_time >= ?) ORDER BY start_time`,
+		uasID, to, from, from)
+	if err != nil {
+		return nil, err
+	}
+	return scanPayloadCandidates(rows)
+}
+
+// ApplyPayloadUpdates 在一个事务内批量更新载货量与票数并写入审计日志，任一条失败则全部回滚
+func (d *SQLDao) ApplyPayloadUpdates(updates []PayloadUpdate, meta AuditMeta) error {
+	tx, err := d.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, u := range updates {
+		if err := d.setFlightPayloadTx(tx, u.ID, u.Payload, u.ExpressCount, meta); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -2,7 +2,12 @@ package dao
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"drone-stats-service/internal/model"
@@ -10,26 +15,94 @@ import (
 	bolt "go.etcd.io/bbolt"
 )
 
-// BoltDB 结构：使用名为 "points" 的 bucket，key 为 timestamp_nano_seq，value 为 JSON 编码的 []FlightTrackPoint
+// BoltDB 结构：待重放的批次存放在 "points" bucket，key 为 timestamp_nano_seq，value 为 JSON 编码的 []FlightTrackPoint；
+// 多次重放失败或无法解析的批次原样移入 "dead" bucket（死信）。两者各有一个 *_meta bucket，以相同 key 记录入队时间、
+// 重放次数及最近一次错误，旧版本入队的批次没有 meta，按首次失败时补建。
+
+// 队列状态
+const (
+	QueuePending = "pending"
+	QueueDead    = "dead"
+)
+
+// 移入死信的原因
+const (
+	DeadReasonRejected = "rejected" // MySQL 多次拒绝写入
+	DeadReasonDecode   = "decode"   // 记录无法解析
+)
+
+var (
+	bucketPending     = []byte("points")
+	bucketPendingMeta = []byte("points_meta")
+	bucketDead        = []byte("dead")
+	bucketDeadMeta    = []byte("dead_meta")
+
+	ErrQueueBatchNotFound = errors.New("queue batch not found")
+)
+
+// QueueMeta 批次的入队时间与重放记录
+type QueueMeta struct {
+	EnqueuedAt    time.Time  `json:"enqueuedAt"`
+	Points        int        `json:"points"`
+	Attempts      int        `json:"attempts"` // MySQL 拒绝写入的次数，连接失败等临时错误不计入
+	LastError     string     `json:"lastError,omitempty"`
+	LastAttemptAt *time.Time `json:"lastAttemptAt,omitempty"`
+	DeadAt        *time.Time `json:"deadAt,omitempty"`
+	DeadReason    string     `json:"deadReason,omitempty"`
+}
+
+// QueueEntry 队列中的一个批次
+type QueueEntry struct {
+	Key         string
+	State       string
+	Bytes       int
+	DecodeError string
+	QueueMeta
+}
+
+// QueueStats 队列统计；Replayed、Failed、DeadLettered 为进程启动以来的计数
+type QueueStats struct {
+	Pending         int
+	PendingBytes    int64
+	Dead            int
+	DeadBytes       int64
+	OldestPendingAt time.Time
+	FileSize        int64
+	MaxAttempts     int
+	Replayed        int64
+	Failed          int64
+	DeadLettered    int64
+}
 
 type Queue struct {
-	db *bolt.DB
+	db          *bolt.DB
+	path        string
+	maxAttempts int
+
+	replayed     atomic.Int64
+	failed       atomic.Int64
+	deadLettered atomic.Int64
 }
 
-func NewQueue(path string) (*Queue, error) {
+// NewQueue 打开队列文件，maxAttempts 为批次被 MySQL 拒绝多少次后移入死信
+func NewQueue(path string, maxAttempts int) (*Queue, error) {
 	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 1 * time.Second})
 	if err != nil {
 		return nil, err
 	}
 	err = db.Update(func(tx *bolt.Tx) error {
-		_, e := tx.CreateBucketIfNotExists([]byte("points"))
-		return e
+		for _, name := range [][]byte{bucketPending, bucketPendingMeta, bucketDead, bucketDeadMeta} {
+			if _, e := tx.CreateBucketIfNotExists(name); e != nil {
+				return e
+			}
+		}
+		return nil
 	})
 	if err != nil {
 		db.Close()
 		return nil, err
 	}
-	return &Queue{db: db}, nil
+	return &Queue{db: db, path: path, maxAttempts: maxAttempts}, nil
 }
 
 func (q *Queue) Close() error {
@@ -45,46 +118,313 @@ func (q *Queue) Enqueue(points []model.FlightTrackPoint) error {
 	if err != nil {
 		return err
 	}
-	key := fmt.Sprintf("%d_%d", time.Now().UnixNano(), time.Now().Unix()%1000)
+	now := time.Now()
+	meta, err := json.Marshal(QueueMeta{EnqueuedAt: now, Points: len(points)})
+	if err != nil {
+		return err
+	}
 	return q.db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte("points"))
-		return b.Put([]byte(key), data)
+		b := tx.Bucket(bucketPending)
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		key := []byte(fmt.Sprintf("%d_%d", now.UnixNano(), seq))
+		if err := b.Put(key, data); err != nil {
+			return err
+		}
+		return tx.Bucket(bucketPendingMeta).Put(key, meta)
 	})
 }
 
-// PeekBatch 返回最多 limit 个批次，结果为 map[key] -> points
+// PeekBatch 返回最多 limit 个批次，结果为 map[key] -> points；无法解析的记录移入死信
 func (q *Queue) PeekBatch(limit int) (map[string][]model.FlightTrackPoint, error) {
 	out := make(map[string][]model.FlightTrackPoint)
+	bad := make(map[string]string)
 	err := q.db.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte("points"))
-		if b == nil {
-			return nil
-		}
-		c := b.Cursor()
-		count := 0
-		for k, v := c.First(); k != nil && count < limit; k, v = c.Next() {
+		c := tx.Bucket(bucketPending).Cursor()
+		for k, v := c.First(); k != nil && len(out) < limit; k, v = c.Next() {
 			var pts []model.FlightTrackPoint
 			if err := json.Unmarshal(v, &pts); err != nil {
-				// 跳过解析失败的记录
+				bad[string(k)] = err.Error()
 				continue
 			}
 			out[string(k)] = pts
-			count++
 		}
 		return nil
 	})
+	if err != nil || len(bad) == 0 {
+		return out, err
+	}
+	err = q.db.Update(func(tx *bolt.Tx) error {
+		now := time.Now()
+		for k, msg := range bad {
+			if err := moveBatch(tx, QueuePending, QueueDead, k, func(m *QueueMeta) {
+				m.LastError, m.DeadAt, m.DeadReason = msg, &now, DeadReasonDecode
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		q.deadLettered.Add(int64(len(bad)))
+	}
 	return out, err
 }
 
-// DeleteKeys 根据 keys 删除队列记录
+// RecordFailure 记录批次被 MySQL 拒绝一次，累计达到 maxAttempts 时移入死信并返回 true
+func (q *Queue) RecordFailure(key string, cause error) (bool, error) {
+	q.failed.Add(1)
+	dead := false
+	err := q.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(bucketPending).Get([]byte(key)) == nil {
+			return ErrQueueBatchNotFound
+		}
+		m := readMeta(tx, QueuePending, key)
+		now := time.Now()
+		m.Attempts++
+		m.LastError, m.LastAttemptAt = cause.Error(), &now
+		if q.maxAttempts > 0 && m.Attempts >= q.maxAttempts {
+			dead = true
+			return moveBatch(tx, QueuePending, QueueDead, key, func(dm *QueueMeta) {
+				*dm = m
+				dm.DeadAt, dm.DeadReason = &now, DeadReasonRejected
+			})
+		}
+		return writeMeta(tx, QueuePending, key, m)
+	})
+	if dead && err == nil {
+		q.deadLettered.Add(1)
+	}
+	return dead, err
+}
+
+// DeleteKeys 根据 keys 删除已成功重放的队列记录
 func (q *Queue) DeleteKeys(keys []string) error {
-	return q.db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte("points"))
+	err := q.db.Update(func(tx *bolt.Tx) error {
+		b, mb := tx.Bucket(bucketPending), tx.Bucket(bucketPendingMeta)
 		for _, k := range keys {
 			if err := b.Delete([]byte(k)); err != nil {
 				return err
 			}
+			if err := mb.Delete([]byte(k)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		q.replayed.Add(int64(len(keys)))
+	}
+	return err
+}
+
+// List 按入队顺序分页列出 state 状态的批次，返回该状态的批次总数
+func (q *Queue) List(state string, offset, limit int) ([]QueueEntry, int, error) {
+	data, _, err := stateBuckets(state)
+	if err != nil {
+		return nil, 0, err
+	}
+	var (
+		out   []QueueEntry
+		total int
+	)
+	err = q.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(data)
+		total = b.Stats().KeyN
+		c := b.Cursor()
+		i := 0
+		for k, v := c.First(); k != nil && len(out) < limit; k, v = c.Next() {
+			if i++; i <= offset {
+				continue
+			}
+			e, _ := newQueueEntry(tx, state, string(k), v)
+			out = append(out, e)
+		}
+		return nil
+	})
+	return out, total, err
+}
+
+// Get 返回单个批次及其轨迹点；记录无法解析时 points 为 nil，错误见 DecodeError
+func (q *Queue) Get(state, key string) (QueueEntry, []model.FlightTrackPoint, error) {
+	data, _, err := stateBuckets(state)
+	if err != nil {
+		return QueueEntry{}, nil, err
+	}
+	var (
+		e   QueueEntry
+		pts []model.FlightTrackPoint
+	)
+	err = q.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(data).Get([]byte(key))
+		if v == nil {
+			return ErrQueueBatchNotFound
+		}
+		e, pts = newQueueEntry(tx, state, key, v)
+		return nil
+	})
+	return e, pts, err
+}
+
+// Requeue 将死信批次移回待重放队列并清零重放次数，keys 为空时移回全部死信，返回移回的批次 key
+func (q *Queue) Requeue(keys []string) ([]string, error) {
+	err := q.db.Update(func(tx *bolt.Tx) error {
+		if len(keys) == 0 {
+			keys = bucketKeys(tx.Bucket(bucketDead))
 		}
+		for _, k := range keys {
+			if tx.Bucket(bucketDead).Get([]byte(k)) == nil {
+				return fmt.Errorf("%w: %s", ErrQueueBatchNotFound, k)
+			}
+			if err := moveBatch(tx, QueueDead, QueuePending, k, func(m *QueueMeta) {
+				m.Attempts, m.LastError, m.LastAttemptAt, m.DeadAt, m.DeadReason = 0, "", nil, nil, ""
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Purge 删除 state 状态下的批次，keys 为空时删除该状态的全部批次，返回删除的批次 key
+func (q *Queue) Purge(state string, keys []string) ([]string, error) {
+	data, meta, err := stateBuckets(state)
+	if err != nil {
+		return nil, err
+	}
+	err = q.db.Update(func(tx *bolt.Tx) error {
+		b, mb := tx.Bucket(data), tx.Bucket(meta)
+		if len(keys) == 0 {
+			keys = bucketKeys(b)
+		}
+		for _, k := range keys {
+			if b.Get([]byte(k)) == nil {
+				return fmt.Errorf("%w: %s", ErrQueueBatchNotFound, k)
+			}
+			if err := b.Delete([]byte(k)); err != nil {
+				return err
+			}
+			if err := mb.Delete([]byte(k)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Stats 返回队列统计
+func (q *Queue) Stats() (QueueStats, error) {
+	s := QueueStats{
+		MaxAttempts:  q.maxAttempts,
+		Replayed:     q.replayed.Load(),
+		Failed:       q.failed.Load(),
+		DeadLettered: q.deadLettered.Load(),
+	}
+	err := q.db.View(func(tx *bolt.Tx) error {
+		count := func(name []byte) (n int, size int64) {
+			_ = tx.Bucket(name).ForEach(func(_, v []byte) error {
+				n++
+				size += int64(len(v))
+				return nil
+			})
+			return n, size
+		}
+		s.Pending, s.PendingBytes = count(bucketPending)
+		s.Dead, s.DeadBytes = count(bucketDead)
+		if k, _ := tx.Bucket(bucketPending).Cursor().First(); k != nil {
+			s.OldestPendingAt = readMeta(tx, QueuePending, string(k)).EnqueuedAt
+		}
+		return nil
+	})
+	if info, statErr := os.Stat(q.path); statErr == nil {
+		s.FileSize = info.Size()
+	}
+	return s, err
+}
+
+func stateBuckets(state string) (data, meta []byte, err error) {
+	switch state {
+	case QueuePending:
+		return bucketPending, bucketPendingMeta, nil
+	case QueueDead:
+		return bucketDead, bucketDeadMeta, nil
+	}
+	return nil, nil, fmt.Errorf("未知的队列状态 %q", state)
+}
+
+// readMeta 读取批次的 meta；旧版本入队的批次没有 meta，入队时间取自 key 中的时间戳
+func readMeta(tx *bolt.Tx, state, key string) QueueMeta {
+	_, meta, _ := stateBuckets(state)
+	var m QueueMeta
+	if v := tx.Bucket(meta).Get([]byte(key)); v != nil && json.Unmarshal(v, &m) == nil {
+		return m
+	}
+	ts, _, _ := strings.Cut(key, "_")
+	if ns, err := strconv.ParseInt(ts, 10, 64); err == nil {
+		m.EnqueuedAt = time.Unix(0, ns)
+	}
+	return m
+}
+
+func writeMeta(tx *bolt.Tx, state, key string, m QueueMeta) error {
+	_, meta, _ := stateBuckets(state)
+	v, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(meta).Put([]byte(key), v)
+}
+
+// moveBatch 将批次原样移到另一状态，update 用于修改其 meta
+func moveBatch(tx *bolt.Tx, from, to, key string, update func(m *QueueMeta)) error {
+	fromData, fromMeta, _ := stateBuckets(from)
+	toData, _, _ := stateBuckets(to)
+	v := tx.Bucket(fromData).Get([]byte(key))
+	if v == nil {
+		return ErrQueueBatchNotFound
+	}
+	m := readMeta(tx, from, key)
+	update(&m)
+	// v 只在事务内有效，Delete 前复制
+	data := append([]byte(nil), v...)
+	if err := tx.Bucket(toData).Put([]byte(key), data); err != nil {
+		return err
+	}
+	if err := writeMeta(tx, to, key, m); err != nil {
+		return err
+	}
+	if err := tx.Bucket(fromData).Delete([]byte(key)); err != nil {
+		return err
+	}
+	return tx.Bucket(fromMeta).Delete([]byte(key))
+}
+
+func newQueueEntry(tx *bolt.Tx, state, key string, v []byte) (QueueEntry, []model.FlightTrackPoint) {
+	e := QueueEntry{Key: key, State: state, Bytes: len(v), QueueMeta: readMeta(tx, state, key)}
+	var pts []model.FlightTrackPoint
+	if err := json.Unmarshal(v, &pts); err != nil {
+		e.DecodeError = err.Error()
+		return e, nil
+	}
+	e.Points = len(pts)
+	return e, pts
+}
+
+func bucketKeys(b *bolt.Bucket) []string {
+	var keys []string
+	_ = b.ForEach(func(k, _ []byte) error {
+		keys = append(keys, string(k))
 		return nil
 	})
+	return keys
 }
@@ -0,0 +1,232 @@
+package dao
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"drone-stats-service/internal/model"
+
+	"github.com/go-sql-driver/mysql"
+	bolt "go.etcd.io/bbolt"
+)
+
+// newTestQueue 临时目录中的队列文件，批次被拒绝 3 次后移入死信
+func newTestQueue(t *testing.T) *Queue {
+	t.Helper()
+	q, err := NewQueue(filepath.Join(t.TempDir(), "queue.db"), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { q.Close() })
+	return q
+}
+
+// enqueueBatches 入队 n 个批次，第 i 个批次含 i+1 个点，返回按入队顺序排列的 key
+func enqueueBatches(t *testing.T, q *Queue, n int) []string {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if err := q.Enqueue(make([]model.FlightTrackPoint, i+1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, _, err := q.List(QueuePending, 0, n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, e := range entries {
+		keys = append(keys, e.Key)
+	}
+	return keys
+}
+
+func queueKeys(t *testing.T, q *Queue, state string) []string {
+	t.Helper()
+	entries, total, err := q.List(state, 0, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != len(entries) {
+		t.Fatalf("%s total = %d, listed %d", state, total, len(entries))
+	}
+	var keys []string
+	for _, e := range entries {
+		keys = append(keys, e.Key)
+	}
+	return keys
+}
+
+func TestQueueRecordFailure(t *testing.T) {
+	q := newTestQueue(t)
+	keys := enqueueBatches(t, q, 2)
+	cause := errors.New("Data too long")
+
+	for i := 1; i <= 3; i++ {
+		dead, err := q.RecordFailure(keys[0], cause)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dead != (i == 3) {
+			t.Fatalf("attempt %d dead = %v", i, dead)
+		}
+		if i < 3 {
+			e, _, err := q.Get(QueuePending, keys[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if e.Attempts != i || e.LastError != cause.Error() || e.LastAttemptAt == nil {
+				t.Errorf("attempt %d meta = %+v", i, e.QueueMeta)
+			}
+		}
+	}
+
+	// 批次及其 meta 一并移入死信
+	if got := queueKeys(t, q, QueuePending); !reflect.DeepEqual(got, keys[1:]) {
+		t.Errorf("pending = %v, want %v", got, keys[1:])
+	}
+	e, pts, err := q.Get(QueueDead, keys[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pts) != 1 || e.Attempts != 3 || e.DeadReason != DeadReasonRejected || e.DeadAt == nil || e.EnqueuedAt.IsZero() {
+		t.Errorf("dead entry = %+v, points = %d", e, len(pts))
+	}
+	if _, err := q.RecordFailure(keys[0], cause); !errors.Is(err, ErrQueueBatchNotFound) {
+		t.Errorf("record failure on dead batch err = %v, want ErrQueueBatchNotFound", err)
+	}
+}
+
+func TestQueuePeekBatchDeadLettersUndecodable(t *testing.T) {
+	q := newTestQueue(t)
+	keys := enqueueBatches(t, q, 2)
+	if err := q.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketPending).Put([]byte(keys[0]), []byte("{not json"))
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	batches, err := q.PeekBatch(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batches) != 1 || len(batches[keys[1]]) != 2 {
+		t.Errorf("batches = %v, want only %s", batches, keys[1])
+	}
+	e, pts, err := q.Get(QueueDead, keys[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pts != nil || e.DecodeError == "" || e.DeadReason != DeadReasonDecode || e.LastError == "" {
+		t.Errorf("dead entry = %+v", e)
+	}
+	s, err := q.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Pending != 1 || s.Dead != 1 || s.DeadLettered != 1 {
+		t.Errorf("stats = %+v", s)
+	}
+}
+
+func TestQueueRequeueAndPurge(t *testing.T) {
+	q := newTestQueue(t)
+	keys := enqueueBatches(t, q, 4)
+	for _, k := range keys[:3] {
+		for i := 0; i < 3; i++ {
+			if _, err := q.RecordFailure(k, errors.New("rejected")); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if got := queueKeys(t, q, QueueDead); !reflect.DeepEqual(got, keys[:3]) {
+		t.Fatalf("dead = %v, want %v", got, keys[:3])
+	}
+
+	// 指定 key 移回，重放次数与错误清零
+	requeued, err := q.Requeue([]string{keys[1]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(requeued, []string{keys[1]}) {
+		t.Errorf("requeued = %v", requeued)
+	}
+	e, _, err := q.Get(QueuePending, keys[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Attempts != 0 || e.LastError != "" || e.LastAttemptAt != nil || e.DeadAt != nil || e.DeadReason != "" {
+		t.Errorf("requeued meta = %+v", e.QueueMeta)
+	}
+	if _, err := q.Requeue([]string{"missing"}); !errors.Is(err, ErrQueueBatchNotFound) {
+		t.Errorf("requeue missing err = %v, want ErrQueueBatchNotFound", err)
+	}
+
+	// 分页列出
+	page, total, err := q.List(QueuePending, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 || len(page) != 1 || page[0].Key != keys[3] || page[0].Points != 4 {
+		t.Errorf("page = %+v, total = %d", page, total)
+	}
+
+	// 不指定 key 时清空该状态
+	purged, err := q.Purge(QueueDead, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(purged)
+	if want := []string{keys[0], keys[2]}; !reflect.DeepEqual(purged, want) {
+		t.Errorf("purged = %v, want %v", purged, want)
+	}
+	if _, err := q.Purge(QueuePending, []string{keys[0]}); !errors.Is(err, ErrQueueBatchNotFound) {
+		t.Errorf("purge missing err = %v, want ErrQueueBatchNotFound", err)
+	}
+	if _, err := q.Purge("unknown", nil); err == nil {
+		t.Error("purge unknown state succeeded")
+	}
+
+	s, err := q.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Pending != 2 || s.Dead != 0 || s.MaxAttempts != 3 || s.Failed != 9 || s.DeadLettered != 3 || s.FileSize == 0 || s.OldestPendingAt.IsZero() {
+		t.Errorf("stats = %+v", s)
+	}
+	if err := q.DeleteKeys(keys[1:2]); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := q.Stats(); s.Pending != 1 || s.Replayed != 1 {
+		t.Errorf("after delete stats = %+v", s)
+	}
+}
+
+func TestIsRejectedByMySQL(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"锁等待超时", &mysql.MySQLError{Number: 1205}, false},
+		{"死锁", &mysql.MySQLError{Number: 1213}, false},
+		{"连接数过多", &mysql.MySQLError{Number: 1040}, false},
+		{"只读实例", &mysql.MySQLError{Number: 1290}, false},
+		{"包装后的临时错误", fmt.Errorf("insert: %w", &mysql.MySQLError{Number: 1213}), false},
+		{"数据过长", &mysql.MySQLError{Number: 1406}, true},
+		{"列不存在", &mysql.MySQLError{Number: 1054}, true},
+		{"包装后的永久错误", fmt.Errorf("insert: %w", &mysql.MySQLError{Number: 1366}), true},
+		{"连接失败", errors.New("dial tcp: connection refused"), false},
+		{"连接中断", mysql.ErrInvalidConn, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRejectedByMySQL(tt.err); got != tt.want {
+				t.Errorf("isRejectedByMySQL(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
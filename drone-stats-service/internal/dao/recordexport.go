@@ -0,0 +1,80 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"drone-stats-service/internal/model"
+)
+
+// recordExportFilter 构造导出飞行记录的过滤条件，与 ExportFlightRecordsToCSVStream 一致，start/end 为零值时不过滤
+func recordExportFilter(orderID, uasID string, start, end time.Time) (string, []interface{}) {
+	query := ""
+	args := []interface{}{}
+	if orderID != "" {
+		query += " AND OrderID = ?"
+		args = append(args, orderID)
+	}
+	if uasID != "" {
+		query += " AND uasID = ?"
+		args = append(args, uasID)
+	}
+	if !start.IsZero() {
+		query += " AND start_time >= ?"
+		args = append(args, start)
+	}
+	if !end.IsZero() {
+		query += " AND end_time <= ?"
+		args = append(args, end)
+	}
+	return query, args
+}
+
+// ForEachFlightRecord 按起飞时间升序逐条回调导出范围内的飞行记录，避免一次性载入内存；
+// byUas 为 true 时先按 uasID 排序，使同一无人机的记录连续输出（按无人机分区导出时使用）
+func (d *SQLDao) ForEachFlightRecord(ctx context.Context, orderID, uasID string, start, end time.Time, byUas bool, fn func(model.FlightRecord) error) error {
+	where, args := recordExportFilter(orderID, uasID, start, end)
+	order := " ORDER BY start_time ASC, id ASC"
+	if byUas {
+		order = " ORDER BY uasID, start_time ASC, id ASC"
+	}
+	query := `SELECT id, OrderID, uasID, start_time, end_time, IFNULL(start_lat,0), IFNULL(start_lng,0), IFNULL(end_lat,0), IFNULL(end_lng,0),
+		IFNULL(distance,0), IFNULL(battery_used,0), IFNULL(energy_method,''), created_at, IFNULL(payload,0), IFNULL(expressCount,0)
+		FROM flight_records WHERE 1=1` + where + order
+
+	rows, err := d.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			r                  model.FlightRecord
+			endTime, createdAt sql.NullTime
+		)
+		if err := rows.Scan(&r.ID, &r.OrderID, &r.UasID, &r.StartTime, &endTime, &r.StartLat, &r.StartLng, &r.EndLat, &r.EndLng,
+			&r.Distance, &r.BatteryUsed, &r.EnergyMethod, &createdAt, &r.Payload, &r.ExpressCount); err != nil {
+			return err
+		}
+		// 未降落的架次 end_time 为空，EndTime 保持零值
+		if endTime.Valid {
+			r.EndTime = endTime.Time
+		}
+		if createdAt.Valid {
+			r.CreatedAt = createdAt.Time
+		}
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// CountFlightRecords 统计 ForEachFlightRecord 将导出的记录数，用于估算导出进度
+func (d *SQLDao) CountFlightRecords(ctx context.Context, orderID, uasID string, start, end time.Time) (int64, error) {
+	where, args := recordExportFilter(orderID, uasID, start, end)
+	var n int64
+	err := d.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM flight_records WHERE 1=1`+where, args...).Scan(&n)
+	return n, err
+}
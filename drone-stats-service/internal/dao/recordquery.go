@@ -0,0 +1,302 @@
+package dao
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// 分页查询的默认/最大单页条数
+const (
+	DefaultRecordPageSize = 100
+	MaxRecordPageSize     = 1000
+)
+
+// BoundingBox 经纬度矩形范围，单位与 flight_records 一致（度 * 1e7），边界包含在内
+type BoundingBox struct {
+	MinLat int64
+	MinLng int64
+	MaxLat int64
+	MaxLng int64
+}
+
+// FlightRecordQuery /record/query 的查询条件
+type FlightRecordQuery struct {
+	OrderID   string
+	UasIDs    []string
+	Model     string
+	StartTime string // "2006-01-02 15:04:05"，start_time >= StartTime
+	EndTime   string // end_time <= EndTime
+
+	MinDistance, MaxDistance float64 // 米，0 表示不限制
+	MinDuration, MaxDuration int64   // 秒，0 表示不限制
+	MinPayload, MaxPayload   int     // 与 payload 字段同单位（kg * 10），0 表示不限制
+
+	StartBBox *BoundingBox
+	EndBBox   *BoundingBox
+
+	// IncompleteOnly 仅返回信息不完整的架次：缺少降落时间，或尚未录入载货量/票数
+	IncompleteOnly bool
+
+	SortBy    string // 见 recordSortColumns，默认 start_time
+	SortOrder string // asc | desc，默认 desc
+	Limit     int
+	Offset    int    // 仅在未提供 Cursor 时生效
+	Cursor    string // 上一页返回的 nextCursor
+}
+
+// FlightRecordPage 分页查询结果
+type FlightRecordPage struct {
+	Records    []map[string]interface{}
+	Total      int // 提供 Cursor 时不统计，为 -1
+	NextCursor string
+}
+
+// recordSortColumn 可排序列：expr 用于 ORDER BY/游标比较，key 用于取出游标值（统一转为字符串），
+// numeric 表示游标值需按数值比较
+type recordSortColumn struct {
+	expr    string
+	key     string
+	numeric bool
+}
+
+// 排序列均有 (列, id) 索引：end_time 与 duration 使用写入时计算的 sort_end_time、duration_sec 列
+func (d *SQLDao) recordSortColumns() map[string]recordSortColumn {
+	return map[string]recordSortColumn{
+		"start_time":   {expr: "start_time", key: d.dateFormat("start_time", "%Y-%m-%d %H:%i:%s")},
+		"end_time":     {expr: "sort_end_time", key: d.dateFormat("sort_end_time", "%Y-%m-%d %H:%i:%s")},
+		"distance":     {expr: "COALESCE(distance, 0)", key: "CAST(COALESCE(distance, 0) AS CHAR)", numeric: true},
+		"battery_used": {expr: "COALESCE(battery_used, 0)", key: "CAST(COALESCE(battery_used, 0) AS CHAR)", numeric: true},
+		"duration":     {expr: "duration_sec", key: "CAST(duration_sec AS CHAR)", numeric: true},
+		"payload":      {expr: "payload", key: "CAST(payload AS CHAR)", numeric: true},
+		"expressCount": {expr: "expressCount", key: "CAST(expressCount AS CHAR)", numeric: true},
+		"id":           {expr: "id", key: "CAST(id AS CHAR)", numeric: true},
+	}
+}
+
+// recordCursor 游标内容：最后一行的排序值与 id，附带排序方式用于校验
+type recordCursor struct {
+	Key   string `json:"k"`
+	ID    int    `json:"id"`
+	Sort  string `json:"s"`
+	Order string `json:"o"`
+}
+
+func encodeRecordCursor(c recordCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeRecordCursor(s string) (recordCursor, error) {
+	var c recordCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, fmt.Errorf("invalid cursor: %w", err)
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("invalid cursor: %w", err)
+	}
+	return c, nil
+}
+
+// buildRecordWhere 根据查询条件拼接 WHERE 子句（不含游标条件）
+func (d *SQLDao) buildRecordWhere(q FlightRecordQuery) (string, []interface{}) {
+	where := " WHERE 1=1"
+	args := []interface{}{}
+	if q.OrderID != "" {
+		where += " AND OrderID=?"
+		args = append(args, q.OrderID)
+	}
+	if len(q.UasIDs) > 0 {
+		placeholders := make([]string, len(q.UasIDs))
+		for i, id := range q.UasIDs {
+			placeholders[i] = "?"
+			args = append(args, id)
+		}
+		where += fmt.Sprintf(" AND uasID IN (%s)", strings.Join(placeholders, ","))
+	}
+	if q.Model != "" {
+		where += " AND EXISTS (SELECT 1 FROM flight_sorties s WHERE s.OrderID = flight_records.OrderID AND s.model = ?)"
+		args = append(args, q.Model)
+	}
+	if q.StartTime != "" {
+		where += " AND start_time >= ?"
+		args = append(args, q.StartTime)
+	}
+	if q.EndTime != "" {
+		where += " AND end_time <= ?"
+		args = append(args, q.EndTime)
+	}
+	if q.MinDistance > 0 {
+		where += " AND distance >= ?"
+		args = append(args, q.MinDistance)
+	}
+	if q.MaxDistance > 0 {
+		where += " AND distance <= ?"
+		args = append(args, q.MaxDistance)
+	}
+	if q.MinDuration > 0 {
+		where += " AND end_time IS NOT NULL AND duration_sec >= ?"
+		args = append(args, q.MinDuration)
+	}
+	if q.MaxDuration > 0 {
+		where += " AND end_time IS NOT NULL AND duration_sec <= ?"
+		args = append(args, q.MaxDuration)
+	}
+	if q.MinPayload > 0 {
+		where += " AND payload >= ?"
+		args = append(args, q.MinPayload)
+	}
+	if q.MaxPayload > 0 {
+		where += " AND payload <= ?"
+		args = append(args, q.MaxPayload)
+	}
+	if b := q.StartBBox; b != nil {
+		where += " AND start_lat BETWEEN ? AND ? AND start_lng BETWEEN ? AND ?"
+		args = append(args, b.MinLat, b.MaxLat, b.MinLng, b.MaxLng)
+	}
+	if b := q.EndBBox; b != nil {
+		where += " AND end_lat BETWEEN ? AND ? AND end_lng BETWEEN ? AND ?"
+		args = append(args, b.MinLat, b.MaxLat, b.MinLng, b.MaxLng)
+	}
+	if q.IncompleteOnly {
+		where += " AND (end_time IS NULL OR payload = 0 OR expressCount = 0)"
+	}
+	return where, args
+}
+
+// QueryFlightRecordsPage 按条件分页查询飞行记录，支持排序、偏移分页与游标（keyset）分页，并返回总数。
+// 游标分页基于 (排序列, id) 比较，深翻页时无需扫描前面的行。
+func (d *SQLDao) QueryFlightRecordsPage(q FlightRecordQuery) (*FlightRecordPage, error) {
+	sortBy := q.SortBy
+	if sortBy == "" {
+		sortBy = "start_time"
+	}
+	col, ok := d.recordSortColumns()[sortBy]
+	if !ok {
+		return nil, fmt.Errorf("unsupported sortBy: %s", sortBy)
+	}
+	order := strings.ToLower(q.SortOrder)
+	if order == "" {
+		order = "desc"
+	}
+	if order != "asc" && order != "desc" {
+		return nil, fmt.Errorf("unsupported sortOrder: %s", q.SortOrder)
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultRecordPageSize
+	}
+	if limit > MaxRecordPageSize {
+		limit = MaxRecordPageSize
+	}
+
+	where, args := d.buildRecordWhere(q)
+
+	// 总数只在首页（或偏移分页）统计，游标翻页时跳过，避免每页都扫描全部匹配行
+	page := &FlightRecordPage{Total: -1}
+	if q.Cursor == "" {
+		if err := d.DB.QueryRow("SELECT COUNT(*) FROM flight_records"+where, args...).Scan(&page.Total); err != nil {
+			return nil, err
+		}
+	}
+
+	query := `SELECT id, OrderID, uasID, start_time, end_time, start_lat, start_lng, end_lat, end_lng, distance, battery_used, IFNULL(energy_method, ''), created_at, payload, expressCount, ` + col.key + ` AS sort_key
+        FROM flight_records` + where
+	if q.Cursor != "" {
+		c, err := decodeRecordCursor(q.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if c.Sort != sortBy || c.Order != order {
+			return nil, fmt.Errorf("cursor does not match sortBy/sortOrder")
+		}
+		cmp := "<"
+		if order == "asc" {
+			cmp = ">"
+		}
+		// MySQL 比较时自动将字符串转为数值；SQLite 中数值总是小于字符串，需显式转换
+		ph := "?"
+		if col.numeric && d.dialect == DialectSQLite {
+			ph = "CAST(? AS NUMERIC)"
+		}
+		query += fmt.Sprintf(" AND (%s %s %s OR (%s = %s AND id %s ?))", col.expr, cmp, ph, col.expr, ph, cmp)
+		args = append(args, c.Key, c.Key, c.ID)
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT ?", col.expr, order, order)
+	args = append(args, limit)
+	if q.Cursor == "" && q.Offset > 0 {
+		query += " OFFSET ?"
+		args = append(args, q.Offset)
+	}
+
+	rows, err := d.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var (
+		lastKey string
+		lastID  int
+	)
+	for rows.Next() {
+		var (
+			id, payload, expressCount          int
+			orderID, uasID, energyMethod       string
+			startTime, endTime, createdAt      sql.NullTime
+			startLat, startLng, endLat, endLng sql.NullInt64
+			distance, batteryUsed              sql.NullFloat64
+			sortKey                            sql.NullString
+		)
+		if err := rows.Scan(&id, &orderID, &uasID, &startTime, &endTime, &startLat, &startLng, &endLat, &endLng, &distance, &batteryUsed, &energyMethod, &createdAt, &payload, &expressCount, &sortKey); err != nil {
+			return nil, err
+		}
+		page.Records = append(page.Records, map[string]interface{}{
+			"id":            id,
+			"OrderID":       orderID,
+			"uasID":         uasID,
+			"start_time":    startTime.Time.Format("2006-01-02 15:04:05"),
+			"end_time":      endTime.Time.Format("2006-01-02 15:04:05"),
+			"start_lat":     startLat.Int64,
+			"start_lng":     startLng.Int64,
+			"end_lat":       endLat.Int64,
+			"end_lng":       endLng.Int64,
+			"distance":      distance.Float64,
+			"battery_used":  batteryUsed.Float64,
+			"energy_method": energyMethod,
+			"created_at":    createdAt.Time.Format("2006-01-02 15:04:05"),
+			"payload":       payload,
+			"expressCount":  expressCount,
+		})
+		lastKey = sortKey.String
+		lastID = id
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// 取满一页才返回下一页游标
+	if len(page.Records) == limit {
+		page.NextCursor = encodeRecordCursor(recordCursor{Key: lastKey, ID: lastID, Sort: sortBy, Order: order})
+	}
+	return page, nil
+}
+
+// RecentOrderIDs 返回最近 n 个架次的 OrderID（按起飞时间倒序）
+func (d *SQLDao) RecentOrderIDs(n int) ([]string, error) {
+	rows, err := d.DB.Query("SELECT OrderID FROM flight_records ORDER BY start_time DESC, id DESC LIMIT ?", n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var oid string
+		if err := rows.Scan(&oid); err != nil {
+			return nil, err
+		}
+		ids = append(ids, oid)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,174 @@
+package dao
+
+import (
+	"database/sql"
+	"time"
+
+	"drone-stats-service/internal/model"
+)
+
+const reportScheduleColumns = `id, name, cron, target, format, partition_by, IFNULL(uasID, ''), range_hours, delivery, delivery_to, retain_days, enabled, last_run_at, next_run_at, created_at`
+
+const reportRunColumns = `id, schedule_id, IFNULL(task_id, ''), trigger_type, period_start, period_end, status, IFNULL(file_path, ''), file_size, IFNULL(error, ''), started_at, finished_at`
+
+// zeroToNull 零值时间写入为 NULL
+func zeroToNull(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanReportSchedule(r rowScanner) (model.ReportSchedule, error) {
+	var (
+		s                model.ReportSchedule
+		lastRun, nextRun sql.NullTime
+	)
+	err := r.Scan(&s.ID, &s.Name, &s.Cron, &s.Target, &s.Format, &s.PartitionBy, &s.UasID, &s.RangeHours, &s.Delivery, &s.DeliveryTo,
+		&s.RetainDays, &s.Enabled, &lastRun, &nextRun, &s.CreatedAt)
+	s.LastRunAt = lastRun.Time
+	s.NextRunAt = nextRun.Time
+	return s, err
+}
+
+func scanReportRun(r rowScanner) (model.ReportRun, error) {
+	var (
+		run      model.ReportRun
+		finished sql.NullTime
+	)
+	err := r.Scan(&run.ID, &run.ScheduleID, &run.TaskID, &run.Trigger, &run.PeriodStart, &run.PeriodEnd, &run.Status, &run.FilePath,
+		&run.FileSize, &run.Error, &run.StartedAt, &finished)
+	run.FinishedAt = finished.Time
+	return run, err
+}
+
+// CreateReportSchedule 新增报表计划，返回自增 id
+func (d *SQLDao) CreateReportSchedule(s model.ReportSchedule) (int64, error) {
+	res, err := d.DB.Exec(`INSERT INTO report_schedules
+		(name, cron, target, format, partition_by, uasID, range_hours, delivery, delivery_to, retain_days, enabled, next_run_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		s.Name, s.Cron, s.Target, s.Format, s.PartitionBy, s.UasID, s.RangeHours, s.Delivery, s.DeliveryTo, s.RetainDays, s.Enabled, zeroToNull(s.NextRunAt))
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// UpdateReportSchedule 整体更新报表计划的配置及下次执行时间
+func (d *SQLDao) UpdateReportSchedule(s model.ReportSchedule) error {
+	_, err := d.DB.Exec(`UPDATE report_schedules SET name=?, cron=?, target=?, format=?, partition_by=?, uasID=?, range_hours=?,
+		delivery=?, delivery_to=?, retain_days=?, enabled=?, next_run_at=? WHERE id=?`,
+		s.Name, s.Cron, s.Target, s.Format, s.PartitionBy, s.UasID, s.RangeHours, s.Delivery, s.DeliveryTo, s.RetainDays, s.Enabled, zeroToNull(s.NextRunAt), s.ID)
+	return err
+}
+
+// SetReportScheduleRunTimes 更新报表计划的上次/下次执行时间
+func (d *SQLDao) SetReportScheduleRunTimes(id int64, lastRun, nextRun time.Time) error {
+	_, err := d.DB.Exec(`UPDATE report_schedules SET last_run_at=?, next_run_at=? WHERE id=?`, zeroToNull(lastRun), zeroToNull(nextRun), id)
+	return err
+}
+
+// DeleteReportSchedule 删除报表计划，执行记录保留
+func (d *SQLDao) DeleteReportSchedule(id int64) error {
+	_, err := d.DB.Exec(`DELETE FROM report_schedules WHERE id=?`, id)
+	return err
+}
+
+// GetReportSchedule 查询单个报表计划，不存在时返回 sql.ErrNoRows
+func (d *SQLDao) GetReportSchedule(id int64) (model.ReportSchedule, error) {
+	return scanReportSchedule(d.DB.QueryRow(`SELECT `+reportScheduleColumns+` FROM report_schedules WHERE id=?`, id))
+}
+
+// ListReportSchedules 查询报表计划，enabledOnly 为 true 时仅返回启用的计划
+func (d *SQLDao) ListReportSchedules(enabledOnly bool) ([]model.ReportSchedule, error) {
+	query := `SELECT ` + reportScheduleColumns + ` FROM report_schedules`
+	if enabledOnly {
+		query += " WHERE enabled = 1"
+	}
+	query += " ORDER BY id"
+	rows, err := d.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []model.ReportSchedule
+	for rows.Next() {
+		s, err := scanReportSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	return list, rows.Err()
+}
+
+// CreateReportRun 新增报表执行记录，返回自增 id
+func (d *SQLDao) CreateReportRun(r model.ReportRun) (int64, error) {
+	res, err := d.DB.Exec(`INSERT INTO report_runs (schedule_id, task_id, trigger_type, period_start, period_end, status, error, started_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.ScheduleID, r.TaskID, r.Trigger, r.PeriodStart, r.PeriodEnd, r.Status, r.Error, r.StartedAt)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// UpdateReportRun 更新执行记录的任务、状态、存档文件及结束时间
+func (d *SQLDao) UpdateReportRun(r model.ReportRun) error {
+	_, err := d.DB.Exec(`UPDATE report_runs SET task_id=?, status=?, file_path=?, file_size=?, error=?, finished_at=? WHERE id=?`,
+		r.TaskID, r.Status, r.FilePath, r.FileSize, r.Error, zeroToNull(r.FinishedAt), r.ID)
+	return err
+}
+
+// GetReportRun 查询单条执行记录
+func (d *SQLDao) GetReportRun(id int64) (model.ReportRun, error) {
+	return scanReportRun(d.DB.QueryRow(`SELECT `+reportRunColumns+` FROM report_runs WHERE id=?`, id))
+}
+
+// ListReportRuns 查询执行记录，scheduleID 为 0 时不过滤，按开始时间倒序
+func (d *SQLDao) ListReportRuns(scheduleID int64, limit int) ([]model.ReportRun, error) {
+	query := `SELECT ` + reportRunColumns + ` FROM report_runs`
+	args := []interface{}{}
+	if scheduleID > 0 {
+		query += " WHERE schedule_id = ?"
+		args = append(args, scheduleID)
+	}
+	query += " ORDER BY started_at DESC, id DESC LIMIT ?"
+	args = append(args, limit)
+	return d.queryReportRuns(query, args...)
+}
+
+// ListPendingReportRuns 查询尚未结束的执行记录（服务重启后恢复跟踪）
+func (d *SQLDao) ListPendingReportRuns() ([]model.ReportRun, error) {
+	return d.queryReportRuns(`SELECT ` + reportRunColumns + ` FROM report_runs WHERE status = 'pending' ORDER BY id`)
+}
+
+// ListExpiredReportRuns 查询存档超过保留期的执行记录：计划未指定保留天数（或已删除）时使用 defaultRetainDays
+func (d *SQLDao) ListExpiredReportRuns(defaultRetainDays int, now time.Time) ([]model.ReportRun, error) {
+	return d.queryReportRuns(`SELECT r.id, r.schedule_id, IFNULL(r.task_id, ''), r.trigger_type, r.period_start, r.period_end, r.status,
+		IFNULL(r.file_path, ''), r.file_size, IFNULL(r.error, ''), r.started_at, r.finished_at
+		FROM report_runs r LEFT JOIN report_schedules s ON s.id = r.schedule_id
+		WHERE r.file_path IS NOT NULL AND r.file_path <> ''
+		  AND r.finished_at < `+d.subDays("?", "CASE WHEN IFNULL(s.retain_days, 0) > 0 THEN s.retain_days ELSE ? END"), now, defaultRetainDays)
+}
+
+func (d *SQLDao) queryReportRuns(query string, args ...interface{}) ([]model.ReportRun, error) {
+	rows, err := d.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []model.ReportRun
+	for rows.Next() {
+		r, err := scanReportRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, r)
+	}
+	return list, rows.Err()
+}
@@ -0,0 +1,152 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"drone-stats-service/internal/model"
+)
+
+// 仓储接口：logic、handler、导出与定时报表通过接口访问关系库与遥测数据，
+// 由 SQLDao（MySQL 或嵌入式 SQLite）与 InfluxDao / MemoryTelemetry 实现。
+// 建表迁移、队列重放等启动与后台任务仍直接使用 SQLDao。
+
+// FlightRecordRepo 飞行记录及其统计
+type FlightRecordRepo interface {
+	// Location 关系库中 DATETIME 的时区
+	Location() *time.Location
+	SaveFlightRecordAndGetOrderID(fr model.FlightRecord) (string, error)
+	FlightRecordExists(orderID string, startTime, endTime time.Time) (bool, error)
+	QueryFlightRecordsPage(q FlightRecordQuery) (*FlightRecordPage, error)
+	RecentOrderIDs(n int) ([]string, error)
+	GetFlightWindow(orderID string, at time.Time) (start, end time.Time, err error)
+	GetFlightReportRecord(id int, orderID string) (FlightReportRecord, error)
+	UpdateFlightPayload(orderID string, payload, expressCount int, meta AuditMeta) error
+
+	GetFlightStats() (totalCount int, totalDistance float64, totalTime int64, err error)
+	GetFlightRecordsStats() (yearStats, monthStats, dayStats []map[string]interface{}, err error)
+	GetSOCUsageStats() (yearStats, monthStats, dayStats []map[string]interface{}, err error)
+	GetAvgSOCPerDistancePayloadStats() (yearStats, monthStats, dayStats []map[string]interface{}, err error)
+	GetPayloadStats() (yearStats, monthStats, dayStats []map[string]interface{}, err error)
+	GetAvgStats() (avgTime float64, avgSOC float64, avgPayload float64, avgGS float64, err error)
+	AggregateFlightRecordSlots(q StatsAggQuery) ([]StatsSlot, error)
+	GetUasFlightTotals(uasIDs []string, start, end time.Time) (map[string]*UasFlightTotals, error)
+	GetUasSOCDrops(uasIDs []string, start, end time.Time) (map[string][]float64, error)
+}
+
+// TrackPointRepo 轨迹点及轨迹清洗统计
+type TrackPointRepo interface {
+	SaveTrackPoints(points []model.FlightTrackPoint) error
+	GetTrackPoints(orderID string) ([]model.FlightTrackPoint, error)
+	GetTrackPointsByRecordId(orderID string) ([]map[string]interface{}, error)
+	GetTrackPointsInRange(orderIDs []string, start, end time.Time) ([]model.FlightTrackPoint, error)
+	QueryTrackPoints(startTime, endTime, orderID string) ([]map[string]interface{}, error)
+	TrackPointsVersion(orderID string) (count int, maxID int64, err error)
+	SaveTrackCleaning(c model.TrackCleaning) error
+	GetTrackCleaning(orderID string) ([]model.TrackCleaning, error)
+}
+
+// SortieRepo 架次登记与无人机信息
+type SortieRepo interface {
+	RegisterSortiesIfNotExist(orderID string, regTime time.Time) error
+	CountTotalSorties() (int, error)
+	CountOnlineSorties() (int, error)
+	ListUasIDs() ([]string, error)
+	GetUasModel(uasID string) (string, error)
+	GetUasModels() (map[string]string, error)
+	GetUasIDByOrderID(orderID string) (string, error)
+}
+
+// ExportRepo 导出所需的流式读取与下载记录
+type ExportRepo interface {
+	CountFlightRecords(ctx context.Context, orderID, uasID string, start, end time.Time) (int64, error)
+	CountTrackPoints(ctx context.Context, orderID, uasID string, start, end time.Time) (int64, error)
+	ForEachFlightRecord(ctx context.Context, orderID, uasID string, start, end time.Time, byUas bool, fn func(model.FlightRecord) error) error
+	ForEachTrackPoint(ctx context.Context, orderID, uasID string, start, end time.Time, fn func(model.FlightTrackPoint) error) error
+	ExportFlightRecordsToExcelStream(ctx context.Context, orderID, uasID, startTime, endTime, filePath string, onRow func()) error
+	ExportFlightRecordsToCSVStream(ctx context.Context, orderID, uasID, startTime, endTime, filePath string, onRow func()) error
+	ExportTrackPointsToExcelStream(ctx context.Context, startTime, endTime, orderID, uasID, filePath string, onRow func()) error
+	ExportTrackPointsToCSVStream(ctx context.Context, startTime, endTime, orderID, uasID, filePath string, onRow func()) error
+	GetUasIDByOrderID(orderID string) (string, error)
+	SaveExportDownload(r model.ExportDownload) error
+	GetExportDownloads(taskID, user string, limit int) ([]model.ExportDownload, error)
+}
+
+// BatteryRepo 单架次电池指标
+type BatteryRepo interface {
+	SaveBatteryFlightMetrics(m model.BatteryFlightMetrics) error
+	GetBatteryFlightMetrics(uasID string, start, end time.Time) ([]model.BatteryFlightMetrics, error)
+	ListRecordsWithoutBatteryMetrics(uasID string, limit int) ([]model.FlightRecord, error)
+}
+
+// MaintenanceRepo 维保计划、维保记录与超期飞行标记
+type MaintenanceRepo interface {
+	ReplaceMaintenancePlan(uasModel string, items []model.MaintenancePlan) error
+	GetMaintenancePlans(uasModel string) ([]model.MaintenancePlan, error)
+	SaveMaintenanceRecord(r model.MaintenanceRecord) (int, error)
+	GetLatestMaintenanceRecords(uasID string, before time.Time) (map[string]model.MaintenanceRecord, error)
+	GetAllLatestMaintenanceRecords(before time.Time) (map[string]map[string]model.MaintenanceRecord, error)
+	FlagMaintenanceOverdue(orderID string, startTime time.Time, items string) error
+	CountMaintenanceOverdueFlights(uasID string, since time.Time) (int, error)
+}
+
+// AuditRepo 审计日志
+type AuditRepo interface {
+	SaveAuditLog(e model.AuditLog) error
+	GetAuditLogs(f AuditFilter) ([]model.AuditLog, error)
+	RevertFlightAudit(id int64, meta AuditMeta) (model.AuditLog, error)
+}
+
+// ReportRepo 定时报表计划与执行记录
+type ReportRepo interface {
+	CreateReportSchedule(s model.ReportSchedule) (int64, error)
+	UpdateReportSchedule(s model.ReportSchedule) error
+	SetReportScheduleRunTimes(id int64, lastRun, nextRun time.Time) error
+	DeleteReportSchedule(id int64) error
+	GetReportSchedule(id int64) (model.ReportSchedule, error)
+	ListReportSchedules(enabledOnly bool) ([]model.ReportSchedule, error)
+	CreateReportRun(r model.ReportRun) (int64, error)
+	UpdateReportRun(r model.ReportRun) error
+	GetReportRun(id int64) (model.ReportRun, error)
+	ListReportRuns(scheduleID int64, limit int) ([]model.ReportRun, error)
+	ListPendingReportRuns() ([]model.ReportRun, error)
+	ListExpiredReportRuns(defaultRetainDays int, now time.Time) ([]model.ReportRun, error)
+}
+
+// PayloadImportRepo 载货量批量导入：匹配候选记录并在一个事务内更新
+type PayloadImportRepo interface {
+	Location() *time.Location
+	FindPayloadCandidatesByOrderIDs(orderIDs []string) ([]PayloadCandidate, error)
+	FindPayloadCandidatesByUas(uasID string, from, to time.Time) ([]PayloadCandidate, error)
+	ApplyPayloadUpdates(updates []PayloadUpdate, meta AuditMeta) error
+}
+
+// StoreStatus 关系库连通性与本地重放队列
+type StoreStatus interface {
+	Ping(ctx context.Context) error
+	// Queue 本地重放队列，未启用时为 nil
+	Queue() *Queue
+}
+
+// TelemetrySource 原始遥测（drone_status）来源。返回的每行为按 _time 透视后的记录，
+// 包含 _time（time.Time）、tag 与字段，与 Influx 查询结果的 Values() 一致
+type TelemetrySource interface {
+	QueryFlightRecords(orderID string, start, end time.Time) ([]map[string]interface{}, error)
+	GetAllUasIDsAndFirstSeen() (map[string]time.Time, error)
+	GetFlightDate(start, end time.Time) ([]map[string]interface{}, error)
+}
+
+var (
+	_ FlightRecordRepo  = (*SQLDao)(nil)
+	_ TrackPointRepo    = (*SQLDao)(nil)
+	_ SortieRepo        = (*SQLDao)(nil)
+	_ ExportRepo        = (*SQLDao)(nil)
+	_ BatteryRepo       = (*SQLDao)(nil)
+	_ MaintenanceRepo   = (*SQLDao)(nil)
+	_ AuditRepo         = (*SQLDao)(nil)
+	_ ReportRepo        = (*SQLDao)(nil)
+	_ PayloadImportRepo = (*SQLDao)(nil)
+	_ StoreStatus       = (*SQLDao)(nil)
+	_ TelemetrySource   = (*InfluxDao)(nil)
+	_ TelemetrySource   = (*MemoryTelemetry)(nil)
+)
@@ -0,0 +1,125 @@
+package dao
+
+import (
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"drone-stats-service/internal/config"
+)
+
+var createTableRe = regexp.MustCompile(`CREATE TABLE IF NOT EXISTS (\w+) \(`)
+
+// mysqlColumns 解析 mysqlSchema 与 mysqlAddedColumns，返回各表的列及建表语句中的普通索引
+func mysqlColumns(t *testing.T) (map[string][]string, []string) {
+	t.Helper()
+	tables := map[string][]string{}
+	var indexes []string
+	for _, stmt := range mysqlSchema {
+		m := createTableRe.FindStringSubmatch(stmt)
+		if m == nil {
+			t.Fatalf("无法解析建表语句: %.60s", stmt)
+		}
+		body := stmt[len(m[0]):strings.LastIndex(stmt, ")")]
+		for _, line := range strings.Split(body, "\n") {
+			fields := strings.Fields(strings.TrimSuffix(strings.TrimSpace(line), ","))
+			if len(fields) == 0 {
+				continue
+			}
+			switch strings.ToUpper(fields[0]) {
+			case "KEY", "INDEX":
+				indexes = append(indexes, fields[1])
+			case "PRIMARY", "UNIQUE", "CONSTRAINT":
+			default:
+				tables[m[1]] = append(tables[m[1]], fields[0])
+			}
+		}
+	}
+	for _, c := range mysqlAddedColumns {
+		if !containsString(tables[c.table], c.column) {
+			tables[c.table] = append(tables[c.table], c.column)
+		}
+	}
+	for _, idx := range mysqlIndexes {
+		indexes = append(indexes, idx.name)
+	}
+	return tables, indexes
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// TestSchemaMatchesMySQL 校验 SQLite 建表结果与 MySQL 建表语句的表、列（不区分大小写）及普通索引一致
+func TestSchemaMatchesMySQL(t *testing.T) {
+	d, err := NewSQLiteDao(config.StorageConf{SQLitePath: ":memory:"}, config.MySQLConf{QueuePath: t.TempDir() + "/queue.jsonl"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, wantIndexes := mysqlColumns(t)
+
+	rows, err := d.DB.Query(`SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		tables = append(tables, name)
+	}
+	rows.Close()
+	var wantTables []string
+	for name := range want {
+		wantTables = append(wantTables, name)
+	}
+	sort.Strings(tables)
+	sort.Strings(wantTables)
+	if !reflect.DeepEqual(tables, wantTables) {
+		t.Fatalf("sqlite tables = %v, mysql tables = %v", tables, wantTables)
+	}
+
+	for _, table := range tables {
+		rows, err := d.DB.Query(`SELECT name FROM pragma_table_info(?)`, table)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, strings.ToLower(name))
+		}
+		rows.Close()
+		var cols []string
+		for _, c := range want[table] {
+			cols = append(cols, strings.ToLower(c))
+		}
+		sort.Strings(got)
+		sort.Strings(cols)
+		if !reflect.DeepEqual(got, cols) {
+			t.Errorf("%s: sqlite columns = %v, mysql columns = %v", table, got, cols)
+		}
+	}
+
+	var gotIndexes []string
+	for _, idx := range sqliteIndexes {
+		gotIndexes = append(gotIndexes, idx.name)
+	}
+	sort.Strings(gotIndexes)
+	sort.Strings(wantIndexes)
+	if !reflect.DeepEqual(gotIndexes, wantIndexes) {
+		t.Errorf("sqlite indexes = %v, mysql indexes = %v", gotIndexes, wantIndexes)
+	}
+}
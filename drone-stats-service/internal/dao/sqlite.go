@@ -0,0 +1,438 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"drone-stats-service/internal/config"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const (
+	defaultSQLitePath = "./data/drone_stats.db"
+	// sqliteTimeLayout DATETIME 在 SQLite 中以文本保存，与 MySQL DATETIME 一样不含时区
+	sqliteTimeLayout = "2006-01-02 15:04:05"
+)
+
+// memoryDBSeq 区分同一进程中的多个内存库
+var memoryDBSeq atomic.Int64
+
+// NewSQLiteDao 打开嵌入式 SQLite 数据库并创建缺失的表，供单机部署、演示与测试使用。
+// DATETIME 字段按 c.Timezone 读写（与 MySQL DSN 中的 loc 相同），created_at 默认值为 UTC 当前时间；
+// 重试与本地队列参数沿用 queue
+func NewSQLiteDao(c config.StorageConf, queue config.MySQLConf) (*SQLDao, error) {
+	loc := time.UTC
+	if c.Timezone != "" {
+		l, err := time.LoadLocation(c.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("SQLite 时区 %s 无效: %w", c.Timezone, err)
+		}
+		loc = l
+	}
+	path := c.SQLitePath
+	if path == "" {
+		path = defaultSQLitePath
+	}
+	params := url.Values{}
+	params.Add("_pragma", "busy_timeout(10000)")
+	// 写事务开始时即加写锁，避免先读后写的事务在并发写入时升级失败
+	params.Set("_txlock", "immediate")
+	var dsn string
+	if path == ":memory:" {
+		// 连接池中的各连接共享同一个内存库，最后一个连接关闭后数据丢失
+		params.Set("mode", "memory")
+		params.Set("cache", "shared")
+		dsn = fmt.Sprintf("file:drone_stats_%d?%s", memoryDBSeq.Add(1), params.Encode())
+	} else {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, err
+		}
+		params.Add("_pragma", "journal_mode(WAL)")
+		dsn = "file:" + path + "?" + params.Encode()
+	}
+
+	db := sql.OpenDB(&sqliteConnector{dsn: dsn, loc: loc, drv: &sqlite.Driver{}})
+	if path == ":memory:" {
+		// 内存库在所有连接关闭后释放，保持一个空闲连接
+		db.SetConnMaxIdleTime(0)
+		db.SetMaxIdleConns(2)
+	}
+	if err := migrateSQLite(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("初始化 SQLite 表失败: %w", err)
+	}
+	return newSQLDao(db, DialectSQLite, loc, queue)
+}
+
+// sqliteSchema 与 mysqlSchema 对应（含 mysqlAddedColumns 中后续补充的列），索引另见 sqliteIndexes
+var sqliteSchema = []string{
+	`CREATE TABLE IF NOT EXISTS flight_sorties (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        OrderID VARCHAR(128) NOT NULL UNIQUE,
+        register_time DATETIME,
+        model VARCHAR(64),
+        manufacturer VARCHAR(64)
+    )`,
+	`CREATE TABLE IF NOT EXISTS flight_records (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        OrderID VARCHAR(128) NOT NULL,
+        uasID VARCHAR(128) NOT NULL,
+        start_time DATETIME NOT NULL,
+        end_time DATETIME,
+        start_lat BIGINT,
+        start_lng BIGINT,
+        end_lat BIGINT,
+        end_lng BIGINT,
+        distance DOUBLE,
+        battery_used DOUBLE,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        payload INT NOT NULL DEFAULT 0,
+        expressCount INT NOT NULL DEFAULT 0,
+        energy_method VARCHAR(16),
+        maintenance_overdue TINYINT NOT NULL DEFAULT 0,
+        maintenance_overdue_items VARCHAR(255),
+        sort_end_time DATETIME,
+        duration_sec INT NOT NULL DEFAULT 0
+    )`,
+	`CREATE TABLE IF NOT EXISTS flight_track_points (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        OrderID VARCHAR(128) NOT NULL,
+        flightStatus VARCHAR(16),
+        timeStamp DATETIME,
+        longitude BIGINT,
+        latitude BIGINT,
+        heightType INT,
+        height INT,
+        altitude INT,
+        VS INT,
+        GS INT,
+        course INT,
+        SOC INT,
+        RM INT,
+        voltage INT,
+        current INT,
+        windSpeed INT,
+        windDirect INT,
+        temperture INT,
+        humidity INT
+    )`,
+	`CREATE TABLE IF NOT EXISTS flight_battery_metrics (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        OrderID VARCHAR(128) NOT NULL,
+        uasID VARCHAR(128) NOT NULL,
+        start_time DATETIME NOT NULL,
+        soc_start INT NOT NULL DEFAULT 0,
+        soc_end INT NOT NULL DEFAULT 0,
+        soc_used DOUBLE NOT NULL DEFAULT 0,
+        capacity_used_ah DOUBLE NOT NULL DEFAULT 0,
+        capacity_method VARCHAR(16) NOT NULL DEFAULT 'none',
+        effective_capacity_ah DOUBLE NOT NULL DEFAULT 0,
+        resistance_mohm DOUBLE NOT NULL DEFAULT 0,
+        min_voltage INT NOT NULL DEFAULT 0,
+        max_current INT NOT NULL DEFAULT 0,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        UNIQUE (OrderID, start_time)
+    )`,
+	`CREATE TABLE IF NOT EXISTS flight_track_cleaning (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        OrderID VARCHAR(128) NOT NULL,
+        uasID VARCHAR(128) NOT NULL,
+        start_time DATETIME NOT NULL,
+        points_total INT NOT NULL DEFAULT 0,
+        points_kept INT NOT NULL DEFAULT 0,
+        dropped_zero INT NOT NULL DEFAULT 0,
+        dropped_time INT NOT NULL DEFAULT 0,
+        dropped_speed INT NOT NULL DEFAULT 0,
+        dropped_accel INT NOT NULL DEFAULT 0,
+        dropped_frozen INT NOT NULL DEFAULT 0,
+        gap_count INT NOT NULL DEFAULT 0,
+        gap_seconds DOUBLE NOT NULL DEFAULT 0,
+        gaps TEXT,
+        smoothing VARCHAR(16) NOT NULL DEFAULT 'none',
+        raw_distance DOUBLE NOT NULL DEFAULT 0,
+        clean_distance DOUBLE NOT NULL DEFAULT 0,
+        raw_energy_kwh DOUBLE NOT NULL DEFAULT 0,
+        clean_energy_kwh DOUBLE NOT NULL DEFAULT 0,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        UNIQUE (OrderID, start_time)
+    )`,
+	`CREATE TABLE IF NOT EXISTS maintenance_plans (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        model VARCHAR(64) NOT NULL,
+        item VARCHAR(64) NOT NULL,
+        interval_hours DOUBLE NOT NULL DEFAULT 0,
+        interval_cycles INT NOT NULL DEFAULT 0,
+        description VARCHAR(255),
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        UNIQUE (model, item)
+    )`,
+	`CREATE TABLE IF NOT EXISTS maintenance_records (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        uasID VARCHAR(128) NOT NULL,
+        item VARCHAR(64) NOT NULL,
+        performed_at DATETIME NOT NULL,
+        flight_hours DOUBLE NOT NULL DEFAULT 0,
+        cycles INT NOT NULL DEFAULT 0,
+        technician VARCHAR(64),
+        note VARCHAR(512),
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    )`,
+	`CREATE TABLE IF NOT EXISTS export_downloads (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        task_id VARCHAR(64) NOT NULL,
+        file_name VARCHAR(255) NOT NULL,
+        user VARCHAR(128) NOT NULL,
+        remote_addr VARCHAR(64) NOT NULL,
+        user_agent VARCHAR(255),
+        range_header VARCHAR(128),
+        status INT NOT NULL,
+        bytes_sent BIGINT NOT NULL DEFAULT 0,
+        downloaded_at DATETIME NOT NULL
+    )`,
+	`CREATE TABLE IF NOT EXISTS audit_logs (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        entity VARCHAR(32) NOT NULL,
+        entity_id VARCHAR(64) NOT NULL,
+        order_id VARCHAR(64),
+        field VARCHAR(64) NOT NULL,
+        old_value TEXT,
+        new_value TEXT,
+        actor VARCHAR(128) NOT NULL,
+        source VARCHAR(16) NOT NULL,
+        batch_id VARCHAR(64),
+        revert_of BIGINT,
+        reverted_by BIGINT,
+        created_at DATETIME NOT NULL
+    )`,
+	`CREATE TABLE IF NOT EXISTS report_schedules (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        name VARCHAR(128) NOT NULL,
+        cron VARCHAR(64) NOT NULL,
+        target VARCHAR(16) NOT NULL,
+        format VARCHAR(16) NOT NULL,
+        partition_by VARCHAR(16) NOT NULL DEFAULT '',
+        uasID VARCHAR(128),
+        range_hours INT NOT NULL DEFAULT 24,
+        delivery VARCHAR(16) NOT NULL,
+        delivery_to VARCHAR(1024) NOT NULL DEFAULT '',
+        retain_days INT NOT NULL DEFAULT 0,
+        enabled TINYINT NOT NULL DEFAULT 1,
+        last_run_at DATETIME NULL,
+        next_run_at DATETIME NULL,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    )`,
+	`CREATE TABLE IF NOT EXISTS report_runs (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        schedule_id BIGINT NOT NULL,
+        task_id VARCHAR(64),
+        trigger_type VARCHAR(16) NOT NULL,
+        period_start DATETIME NOT NULL,
+        period_end DATETIME NOT NULL,
+        status VARCHAR(16) NOT NULL,
+        file_path VARCHAR(512),
+        file_size BIGINT NOT NULL DEFAULT 0,
+        error VARCHAR(1024),
+        started_at DATETIME NOT NULL,
+        finished_at DATETIME NULL
+    )`,
+}
+
+var sqliteIndexes = []struct{ table, name, cols string }{
+	{"flight_battery_metrics", "idx_battery_uas_start", "uasID, start_time"},
+	{"maintenance_records", "idx_maint_uas_item", "uasID, item, performed_at"},
+	{"export_downloads", "idx_download_task", "task_id, downloaded_at"},
+	{"export_downloads", "idx_download_user", "user, downloaded_at"},
+	{"audit_logs", "idx_audit_entity", "entity, entity_id, created_at"},
+	{"audit_logs", "idx_audit_order", "order_id, created_at"},
+	{"audit_logs", "idx_audit_actor", "actor, created_at"},
+	{"audit_logs", "idx_audit_batch", "batch_id"},
+	{"report_runs", "idx_report_runs_schedule", "schedule_id, started_at"},
+	{"report_runs", "idx_report_runs_status", "status"},
+	{"flight_records", "idx_records_start_time", "start_time, id"},
+	{"flight_records", "idx_records_uas_start", "uasID, start_time"},
+	{"flight_records", "idx_records_order", "OrderID"},
+	{"flight_records", "idx_records_end_time", "sort_end_time, id"},
+	{"flight_records", "idx_records_duration", "duration_sec, id"},
+	{"flight_track_points", "idx_points_order_time", "orderID, timeStamp"},
+}
+
+func migrateSQLite(db *sql.DB) error {
+	for _, stmt := range sqliteSchema {
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	for _, idx := range sqliteIndexes {
+		if _, err := db.Exec(fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (%s)", idx.name, idx.table, idx.cols)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isRejectedBySQLite 判断写入是否被 SQLite 拒绝（约束、类型或语句问题，重试也不会成功）；
+// 忙、锁、磁盘等错误视为临时错误
+func isRejectedBySQLite(err error) bool {
+	var se *sqlite.Error
+	if !errors.As(err, &se) {
+		return false
+	}
+	switch se.Code() & 0xff {
+	case sqlite3.SQLITE_ERROR, sqlite3.SQLITE_TOOBIG, sqlite3.SQLITE_CONSTRAINT, sqlite3.SQLITE_MISMATCH, sqlite3.SQLITE_RANGE:
+		return true
+	}
+	return false
+}
+
+// nullDateTime 扫描可能为 NULL 的时间。SQLite 中 MIN/MAX 等表达式没有声明类型，结果为文本，按 loc 解析
+type nullDateTime struct {
+	loc   *time.Location
+	Time  time.Time
+	Valid bool
+}
+
+func (n *nullDateTime) Scan(v interface{}) error {
+	n.Time, n.Valid = time.Time{}, false
+	switch t := v.(type) {
+	case nil:
+		return nil
+	case time.Time:
+		n.Time = t
+	case string:
+		return n.parse(t)
+	case []byte:
+		return n.parse(string(t))
+	default:
+		return fmt.Errorf("无法将 %T 转换为时间", v)
+	}
+	n.Valid = true
+	return nil
+}
+
+func (n *nullDateTime) parse(s string) error {
+	t, err := time.ParseInLocation(sqliteTimeLayout, s, n.loc)
+	if err != nil {
+		return err
+	}
+	n.Time, n.Valid = t, true
+	return nil
+}
+
+// sqliteConnector 包装 SQLite 驱动，使时间的读写与 MySQL（parseTime=true&loc=...）一致：
+// 写入时转换到 loc 并以不含时区的文本保存，读出 DATETIME/TIMESTAMP 列时按 loc 解释
+type sqliteConnector struct {
+	dsn string
+	loc *time.Location
+	drv *sqlite.Driver
+}
+
+func (c *sqliteConnector) Connect(context.Context) (driver.Conn, error) {
+	conn, err := c.drv.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &sqliteConn{Conn: conn, loc: c.loc}, nil
+}
+
+func (c *sqliteConnector) Driver() driver.Driver {
+	return c.drv
+}
+
+// sqliteConn 实现 database/sql 使用的各可选接口，转发给 SQLite 连接
+type sqliteConn struct {
+	driver.Conn
+	loc *time.Location
+}
+
+func (c *sqliteConn) CheckNamedValue(nv *driver.NamedValue) error {
+	v, err := driver.DefaultParameterConverter.ConvertValue(nv.Value)
+	if err != nil {
+		return err
+	}
+	if t, ok := v.(time.Time); ok {
+		v = t.In(c.loc).Format(sqliteTimeLayout)
+	}
+	nv.Value = v
+	return nil
+}
+
+func (c *sqliteConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return c.Conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+}
+
+func (c *sqliteConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	st, err := c.Conn.(driver.ConnPrepareContext).PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &sqliteStmt{Stmt: st, loc: c.loc}, nil
+}
+
+func (c *sqliteConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.Conn.(driver.ExecerContext).ExecContext(ctx, query, args)
+}
+
+func (c *sqliteConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	rows, err := c.Conn.(driver.QueryerContext).QueryContext(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &sqliteRows{Rows: rows, loc: c.loc}, nil
+}
+
+func (c *sqliteConn) Ping(ctx context.Context) error {
+	return c.Conn.(driver.Pinger).Ping(ctx)
+}
+
+func (c *sqliteConn) ResetSession(ctx context.Context) error {
+	return c.Conn.(driver.SessionResetter).ResetSession(ctx)
+}
+
+func (c *sqliteConn) IsValid() bool {
+	return c.Conn.(driver.Validator).IsValid()
+}
+
+type sqliteStmt struct {
+	driver.Stmt
+	loc *time.Location
+}
+
+func (s *sqliteStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.Stmt.(driver.StmtExecContext).ExecContext(ctx, args)
+}
+
+func (s *sqliteStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	rows, err := s.Stmt.(driver.StmtQueryContext).QueryContext(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	return &sqliteRows{Rows: rows, loc: s.loc}, nil
+}
+
+type sqliteRows struct {
+	driver.Rows
+	loc *time.Location
+}
+
+// Next 驱动将不含时区的时间文本解析为 UTC，这里改为 loc 中的同一墙上时间
+func (r *sqliteRows) Next(dest []driver.Value) error {
+	if err := r.Rows.Next(dest); err != nil {
+		return err
+	}
+	for i, v := range dest {
+		if t, ok := v.(time.Time); ok {
+			dest[i] = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), r.loc)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package dao
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+
+	"drone-stats-service/internal/config"
+	"drone-stats-service/internal/model"
+)
+
+var daoBase = time.Date(2025, 6, 20, 8, 0, 0, 0, time.UTC)
+
+// newTestDao 内存 SQLite，记录 id 按插入顺序从 1 开始
+func newTestDao(t *testing.T) *SQLDao {
+	t.Helper()
+	d, err := NewSQLiteDao(config.StorageConf{SQLitePath: ":memory:"}, config.MySQLConf{QueuePath: t.TempDir() + "/queue.jsonl"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestReportScheduleCRUD(t *testing.T) {
+	d := newTestDao(t)
+	next := daoBase.Add(time.Hour)
+	s := model.ReportSchedule{
+		Name: "日报", Cron: "0 8 * * *", Target: "records", Format: "csv", RangeHours: 24,
+		Delivery: "local", DeliveryTo: "daily", Enabled: true, NextRunAt: next,
+	}
+	id, err := d.CreateReportSchedule(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.CreateReportSchedule(model.ReportSchedule{Name: "停用", Cron: "0 0 * * 1", Target: "records", Format: "csv", Delivery: "local"}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := d.GetReportSchedule(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != s.Name || got.Cron != s.Cron || got.RangeHours != 24 || !got.Enabled || !got.NextRunAt.Equal(next) || !got.LastRunAt.IsZero() {
+		t.Errorf("schedule = %+v", got)
+	}
+
+	got.Enabled = false
+	got.DeliveryTo = "weekly"
+	if err := d.UpdateReportSchedule(got); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetReportScheduleRunTimes(id, next, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = d.GetReportSchedule(id); err != nil {
+		t.Fatal(err)
+	}
+	if got.Enabled || got.DeliveryTo != "weekly" || !got.LastRunAt.Equal(next) || !got.NextRunAt.IsZero() {
+		t.Errorf("updated schedule = %+v", got)
+	}
+
+	all, err := d.ListReportSchedules(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enabled, err := d.ListReportSchedules(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 || len(enabled) != 0 {
+		t.Errorf("all = %d, enabled = %d, want 2, 0", len(all), len(enabled))
+	}
+
+	if err := d.DeleteReportSchedule(id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.GetReportSchedule(id); err != sql.ErrNoRows {
+		t.Errorf("get deleted schedule err = %v, want sql.ErrNoRows", err)
+	}
+}
+
+// auditFields 按时间顺序返回审计条目的 字段:旧值->新值
+func auditFields(t *testing.T, d *SQLDao, f AuditFilter) []string {
+	t.Helper()
+	logs, err := d.GetAuditLogs(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []string
+	for i := len(logs) - 1; i >= 0; i-- {
+		out = append(out, logs[i].Field+":"+logs[i].OldValue+"->"+logs[i].NewValue)
+	}
+	return out
+}
+
+func TestFlagMaintenanceOverdue(t *testing.T) {
+	d := newTestDao(t)
+	if err := d.SaveFlightRecord("O-1", "U1", daoBase, daoBase.Add(20*time.Minute), 0, 0, 0, 0, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	// 重复标记相同项目不再写审计日志，不存在的架次忽略
+	for _, items := range []string{"桨叶", "桨叶", "桨叶,电机"} {
+		if err := d.FlagMaintenanceOverdue("O-1", daoBase, items); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.FlagMaintenanceOverdue("O-9", daoBase, "桨叶"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"maintenance_overdue_items:->桨叶", "maintenance_overdue_items:桨叶->桨叶,电机"}
+	if got := auditFields(t, d, AuditFilter{Source: model.AuditSourceSystem, Limit: 10}); !reflect.DeepEqual(got, want) {
+		t.Errorf("audit = %v, want %v", got, want)
+	}
+	n, err := d.CountMaintenanceOverdueFlights("U1", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("overdue flights = %d, want 1", n)
+	}
+}
+
+func TestRevertFlightAudit(t *testing.T) {
+	d := newTestDao(t)
+	if err := d.SaveFlightRecord("O-1", "U1", daoBase, daoBase.Add(20*time.Minute), 0, 0, 0, 0, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	meta := AuditMeta{Actor: "tester", Source: model.AuditSourceAPI}
+	if err := d.UpdateFlightPayload("O-1", 50, 3, meta); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.UpdateFlightPayload("O-1", 60, 3, meta); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"payload:0->50", "expressCount:0->3", "payload:50->60"}
+	if got := auditFields(t, d, AuditFilter{OrderID: "O-1", Limit: 10}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("audit = %v, want %v", got, want)
+	}
+	// 倒序：logs[0] 为 50->60，logs[2] 为 0->50
+	logs, err := d.GetAuditLogs(AuditFilter{OrderID: "O-1", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := logs[2]
+
+	// 之后又被修改过的条目不能撤销
+	if _, err := d.RevertFlightAudit(first.ID, meta); err != ErrAuditStale {
+		t.Errorf("revert stale err = %v, want ErrAuditStale", err)
+	}
+	e, err := d.RevertFlightAudit(logs[0].ID, meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Source != model.AuditSourceRevert || e.RevertOf != logs[0].ID || e.OldValue != "60" || e.NewValue != "50" {
+		t.Errorf("revert entry = %+v", e)
+	}
+	if _, err := d.RevertFlightAudit(logs[0].ID, meta); err != ErrAuditReverted {
+		t.Errorf("revert twice err = %v, want ErrAuditReverted", err)
+	}
+	var payload int
+	if err := d.DB.QueryRow(`SELECT payload FROM flight_records WHERE OrderID = 'O-1'`).Scan(&payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload != 50 {
+		t.Errorf("payload = %d, want 50", payload)
+	}
+}
+
+func TestMemoryTelemetryQueryFlightRecords(t *testing.T) {
+	m := NewMemoryTelemetry()
+	for i := 0; i < 4; i++ {
+		ts := daoBase.Add(time.Duration(i) * time.Second)
+		m.Write(telemetryMeasurement, map[string]string{"orderID": "O-1"}, map[string]interface{}{"SOC": int64(90 - i)}, ts)
+		m.Write(telemetryMeasurement, map[string]string{"orderID": "O-2"}, map[string]interface{}{"SOC": int64(50)}, ts)
+	}
+	// 同一时间戳的字段合并为一行，其他 measurement 不返回
+	m.Write(telemetryMeasurement, map[string]string{"orderID": "O-1"}, map[string]interface{}{"flightStatus": "TakeOff"}, daoBase.Add(time.Second))
+	m.Write("other", map[string]string{"orderID": "O-1"}, map[string]interface{}{"SOC": int64(1)}, daoBase.Add(time.Second))
+
+	rows, err := m.QueryFlightRecords("O-1", daoBase.Add(time.Second), daoBase.Add(3*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("rows = %d, want 2: %v", len(rows), rows)
+	}
+	for i, want := range []int64{89, 88} {
+		if rows[i]["SOC"] != want || !rows[i]["_time"].(time.Time).Equal(daoBase.Add(time.Duration(i+1)*time.Second)) {
+			t.Errorf("row %d = %v", i, rows[i])
+		}
+	}
+	if rows[0]["flightStatus"] != "TakeOff" || rows[0]["orderID"] != "O-1" {
+		t.Errorf("row 0 = %v, want merged fields and tags", rows[0])
+	}
+}
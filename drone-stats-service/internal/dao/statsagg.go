@@ -0,0 +1,153 @@
+package dao
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// 统计分组维度
+const (
+	StatsGroupNone         = ""
+	StatsGroupUasID        = "uasID"
+	StatsGroupModel        = "model"
+	StatsGroupManufacturer = "manufacturer"
+)
+
+// StatsSlotMinutes 预聚合槽宽（分钟）。所有时区偏移均为 15 分钟的整数倍，
+// 因此按 15 分钟预聚合后可以在任意时区下无损地重新分桶。
+const StatsSlotMinutes = 15
+
+// StatsSlot 单个 15 分钟槽、单个分组内的原始累计值
+type StatsSlot struct {
+	Slot         time.Time // 槽起点
+	Group        string
+	Count        int     // 架次数
+	Finished     int     // 有降落时间的架次数
+	Distance     float64 // 米
+	Duration     float64 // 秒
+	Battery      float64 // kWh
+	BatteryCount int     // battery_used 非空的架次数
+	Payload      float64 // 千克
+	PayloadCount int     // 已录入载货量的架次数
+	Express      int     // 票数
+	GSSum        float64 // 轨迹点地速之和（m/s）
+	GSCount      int     // 轨迹点数
+}
+
+// StatsAggQuery 预聚合查询条件，时间按 start_time 过滤：[Start, End)
+type StatsAggQuery struct {
+	Start     time.Time
+	End       time.Time
+	GroupBy   string
+	IncludeGS bool // 是否关联轨迹点计算地速（开销较大，仅平均速度需要）
+}
+
+// Location 返回 MySQL 连接使用的时区（DSN 中的 loc），DATETIME 字段均按该时区存储
+func (d *SQLDao) Location() *time.Location {
+	if d.loc == nil {
+		return time.Local
+	}
+	return d.loc
+}
+
+// AggregateFlightRecordSlots 以 15 分钟为槽、按分组维度预聚合 flight_records，
+// 由调用方根据时区与粒度重新分桶。
+func (d *SQLDao) AggregateFlightRecordSlots(q StatsAggQuery) ([]StatsSlot, error) {
+	var groupExpr, join string
+	switch q.GroupBy {
+	case StatsGroupNone:
+		groupExpr = "''"
+	case StatsGroupUasID:
+		groupExpr = "r.uasID"
+	case StatsGroupModel:
+		groupExpr = "IFNULL(s.model, '')"
+		join = " LEFT JOIN flight_sorties s ON s.OrderID = r.OrderID"
+	case StatsGroupManufacturer:
+		groupExpr = "IFNULL(s.manufacturer, '')"
+		join = " LEFT JOIN flight_sorties s ON s.OrderID = r.OrderID"
+	default:
+		return nil, fmt.Errorf("unsupported groupBy: %s", q.GroupBy)
+	}
+
+	rangeWhere := func(alias string) (string, []interface{}) {
+		where := " WHERE 1=1"
+		var args []interface{}
+		if !q.Start.IsZero() {
+			where += " AND " + alias + ".start_time >= ?"
+			args = append(args, q.Start)
+		}
+		if !q.End.IsZero() {
+			where += " AND " + alias + ".start_time < ?"
+			args = append(args, q.End)
+		}
+		return where, args
+	}
+	where, args := rangeWhere("fr")
+
+	// 地速按架次先在轨迹点表中聚合一次再关联，只聚合区间内架次的轨迹点
+	gsCols, gsJoin := ", 0 AS gs_sum, 0 AS gs_cnt", ""
+	if q.IncludeGS {
+		gsWhere, gsArgs := rangeWhere("fr2")
+		gsCols = ", IFNULL(g.gs_sum, 0) AS gs_sum, IFNULL(g.gs_cnt, 0) AS gs_cnt"
+		gsJoin = `
+            LEFT JOIN (
+                SELECT p.orderID, SUM(p.GS) / 10.0 AS gs_sum, COUNT(p.GS) AS gs_cnt
+                FROM flight_track_points p
+                WHERE p.orderID IN (SELECT fr2.OrderID FROM flight_records fr2` + gsWhere + `)
+                GROUP BY p.orderID
+            ) g ON g.orderID = fr.OrderID`
+		args = append(gsArgs, args...)
+	}
+
+	query := fmt.Sprintf(`
+        SELECT
+            %s AS slot,
+            %s AS grp,
+            COUNT(*),
+            SUM(r.end_time IS NOT NULL),
+            SUM(IFNULL(r.distance, 0)),
+            SUM(IFNULL(%s, 0)),
+            SUM(IFNULL(r.battery_used, 0)),
+            SUM(r.battery_used IS NOT NULL),
+            SUM(IFNULL(r.payload, 0)) / 10.0,
+            SUM(r.payload > 0),
+            SUM(IFNULL(r.expressCount, 0)),
+            SUM(r.gs_sum),
+            SUM(r.gs_cnt)
+        FROM (
+            SELECT fr.OrderID, fr.uasID, fr.start_time, fr.end_time, fr.distance, fr.battery_used, fr.payload, fr.expressCount%s
+            FROM flight_records fr%s%s
+        ) r%s
+        GROUP BY slot, grp
+        ORDER BY slot`, d.minuteSlot("r.start_time", StatsSlotMinutes), groupExpr,
+		d.greatest(d.secondsBetween("r.start_time", "r.end_time"), "0"), gsCols, gsJoin, where, join)
+
+	rows, err := d.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	loc := d.Location()
+	var slots []StatsSlot
+	for rows.Next() {
+		var (
+			slotStr string
+			s       StatsSlot
+			gsSum   sql.NullFloat64
+			gsCnt   sql.NullInt64
+		)
+		if err := rows.Scan(&slotStr, &s.Group, &s.Count, &s.Finished, &s.Distance, &s.Duration, &s.Battery, &s.BatteryCount, &s.Payload, &s.PayloadCount, &s.Express, &gsSum, &gsCnt); err != nil {
+			return nil, err
+		}
+		t, err := time.ParseInLocation("2006-01-02 15:04", slotStr, loc)
+		if err != nil {
+			return nil, err
+		}
+		s.Slot = t
+		s.GSSum = gsSum.Float64
+		s.GSCount = int(gsCnt.Int64)
+		slots = append(slots, s)
+	}
+	return slots, rows.Err()
+}
@@ -0,0 +1,40 @@
+package dao
+
+import (
+	"drone-stats-service/internal/model"
+)
+
+// SaveTrackCleaning 写入单架次轨迹清洗统计，同一架次（OrderID + start_time）重复写入时覆盖
+func (d *SQLDao) SaveTrackCleaning(c model.TrackCleaning) error {
+	_, err := d.DB.Exec(`INSERT INTO flight_track_cleaning
+		(OrderID, uasID, start_time, points_total, points_kept, dropped_zero, dropped_time, dropped_speed, dropped_accel, dropped_frozen,
+		 gap_count, gap_seconds, gaps, smoothing, raw_distance, clean_distance, raw_energy_kwh, clean_energy_kwh)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`+
+		d.onDuplicateUpdate("OrderID, start_time", "uasID", "points_total", "points_kept",
+			"dropped_zero", "dropped_time", "dropped_speed", "dropped_accel", "dropped_frozen",
+			"gap_count", "gap_seconds", "gaps", "smoothing", "raw_distance", "clean_distance", "raw_energy_kwh", "clean_energy_kwh"),
+		c.OrderID, c.UasID, c.StartTime, c.PointsTotal, c.PointsKept, c.DroppedZero, c.DroppedTime, c.DroppedSpeed, c.DroppedAccel, c.DroppedFrozen,
+		c.GapCount, c.GapSeconds, c.Gaps, c.Smoothing, c.RawDistance, c.CleanDistance, c.RawEnergyKWh, c.CleanEnergyKWh)
+	return err
+}
+
+// GetTrackCleaning 查询某 OrderID 各架次的轨迹清洗统计，按起飞时间升序
+func (d *SQLDao) GetTrackCleaning(orderID string) ([]model.TrackCleaning, error) {
+	rows, err := d.DB.Query(`SELECT id, OrderID, uasID, start_time, points_total, points_kept, dropped_zero, dropped_time, dropped_speed, dropped_accel, dropped_frozen,
+		gap_count, gap_seconds, IFNULL(gaps, '[]'), smoothing, raw_distance, clean_distance, raw_energy_kwh, clean_energy_kwh
+		FROM flight_track_cleaning WHERE OrderID = ? ORDER BY start_time ASC`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []model.TrackCleaning
+	for rows.Next() {
+		var c model.TrackCleaning
+		if err := rows.Scan(&c.ID, &c.OrderID, &c.UasID, &c.StartTime, &c.PointsTotal, &c.PointsKept, &c.DroppedZero, &c.DroppedTime, &c.DroppedSpeed, &c.DroppedAccel, &c.DroppedFrozen,
+			&c.GapCount, &c.GapSeconds, &c.Gaps, &c.Smoothing, &c.RawDistance, &c.CleanDistance, &c.RawEnergyKWh, &c.CleanEnergyKWh); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
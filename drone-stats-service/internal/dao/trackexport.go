@@ -0,0 +1,112 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"drone-stats-service/internal/model"
+)
+
+// utcWallClock 轨迹点 timeStamp 以 UTC 墙上时间写入，按 DSN 时区读出后需还原为 UTC 时刻
+func utcWallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+// trackExportFilter 构造导出轨迹点的 WHERE 条件，选取规则与 ExportTrackPointsToCSVStream 一致：
+// 指定 orderID 时导出该 OrderID；否则按起降时间（及 uasID）从 flight_records 选出 OrderID；均未指定时导出全部。
+// 返回 ok=false 表示没有匹配的架次。
+func (d *SQLDao) trackExportFilter(ctx context.Context, orderID, uasID string, start, end time.Time) (where string, args []interface{}, ok bool, err error) {
+	if orderID != "" {
+		return " WHERE orderID = ?", []interface{}{orderID}, true, nil
+	}
+	if start.IsZero() && end.IsZero() {
+		return "", nil, true, nil
+	}
+	recQuery := `SELECT DISTINCT OrderID FROM flight_records WHERE 1=1`
+	recArgs := []interface{}{}
+	if !start.IsZero() {
+		recQuery += " AND start_time >= ?"
+		recArgs = append(recArgs, start)
+	}
+	if !end.IsZero() {
+		recQuery += " AND end_time <= ?"
+		recArgs = append(recArgs, end)
+	}
+	if uasID != "" {
+		recQuery += " AND uasID = ?"
+		recArgs = append(recArgs, uasID)
+	}
+	rows, err := d.DB.QueryContext(ctx, recQuery, recArgs...)
+	if err != nil {
+		return "", nil, false, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var oid string
+		if err := rows.Scan(&oid); err != nil {
+			return "", nil, false, err
+		}
+		args = append(args, oid)
+	}
+	if err := rows.Err(); err != nil {
+		return "", nil, false, err
+	}
+	if len(args) == 0 {
+		return "", nil, false, nil
+	}
+	return " WHERE orderID IN (?" + strings.Repeat(", ?", len(args)-1) + ")", args, true, nil
+}
+
+// ForEachTrackPoint 按 OrderID、时间升序逐点回调导出范围内的轨迹点，避免一次性载入内存。
+// 选取规则见 trackExportFilter，ctx 取消时查询中止并返回 ctx 错误。
+func (d *SQLDao) ForEachTrackPoint(ctx context.Context, orderID, uasID string, start, end time.Time, fn func(model.FlightTrackPoint) error) error {
+	where, args, ok, err := d.trackExportFilter(ctx, orderID, uasID, start, end)
+	if err != nil || !ok {
+		return err
+	}
+	query := `SELECT id, orderID, flightStatus, timeStamp, longitude, latitude, heightType, height, altitude, VS, GS, course, SOC, RM, voltage, current, windSpeed, windDirect, temperture, humidity FROM flight_track_points` +
+		where + " ORDER BY orderID, timeStamp ASC, id ASC"
+
+	rows, err := d.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p model.FlightTrackPoint
+		if err := rows.Scan(&p.ID, &p.OrderID, &p.FlightStatus, &p.TimeStamp, &p.Longitude, &p.Latitude, &p.HeightType, &p.Height, &p.Altitude, &p.VS, &p.GS, &p.Course, &p.SOC, &p.RM, &p.Voltage, &p.Current, &p.WindSpeed, &p.WindDirect, &p.Temperture, &p.Humidity); err != nil {
+			return err
+		}
+		p.TimeStamp = utcWallClock(p.TimeStamp)
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// CountTrackPoints 统计 ForEachTrackPoint 将导出的轨迹点数，用于估算导出进度
+func (d *SQLDao) CountTrackPoints(ctx context.Context, orderID, uasID string, start, end time.Time) (int64, error) {
+	where, args, ok, err := d.trackExportFilter(ctx, orderID, uasID, start, end)
+	if err != nil || !ok {
+		return 0, err
+	}
+	var n int64
+	err = d.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM flight_track_points`+where, args...).Scan(&n)
+	return n, err
+}
+
+// GetUasIDByOrderID 查询 OrderID 对应的无人机编号，无记录时返回空串
+func (d *SQLDao) GetUasIDByOrderID(orderID string) (string, error) {
+	var uasID sql.NullString
+	err := d.DB.QueryRow(`SELECT uasID FROM flight_records WHERE OrderID = ? ORDER BY start_time DESC LIMIT 1`, orderID).Scan(&uasID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return uasID.String, nil
+}
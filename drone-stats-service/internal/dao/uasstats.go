@@ -0,0 +1,110 @@
+package dao
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// UasFlightTotals 单架无人机在统计区间内的飞行汇总
+type UasFlightTotals struct {
+	UasID          string
+	Flights        int
+	FlightSeconds  float64
+	Distance       float64 // 米
+	Battery        float64 // kWh
+	Payload        float64 // 千克
+	PayloadBattery float64 // 已录入载货量的架次的耗电之和（kWh），用于计算每千克耗电
+	ExpressCount   int
+	FirstStart     time.Time
+	LastEnd        time.Time
+}
+
+// uasFilter 构造 uasID IN (...) 与起飞时间区间条件，列名带 r. 前缀
+func uasFilter(uasIDs []string, start, end time.Time) (string, []interface{}) {
+	placeholders := make([]string, len(uasIDs))
+	args := make([]interface{}, 0, len(uasIDs)+2)
+	for i, id := range uasIDs {
+		placeholders[i] = "?"
+		args = append(args, id)
+	}
+	where := fmt.Sprintf(" WHERE r.uasID IN (%s)", strings.Join(placeholders, ","))
+	if !start.IsZero() {
+		where += " AND r.start_time >= ?"
+		args = append(args, start)
+	}
+	if !end.IsZero() {
+		where += " AND r.start_time < ?"
+		args = append(args, end)
+	}
+	return where, args
+}
+
+// GetUasFlightTotals 按 uasID 汇总 flight_records，返回 uasID -> 汇总（无记录的 uasID 不出现在结果中）
+func (d *SQLDao) GetUasFlightTotals(uasIDs []string, start, end time.Time) (map[string]*UasFlightTotals, error) {
+	out := make(map[string]*UasFlightTotals)
+	if len(uasIDs) == 0 {
+		return out, nil
+	}
+	where, args := uasFilter(uasIDs, start, end)
+	rows, err := d.DB.Query(`
+        SELECT
+            r.uasID,
+            COUNT(*),
+            SUM(IFNULL(`+d.greatest(d.secondsBetween("r.start_time", "r.end_time"), "0")+`, 0)),
+            SUM(IFNULL(r.distance, 0)),
+            SUM(IFNULL(r.battery_used, 0)),
+            SUM(r.payload) / 10.0,
+            SUM(CASE WHEN r.payload > 0 THEN IFNULL(r.battery_used, 0) ELSE 0 END),
+            SUM(r.expressCount),
+            MIN(r.start_time),
+            MAX(r.end_time)
+        FROM flight_records r`+where+`
+        GROUP BY r.uasID`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			t                  UasFlightTotals
+			firstStart, lastEd = nullDateTime{loc: d.loc}, nullDateTime{loc: d.loc}
+		)
+		if err := rows.Scan(&t.UasID, &t.Flights, &t.FlightSeconds, &t.Distance, &t.Battery, &t.Payload, &t.PayloadBattery, &t.ExpressCount, &firstStart, &lastEd); err != nil {
+			return nil, err
+		}
+		t.FirstStart = firstStart.Time
+		t.LastEnd = lastEd.Time
+		out[t.UasID] = &t
+	}
+	return out, rows.Err()
+}
+
+// GetUasSOCDrops 返回每个 uasID 各架次的 SOC 降幅（百分点），SOC 为 0 的轨迹点视为无数据
+func (d *SQLDao) GetUasSOCDrops(uasIDs []string, start, end time.Time) (map[string][]float64, error) {
+	out := make(map[string][]float64)
+	if len(uasIDs) == 0 {
+		return out, nil
+	}
+	where, args := uasFilter(uasIDs, start, end)
+	rows, err := d.DB.Query(`
+        SELECT r.uasID, MAX(p.SOC) - MIN(p.SOC)
+        FROM flight_records r
+        JOIN flight_track_points p ON p.orderID = r.OrderID`+where+` AND p.SOC > 0
+        GROUP BY r.id, r.uasID`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			uasID string
+			drop  float64
+		)
+		if err := rows.Scan(&uasID, &drop); err != nil {
+			return nil, err
+		}
+		out[uasID] = append(out[uasID], drop)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,121 @@
+package dao
+
+import (
+	"math"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"drone-stats-service/internal/model"
+)
+
+// socTrack 架次的轨迹点，每 10 秒一个点，依次使用给定的 SOC
+func socTrack(orderID string, start time.Time, socs ...int) []model.FlightTrackPoint {
+	points := make([]model.FlightTrackPoint, len(socs))
+	for i, soc := range socs {
+		points[i] = model.FlightTrackPoint{OrderID: orderID, FlightStatus: "Inflight", TimeStamp: start.Add(time.Duration(i) * 10 * time.Second), SOC: soc}
+	}
+	return points
+}
+
+// seedUasStats U1 在统计区间内有两个已降落架次与一个未降落架次，区间外另有一个架次；U2 有一个无 SOC 数据的架次
+func seedUasStats(t *testing.T, d *SQLDao) {
+	t.Helper()
+	flights := []struct {
+		orderID, uasID string
+		start          time.Time
+		dur            time.Duration // 0 表示未降落
+		distance, kwh  float64
+		socs           []int
+	}{
+		{"O-1", "U1", daoBase, 30 * time.Minute, 1000, 0.2, []int{90, 80, 0, 60}},
+		{"O-2", "U1", daoBase.Add(time.Hour), time.Hour, 2000, 0.4, []int{70, 55}},
+		{"O-3", "U1", daoBase.Add(3 * time.Hour), 0, 0, 0, nil},
+		{"O-4", "U1", daoBase.Add(48 * time.Hour), 10 * time.Minute, 500, 0.1, []int{50, 10}},
+		{"O-5", "U2", daoBase, 10 * time.Minute, 300, 0.05, []int{0, 0}},
+	}
+	for _, f := range flights {
+		var end time.Time
+		if f.dur > 0 {
+			end = f.start.Add(f.dur)
+		}
+		if err := d.SaveFlightRecord(f.orderID, f.uasID, f.start, end, 0, 0, 0, 0, f.distance, f.kwh); err != nil {
+			t.Fatal(err)
+		}
+		if err := d.SaveTrackPoints(socTrack(f.orderID, f.start, f.socs...)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// O-1 载货 5kg（0.1kg 单位）、2 票
+	if err := d.UpdateFlightPayload("O-1", 50, 2, AuditMeta{Actor: "test"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetUasFlightTotals(t *testing.T) {
+	d := newTestDao(t)
+	seedUasStats(t, d)
+	ids := []string{"U1", "U2", "U3"}
+
+	got, err := d.GetUasFlightTotals(ids, daoBase.Add(-time.Hour), daoBase.Add(24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 未降落架次计入架次数，不计飞行时长；无记录的 U3 不出现
+	if len(got) != 2 || got["U3"] != nil {
+		t.Fatalf("totals = %v", got)
+	}
+	u1 := got["U1"]
+	if u1.Flights != 3 || u1.FlightSeconds != 5400 || u1.Distance != 3000 || !approx(u1.Battery, 0.6) ||
+		u1.Payload != 5 || !approx(u1.PayloadBattery, 0.2) || u1.ExpressCount != 2 {
+		t.Errorf("U1 totals = %+v", u1)
+	}
+	if !u1.FirstStart.Equal(daoBase) || !u1.LastEnd.Equal(daoBase.Add(2*time.Hour)) {
+		t.Errorf("U1 first start = %v, last end = %v", u1.FirstStart, u1.LastEnd)
+	}
+	if u2 := got["U2"]; u2.Flights != 1 || u2.FlightSeconds != 600 || u2.Payload != 0 || u2.PayloadBattery != 0 {
+		t.Errorf("U2 totals = %+v", u2)
+	}
+
+	// 区间按起飞时间左闭右开：O-2 恰在终点起飞时不计入
+	got, err = d.GetUasFlightTotals([]string{"U1"}, daoBase, daoBase.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u1 := got["U1"]; u1 == nil || u1.Flights != 1 || u1.FlightSeconds != 1800 {
+		t.Errorf("half-open range totals = %+v", u1)
+	}
+	// 不限区间
+	if got, err = d.GetUasFlightTotals([]string{"U1"}, time.Time{}, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if u1 := got["U1"]; u1 == nil || u1.Flights != 4 || u1.Distance != 3500 {
+		t.Errorf("unbounded totals = %+v", u1)
+	}
+	if got, err = d.GetUasFlightTotals(nil, time.Time{}, time.Time{}); err != nil || len(got) != 0 {
+		t.Errorf("empty ids = %v, %v", got, err)
+	}
+}
+
+func TestGetUasSOCDrops(t *testing.T) {
+	d := newTestDao(t)
+	seedUasStats(t, d)
+
+	got, err := d.GetUasSOCDrops([]string{"U1", "U2"}, daoBase.Add(-time.Hour), daoBase.Add(24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// SOC 为 0 的点不参与：O-1 为 90-60，O-2 为 70-55；区间外的 O-4 与全为 0 的 U2 不出现
+	for _, drops := range got {
+		sort.Float64s(drops)
+	}
+	if want := map[string][]float64{"U1": {15, 30}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("drops = %v, want %v", got, want)
+	}
+	if got, err = d.GetUasSOCDrops([]string{"U1"}, time.Time{}, time.Time{}); err != nil || len(got["U1"]) != 3 {
+		t.Errorf("unbounded drops = %v, %v", got, err)
+	}
+}
+
+func approx(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
@@ -0,0 +1,158 @@
+package downsample
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Spec 需要降采样的 measurement。Stats 中的数值字段在每个窗口输出 <字段>_mean、<字段>_min、<字段>_max，
+// Last 中的字段输出窗口内最后一个值（保持原类型），另输出窗口内的原始点数 count
+type Spec struct {
+	Measurement string
+	Tags        []string // 序列的 tag，与写入方一致
+	Stats       []string
+	Last        []string
+}
+
+// Specs drone_status 由 drone-api 写入，vehicle_info 由 autonomous-vehicle 写入
+var Specs = []Spec{
+	{
+		Measurement: "drone_status",
+		Tags:        []string{"flightCode", "sn"},
+		Stats:       []string{"height", "GS", "SOC"},
+		Last:        []string{"longitude", "latitude", "altitude", "orderID", "uasID", "flightStatus"},
+	},
+	{
+		Measurement: "vehicle_info",
+		Tags:        []string{"parkCode", "parkName", "vin", "vinId"},
+		Stats:       []string{"speed", "realBattery"},
+		Last:        []string{"lon", "lat", "driveMode"},
+	},
+}
+
+// FindSpec 按 measurement 查找降采样配置
+func FindSpec(measurement string) (Spec, bool) {
+	for _, s := range Specs {
+		if s.Measurement == measurement {
+			return s, true
+		}
+	}
+	return Spec{}, false
+}
+
+// Point 一个原始点（按 _time 透视后的一行）或一个聚合窗口
+type Point struct {
+	Time   time.Time
+	Tags   map[string]string
+	Fields map[string]interface{}
+}
+
+type window struct {
+	start    time.Time
+	tags     map[string]string
+	count    int64
+	sum      map[string]float64
+	n        map[string]int64
+	min, max map[string]float64
+	last     map[string]interface{}
+	lastAt   map[string]time.Time
+}
+
+// Aggregate 按序列（tag 组合）和对齐到 every 的窗口聚合原始点，窗口时间为窗口起点。
+// 输入顺序不限，输出按时间、序列排序；窗口内没有数值的统计字段不输出
+func Aggregate(spec Spec, every time.Duration, points []Point) []Point {
+	windows := make(map[string]*window)
+	for _, p := range points {
+		start := p.Time.Truncate(every)
+		tags := make(map[string]string, len(spec.Tags))
+		for _, k := range spec.Tags {
+			if v := p.Tags[k]; v != "" {
+				tags[k] = v
+			}
+		}
+		key := windowKey(start, tags)
+		w := windows[key]
+		if w == nil {
+			w = &window{
+				start: start, tags: tags,
+				sum: map[string]float64{}, n: map[string]int64{},
+				min: map[string]float64{}, max: map[string]float64{},
+				last: map[string]interface{}{}, lastAt: map[string]time.Time{},
+			}
+			windows[key] = w
+		}
+		w.count++
+		for _, f := range spec.Stats {
+			v, ok := toFloat(p.Fields[f])
+			if !ok {
+				continue
+			}
+			if w.n[f] == 0 || v < w.min[f] {
+				w.min[f] = v
+			}
+			if w.n[f] == 0 || v > w.max[f] {
+				w.max[f] = v
+			}
+			w.sum[f] += v
+			w.n[f]++
+		}
+		for _, f := range spec.Last {
+			v := p.Fields[f]
+			if v == nil {
+				continue
+			}
+			if at, ok := w.lastAt[f]; !ok || !p.Time.Before(at) {
+				w.last[f], w.lastAt[f] = v, p.Time
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(windows))
+	for k := range windows {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]Point, 0, len(keys))
+	for _, k := range keys {
+		w := windows[k]
+		fields := map[string]interface{}{"count": w.count}
+		for f, n := range w.n {
+			fields[f+"_mean"] = w.sum[f] / float64(n)
+			fields[f+"_min"] = w.min[f]
+			fields[f+"_max"] = w.max[f]
+		}
+		for f, v := range w.last {
+			fields[f] = v
+		}
+		out = append(out, Point{Time: w.start, Tags: w.tags, Fields: fields})
+	}
+	return out
+}
+
+// windowKey 以定长的纳秒时间开头，使按 key 排序即按时间排序
+func windowKey(start time.Time, tags map[string]string) string {
+	var b strings.Builder
+	b.WriteString(start.UTC().Format("20060102150405.000000000"))
+	names := make([]string, 0, len(tags))
+	for k := range tags {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		b.WriteString("\n" + k + "=" + tags[k])
+	}
+	return b.String()
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	}
+	return 0, false
+}
@@ -0,0 +1,69 @@
+package downsample
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+var aggBase = time.Date(2025, 6, 20, 8, 0, 0, 0, time.UTC)
+
+func TestAggregate(t *testing.T) {
+	spec := Spec{
+		Measurement: "drone_status",
+		Tags:        []string{"sn"},
+		Stats:       []string{"height", "SOC"},
+		Last:        []string{"flightStatus", "longitude"},
+	}
+	at := func(sec int) time.Time { return aggBase.Add(time.Duration(sec) * time.Second) }
+	// 输入乱序：A 在 [0,10) 窗口有 3 个点（含只有 height 的点），[10,20) 窗口 1 个点；B 在 [0,10) 窗口 1 个点
+	points := []Point{
+		{Time: at(12), Tags: map[string]string{"sn": "A"}, Fields: map[string]interface{}{"height": 40.0, "flightStatus": "Land"}},
+		{Time: at(5), Tags: map[string]string{"sn": "A"}, Fields: map[string]interface{}{"height": int64(30), "SOC": int64(80), "flightStatus": "Inflight", "longitude": int64(2)}},
+		{Time: at(0), Tags: map[string]string{"sn": "A"}, Fields: map[string]interface{}{"height": 10.0, "SOC": int64(90), "flightStatus": "TakeOff", "longitude": int64(1)}},
+		{Time: at(3), Tags: map[string]string{"sn": "B", "other": "x"}, Fields: map[string]interface{}{"height": 5.0}},
+		// 最后一个点只有 height，不覆盖已有的最后 flightStatus / longitude
+		{Time: at(9), Tags: map[string]string{"sn": "A"}, Fields: map[string]interface{}{"height": 20.0}},
+	}
+	got := Aggregate(spec, 10*time.Second, points)
+	want := []Point{
+		{
+			Time: at(0),
+			Tags: map[string]string{"sn": "A"},
+			Fields: map[string]interface{}{
+				"count":       int64(3),
+				"height_mean": 20.0, "height_min": 10.0, "height_max": 30.0,
+				"SOC_mean": 85.0, "SOC_min": 80.0, "SOC_max": 90.0,
+				"flightStatus": "Inflight", "longitude": int64(2),
+			},
+		},
+		{
+			// 不在 spec.Tags 中的 tag 不区分序列
+			Time:   at(0),
+			Tags:   map[string]string{"sn": "B"},
+			Fields: map[string]interface{}{"count": int64(1), "height_mean": 5.0, "height_min": 5.0, "height_max": 5.0},
+		},
+		{
+			Time:   at(10),
+			Tags:   map[string]string{"sn": "A"},
+			Fields: map[string]interface{}{"count": int64(1), "height_mean": 40.0, "height_min": 40.0, "height_max": 40.0, "flightStatus": "Land"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Aggregate =\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestAggregateLastSameTimestamp(t *testing.T) {
+	spec := Spec{Last: []string{"flightStatus"}}
+	// 同一时间戳的多个值取后出现的
+	points := []Point{
+		{Time: aggBase.Add(time.Second), Fields: map[string]interface{}{"flightStatus": "Inflight"}},
+		{Time: aggBase.Add(time.Second), Fields: map[string]interface{}{"flightStatus": "Land"}},
+		{Time: aggBase, Fields: map[string]interface{}{"flightStatus": "TakeOff"}},
+	}
+	got := Aggregate(spec, time.Minute, points)
+	if len(got) != 1 || got[0].Fields["flightStatus"] != "Land" || got[0].Fields["count"] != int64(3) {
+		t.Errorf("Aggregate = %v", got)
+	}
+}
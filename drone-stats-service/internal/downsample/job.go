@@ -0,0 +1,328 @@
+package downsample
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"drone-stats-service/internal/config"
+
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	"github.com/influxdata/influxdb-client-go/v2/domain"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+const (
+	defaultIntervalSeconds = 60
+	defaultLagSeconds      = 60
+	defaultMaxPoints       = 2000
+	// windowsPerQuery 每次查询原始数据覆盖的窗口数，10s 窗口即每次 1 小时
+	windowsPerQuery = 360
+	// rawDeleteInterval 原始数据过期删除的执行间隔
+	rawDeleteInterval = time.Hour
+)
+
+// defaultTiers 未配置 Tiers 时使用：10s 聚合保留 30 天，1m 聚合保留 365 天
+var defaultTiers = []config.DownsampleTier{
+	{Every: "10s", RetentionDays: 30},
+	{Every: "1m", RetentionDays: 365},
+}
+
+// Tier 一个降采样级别，聚合结果写入独立的 bucket，由 bucket 的保留策略过期
+type Tier struct {
+	Name      string // 配置中的 Every，如 10s
+	Every     time.Duration
+	Bucket    string
+	Retention time.Duration // 0 表示永久保留
+}
+
+// Job 降采样与原始数据保留任务：定期将原始遥测按各级别聚合写入对应 bucket，
+// 并删除超过保留期且已完成降采样的原始数据。同时为查询选择合适的分辨率。
+type Job struct {
+	client       influxdb2.Client
+	org          string
+	bucket       string // 原始数据 bucket
+	tiers        []Tier // 按窗口从小到大
+	rawRetention time.Duration
+	interval     time.Duration
+	lag          time.Duration
+	maxPoints    int
+	enabled      bool
+
+	runMu        sync.Mutex
+	bucketsReady bool
+	lastDelete   time.Time
+
+	mu         sync.Mutex
+	watermarks map[string]time.Time // tier/measurement -> 已完成降采样的截止时间（不含）
+}
+
+// NewJob 按配置创建任务，需调用 Start 启动；未启用时仍可用于查询原始数据。
+// client 为 nil（遥测不在 InfluxDB 中）时任务不启用，查询返回错误
+func NewJob(client influxdb2.Client, org, bucket string, c config.DownsampleConf) (*Job, error) {
+	j := &Job{
+		client:       client,
+		org:          org,
+		bucket:       bucket,
+		rawRetention: time.Duration(c.RawRetentionDays) * 24 * time.Hour,
+		interval:     time.Duration(c.IntervalSeconds) * time.Second,
+		lag:          time.Duration(c.LagSeconds) * time.Second,
+		maxPoints:    c.MaxPoints,
+		enabled:      c.Enabled,
+		watermarks:   map[string]time.Time{},
+	}
+	if j.interval <= 0 {
+		j.interval = defaultIntervalSeconds * time.Second
+	}
+	if j.lag <= 0 {
+		j.lag = defaultLagSeconds * time.Second
+	}
+	if j.maxPoints <= 0 {
+		j.maxPoints = defaultMaxPoints
+	}
+	if client == nil {
+		j.enabled = false
+	}
+	if !j.enabled {
+		return j, nil
+	}
+	confTiers := c.Tiers
+	if len(confTiers) == 0 {
+		confTiers = defaultTiers
+	}
+	seen := map[string]bool{}
+	for _, tc := range confTiers {
+		every, err := time.ParseDuration(tc.Every)
+		if err != nil || every < time.Second || (24*time.Hour)%every != 0 {
+			return nil, fmt.Errorf("降采样窗口 %q 无效：须为能整除 24h 的时长，如 10s、1m", tc.Every)
+		}
+		if seen[tc.Every] {
+			return nil, fmt.Errorf("降采样窗口 %s 重复", tc.Every)
+		}
+		seen[tc.Every] = true
+		name := tc.Bucket
+		if name == "" {
+			name = bucket + "_" + tc.Every
+		}
+		j.tiers = append(j.tiers, Tier{
+			Name:      tc.Every,
+			Every:     every,
+			Bucket:    name,
+			Retention: time.Duration(tc.RetentionDays) * 24 * time.Hour,
+		})
+	}
+	sort.Slice(j.tiers, func(a, b int) bool { return j.tiers[a].Every < j.tiers[b].Every })
+	return j, nil
+}
+
+// Enabled 是否启用降采样
+func (j *Job) Enabled() bool { return j.enabled }
+
+// Tiers 返回降采样级别，按窗口从小到大
+func (j *Job) Tiers() []Tier { return j.tiers }
+
+// Start 在后台定期执行降采样与原始数据删除
+func (j *Job) Start() {
+	if !j.enabled {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+		for {
+			if err := j.RunOnce(context.Background()); err != nil {
+				logx.Errorf("降采样失败: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// RunOnce 执行一轮：确保各级别 bucket 存在且保留期与配置一致，补齐各级别的聚合，再删除过期原始数据
+func (j *Job) RunOnce(ctx context.Context) error {
+	j.runMu.Lock()
+	defer j.runMu.Unlock()
+	if !j.bucketsReady {
+		if err := j.ensureBuckets(ctx); err != nil {
+			return err
+		}
+		j.bucketsReady = true
+	}
+	var errs []string
+	for _, spec := range Specs {
+		for _, t := range j.tiers {
+			if err := j.downsample(ctx, spec, t); err != nil {
+				errs = append(errs, fmt.Sprintf("%s/%s: %v", t.Name, spec.Measurement, err))
+			}
+		}
+	}
+	if j.rawRetention > 0 && time.Since(j.lastDelete) >= rawDeleteInterval {
+		ok := true
+		for _, spec := range Specs {
+			if err := j.deleteExpiredRaw(ctx, spec); err != nil {
+				errs = append(errs, fmt.Sprintf("删除过期原始数据 %s: %v", spec.Measurement, err))
+				ok = false
+			}
+		}
+		if ok {
+			j.lastDelete = time.Now()
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// ensureBuckets 创建缺失的降采样 bucket，已存在的按配置更新保留期
+func (j *Job) ensureBuckets(ctx context.Context) error {
+	bapi := j.client.BucketsAPI()
+	for _, t := range j.tiers {
+		rule := domain.RetentionRule{EverySeconds: int64(t.Retention / time.Second)}
+		b, err := bapi.FindBucketByName(ctx, t.Bucket)
+		if err != nil {
+			org, oerr := j.client.OrganizationsAPI().FindOrganizationByName(ctx, j.org)
+			if oerr != nil {
+				return fmt.Errorf("查找组织 %s 失败: %w", j.org, oerr)
+			}
+			if _, err := bapi.CreateBucketWithName(ctx, org, t.Bucket, rule); err != nil {
+				return fmt.Errorf("创建 bucket %s 失败: %w", t.Bucket, err)
+			}
+			logx.Infof("已创建降采样 bucket %s（保留 %v）", t.Bucket, t.Retention)
+			continue
+		}
+		if len(b.RetentionRules) == 1 && b.RetentionRules[0].EverySeconds == rule.EverySeconds {
+			continue
+		}
+		b.RetentionRules = domain.RetentionRules{rule}
+		if _, err := bapi.UpdateBucket(ctx, b); err != nil {
+			return fmt.Errorf("更新 bucket %s 保留期失败: %w", t.Bucket, err)
+		}
+	}
+	return nil
+}
+
+// downsample 从上次的截止时间起按块查询原始数据、聚合并写入级别 bucket，直到 now - lag。
+// 同一窗口重复写入时覆盖，中断后重新执行是安全的
+func (j *Job) downsample(ctx context.Context, spec Spec, t Tier) error {
+	from, err := j.watermark(ctx, spec, t)
+	if err != nil {
+		return err
+	}
+	until := time.Now().Add(-j.lag).Truncate(t.Every)
+	if t.Retention > 0 {
+		// 超出保留期的窗口写入后会被立即过期，无需处理
+		if oldest := time.Now().Add(-t.Retention).Truncate(t.Every); from.Before(oldest) {
+			from = oldest
+		}
+	}
+	wapi := j.client.WriteAPIBlocking(j.org, t.Bucket)
+	for from.Before(until) {
+		to := from.Add(t.Every * windowsPerQuery)
+		if to.After(until) {
+			to = until
+		}
+		raw, err := j.queryRaw(ctx, spec, from, to, nil)
+		if err != nil {
+			return err
+		}
+		agg := Aggregate(spec, t.Every, raw)
+		for i := 0; i < len(agg); i += 1000 {
+			batch := agg[i:min(i+1000, len(agg))]
+			pts := make([]*write.Point, 0, len(batch))
+			for _, p := range batch {
+				pts = append(pts, write.NewPoint(spec.Measurement, p.Tags, p.Fields, p.Time))
+			}
+			if err := wapi.WritePoint(ctx, pts...); err != nil {
+				return fmt.Errorf("写入 %s 失败: %w", t.Bucket, err)
+			}
+		}
+		from = to
+		j.setWatermark(spec, t, from)
+	}
+	return nil
+}
+
+// watermark 返回级别 bucket 中已完成降采样的截止时间：进程内有缓存时直接使用，否则取级别 bucket 中
+// 最后一个窗口的结束时间；级别 bucket 为空时从最早的原始数据开始
+func (j *Job) watermark(ctx context.Context, spec Spec, t Tier) (time.Time, error) {
+	j.mu.Lock()
+	wm, ok := j.watermarks[t.Name+"/"+spec.Measurement]
+	j.mu.Unlock()
+	if ok {
+		return wm, nil
+	}
+	last, err := j.edgeTime(ctx, t.Bucket, spec.Measurement, "count", "last")
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !last.IsZero() {
+		wm = last.Add(t.Every)
+	} else {
+		first, err := j.edgeTime(ctx, j.bucket, spec.Measurement, spec.Stats[0], "first")
+		if err != nil {
+			return time.Time{}, err
+		}
+		if first.IsZero() {
+			// 没有原始数据，下次从当前时刻开始
+			first = time.Now().Add(-j.lag)
+		}
+		wm = first.Truncate(t.Every)
+	}
+	j.setWatermark(spec, t, wm)
+	return wm, nil
+}
+
+func (j *Job) setWatermark(spec Spec, t Tier, wm time.Time) {
+	j.mu.Lock()
+	j.watermarks[t.Name+"/"+spec.Measurement] = wm
+	j.mu.Unlock()
+}
+
+// Watermark 返回级别已完成降采样的截止时间，尚未执行过时为零值
+func (j *Job) Watermark(measurement string, t Tier) time.Time {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.watermarks[t.Name+"/"+measurement]
+}
+
+// edgeTime 返回 bucket 中 measurement 的 field 最早（fn=first）或最晚（fn=last）的时间，没有数据时为零值
+func (j *Job) edgeTime(ctx context.Context, bucket, measurement, field, fn string) (time.Time, error) {
+	flux := fmt.Sprintf(`from(bucket: %s)
+  |> range(start: 0)
+  |> filter(fn: (r) => r._measurement == %s and r._field == %s)
+  |> %s()`, fluxString(bucket), fluxString(measurement), fluxString(field), fn)
+	result, err := j.client.QueryAPI(j.org).Query(ctx, flux)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer result.Close()
+	var edge time.Time
+	for result.Next() {
+		t := result.Record().Time()
+		if edge.IsZero() || (fn == "first" && t.Before(edge)) || (fn == "last" && t.After(edge)) {
+			edge = t
+		}
+	}
+	return edge, result.Err()
+}
+
+// deleteExpiredRaw 删除超过保留期的原始数据；尚未完成降采样的部分保留，避免数据丢失
+func (j *Job) deleteExpiredRaw(ctx context.Context, spec Spec) error {
+	cutoff := time.Now().Add(-j.rawRetention)
+	for _, t := range j.tiers {
+		wm := j.Watermark(spec.Measurement, t)
+		if wm.IsZero() {
+			return nil
+		}
+		if wm.Before(cutoff) {
+			cutoff = wm
+		}
+	}
+	return j.client.DeleteAPI().DeleteWithName(ctx, j.org, j.bucket, time.Unix(0, 0), cutoff,
+		fmt.Sprintf(`_measurement=%q`, spec.Measurement))
+}
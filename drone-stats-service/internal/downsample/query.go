@@ -0,0 +1,227 @@
+package downsample
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ResolutionRaw 原始数据；其余分辨率为降采样级别的名称（如 10s）
+const ResolutionRaw = "raw"
+
+// rawInterval 原始遥测的标称上报间隔，用于估算原始数据的点数
+const rawInterval = time.Second
+
+// maxQueryWindows 单次查询的窗口数（原始数据按 rawInterval 估算）上限，防止指定分辨率时返回过多数据
+const maxQueryWindows = 200000
+
+// ErrRawExpired 查询范围早于原始数据保留期，原始数据已被降采样任务删除
+var ErrRawExpired = errors.New("原始数据已超出保留期")
+
+// fluxMetaColumns 透视后不属于 tag 或字段的列
+var fluxMetaColumns = map[string]bool{
+	"result": true, "table": true, "_start": true, "_stop": true, "_time": true, "_measurement": true,
+}
+
+// Series 查询结果，Every 为 0 表示原始数据
+type Series struct {
+	Resolution string
+	Every      time.Duration
+	Points     []Point
+}
+
+// Resolution 选择查询 [start, end) 使用的分辨率：在数据仍在保留期内的分辨率中，选择点数不超过 maxPoints 的最细分辨率；
+// 都超过时选择最粗的。maxPoints <= 0 时使用配置的 MaxPoints
+func (j *Job) Resolution(start, end time.Time, maxPoints int) string {
+	if maxPoints <= 0 {
+		maxPoints = j.maxPoints
+	}
+	span := end.Sub(start)
+	now := time.Now()
+	available := func(retention time.Duration) bool {
+		return retention <= 0 || !start.Before(now.Add(-retention))
+	}
+	var candidates []string
+	if available(j.rawRetention) {
+		if int(span/rawInterval) <= maxPoints || len(j.tiers) == 0 {
+			return ResolutionRaw
+		}
+		candidates = append(candidates, ResolutionRaw)
+	}
+	for _, t := range j.tiers {
+		if !available(t.Retention) {
+			continue
+		}
+		if int(span/t.Every) <= maxPoints {
+			return t.Name
+		}
+		candidates = append(candidates, t.Name)
+	}
+	if len(candidates) > 0 {
+		return candidates[len(candidates)-1]
+	}
+	// 起点早于所有保留期：使用保留最久的级别，可返回其中仍保留的部分
+	best, bestRetention := ResolutionRaw, j.rawRetention
+	for _, t := range j.tiers {
+		if t.Retention > bestRetention {
+			best, bestRetention = t.Name, t.Retention
+		}
+	}
+	return best
+}
+
+// CheckRaw 检查从 start 开始的原始数据是否仍在保留期内。降采样任务启用且配置了 RawRetentionDays 时，
+// 早于保留期的原始数据已删除，直接读取原始数据的接口据此返回 ErrRawExpired，而不是返回空结果
+func (j *Job) CheckRaw(start time.Time) error {
+	if j == nil || !j.enabled || j.rawRetention <= 0 {
+		return nil
+	}
+	if cutoff := time.Now().Add(-j.rawRetention); start.Before(cutoff) {
+		return fmt.Errorf("%w：仅保留 %s 之后的原始数据，更早的数据请通过 /telemetry/series 按降采样分辨率查询",
+			ErrRawExpired, cutoff.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// Query 查询 measurement 在 [start, end) 内满足 filter 的数据。resolution 为空或 auto 时按 maxPoints 自动选择；
+// 降采样级别中尚未完成聚合的最新一段由原始数据即时聚合补齐，结果与 Aggregate 一致。
+// filter 的 key 可以是 tag 或字段（如 orderID），值须完全相等
+func (j *Job) Query(ctx context.Context, measurement string, filter map[string]string, start, end time.Time, resolution string, maxPoints int) (Series, error) {
+	if j.client == nil {
+		return Series{}, fmt.Errorf("未配置 InfluxDB")
+	}
+	spec, ok := FindSpec(measurement)
+	if !ok {
+		return Series{}, fmt.Errorf("不支持的 measurement %q", measurement)
+	}
+	if resolution == "" || resolution == "auto" {
+		resolution = j.Resolution(start, end, maxPoints)
+	} else if resolution == ResolutionRaw {
+		// 显式指定原始数据时不回退到降采样级别，超出保留期直接报错
+		if err := j.CheckRaw(start); err != nil {
+			return Series{}, err
+		}
+	}
+	if resolution == ResolutionRaw {
+		if end.Sub(start)/rawInterval > maxQueryWindows {
+			return Series{}, fmt.Errorf("时间范围过大，原始数据最多查询 %v", maxQueryWindows*rawInterval)
+		}
+		pts, err := j.queryRaw(ctx, spec, start, end, filter)
+		return Series{Resolution: ResolutionRaw, Points: pts}, err
+	}
+	var tier *Tier
+	for i := range j.tiers {
+		if j.tiers[i].Name == resolution {
+			tier = &j.tiers[i]
+		}
+	}
+	if tier == nil {
+		return Series{}, fmt.Errorf("未配置分辨率 %q", resolution)
+	}
+	if end.Sub(start)/tier.Every > maxQueryWindows {
+		return Series{}, fmt.Errorf("时间范围过大，%s 分辨率最多查询 %v", tier.Name, maxQueryWindows*tier.Every)
+	}
+	out := Series{Resolution: tier.Name, Every: tier.Every}
+
+	// 级别 bucket 中已完成的部分
+	split := j.Watermark(spec.Measurement, *tier)
+	if split.IsZero() || split.After(end) {
+		split = end
+	}
+	if split.After(start) {
+		pts, err := j.query(ctx, tier.Bucket, spec, start, split, filter)
+		if err != nil {
+			return out, err
+		}
+		out.Points = pts
+	}
+	// 尚未聚合的部分：从窗口起点查询原始数据即时聚合，保证首个窗口完整
+	if split.Before(end) {
+		from := split
+		if from.Before(start) {
+			from = start
+		}
+		raw, err := j.queryRaw(ctx, spec, from.Truncate(tier.Every), end, filter)
+		if err != nil {
+			return out, err
+		}
+		out.Points = append(out.Points, Aggregate(spec, tier.Every, raw)...)
+	}
+	return out, nil
+}
+
+// queryRaw 查询原始数据 bucket
+func (j *Job) queryRaw(ctx context.Context, spec Spec, start, end time.Time, filter map[string]string) ([]Point, error) {
+	return j.query(ctx, j.bucket, spec, start, end, filter)
+}
+
+// query 查询 bucket 中 measurement 在 [start, end) 内的数据并按 _time 透视，tag 条件在透视前过滤，字段条件在透视后过滤
+func (j *Job) query(ctx context.Context, bucket string, spec Spec, start, end time.Time, filter map[string]string) ([]Point, error) {
+	isTag := map[string]bool{}
+	for _, k := range spec.Tags {
+		isTag[k] = true
+	}
+	var tagConds, fieldConds []string
+	keys := make([]string, 0, len(filter))
+	for k := range filter {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		cond := fmt.Sprintf("r[%s] == %s", fluxString(k), fluxString(filter[k]))
+		if isTag[k] {
+			tagConds = append(tagConds, cond)
+		} else {
+			fieldConds = append(fieldConds, cond)
+		}
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, `from(bucket: %s)
+  |> range(start: %s, stop: %s)
+  |> filter(fn: (r) => r._measurement == %s)`,
+		fluxString(bucket), start.UTC().Format(time.RFC3339Nano), end.UTC().Format(time.RFC3339Nano), fluxString(spec.Measurement))
+	if len(tagConds) > 0 {
+		fmt.Fprintf(&b, "\n  |> filter(fn: (r) => %s)", strings.Join(tagConds, " and "))
+	}
+	b.WriteString("\n  |> pivot(rowKey: [\"_time\"], columnKey: [\"_field\"], valueColumn: \"_value\")")
+	if len(fieldConds) > 0 {
+		fmt.Fprintf(&b, "\n  |> filter(fn: (r) => %s)", strings.Join(fieldConds, " and "))
+	}
+
+	result, err := j.client.QueryAPI(j.org).Query(ctx, b.String())
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+	var out []Point
+	for result.Next() {
+		rec := result.Record()
+		p := Point{Time: rec.Time(), Tags: map[string]string{}, Fields: map[string]interface{}{}}
+		for k, v := range rec.Values() {
+			switch {
+			case v == nil || fluxMetaColumns[k]:
+			case isTag[k]:
+				if s, ok := v.(string); ok {
+					p.Tags[k] = s
+				}
+			default:
+				p.Fields[k] = v
+			}
+		}
+		out = append(out, p)
+	}
+	if err := result.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(out, func(a, b int) bool { return out[a].Time.Before(out[b].Time) })
+	return out, nil
+}
+
+// fluxString 将 s 写为 Flux 字符串字面量
+func fluxString(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "${", `\${`)
+	return `"` + r.Replace(s) + `"`
+}
@@ -0,0 +1,64 @@
+package downsample
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestResolution(t *testing.T) {
+	day := 24 * time.Hour
+	tiers := []Tier{
+		{Name: "10s", Every: 10 * time.Second, Retention: 30 * day},
+		{Name: "1m", Every: time.Minute, Retention: 180 * day},
+		{Name: "1h", Every: time.Hour},
+	}
+	now := time.Now()
+	tests := []struct {
+		name      string
+		tiers     []Tier
+		start     time.Time
+		span      time.Duration
+		maxPoints int
+		want      string
+	}{
+		{name: "短范围使用原始数据", tiers: tiers, start: now.Add(-time.Hour), span: 10 * time.Minute, want: ResolutionRaw},
+		{name: "原始点数超出", tiers: tiers, start: now.Add(-3 * time.Hour), span: 2 * time.Hour, want: "10s"},
+		{name: "选择最细的满足级别", tiers: tiers, start: now.Add(-2 * day), span: day, want: "1h"},
+		{name: "指定 maxPoints", tiers: tiers, start: now.Add(-2 * day), span: day, maxPoints: 2000, want: "1m"},
+		{name: "原始数据超出保留期", tiers: tiers, start: now.Add(-10 * day), span: 10 * time.Minute, want: "10s"},
+		{name: "10s 级别超出保留期", tiers: tiers, start: now.Add(-40 * day), span: 10 * time.Minute, want: "1m"},
+		{name: "都超出 maxPoints 时选最粗", tiers: tiers[:2], start: now.Add(-20 * day), span: 10 * day, want: "1m"},
+		{name: "早于所有保留期", tiers: tiers[:2], start: now.Add(-400 * day), span: time.Hour, want: "1m"},
+		{name: "没有降采样级别", start: now.Add(-400 * day), span: 10 * day, want: ResolutionRaw},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := &Job{tiers: tt.tiers, rawRetention: 7 * day, maxPoints: 1000}
+			if len(tt.tiers) == 0 {
+				j.rawRetention = 0
+			}
+			if got := j.Resolution(tt.start, tt.start.Add(tt.span), tt.maxPoints); got != tt.want {
+				t.Errorf("Resolution = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckRaw(t *testing.T) {
+	now := time.Now()
+	j := &Job{enabled: true, rawRetention: 7 * 24 * time.Hour}
+	if err := j.CheckRaw(now.Add(-time.Hour)); err != nil {
+		t.Errorf("recent start err = %v", err)
+	}
+	if err := j.CheckRaw(now.Add(-8 * 24 * time.Hour)); !errors.Is(err, ErrRawExpired) {
+		t.Errorf("expired start err = %v, want ErrRawExpired", err)
+	}
+	// 未启用或未创建任务时原始数据不会被删除
+	var nilJob *Job
+	for _, j := range []*Job{{rawRetention: 7 * 24 * time.Hour}, nilJob} {
+		if err := j.CheckRaw(now.Add(-30 * 24 * time.Hour)); err != nil {
+			t.Errorf("disabled job err = %v", err)
+		}
+	}
+}
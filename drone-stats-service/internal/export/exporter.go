@@ -5,6 +5,8 @@ import (
 	"bufio"
 	"encoding/csv"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -27,22 +29,23 @@ func ExportMapsToExcel(records []map[string]interface{}, filePath string) error
 	}
 
 	// 我们为已知字段提供友好表头并固定列顺序；对于未知字段，附加在末尾
-	preferredOrder := []string{"id", "OrderID", "uasID", "start_time", "end_time", "start_lat", "start_lng", "end_lat", "end_lng", "distance", "battery_used", "created_at", "payload", "expressCount"}
+	preferredOrder := []string{"id", "OrderID", "uasID", "start_time", "end_time", "start_lat", "start_lng", "end_lat", "end_lng", "distance", "battery_used", "energy_method", "created_at", "payload", "expressCount"}
 	headerNames := map[string]string{
-		"id":           "ID",
-		"OrderID":      "Order ID",
-		"uasID":        "UAS ID",
-		"start_time":   "Start Time",
-		"end_time":     "End Time",
-		"start_lat":    "Start Latitude",
-		"start_lng":    "Start Longitude",
-		"end_lat":      "End Latitude",
-		"end_lng":      "End Longitude",
-		"distance":     "Distance (m)",
-		"battery_used": "Battery Used (A.h)",
-		"created_at":   "Created At",
-		"payload":      "Payload (kg)",
-		"expressCount": "Express Count",
+		"id":            "ID",
+		"OrderID":       "Order ID",
+		"uasID":         "UAS ID",
+		"start_time":    "Start Time",
+		"end_time":      "End Time",
+		"start_lat":     "Start Latitude",
+		"start_lng":     "Start Longitude",
+		"end_lat":       "End Latitude",
+		"end_lng":       "End Longitude",
+		"distance":      "Distance (m)",
+		"battery_used":  "Battery Used (kWh)",
+		"energy_method": "Energy Method",
+		"created_at":    "Created At",
+		"payload":       "Payload (kg)",
+		"expressCount":  "Express Count",
 	}
 
 	// 构造列顺序
@@ -235,3 +238,34 @@ func CreateZip(files []string, zipPath string) error {
 	}
 	return nil
 }
+
+// CreateZipDir 将目录下的全部文件按相对路径流式打包为 zip（用于分区导出）
+func CreateZipDir(dir, zipPath string) error {
+	zf, err := os.Create(zipPath)
+	if err != nil {
+		return err
+	}
+	defer zf.Close()
+	zw := zip.NewWriter(zf)
+	defer zw.Close()
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		w, err := zw.Create(filepath.ToSlash(rel))
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(w, f)
+		return err
+	})
+}
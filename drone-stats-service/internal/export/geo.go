@@ -0,0 +1,324 @@
+package export
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"drone-stats-service/internal/dao"
+	"drone-stats-service/internal/model"
+)
+
+// 地理格式（仅用于轨迹导出）
+const (
+	FormatGeoJSON = "geojson"
+	FormatKML     = "kml"
+	FormatGPX     = "gpx"
+)
+
+// IsGeoFormat 是否为地理格式
+func IsGeoFormat(format string) bool {
+	return format == FormatGeoJSON || format == FormatKML || format == FormatGPX
+}
+
+// GeoContentType 地理格式对应的 Content-Type
+func GeoContentType(format string) string {
+	switch format {
+	case FormatGeoJSON:
+		return "application/geo+json"
+	case FormatKML:
+		return "application/vnd.google-earth.kml+xml"
+	case FormatGPX:
+		return "application/gpx+xml"
+	}
+	return "application/octet-stream"
+}
+
+// geoFlight 一次飞行（同一 OrderID 下从 TakeOff 开始的连续轨迹点）
+type geoFlight struct {
+	OrderID string
+	Points  []model.FlightTrackPoint
+}
+
+type geoWriter interface {
+	begin() error
+	flight(f geoFlight) error
+	end() error
+}
+
+// ExportTrackPointsToGeo 将轨迹点按飞行分段导出为 GeoJSON / KML / GPX 文件。
+// 逐架次缓冲写出，(0,0) 坐标的点不参与几何；onRow 可为空，每读取一个轨迹点回调一次。
+func ExportTrackPointsToGeo(ctx context.Context, mysql dao.ExportRepo, format, orderID, uasID string, start, end time.Time, filePath string, onRow func()) error {
+	f, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	bw := bufio.NewWriter(f)
+
+	var gw geoWriter
+	switch format {
+	case FormatGeoJSON:
+		gw = &geoJSONWriter{w: bw}
+	case FormatKML:
+		gw = &kmlWriter{w: bw}
+	case FormatGPX:
+		gw = &gpxWriter{w: bw}
+	default:
+		return fmt.Errorf("unsupported geo format %s", format)
+	}
+	if err := gw.begin(); err != nil {
+		return err
+	}
+	var cur geoFlight
+	flush := func() error {
+		if len(cur.Points) == 0 {
+			return nil
+		}
+		err := gw.flight(cur)
+		cur = geoFlight{}
+		return err
+	}
+	err = mysql.ForEachTrackPoint(ctx, orderID, uasID, start, end, func(p model.FlightTrackPoint) error {
+		if onRow != nil {
+			onRow()
+		}
+		if p.Latitude == 0 && p.Longitude == 0 {
+			return nil
+		}
+		if p.OrderID != cur.OrderID || p.FlightStatus == "TakeOff" {
+			if err := flush(); err != nil {
+				return err
+			}
+			cur.OrderID = p.OrderID
+		}
+		cur.Points = append(cur.Points, p)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	if err := gw.end(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func lngLat(p model.FlightTrackPoint) (float64, float64) {
+	return float64(p.Longitude) / 1e7, float64(p.Latitude) / 1e7
+}
+
+// altitudeM 海拔（m）
+func altitudeM(p model.FlightTrackPoint) float64 {
+	return float64(p.Altitude) / 10
+}
+
+func fmtFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// geoJSONWriter 每次飞行输出一条 LineString 及各轨迹点的 Point 要素
+type geoJSONWriter struct {
+	w     io.Writer
+	count int
+}
+
+type geoJSONFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   geoJSONGeometry        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type geoJSONGeometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+func (g *geoJSONWriter) begin() error {
+	_, err := io.WriteString(g.w, `{"type":"FeatureCollection","features":[`)
+	return err
+}
+
+func (g *geoJSONWriter) feature(f geoJSONFeature) error {
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	if g.count > 0 {
+		if _, err := io.WriteString(g.w, ",\n"); err != nil {
+			return err
+		}
+	}
+	g.count++
+	_, err = g.w.Write(data)
+	return err
+}
+
+func (g *geoJSONWriter) flight(f geoFlight) error {
+	first, last := f.Points[0], f.Points[len(f.Points)-1]
+	coords := make([][3]float64, len(f.Points))
+	for i, p := range f.Points {
+		lng, lat := lngLat(p)
+		coords[i] = [3]float64{lng, lat, altitudeM(p)}
+	}
+	geomType := "LineString"
+	var geom interface{} = coords
+	if len(coords) == 1 {
+		geomType, geom = "Point", coords[0]
+	}
+	err := g.feature(geoJSONFeature{
+		Type:     "Feature",
+		Geometry: geoJSONGeometry{Type: geomType, Coordinates: geom},
+		Properties: map[string]interface{}{
+			"featureType": "flight",
+			"orderID":     f.OrderID,
+			"startTime":   first.TimeStamp.UTC().Format(time.RFC3339),
+			"endTime":     last.TimeStamp.UTC().Format(time.RFC3339),
+			"points":      len(f.Points),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	for i, p := range f.Points {
+		err := g.feature(geoJSONFeature{
+			Type:     "Feature",
+			Geometry: geoJSONGeometry{Type: "Point", Coordinates: coords[i]},
+			Properties: map[string]interface{}{
+				"featureType":  "point",
+				"orderID":      p.OrderID,
+				"flightStatus": p.FlightStatus,
+				"time":         p.TimeStamp.UTC().Format(time.RFC3339),
+				"height":       float64(p.Height) / 10,
+				"altitude":     altitudeM(p),
+				"VS":           float64(p.VS) / 10,
+				"GS":           float64(p.GS) / 10,
+				"course":       float64(p.Course) / 10,
+				"SOC":          p.SOC,
+				"voltage":      p.Voltage,
+				"current":      p.Current,
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *geoJSONWriter) end() error {
+	_, err := io.WriteString(g.w, "]}\n")
+	return err
+}
+
+// kmlWriter 每次飞行输出一个带 gx:Track 的 Placemark，海拔按 absolute 模式输出三维轨迹
+type kmlWriter struct {
+	w io.Writer
+}
+
+func (k *kmlWriter) begin() error {
+	_, err := io.WriteString(k.w, xml.Header+`<kml xmlns="http://www.opengis.net/kml/2.2" xmlns:gx="http://www.google.com/kml/ext/2.2">
+<Document>
+<name>flight tracks</name>
+<Schema id="trackSchema">
+<gx:SimpleArrayField name="SOC" type="int"><displayName>SOC (%)</displayName></gx:SimpleArrayField>
+<gx:SimpleArrayField name="GS" type="float"><displayName>GS (m/s)</displayName></gx:SimpleArrayField>
+<gx:SimpleArrayField name="height" type="float"><displayName>Height (m)</displayName></gx:SimpleArrayField>
+</Schema>
+`)
+	return err
+}
+
+func (k *kmlWriter) flight(f geoFlight) error {
+	bw := &errWriter{w: k.w}
+	bw.printf("<Placemark>\n<name>")
+	bw.escape(f.OrderID + " " + f.Points[0].TimeStamp.UTC().Format(time.RFC3339))
+	bw.printf("</name>\n<gx:Track>\n<altitudeMode>absolute</altitudeMode>\n")
+	for _, p := range f.Points {
+		bw.printf("<when>%s</when>\n", p.TimeStamp.UTC().Format(time.RFC3339))
+	}
+	for _, p := range f.Points {
+		lng, lat := lngLat(p)
+		bw.printf("<gx:coord>%s %s %s</gx:coord>\n", fmtFloat(lng), fmtFloat(lat), fmtFloat(altitudeM(p)))
+	}
+	bw.printf("<ExtendedData>\n<SchemaData schemaUrl=\"#trackSchema\">\n")
+	arrays := []struct {
+		name  string
+		value func(model.FlightTrackPoint) string
+	}{
+		{"SOC", func(p model.FlightTrackPoint) string { return strconv.Itoa(p.SOC) }},
+		{"GS", func(p model.FlightTrackPoint) string { return fmtFloat(float64(p.GS) / 10) }},
+		{"height", func(p model.FlightTrackPoint) string { return fmtFloat(float64(p.Height) / 10) }},
+	}
+	for _, a := range arrays {
+		bw.printf("<gx:SimpleArrayData name=\"%s\">\n", a.name)
+		for _, p := range f.Points {
+			bw.printf("<gx:value>%s</gx:value>\n", a.value(p))
+		}
+		bw.printf("</gx:SimpleArrayData>\n")
+	}
+	bw.printf("</SchemaData>\n</ExtendedData>\n</gx:Track>\n</Placemark>\n")
+	return bw.err
+}
+
+func (k *kmlWriter) end() error {
+	_, err := io.WriteString(k.w, "</Document>\n</kml>\n")
+	return err
+}
+
+// gpxWriter 每次飞行输出一个 trk（GPX 1.1）
+type gpxWriter struct {
+	w io.Writer
+}
+
+func (g *gpxWriter) begin() error {
+	_, err := io.WriteString(g.w, xml.Header+`<gpx version="1.1" creator="drone-stats-service" xmlns="http://www.topografix.com/GPX/1/1">
+`)
+	return err
+}
+
+func (g *gpxWriter) flight(f geoFlight) error {
+	bw := &errWriter{w: g.w}
+	bw.printf("<trk>\n<name>")
+	bw.escape(f.OrderID + " " + f.Points[0].TimeStamp.UTC().Format(time.RFC3339))
+	bw.printf("</name>\n<trkseg>\n")
+	for _, p := range f.Points {
+		lng, lat := lngLat(p)
+		bw.printf("<trkpt lat=\"%s\" lon=\"%s\"><ele>%s</ele><time>%s</time></trkpt>\n",
+			fmtFloat(lat), fmtFloat(lng), fmtFloat(altitudeM(p)), p.TimeStamp.UTC().Format(time.RFC3339))
+	}
+	bw.printf("</trkseg>\n</trk>\n")
+	return bw.err
+}
+
+func (g *gpxWriter) end() error {
+	_, err := io.WriteString(g.w, "</gpx>\n")
+	return err
+}
+
+// errWriter 记录首个写错误，后续写入直接跳过
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (e *errWriter) printf(format string, args ...interface{}) {
+	if e.err == nil {
+		_, e.err = fmt.Fprintf(e.w, format, args...)
+	}
+}
+
+func (e *errWriter) escape(s string) {
+	if e.err == nil {
+		e.err = xml.EscapeText(e.w, []byte(s))
+	}
+}
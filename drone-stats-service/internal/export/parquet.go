@@ -0,0 +1,357 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"drone-stats-service/internal/dao"
+	"drone-stats-service/internal/model"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// FormatParquet 列式 Parquet 格式（仅异步导出）
+const FormatParquet = "parquet"
+
+// Parquet 分区方式：不分区输出单个文件；按天 / 按无人机输出 Hive 风格目录（date=2024-01-02/、uas=UAS01/）
+const (
+	PartitionNone = ""
+	PartitionDay  = "day"
+	PartitionUas  = "uas"
+)
+
+const (
+	// parquetRowGroupRows 每个行组的最大行数，写满即落盘，控制单分区内存占用
+	parquetRowGroupRows = 128 * 1024
+	// parquetBatchRows 每个分区缓冲多少行后批量写入 writer
+	parquetBatchRows = 1024
+	// parquetMaxOpenParts 同时打开的分区文件上限，超出时关闭最久未写入的分区，该分区再有数据时写入新的 part-N 文件
+	parquetMaxOpenParts = 16
+)
+
+// exportDayZone 按天分区使用的时区（与导出中 +8 小时的约定一致）
+var exportDayZone = time.FixedZone("UTC+8", 8*3600)
+
+// IsParquetPartition 分区参数是否合法
+func IsParquetPartition(partitionBy string) bool {
+	return partitionBy == PartitionNone || partitionBy == PartitionDay || partitionBy == PartitionUas
+}
+
+// parquetRecordRow flight_records 的 Parquet 行：时间为 UTC 毫秒时间戳，经纬度为十进制度，距离为米
+type parquetRecordRow struct {
+	ID             int64     `parquet:"id"`
+	OrderID        string    `parquet:"order_id,dict"`
+	UasID          string    `parquet:"uas_id,dict"`
+	StartTime      time.Time `parquet:"start_time,timestamp(millisecond)"`
+	EndTimeMs      int64     `parquet:"end_time,optional,timestamp(millisecond)"` // UTC 毫秒时间戳，未降落时为 0（写为 null）
+	DurationS      *float64  `parquet:"duration_s,optional"`
+	StartLatDeg    float64   `parquet:"start_lat_deg"`
+	StartLngDeg    float64   `parquet:"start_lng_deg"`
+	EndLatDeg      float64   `parquet:"end_lat_deg"`
+	EndLngDeg      float64   `parquet:"end_lng_deg"`
+	DistanceM      float64   `parquet:"distance_m"`
+	BatteryUsedKWh float64   `parquet:"battery_used_kwh"`
+	EnergyMethod   string    `parquet:"energy_method,dict"`
+	PayloadKg      float64   `parquet:"payload_kg"`
+	ExpressCount   int32     `parquet:"express_count"`
+	CreatedAt      time.Time `parquet:"created_at,timestamp(millisecond)"`
+}
+
+// parquetTrackRow flight_track_points 的 Parquet 行：高度为米，速度为 m/s，电压/电流为 V/A
+type parquetTrackRow struct {
+	ID           int64     `parquet:"id"`
+	OrderID      string    `parquet:"order_id,dict"`
+	UasID        string    `parquet:"uas_id,dict"`
+	FlightStatus string    `parquet:"flight_status,dict"`
+	Time         time.Time `parquet:"time,timestamp(millisecond)"`
+	LatDeg       float64   `parquet:"lat_deg"`
+	LngDeg       float64   `parquet:"lng_deg"`
+	HeightType   int32     `parquet:"height_type"`
+	HeightM      float64   `parquet:"height_m"`
+	AltitudeM    float64   `parquet:"altitude_m"`
+	VSMps        float64   `parquet:"vs_mps"`
+	GSMps        float64   `parquet:"gs_mps"`
+	CourseDeg    float64   `parquet:"course_deg"`
+	SOC          int32     `parquet:"soc_pct"`
+	RM           int32     `parquet:"rm"`
+	VoltageV     float64   `parquet:"voltage_v"`
+	CurrentA     float64   `parquet:"current_a"`
+	WindSpeed    int32     `parquet:"wind_speed"`
+	WindDirect   int32     `parquet:"wind_direct"`
+	Temperture   int32     `parquet:"temperature"`
+	Humidity     int32     `parquet:"humidity"`
+}
+
+// ExportFlightRecordsToParquet 流式导出飞行记录。
+// partitionBy 为空时 out 为文件路径；否则 out 为目录，按分区写入 out/<key>=<value>/part-NNNNN.parquet
+// （同时打开的分区数超过 parquetMaxOpenParts 时，同一分区可能拆分为多个 part 文件）。
+// onRow 可为空，每写入一行回调一次。
+func ExportFlightRecordsToParquet(ctx context.Context, mysql dao.ExportRepo, orderID, uasID string, start, end time.Time, partitionBy, out string, onRow func()) error {
+	sink, err := newParquetSink[parquetRecordRow](out, partitionBy)
+	if err != nil {
+		return err
+	}
+	// 按无人机分区时按 uasID 排序读取，使每个分区连续写入，避免分区文件被反复淘汰
+	err = mysql.ForEachFlightRecord(ctx, orderID, uasID, start, end, partitionBy == PartitionUas, func(r model.FlightRecord) error {
+		row := parquetRecordRow{
+			ID:             int64(r.ID),
+			OrderID:        r.OrderID,
+			UasID:          r.UasID,
+			StartTime:      r.StartTime.UTC(),
+			StartLatDeg:    float64(r.StartLat) / 1e7,
+			StartLngDeg:    float64(r.StartLng) / 1e7,
+			EndLatDeg:      float64(r.EndLat) / 1e7,
+			EndLngDeg:      float64(r.EndLng) / 1e7,
+			DistanceM:      r.Distance,
+			BatteryUsedKWh: r.BatteryUsed,
+			EnergyMethod:   r.EnergyMethod,
+			PayloadKg:      r.Payload / 10,
+			ExpressCount:   int32(r.ExpressCount),
+			CreatedAt:      r.CreatedAt.UTC(),
+		}
+		if !r.EndTime.IsZero() {
+			duration := r.EndTime.Sub(r.StartTime).Seconds()
+			row.EndTimeMs, row.DurationS = r.EndTime.UnixMilli(), &duration
+		}
+		if err := sink.write(partitionValue(partitionBy, r.StartTime, r.UasID), row); err != nil {
+			return err
+		}
+		if onRow != nil {
+			onRow()
+		}
+		return nil
+	})
+	if err != nil {
+		sink.abort()
+		return err
+	}
+	return sink.close()
+}
+
+// ExportTrackPointsToParquet 流式导出轨迹点，选取规则与 ForEachTrackPoint 一致；输出约定同 ExportFlightRecordsToParquet。
+func ExportTrackPointsToParquet(ctx context.Context, mysql dao.ExportRepo, orderID, uasID string, start, end time.Time, partitionBy, out string, onRow func()) error {
+	sink, err := newParquetSink[parquetTrackRow](out, partitionBy)
+	if err != nil {
+		return err
+	}
+	// 轨迹点表无 uasID，按 OrderID 查询并缓存
+	uasOf := map[string]string{}
+	err = mysql.ForEachTrackPoint(ctx, orderID, uasID, start, end, func(p model.FlightTrackPoint) error {
+		uas, ok := uasOf[p.OrderID]
+		if !ok {
+			var err error
+			if uas, err = mysql.GetUasIDByOrderID(p.OrderID); err != nil {
+				return err
+			}
+			uasOf[p.OrderID] = uas
+		}
+		row := parquetTrackRow{
+			ID:           p.ID,
+			OrderID:      p.OrderID,
+			UasID:        uas,
+			FlightStatus: p.FlightStatus,
+			Time:         p.TimeStamp.UTC(),
+			LatDeg:       float64(p.Latitude) / 1e7,
+			LngDeg:       float64(p.Longitude) / 1e7,
+			HeightType:   int32(p.HeightType),
+			HeightM:      float64(p.Height) / 10,
+			AltitudeM:    float64(p.Altitude) / 10,
+			VSMps:        float64(p.VS) / 10,
+			GSMps:        float64(p.GS) / 10,
+			CourseDeg:    float64(p.Course) / 10,
+			SOC:          int32(p.SOC),
+			RM:           int32(p.RM),
+			VoltageV:     float64(p.Voltage) / 1000,
+			CurrentA:     float64(p.Current) / 1000,
+			WindSpeed:    int32(p.WindSpeed),
+			WindDirect:   int32(p.WindDirect),
+			Temperture:   int32(p.Temperture),
+			Humidity:     int32(p.Humidity),
+		}
+		if err := sink.write(partitionValue(partitionBy, p.TimeStamp, uas), row); err != nil {
+			return err
+		}
+		if onRow != nil {
+			onRow()
+		}
+		return nil
+	})
+	if err != nil {
+		sink.abort()
+		return err
+	}
+	return sink.close()
+}
+
+// partitionValue 计算一行所属分区目录名，不分区时返回空串
+func partitionValue(partitionBy string, t time.Time, uasID string) string {
+	switch partitionBy {
+	case PartitionDay:
+		return "date=" + t.In(exportDayZone).Format("2006-01-02")
+	case PartitionUas:
+		if uasID == "" {
+			uasID = "unknown"
+		}
+		return "uas=" + sanitizePartition(uasID)
+	}
+	return ""
+}
+
+// sanitizePartition 替换分区值中不适合作为目录名的字符
+func sanitizePartition(v string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|', '=', ' ':
+			return '_'
+		}
+		return r
+	}, v)
+}
+
+// parquetPart 单个分区的输出文件及待写缓冲
+type parquetPart[T any] struct {
+	f        *os.File
+	w        *parquet.GenericWriter[T]
+	buf      []T
+	lastUsed int64 // 最近一次写入的序号，用于淘汰最久未写入的分区
+}
+
+// parquetSink 按分区懒创建 writer，分批写入并按行组落盘；打开的分区数有上限，避免分区过多时文件句柄与缓冲无限增长
+type parquetSink[T any] struct {
+	out         string
+	partitioned bool
+	parts       map[string]*parquetPart[T]
+	nextFile    map[string]int // 分区下一个 part 文件的序号
+	seq         int64
+}
+
+func newParquetSink[T any](out, partitionBy string) (*parquetSink[T], error) {
+	if !IsParquetPartition(partitionBy) {
+		return nil, fmt.Errorf("unsupported partitionBy %s", partitionBy)
+	}
+	s := &parquetSink[T]{out: out, partitioned: partitionBy != PartitionNone, parts: map[string]*parquetPart[T]{}, nextFile: map[string]int{}}
+	if s.partitioned {
+		if err := os.MkdirAll(out, 0o755); err != nil {
+			return nil, err
+		}
+	} else if _, err := s.part(""); err != nil {
+		// 不分区时即使无数据也输出带 schema 的空文件
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *parquetSink[T]) part(key string) (*parquetPart[T], error) {
+	s.seq++
+	if p, ok := s.parts[key]; ok {
+		p.lastUsed = s.seq
+		return p, nil
+	}
+	if len(s.parts) >= parquetMaxOpenParts {
+		if err := s.evict(); err != nil {
+			return nil, err
+		}
+	}
+	path := s.out
+	if s.partitioned {
+		dir := filepath.Join(s.out, key)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+		path = filepath.Join(dir, fmt.Sprintf("part-%05d.parquet", s.nextFile[key]))
+		s.nextFile[key]++
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	p := &parquetPart[T]{
+		f: f,
+		w: parquet.NewGenericWriter[T](f,
+			parquet.Compression(&parquet.Zstd),
+			parquet.MaxRowsPerRowGroup(parquetRowGroupRows),
+		),
+		buf:      make([]T, 0, parquetBatchRows),
+		lastUsed: s.seq,
+	}
+	s.parts[key] = p
+	return p, nil
+}
+
+// evict 写完并关闭最久未写入的分区
+func (s *parquetSink[T]) evict() error {
+	oldest := ""
+	for k, p := range s.parts {
+		if oldest == "" || p.lastUsed < s.parts[oldest].lastUsed {
+			oldest = k
+		}
+	}
+	p := s.parts[oldest]
+	delete(s.parts, oldest)
+	return p.close()
+}
+
+func (s *parquetSink[T]) write(key string, row T) error {
+	p, err := s.part(key)
+	if err != nil {
+		return err
+	}
+	p.buf = append(p.buf, row)
+	if len(p.buf) >= parquetBatchRows {
+		return p.flush()
+	}
+	return nil
+}
+
+// close 写出剩余缓冲并关闭 writer 与文件，返回遇到的第一个错误
+func (p *parquetPart[T]) close() error {
+	err := p.flush()
+	if cerr := p.w.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := p.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (p *parquetPart[T]) flush() error {
+	if len(p.buf) == 0 {
+		return nil
+	}
+	_, err := p.w.Write(p.buf)
+	p.buf = p.buf[:0]
+	return err
+}
+
+// close 写出剩余缓冲并关闭全部分区文件，返回遇到的第一个错误
+func (s *parquetSink[T]) close() error {
+	keys := make([]string, 0, len(s.parts))
+	for k := range s.parts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var firstErr error
+	for _, k := range keys {
+		if err := s.parts[k].close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	s.parts = nil
+	return firstErr
+}
+
+// abort 出错时关闭已打开的 writer 与文件并丢弃缓冲，不保证产物完整
+func (s *parquetSink[T]) abort() {
+	for _, p := range s.parts {
+		p.buf = nil
+		_ = p.w.Close()
+		_ = p.f.Close()
+	}
+	s.parts = nil
+}
@@ -0,0 +1,99 @@
+package export
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultLinkTTL = time.Hour
+	// signingKeyFile 未配置密钥时持久化随机密钥的文件名，保证重启后已发出的链接仍然有效
+	signingKeyFile = ".signing_key"
+)
+
+var (
+	ErrLinkInvalid = errors.New("下载链接签名无效")
+	ErrLinkExpired = errors.New("下载链接已过期")
+)
+
+// randomHex 返回 n 字节随机数的十六进制串
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// loadSigningKey 优先使用配置的密钥，否则读取或生成任务目录下的随机密钥
+func loadSigningKey(dir, configured string) ([]byte, error) {
+	if configured != "" {
+		return []byte(configured), nil
+	}
+	p := filepath.Join(dir, signingKeyFile)
+	if data, err := os.ReadFile(p); err == nil && len(strings.TrimSpace(string(data))) >= 32 {
+		return []byte(strings.TrimSpace(string(data))), nil
+	}
+	key, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(p, []byte(key), 0o600); err != nil {
+		return nil, err
+	}
+	return []byte(key), nil
+}
+
+// sign 计算任务 id 与过期时间的 HMAC-SHA256 签名
+func (tm *TaskManager) sign(id string, expires int64) string {
+	mac := hmac.New(sha256.New, tm.signKey)
+	mac.Write([]byte(id + "\n" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignDownload 生成任务下载参数（id、expires、sig），返回查询串及过期时间
+func (tm *TaskManager) SignDownload(id string) (string, time.Time) {
+	exp := time.Now().Add(tm.linkTTL).Truncate(time.Second)
+	v := url.Values{}
+	v.Set("id", id)
+	v.Set("expires", strconv.FormatInt(exp.Unix(), 10))
+	v.Set("sig", tm.sign(id, exp.Unix()))
+	return v.Encode(), exp
+}
+
+// VerifyDownload 校验下载链接签名及有效期
+func (tm *TaskManager) VerifyDownload(id, expires, sig string) error {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || sig == "" {
+		return ErrLinkInvalid
+	}
+	if !hmac.Equal([]byte(sig), []byte(tm.sign(id, exp))) {
+		return ErrLinkInvalid
+	}
+	if time.Now().Unix() > exp {
+		return ErrLinkExpired
+	}
+	return nil
+}
+
+// OwnerToken 返回任务访问令牌：创建任务时交给提交者，查询状态、取消任务时出示。
+// 令牌由签名密钥派生，不随任务列表等接口返回，无需持久化
+func (tm *TaskManager) OwnerToken(id string) string {
+	mac := hmac.New(sha256.New, tm.signKey)
+	mac.Write([]byte("owner\n" + id))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyOwner 校验任务访问令牌
+func (tm *TaskManager) VerifyOwner(id, token string) bool {
+	return token != "" && hmac.Equal([]byte(token), []byte(tm.OwnerToken(id)))
+}
@@ -1,55 +1,132 @@
 package export
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"drone-stats-service/internal/config"
 	"drone-stats-service/internal/dao"
 )
 
 // 任务状态
 const (
-	TaskStatusPending = "pending"
-	TaskStatusRunning = "running"
-	TaskStatusDone    = "done"
-	TaskStatusFailed  = "failed"
+	TaskStatusPending  = "pending"
+	TaskStatusRunning  = "running"
+	TaskStatusDone     = "done"
+	TaskStatusFailed   = "failed"
+	TaskStatusCanceled = "canceled"
 )
 
+// 任务优先级：数值越大越先执行，同优先级按创建时间先后
+const (
+	PriorityLow    = -10
+	PriorityNormal = 0
+	PriorityHigh   = 10
+)
+
+const (
+	defaultExportWorkers      = 2
+	defaultExportPerUserLimit = 1
+	defaultExportRetainDays   = 7
+	// progressEveryRows 每写入多少行刷新一次任务进度
+	progressEveryRows = 1000
+)
+
+var (
+	// ErrTaskFinished 任务已结束（完成/失败/已取消），无法取消
+	ErrTaskFinished = errors.New("任务已结束")
+	// ErrInvalidRequest 导出参数不合法，创建任务时直接拒绝
+	ErrInvalidRequest = errors.New("导出参数无效")
+)
+
+// invalidRequest 返回包装 ErrInvalidRequest 的错误
+func invalidRequest(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidRequest, fmt.Sprintf(format, args...))
+}
+
 // Task 描述一个导出任务的元信息（持久化到磁盘）
 type Task struct {
-	ID         string    `json:"id"`
-	Target     string    `json:"target"` // records | trajectory | both
-	OrderID    string    `json:"orderId"`
-	UasID      string    `json:"uasId"`
-	StartTime  string    `json:"startTime"`
-	EndTime    string    `json:"endTime"`
-	Format     string    `json:"format"` // xlsx | csv
-	Status     string    `json:"status"`
-	ResultFile string    `json:"resultFile"` // 本地文件路径
-	Error      string    `json:"error"`
-	CreatedAt  time.Time `json:"createdAt"`
-	FinishedAt time.Time `json:"finishedAt"`
+	ID        string `json:"id"`
+	Target    string `json:"target"` // records | trajectory | both
+	OrderID   string `json:"orderId"`
+	UasID     string `json:"uasId"`
+	StartTime string `json:"startTime"`
+	EndTime   string `json:"endTime"`
+	Format    string `json:"format"` // xlsx | csv | geojson | kml | gpx（地理格式仅用于轨迹） | parquet
+	// PartitionBy 仅 parquet 有效：day | uas，非空时结果为按分区目录打包的 zip
+	PartitionBy string    `json:"partitionBy,omitempty"`
+	User        string    `json:"user"`     // 提交者（客户端地址，或可信网关设置的 X-User-ID），用于按用户限制并发
+	Priority    int       `json:"priority"` // 越大越优先
+	Status      string    `json:"status"`
+	Progress    float64   `json:"progress"`    // 进度百分比（按预估总行数计算，完成时为 100）
+	RowsWritten int64     `json:"rowsWritten"` // 已写出的行数
+	TotalRows   int64     `json:"totalRows"`   // 开始执行时预估的总行数
+	ResultFile  string    `json:"resultFile"`  // 本地文件路径
+	Error       string    `json:"error"`
+	CreatedAt   time.Time `json:"createdAt"`
+	StartedAt   time.Time `json:"startedAt"`
+	FinishedAt  time.Time `json:"finishedAt"`
+}
+
+// TaskRequest 创建导出任务的参数
+type TaskRequest struct {
+	Target      string
+	OrderID     string
+	UasID       string
+	StartTime   string
+	EndTime     string
+	Format      string
+	PartitionBy string
+	User        string
+	Priority    int
+}
+
+// TaskFilter 任务列表过滤条件，空值表示不过滤
+type TaskFilter struct {
+	Status  string
+	Target  string
+	Format  string
+	User    string
+	OrderID string
+	UasID   string
+	Offset  int
+	Limit   int
 }
 
-// TaskManager 管理导出任务队列并执行
+// TaskManager 管理导出任务队列，由固定数量的 worker 按优先级并发执行
 type TaskManager struct {
-	mysql   *dao.MySQLDao
-	influx  *dao.InfluxDao
+	mysql   dao.ExportRepo
+	influx  dao.TelemetrySource
 	tasks   map[string]*Task
-	mu      sync.RWMutex
-	queue   chan string
-	dir     string // 存储任务元数据及输出的根目录
-	baseURL string // 用于生成下载 URL 的基路径（可为空，返回相对路径）
+	mu      sync.Mutex
+	cond    *sync.Cond                    // 有任务入队或有任务结束时唤醒 worker
+	pending []string                      // 待执行的任务 id
+	running map[string]int                // 每个用户正在执行的任务数
+	cancels map[string]context.CancelFunc // 正在执行的任务的取消函数
+	dir     string                        // 存储任务元数据及输出的根目录
+	baseURL string                        // 用于生成下载 URL 的基路径（可为空，返回相对路径）
+
+	workers      int
+	perUserLimit int
+	signKey      []byte        // 下载链接签名密钥
+	linkTTL      time.Duration // 下载链接有效期
+	onFinish     []func(Task)  // 任务结束（完成/失败/取消）时的回调
 }
 
 // NewTaskManager 创建 TaskManager，并启动后台 worker
-// dir 为任务工作目录（如果为空，使用系统临时目录下 drone_export_tasks）
-func NewTaskManager(mysql *dao.MySQLDao, influx *dao.InfluxDao, dir, baseURL string) (*TaskManager, error) {
+// c.Dir 为任务工作目录（如果为空，使用系统临时目录下 drone_export_tasks）
+func NewTaskManager(mysql dao.ExportRepo, influx dao.TelemetrySource, c config.ExportConf, baseURL string) (*TaskManager, error) {
+	dir := c.Dir
 	if dir == "" {
 		dir = filepath.Join(os.TempDir(), "drone_export_tasks")
 	}
@@ -57,30 +134,57 @@ func NewTaskManager(mysql *dao.MySQLDao, influx *dao.InfluxDao, dir, baseURL str
 		return nil, err
 	}
 	tm := &TaskManager{
-		mysql:   mysql,
-		influx:  influx,
-		tasks:   make(map[string]*Task),
-		queue:   make(chan string, 100),
-		dir:     dir,
-		baseURL: baseURL,
-	}
-	// load existing tasks metadata if any
+		mysql:        mysql,
+		influx:       influx,
+		tasks:        make(map[string]*Task),
+		running:      make(map[string]int),
+		cancels:      make(map[string]context.CancelFunc),
+		dir:          dir,
+		baseURL:      baseURL,
+		workers:      c.Workers,
+		perUserLimit: c.PerUserLimit,
+	}
+	tm.cond = sync.NewCond(&tm.mu)
+	key, err := loadSigningKey(dir, c.SigningKey)
+	if err != nil {
+		return nil, err
+	}
+	tm.signKey = key
+	tm.linkTTL = time.Duration(c.LinkTTLSeconds) * time.Second
+	if tm.linkTTL <= 0 {
+		tm.linkTTL = defaultLinkTTL
+	}
+	if tm.workers <= 0 {
+		tm.workers = defaultExportWorkers
+	}
+	if tm.perUserLimit <= 0 {
+		tm.perUserLimit = defaultExportPerUserLimit
+	}
+	retainDays := c.RetainDays
+	if retainDays <= 0 {
+		retainDays = defaultExportRetainDays
+	}
+	// load existing tasks metadata if any（中断的任务重新入队）
 	tm.loadTasksFromDisk()
 	// 启动 worker
-	go tm.worker()
-	// 启动周期性清理（默认保留7天，每24小时执行一次）
-	go tm.periodicCleaner(24*time.Hour, 7)
+	for i := 0; i < tm.workers; i++ {
+		go tm.worker()
+	}
+	// 启动周期性清理（每24小时执行一次）
+	go tm.periodicCleaner(24*time.Hour, retainDays)
 	return tm, nil
 }
 
-// CreateTask 新建并入队一个导出任务，返回 task id
-func (tm *TaskManager) CreateTask(target, orderID, uasID, startTime, endTime, format string) (string, error) {
+// CreateTask 新建并入队一个导出任务，返回 task id；队列无上限，不会阻塞。
+// 导出对象、格式或分区参数不合法时返回包装 ErrInvalidRequest 的错误
+func (tm *TaskManager) CreateTask(req TaskRequest) (string, error) {
+	target, format := req.Target, strings.ToLower(req.Format)
 	if format == "" {
 		format = "xlsx"
 	}
-	// 构造符合要求的 task id: 类型字母 + 时间戳(YYYYMMDDhhmmss) + 格式字母
+	// 构造符合要求的 task id: 类型字母 + 时间戳(YYYYMMDDhhmmss) + 格式字母 + "-" + 128 位随机串（不可猜测）
 	// 类型: records->R, trajectory->T, both->B
-	// 格式: .xlsx->X, .csv->C
+	// 格式: .xlsx->X, .csv->C, .geojson->G, .kml->K, .gpx->P, .parquet->Q
 	var prefix string
 	switch target {
 	case "records":
@@ -90,82 +194,273 @@ func (tm *TaskManager) CreateTask(target, orderID, uasID, startTime, endTime, fo
 	case "both":
 		prefix = "B"
 	default:
-		prefix = "U" // unknown
+		return "", invalidRequest("target 仅支持 records、trajectory、both")
+	}
+	if IsGeoFormat(format) && target == "records" {
+		return "", invalidRequest("records 不支持地理格式导出")
+	}
+	partitionBy := strings.ToLower(req.PartitionBy)
+	if partitionBy != "" && format != FormatParquet {
+		return "", invalidRequest("partitionBy 仅支持 parquet 格式")
+	}
+	if !IsParquetPartition(partitionBy) {
+		return "", invalidRequest("partitionBy 仅支持 day、uas")
 	}
 	var fchar string
-	if strings.ToLower(format) == "csv" {
+	switch format {
+	case "csv":
 		fchar = "C"
-	} else {
+	case FormatGeoJSON:
+		fchar = "G"
+	case FormatKML:
+		fchar = "K"
+	case FormatGPX:
+		fchar = "P"
+	case FormatParquet:
+		fchar = "Q"
+	case "xlsx":
 		fchar = "X"
+	default:
+		return "", invalidRequest("format 仅支持 xlsx、csv、geojson、kml、gpx、parquet")
 	}
 	ts := time.Now().Format("20060102150405")
-	id := prefix + ts + fchar
-	// 如果碰巧冲突（同一秒创建了同样类型和格式的任务），追加序号保证唯一
-	tm.mu.RLock()
-	_, exists := tm.tasks[id]
-	tm.mu.RUnlock()
-	if exists {
-		// 简单尝试加后缀 -1,-2 ...
-		for i := 1; ; i++ {
-			try := fmt.Sprintf("%s-%d", id, i)
-			tm.mu.RLock()
-			_, ex := tm.tasks[try]
-			tm.mu.RUnlock()
-			if !ex {
-				id = try
-				break
-			}
-		}
+	suffix, err := randomHex(16)
+	if err != nil {
+		return "", err
+	}
+	id := prefix + ts + fchar + "-" + suffix
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if _, exists := tm.tasks[id]; exists {
+		return "", fmt.Errorf("task id 冲突，请重试")
 	}
 	task := &Task{
-		ID:        id,
-		Target:    target,
-		OrderID:   orderID,
-		UasID:     uasID,
-		StartTime: startTime,
-		EndTime:   endTime,
-		Format:    format,
-		Status:    TaskStatusPending,
-		CreatedAt: time.Now(),
+		ID:          id,
+		Target:      target,
+		OrderID:     req.OrderID,
+		UasID:       req.UasID,
+		StartTime:   req.StartTime,
+		EndTime:     req.EndTime,
+		Format:      format,
+		PartitionBy: partitionBy,
+		User:        req.User,
+		Priority:    req.Priority,
+		Status:      TaskStatusPending,
+		CreatedAt:   time.Now(),
 	}
-	tm.mu.Lock()
-	tm.tasks[id] = task
-	tm.mu.Unlock()
 	if err := tm.saveTaskToDisk(task); err != nil {
 		return "", err
 	}
+	tm.tasks[id] = task
 	// enqueue
-	tm.queue <- id
+	tm.pending = append(tm.pending, id)
+	tm.cond.Broadcast()
 	return id, nil
 }
 
-// GetTask 返回任务元信息
+// OnFinish 注册任务结束（完成/失败/取消）时的回调，回调在 worker 或取消调用方的 goroutine 中执行
+func (tm *TaskManager) OnFinish(fn func(Task)) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.onFinish = append(tm.onFinish, fn)
+}
+
+// notifyFinish 以任务快照调用结束回调，调用方不得持有锁
+func (tm *TaskManager) notifyFinish(id string) {
+	tm.mu.Lock()
+	t, ok := tm.tasks[id]
+	if !ok {
+		tm.mu.Unlock()
+		return
+	}
+	snap := *t
+	fns := append([]func(Task){}, tm.onFinish...)
+	tm.mu.Unlock()
+	for _, fn := range fns {
+		fn(snap)
+	}
+}
+
+// GetTask 返回任务元信息的快照
 func (tm *TaskManager) GetTask(id string) (*Task, bool) {
-	tm.mu.RLock()
-	defer tm.mu.RUnlock()
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
 	t, ok := tm.tasks[id]
-	return t, ok
+	if !ok {
+		return nil, false
+	}
+	c := *t
+	return &c, true
 }
 
-// worker 循环处理任务
-func (tm *TaskManager) worker() {
-	for id := range tm.queue {
-		tm.runTask(id)
+// ListTasks 按创建时间倒序返回符合条件的任务快照及总数
+func (tm *TaskManager) ListTasks(f TaskFilter) ([]Task, int) {
+	tm.mu.Lock()
+	list := make([]Task, 0, len(tm.tasks))
+	for _, t := range tm.tasks {
+		if (f.Status != "" && t.Status != f.Status) ||
+			(f.Target != "" && t.Target != f.Target) ||
+			(f.Format != "" && t.Format != f.Format) ||
+			(f.User != "" && t.User != f.User) ||
+			(f.OrderID != "" && t.OrderID != f.OrderID) ||
+			(f.UasID != "" && t.UasID != f.UasID) {
+			continue
+		}
+		list = append(list, *t)
+	}
+	tm.mu.Unlock()
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].CreatedAt.Equal(list[j].CreatedAt) {
+			return list[i].CreatedAt.After(list[j].CreatedAt)
+		}
+		return list[i].ID > list[j].ID
+	})
+	total := len(list)
+	if f.Offset > 0 {
+		if f.Offset >= len(list) {
+			return []Task{}, total
+		}
+		list = list[f.Offset:]
 	}
+	if f.Limit > 0 && f.Limit < len(list) {
+		list = list[:f.Limit]
+	}
+	return list, total
 }
 
-// runTask 执行导出并更新任务状态
-func (tm *TaskManager) runTask(id string) {
+// CancelTask 取消任务：排队中的直接标记为已取消，执行中的通过 context 中止 DAO 查询
+func (tm *TaskManager) CancelTask(id string) error {
 	tm.mu.Lock()
 	task, ok := tm.tasks[id]
 	if !ok {
 		tm.mu.Unlock()
-		return
+		return os.ErrNotExist
 	}
-	task.Status = TaskStatusRunning
-	tm.saveTaskToDisk(task)
-	tm.mu.Unlock()
+	if task.Status == TaskStatusPending {
+		for i, pid := range tm.pending {
+			if pid == id {
+				tm.pending = append(tm.pending[:i], tm.pending[i+1:]...)
+				break
+			}
+		}
+		task.Status = TaskStatusCanceled
+		task.FinishedAt = time.Now()
+		err := tm.saveTaskToDisk(task)
+		tm.mu.Unlock()
+		tm.notifyFinish(id)
+		return err
+	}
+	defer tm.mu.Unlock()
+	switch task.Status {
+	case TaskStatusRunning:
+		if cancel, ok := tm.cancels[id]; ok {
+			cancel()
+		}
+		return nil
+	default:
+		return ErrTaskFinished
+	}
+}
+
+// worker 循环取出可执行的任务并执行
+func (tm *TaskManager) worker() {
+	for {
+		tm.mu.Lock()
+		var task *Task
+		for {
+			if task = tm.nextLocked(); task != nil {
+				break
+			}
+			tm.cond.Wait()
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		task.Status = TaskStatusRunning
+		task.StartedAt = time.Now()
+		tm.running[task.User]++
+		tm.cancels[task.ID] = cancel
+		_ = tm.saveTaskToDisk(task)
+		tm.mu.Unlock()
+
+		tm.runTask(ctx, task)
+		cancel()
+
+		tm.mu.Lock()
+		if tm.running[task.User]--; tm.running[task.User] <= 0 {
+			delete(tm.running, task.User)
+		}
+		delete(tm.cancels, task.ID)
+		tm.cond.Broadcast()
+		tm.mu.Unlock()
+		tm.notifyFinish(task.ID)
+	}
+}
 
+// nextLocked 取出优先级最高、且提交者未达到并发上限的任务，没有时返回 nil；调用方需持有锁
+func (tm *TaskManager) nextLocked() *Task {
+	best := -1
+	for i, id := range tm.pending {
+		t := tm.tasks[id]
+		if tm.running[t.User] >= tm.perUserLimit {
+			continue
+		}
+		if best < 0 {
+			best = i
+			continue
+		}
+		b := tm.tasks[tm.pending[best]]
+		if t.Priority > b.Priority || (t.Priority == b.Priority && t.CreatedAt.Before(b.CreatedAt)) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return nil
+	}
+	task := tm.tasks[tm.pending[best]]
+	tm.pending = append(tm.pending[:best], tm.pending[best+1:]...)
+	return task
+}
+
+// setProgress 更新任务进度，完成前最多显示 99%
+func (tm *TaskManager) setProgress(task *Task, rows, total int64) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	task.RowsWritten = rows
+	if total > 0 {
+		p := float64(rows) * 100 / float64(total)
+		if p > 99 {
+			p = 99
+		}
+		task.Progress = float64(int(p*10)) / 10
+	}
+}
+
+// countRows 预估任务需要写出的总行数，失败时返回 0（仅显示行数不显示百分比）
+func (tm *TaskManager) countRows(ctx context.Context, task *Task, st, ed time.Time) int64 {
+	if tm.mysql == nil {
+		return 0
+	}
+	var total int64
+	if task.Target == "records" || task.Target == "both" {
+		n, err := tm.mysql.CountFlightRecords(ctx, task.OrderID, task.UasID, st, ed)
+		if err != nil {
+			return 0
+		}
+		total += n
+	}
+	if task.Target == "trajectory" || task.Target == "both" {
+		n, err := tm.mysql.CountTrackPoints(ctx, task.OrderID, task.UasID, st, ed)
+		if err != nil {
+			return 0
+		}
+		total += n
+	}
+	return total
+}
+
+// runTask 执行导出并更新任务状态，ctx 取消时任务标记为已取消并删除中间产物
+func (tm *TaskManager) runTask(ctx context.Context, task *Task) {
+	id := task.ID
 	// 生成任务目录
 	taskDir := filepath.Join(tm.dir, id)
 	_ = os.MkdirAll(taskDir, 0o755)
@@ -175,8 +470,21 @@ func (tm *TaskManager) runTask(id string) {
 		recExt = "csv"
 		trajExt = "csv"
 	}
+	if IsGeoFormat(task.Format) {
+		trajExt = task.Format
+	}
+	if task.Format == FormatParquet {
+		recExt = FormatParquet
+		trajExt = FormatParquet
+	}
 	recordFile := filepath.Join(taskDir, "flightRecord."+recExt)
 	trajFile := filepath.Join(taskDir, "flightTrajectory."+trajExt)
+	// parquet 分区导出时各自输出为目录，最终整体打包
+	partitionDir := filepath.Join(taskDir, "parquet")
+	if task.PartitionBy != "" {
+		recordFile = filepath.Join(partitionDir, "flightRecord")
+		trajFile = filepath.Join(partitionDir, "flightTrajectory")
+	}
 
 	// parse times
 	var err error
@@ -185,16 +493,31 @@ func (tm *TaskManager) runTask(id string) {
 	st_record := st.Add(8 * time.Hour)
 	ed_record := ed.Add(8 * time.Hour)
 
+	// 进度：预估总行数，每写出 progressEveryRows 行刷新一次
+	total := tm.countRows(ctx, task, st, ed)
+	tm.mu.Lock()
+	task.TotalRows = total
+	tm.mu.Unlock()
+	var rows atomic.Int64
+	onRow := func() {
+		if n := rows.Add(1); n%progressEveryRows == 0 {
+			tm.setProgress(task, n, total)
+		}
+	}
+
 	// helper local functions
 	exportRecords := func() error {
 		if tm.mysql == nil {
 			return fmt.Errorf("mysql not configured")
 		}
 		// 使用流式导出以减少内存占用
+		if task.Format == FormatParquet {
+			return ExportFlightRecordsToParquet(ctx, tm.mysql, task.OrderID, task.UasID, st, ed, task.PartitionBy, recordFile, onRow)
+		}
 		if task.Format == "csv" {
-			return tm.mysql.ExportFlightRecordsToCSVStream(task.OrderID, task.UasID, st_record.Format("2006-01-02 15:04:05"), ed_record.Format("2006-01-02 15:04:05"), recordFile)
+			return tm.mysql.ExportFlightRecordsToCSVStream(ctx, task.OrderID, task.UasID, st_record.Format("2006-01-02 15:04:05"), ed_record.Format("2006-01-02 15:04:05"), recordFile, onRow)
 		}
-		return tm.mysql.ExportFlightRecordsToExcelStream(task.OrderID, task.UasID, st_record.Format("2006-01-02 15:04:05"), ed_record.Format("2006-01-02 15:04:05"), recordFile)
+		return tm.mysql.ExportFlightRecordsToExcelStream(ctx, task.OrderID, task.UasID, st_record.Format("2006-01-02 15:04:05"), ed_record.Format("2006-01-02 15:04:05"), recordFile, onRow)
 	}
 
 	exportTrajectory := func() error {
@@ -202,49 +525,71 @@ func (tm *TaskManager) runTask(id string) {
 			return fmt.Errorf("mysql not configured")
 		}
 		// 使用流式导出轨迹点
+		if task.Format == FormatParquet {
+			return ExportTrackPointsToParquet(ctx, tm.mysql, task.OrderID, task.UasID, st, ed, task.PartitionBy, trajFile, onRow)
+		}
+		if IsGeoFormat(task.Format) {
+			return ExportTrackPointsToGeo(ctx, tm.mysql, task.Format, task.OrderID, task.UasID, st, ed, trajFile, onRow)
+		}
 		if task.Format == "csv" {
-			return tm.mysql.ExportTrackPointsToCSVStream(task.StartTime, task.EndTime, task.OrderID, task.UasID, trajFile)
+			return tm.mysql.ExportTrackPointsToCSVStream(ctx, task.StartTime, task.EndTime, task.OrderID, task.UasID, trajFile, onRow)
 		}
-		return tm.mysql.ExportTrackPointsToExcelStream(task.StartTime, task.EndTime, task.OrderID, task.UasID, trajFile)
+		return tm.mysql.ExportTrackPointsToExcelStream(ctx, task.StartTime, task.EndTime, task.OrderID, task.UasID, trajFile, onRow)
 	}
 
+	var resultFile string
 	switch task.Target {
 	case "records":
 		err = exportRecords()
 		if err == nil {
-			task.ResultFile = recordFile
+			resultFile = recordFile
 		}
 	case "trajectory":
 		err = exportTrajectory()
 		if err == nil {
-			task.ResultFile = trajFile
+			resultFile = trajFile
 		}
 	case "both":
 		// generate both and zip
 		if err = exportRecords(); err == nil {
-			if err = exportTrajectory(); err == nil {
+			if err = exportTrajectory(); err == nil && task.PartitionBy == "" {
 				zipPath := filepath.Join(taskDir, "flight_export.zip")
 				if e := CreateZip([]string{recordFile, trajFile}, zipPath); e != nil {
 					err = e
 				} else {
-					task.ResultFile = zipPath
+					resultFile = zipPath
 				}
 			}
 		}
 	default:
 		err = fmt.Errorf("unknown target %s", task.Target)
 	}
+	if err == nil && task.PartitionBy != "" {
+		zipPath := filepath.Join(taskDir, "flight_export.zip")
+		if err = CreateZipDir(partitionDir, zipPath); err == nil {
+			resultFile = zipPath
+			_ = os.RemoveAll(partitionDir)
+		}
+	}
 
 	tm.mu.Lock()
-	if err != nil {
+	defer tm.mu.Unlock()
+	task.RowsWritten = rows.Load()
+	switch {
+	case ctx.Err() != nil:
+		// 被取消：删除不完整的产物
+		task.Status = TaskStatusCanceled
+		_ = os.RemoveAll(taskDir)
+	case err != nil:
 		task.Status = TaskStatusFailed
 		task.Error = err.Error()
-	} else {
+	default:
 		task.Status = TaskStatusDone
+		task.ResultFile = resultFile
+		task.Progress = 100
 	}
 	task.FinishedAt = time.Now()
 	_ = tm.saveTaskToDisk(task)
-	tm.mu.Unlock()
 }
 
 // storage helpers
@@ -279,9 +624,14 @@ func (tm *TaskManager) loadTasksFromDisk() {
 			continue
 		}
 		tm.tasks[t.ID] = &t
-		// 若之前是 running 或 pending，重新入队 pending
+		// 若之前是 running（服务中断）或 pending，重置进度后重新入队
 		if t.Status == TaskStatusPending || t.Status == TaskStatusRunning {
-			tm.queue <- t.ID
+			t.Status = TaskStatusPending
+			t.Progress, t.RowsWritten, t.TotalRows = 0, 0, 0
+			t.StartedAt = time.Time{}
+			_ = os.RemoveAll(filepath.Join(tm.dir, t.ID))
+			_ = tm.saveTaskToDisk(&t)
+			tm.pending = append(tm.pending, t.ID)
 		}
 	}
 }
@@ -295,13 +645,14 @@ func (tm *TaskManager) periodicCleaner(interval time.Duration, retainDays int) {
 	}
 }
 
-// CleanOlderThan 删除 finish 时间早于 retainDays 天的任务和产物
+// CleanOlderThan 删除 finish 时间早于 retainDays 天的已结束任务和产物
 func (tm *TaskManager) CleanOlderThan(retainDays int) {
 	cutoff := time.Now().Add(-time.Duration(retainDays) * 24 * time.Hour)
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
 	for id, t := range tm.tasks {
-		if (t.Status == TaskStatusDone || t.Status == TaskStatusFailed) && !t.FinishedAt.IsZero() && t.FinishedAt.Before(cutoff) {
+		finished := t.Status == TaskStatusDone || t.Status == TaskStatusFailed || t.Status == TaskStatusCanceled
+		if finished && !t.FinishedAt.IsZero() && t.FinishedAt.Before(cutoff) {
 			// 删除文件夹和元数据
 			taskDir := filepath.Join(tm.dir, id)
 			_ = os.RemoveAll(taskDir)
@@ -311,12 +662,16 @@ func (tm *TaskManager) CleanOlderThan(retainDays int) {
 	}
 }
 
-// StatusURL 返回任务状态查询的完整或相对 URL
+// StatusURL 返回任务状态查询的相对 URL，带任务访问令牌
 func (tm *TaskManager) StatusURL(id string) string {
-	return "/record/exportStatus?id=" + id
+	v := url.Values{}
+	v.Set("id", id)
+	v.Set("token", tm.OwnerToken(id))
+	return "/record/exportStatus?" + v.Encode()
 }
 
-// DownloadURL 返回任务下载的完整或相对 URL
+// DownloadURL 返回任务下载的相对 URL，带 HMAC 签名及过期时间
 func (tm *TaskManager) DownloadURL(id string) string {
-	return "/record/exportDownload?id=" + id
+	q, _ := tm.SignDownload(id)
+	return "/record/exportDownload?" + q
 }
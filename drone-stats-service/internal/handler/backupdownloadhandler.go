@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"drone-stats-service/internal/audit"
+	"drone-stats-service/internal/backup"
+	"drone-stats-service/internal/svc"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/rest/pathvar"
+)
+
+// 下载备份，返回包含备份目录全部文件（已压缩的 SQL/line-protocol、manifest.json 等）的 tar 包；
+// chain=true 时包含恢复该备份所需的整条备份链，解包后可直接用于 restore 子命令
+// GET /backup/:id/download?chain=true，需携带 X-Admin-Token
+func BackupDownloadHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := audit.CheckAdminToken(svcCtx.Config.Audit.AdminToken, r.Header.Get("X-Admin-Token")); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		id := pathvar.Vars(r)["id"]
+		a, err := svcCtx.Backup.OpenArchive(id, r.URL.Query().Get("chain") == "true")
+		switch {
+		case errors.Is(err, backup.ErrBackupNotFound):
+			http.Error(w, "备份不存在", http.StatusNotFound)
+			return
+		case errors.Is(err, backup.ErrBackupRunning):
+			http.Error(w, "备份尚未完成", http.StatusConflict)
+			return
+		case err != nil:
+			http.Error(w, "打开备份失败: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer a.Close()
+
+		w.Header().Set("Content-Type", "application/x-tar")
+		w.Header().Set("Content-Disposition", "attachment; filename="+a.Name)
+		n, err := a.WriteTo(w)
+		if err != nil {
+			logx.WithContext(r.Context()).Errorf("下载备份 %s 中断: 已发送 %d 字节: %v", id, n, err)
+			return
+		}
+		logx.WithContext(r.Context()).Infof("下载备份 %s: %d 字节 user=%s", a.Name, n, audit.Actor(r.Context()))
+	}
+}
@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"net/http"
+
+	"drone-stats-service/internal/logic"
+	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/types"
+
+	"github.com/zeromicro/go-zero/rest/httpx"
+)
+
+func BackupListHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req types.BackupListReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+			return
+		}
+
+		l := logic.NewBackupListLogic(r.Context(), svcCtx)
+		resp, err := l.BackupList(&req)
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+		} else {
+			httpx.OkJsonCtx(r.Context(), w, resp)
+		}
+	}
+}
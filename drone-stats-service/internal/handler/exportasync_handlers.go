@@ -2,16 +2,29 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
+	"drone-stats-service/internal/audit"
+	"drone-stats-service/internal/export"
+	"drone-stats-service/internal/model"
 	"drone-stats-service/internal/svc"
 	"drone-stats-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/rest/httpx"
 )
 
 // 创建导出任务（异步）
 // POST /record/exportAsync
-// body: { startTime, endTime, OrderID, uasID, target }
+// body: { startTime, endTime, OrderID, uasID, target, format, partitionBy, priority }
+// 提交者用于按用户限制并发：默认为 TCP 对端地址，Export.TrustUserHeader 开启时取请求头 X-User-ID
 func CreateExportTaskHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var raw map[string]interface{}
@@ -41,26 +54,44 @@ func CreateExportTaskHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 		if v, ok := raw["format"].(string); ok && v != "" {
 			format = v
 		}
+		partitionBy := ""
+		if v, ok := raw["partitionBy"].(string); ok {
+			partitionBy = v // 仅 parquet：day | uas
+		}
+		priority, ok := parseExportPriority(raw["priority"])
+		if !ok {
+			http.Error(w, "priority 仅支持 high、normal、low 或整数", http.StatusBadRequest)
+			return
+		}
 		if svcCtx.TaskManager == nil {
 			http.Error(w, "TaskManager 未启用", http.StatusInternalServerError)
 			return
 		}
-		id, err := svcCtx.TaskManager.CreateTask(target, req.OrderID, req.UasID, req.StartTime, req.EndTime, format)
+		id, err := svcCtx.TaskManager.CreateTask(export.TaskRequest{
+			Target:      target,
+			OrderID:     req.OrderID,
+			UasID:       req.UasID,
+			StartTime:   req.StartTime,
+			EndTime:     req.EndTime,
+			Format:      format,
+			PartitionBy: partitionBy,
+			User:        exportSubmitter(svcCtx, r),
+			Priority:    priority,
+		})
 		if err != nil {
-			http.Error(w, "创建任务失败: "+err.Error(), http.StatusInternalServerError)
+			if errors.Is(err, export.ErrInvalidRequest) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else {
+				http.Error(w, "创建任务失败: "+err.Error(), http.StatusInternalServerError)
+			}
 			return
 		}
-		// 返回完整下载/状态 URL（TaskManager 负责拼接 baseURL）
-		statusUrl := "/record/exportStatus?id=" + id
-		downloadUrl := "/record/exportDownload?id=" + id
-		if svcCtx.TaskManager != nil {
-			statusUrl = svcCtx.TaskManager.StatusURL(id)
-			downloadUrl = svcCtx.TaskManager.DownloadURL(id)
-		}
+		// 状态 URL 带任务访问令牌，只有持有令牌的提交者（或管理员）能查询状态、取消任务
 		resp := map[string]string{
 			"taskId":      id,
-			"statusUrl":   statusUrl,
-			"downloadUrl": downloadUrl,
+			"token":       svcCtx.TaskManager.OwnerToken(id),
+			"statusUrl":   svcCtx.TaskManager.StatusURL(id),
+			"downloadUrl": svcCtx.TaskManager.DownloadURL(id),
 		}
 		// 在客户端排序/显示中包含createdAt字段
 		if t, ok := svcCtx.TaskManager.GetTask(id); ok {
@@ -73,8 +104,8 @@ func CreateExportTaskHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 	}
 }
 
-// 查询导出任务状态
-// GET /record/exportStatus?id=xxx
+// 查询导出任务状态，已完成的任务附带新签发的下载链接；需出示任务访问令牌或管理员令牌
+// GET /record/exportStatus?id=xxx&token=xxx
 func ExportStatusHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id := r.URL.Query().Get("id")
@@ -86,21 +117,36 @@ func ExportStatusHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 			http.Error(w, "TaskManager 未启用", http.StatusInternalServerError)
 			return
 		}
+		if !authorizeExportTask(svcCtx, r, id) {
+			rejectExportTask(w, r, id)
+			return
+		}
 		t, ok := svcCtx.TaskManager.GetTask(id)
 		if !ok {
 			http.Error(w, "任务未找到", http.StatusNotFound)
 			return
 		}
+		resp := struct {
+			*export.Task
+			DownloadURL   string `json:"downloadUrl,omitempty"`
+			LinkExpiresAt string `json:"linkExpiresAt,omitempty"`
+		}{Task: t}
+		if t.Status == export.TaskStatusDone {
+			q, exp := svcCtx.TaskManager.SignDownload(id)
+			resp.DownloadURL = "/record/exportDownload?" + q
+			resp.LinkExpiresAt = exp.Format(time.RFC3339)
+		}
 		w.Header().Set("Content-Type", "application/json")
-		_ = json.NewEncoder(w).Encode(t)
+		_ = json.NewEncoder(w).Encode(resp)
 	}
 }
 
-// 下载导出结果
-// GET /record/exportDownload?id=xxx
+// 下载导出结果，需携带 exportStatus/exportAsync 返回的签名链接参数，支持 Range 断点续传
+// GET /record/exportDownload?id=xxx&expires=unix&sig=hex
 func ExportDownloadHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		id := r.URL.Query().Get("id")
+		q := r.URL.Query()
+		id := q.Get("id")
 		if id == "" {
 			http.Error(w, "missing id", http.StatusBadRequest)
 			return
@@ -109,16 +155,250 @@ func ExportDownloadHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 			http.Error(w, "TaskManager 未启用", http.StatusInternalServerError)
 			return
 		}
+		if err := svcCtx.TaskManager.VerifyDownload(id, q.Get("expires"), q.Get("sig")); err != nil {
+			logx.WithContext(r.Context()).Infof("拒绝下载导出结果: task=%s user=%s err=%v", id, audit.Actor(r.Context()), err)
+			if errors.Is(err, export.ErrLinkExpired) {
+				http.Error(w, err.Error(), http.StatusGone)
+			} else {
+				http.Error(w, err.Error(), http.StatusForbidden)
+			}
+			return
+		}
 		t, ok := svcCtx.TaskManager.GetTask(id)
 		if !ok {
 			http.Error(w, "任务未找到", http.StatusNotFound)
 			return
 		}
-		if t.Status != "done" {
+		if t.Status != export.TaskStatusDone {
 			http.Error(w, "任务未完成", http.StatusBadRequest)
 			return
 		}
-		// 返回文件
-		http.ServeFile(w, r, t.ResultFile)
+		f, err := os.Open(t.ResultFile)
+		if err != nil {
+			http.Error(w, "导出文件不存在", http.StatusNotFound)
+			return
+		}
+		defer f.Close()
+		fi, err := f.Stat()
+		if err != nil {
+			http.Error(w, "读取导出文件失败: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		name := filepath.Base(t.ResultFile)
+		w.Header().Set("Content-Disposition", "attachment; filename="+name)
+		// ServeContent 处理 Range / If-Range / HEAD，便于大文件断点续传
+		cw := &countingResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		http.ServeContent(cw, r, name, fi.ModTime(), f)
+
+		if svcCtx.Exports != nil {
+			rec := model.ExportDownload{
+				TaskID:       id,
+				FileName:     name,
+				User:         audit.Actor(r.Context()),
+				RemoteAddr:   httpx.GetRemoteAddr(r),
+				UserAgent:    truncateHeader(r.UserAgent(), 255),
+				RangeHeader:  truncateHeader(r.Header.Get("Range"), 128),
+				Status:       cw.status,
+				BytesSent:    cw.bytes,
+				DownloadedAt: time.Now(),
+			}
+			if err := svcCtx.Exports.SaveExportDownload(rec); err != nil {
+				logx.WithContext(r.Context()).Errorf("记录导出下载失败: %v", err)
+			}
+		}
 	}
 }
+
+// 导出结果下载记录，需管理员令牌（X-Admin-Token）
+// GET /record/exportDownloads?id=&user=&limit=
+func ExportDownloadsHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if svcCtx.Exports == nil {
+			http.Error(w, "MySQL 未配置", http.StatusInternalServerError)
+			return
+		}
+		if err := audit.CheckAdminToken(svcCtx.Config.Audit.AdminToken, r.Header.Get("X-Admin-Token")); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		q := r.URL.Query()
+		limit := 100
+		if v := q.Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				http.Error(w, "limit 参数错误", http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+		list, err := svcCtx.Exports.GetExportDownloads(q.Get("id"), q.Get("user"), limit)
+		if err != nil {
+			http.Error(w, "查询下载记录失败: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if list == nil {
+			list = []model.ExportDownload{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(list)
+	}
+}
+
+// countingResponseWriter 记录响应状态码与写出的字节数
+type countingResponseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (c *countingResponseWriter) WriteHeader(code int) {
+	c.status = code
+	c.ResponseWriter.WriteHeader(code)
+}
+
+func (c *countingResponseWriter) Write(b []byte) (int, error) {
+	n, err := c.ResponseWriter.Write(b)
+	c.bytes += int64(n)
+	return n, err
+}
+
+// truncateHeader 截断请求头以适应列长度
+func truncateHeader(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// 导出任务列表，需管理员令牌（X-Admin-Token）
+// GET /record/exportTasks?status=&target=&format=&user=&OrderID=&uasID=&offset=&limit=
+// 按创建时间倒序返回，limit 默认 50
+func ListExportTasksHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if svcCtx.TaskManager == nil {
+			http.Error(w, "TaskManager 未启用", http.StatusInternalServerError)
+			return
+		}
+		if err := audit.CheckAdminToken(svcCtx.Config.Audit.AdminToken, r.Header.Get("X-Admin-Token")); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		q := r.URL.Query()
+		f := export.TaskFilter{
+			Status:  q.Get("status"),
+			Target:  q.Get("target"),
+			Format:  strings.ToLower(q.Get("format")),
+			User:    q.Get("user"),
+			OrderID: q.Get("OrderID"),
+			UasID:   q.Get("uasID"),
+			Limit:   50,
+		}
+		if v := q.Get("offset"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				http.Error(w, "offset 参数错误", http.StatusBadRequest)
+				return
+			}
+			f.Offset = n
+		}
+		if v := q.Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				http.Error(w, "limit 参数错误", http.StatusBadRequest)
+				return
+			}
+			f.Limit = n
+		}
+		tasks, total := svcCtx.TaskManager.ListTasks(f)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"total": total,
+			"tasks": tasks,
+		})
+	}
+}
+
+// 取消导出任务：排队中的直接取消，执行中的中止查询并删除中间产物；需出示任务访问令牌或管理员令牌
+// DELETE /record/exportTasks?id=xxx&token=xxx
+func CancelExportTaskHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.URL.Query().Get("id")
+		if id == "" {
+			http.Error(w, "missing id", http.StatusBadRequest)
+			return
+		}
+		if svcCtx.TaskManager == nil {
+			http.Error(w, "TaskManager 未启用", http.StatusInternalServerError)
+			return
+		}
+		if !authorizeExportTask(svcCtx, r, id) {
+			rejectExportTask(w, r, id)
+			return
+		}
+		if err := svcCtx.TaskManager.CancelTask(id); err != nil {
+			switch {
+			case errors.Is(err, os.ErrNotExist):
+				http.Error(w, "任务未找到", http.StatusNotFound)
+			case errors.Is(err, export.ErrTaskFinished):
+				http.Error(w, err.Error(), http.StatusConflict)
+			default:
+				http.Error(w, "取消任务失败: "+err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+		t, _ := svcCtx.TaskManager.GetTask(id)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(t)
+	}
+}
+
+// authorizeExportTask 校验调用方对任务的访问权：任务访问令牌（token 参数或 X-Task-Token 请求头）或管理员令牌
+func authorizeExportTask(svcCtx *svc.ServiceContext, r *http.Request, id string) bool {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		token = r.Header.Get("X-Task-Token")
+	}
+	if svcCtx.TaskManager.VerifyOwner(id, token) {
+		return true
+	}
+	return audit.CheckAdminToken(svcCtx.Config.Audit.AdminToken, r.Header.Get("X-Admin-Token")) == nil
+}
+
+// rejectExportTask 拒绝无权访问任务的请求，不区分任务是否存在
+func rejectExportTask(w http.ResponseWriter, r *http.Request, id string) {
+	logx.WithContext(r.Context()).Infof("拒绝访问导出任务: task=%s user=%s", id, audit.Actor(r.Context()))
+	http.Error(w, "无权访问该任务", http.StatusForbidden)
+}
+
+// exportSubmitter 返回导出任务的提交者，仅在配置信任网关时采用客户端可设置的 X-User-ID
+func exportSubmitter(svcCtx *svc.ServiceContext, r *http.Request) string {
+	return audit.RequestActor(r, svcCtx.Config.Export.TrustUserHeader)
+}
+
+// parseExportPriority 解析优先级：high | normal | low 或整数，缺省为 normal；
+// 整数限制在 PriorityLow..PriorityHigh 之间，避免调用方以极大的优先级插队
+func parseExportPriority(v interface{}) (int, bool) {
+	switch p := v.(type) {
+	case nil:
+		return export.PriorityNormal, true
+	case float64:
+		return clampExportPriority(p), true
+	case string:
+		switch strings.ToLower(p) {
+		case "", "normal":
+			return export.PriorityNormal, true
+		case "high":
+			return export.PriorityHigh, true
+		case "low":
+			return export.PriorityLow, true
+		}
+		if n, err := strconv.Atoi(p); err == nil {
+			return clampExportPriority(float64(n)), true
+		}
+	}
+	return 0, false
+}
+
+func clampExportPriority(p float64) int {
+	return int(math.Max(export.PriorityLow, math.Min(p, export.PriorityHigh)))
+}
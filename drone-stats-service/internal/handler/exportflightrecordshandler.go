@@ -17,6 +17,7 @@ import (
 
 // 支持导出三类：records（飞行主表 flight_records）、trajectory（轨迹表 flight_track_points）、both（两者一起，返回 zip）
 // 导出优先使用 Influx（仅当时间跨度 <= 72 小时）导出 records；trajectory 始终从 MySQL 导出。
+// format 支持 xlsx、csv；轨迹另支持 geojson、kml、gpx，此时 both 中的 records 仍为 xlsx。
 func ExportFlightRecordsHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// 读取 body
@@ -66,6 +67,13 @@ func ExportFlightRecordsHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 			recExt = "csv"
 			trajExt = "csv"
 		}
+		if export.IsGeoFormat(format) {
+			if target == "records" {
+				http.Error(w, "records 不支持地理格式导出", http.StatusBadRequest)
+				return
+			}
+			trajExt = format
+		}
 		recordFile := filepath.Join(tmpDir, "flightRecord."+recExt)
 		trajFile := filepath.Join(tmpDir, "flightTrajectory."+trajExt)
 		var toServePath string
@@ -73,7 +81,7 @@ func ExportFlightRecordsHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 
 		// helper: export records（始终使用 MySQL）
 		exportRecords := func() error {
-			if svcCtx.MySQLDao == nil {
+			if svcCtx.Exports == nil {
 				return fmtError("MySQL 未配置，无法导出")
 			}
 			// MySQL 查询需要格式化时间
@@ -81,19 +89,22 @@ func ExportFlightRecordsHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 			ed := end.Add(8 * time.Hour).Format("2006-01-02 15:04:05")
 			// 使用 MySQL 的查询/流式接口导出 records
 			if format == "csv" {
-				return svcCtx.MySQLDao.ExportFlightRecordsToCSVStream(req.OrderID, req.UasID, st, ed, recordFile)
+				return svcCtx.Exports.ExportFlightRecordsToCSVStream(r.Context(), req.OrderID, req.UasID, st, ed, recordFile, nil)
 			}
-			return svcCtx.MySQLDao.ExportFlightRecordsToExcelStream(req.OrderID, req.UasID, st, ed, recordFile)
+			return svcCtx.Exports.ExportFlightRecordsToExcelStream(r.Context(), req.OrderID, req.UasID, st, ed, recordFile, nil)
 		}
 
 		// helper: export trajectory (always from MySQL)
 		exportTrajectory := func() error {
-			if svcCtx.MySQLDao == nil {
+			if svcCtx.Exports == nil {
 				return fmtError("MySQL 未配置，无法导出轨迹")
 			}
+			if export.IsGeoFormat(format) {
+				return export.ExportTrackPointsToGeo(r.Context(), svcCtx.Exports, format, req.OrderID, req.UasID, start, end, trajFile, nil)
+			}
 			st := start.Format("2006-01-02 15:04:05")
 			ed := end.Format("2006-01-02 15:04:05")
-			pts, err := svcCtx.MySQLDao.QueryTrackPoints(st, ed, req.OrderID)
+			pts, err := svcCtx.TrackPoints.QueryTrackPoints(st, ed, req.OrderID)
 			if err != nil {
 				return err
 			}
@@ -153,6 +164,8 @@ func ExportFlightRecordsHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 			w.Header().Set("Content-Type", "application/zip")
 		} else if strings.HasSuffix(serveName, ".csv") {
 			w.Header().Set("Content-Type", "text/csv")
+		} else if export.IsGeoFormat(format) {
+			w.Header().Set("Content-Type", export.GeoContentType(format))
 		} else {
 			w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
 		}
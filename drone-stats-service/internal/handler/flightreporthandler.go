@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"time"
+
+	"drone-stats-service/internal/logic"
+	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/types"
+
+	"github.com/zeromicro/go-zero/rest/httpx"
+)
+
+// 单架次 PDF 报告
+// GET /record/flightReport?id= 或 ?orderID=
+func FlightReportHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req types.FlightReportReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+			return
+		}
+
+		l := logic.NewFlightReportLogic(r.Context(), svcCtx)
+		data, name, err := l.FlightReport(&req)
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", "attachment; filename="+name)
+		http.ServeContent(w, r, name, time.Now(), bytes.NewReader(data))
+	}
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"drone-stats-service/internal/audit"
+	"drone-stats-service/internal/importer"
+	"drone-stats-service/internal/svc"
+)
+
+// maxPayloadImportBytes 导入文件大小上限
+const maxPayloadImportBytes = 20 << 20
+
+// 批量导入载货量与票数
+// POST /record/payloadImport  multipart/form-data
+// file: xlsx/csv，列 orderID（或 uasID + time）、payload（kg）、expressCount
+// dryRun: 默认 true，仅返回匹配预览；为 false 时在一个事务内应用所有 update 行
+// toleranceSeconds: 按时间匹配架次的容差，默认 300
+// overwrite: 为 true 时覆盖已录入的不同值，否则这些行为 conflict 不更新
+func PayloadImportHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if svcCtx.Payloads == nil {
+			http.Error(w, "关系库未配置", http.StatusInternalServerError)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxPayloadImportBytes)
+		if err := r.ParseMultipartForm(maxPayloadImportBytes); err != nil {
+			http.Error(w, "请求参数错误: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		file, fh, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "缺少上传文件 file", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+
+		dryRun := true
+		if v := r.FormValue("dryRun"); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				http.Error(w, "dryRun 参数错误", http.StatusBadRequest)
+				return
+			}
+			dryRun = b
+		}
+		opts := importer.Options{Tolerance: importer.DefaultTolerance}
+		if v := r.FormValue("toleranceSeconds"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 || n > 86400 {
+				http.Error(w, "toleranceSeconds 需为 1-86400 的整数", http.StatusBadRequest)
+				return
+			}
+			opts.Tolerance = time.Duration(n) * time.Second
+		}
+		if v := r.FormValue("overwrite"); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				http.Error(w, "overwrite 参数错误", http.StatusBadRequest)
+				return
+			}
+			opts.Overwrite = b
+		}
+
+		rows, err := importer.ParseFile(file, fh.Filename, svcCtx.Payloads.Location())
+		if err != nil {
+			http.Error(w, "解析文件失败: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		res, err := importer.Plan(svcCtx.Payloads, rows, opts)
+		if err != nil {
+			http.Error(w, "匹配飞行记录失败: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		res.DryRun = dryRun
+		if !dryRun {
+			if err := importer.Apply(svcCtx.Payloads, res, audit.Actor(r.Context())); err != nil {
+				http.Error(w, "导入失败，已全部回滚: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(res)
+	}
+}
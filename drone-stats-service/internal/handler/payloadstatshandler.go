@@ -3,6 +3,7 @@ package handler
 import (
 	"net/http"
 
+	"drone-stats-service/internal/logic"
 	"drone-stats-service/internal/svc"
 	"drone-stats-service/internal/types"
 
@@ -11,7 +12,16 @@ import (
 
 func PayloadStatsHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		yearStats, monthStats, dayStats, err := svcCtx.MySQLDao.GetPayloadStats()
+		var req types.StatsQueryReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+			return
+		}
+		if !logic.IsLegacyStatsReq(&req) {
+			serveStatsSeries(w, r, svcCtx, &req, logic.PayloadStatsMetrics)
+			return
+		}
+		yearStats, monthStats, dayStats, err := svcCtx.Records.GetPayloadStats()
 		if err != nil {
 			httpx.Error(w, err)
 			return
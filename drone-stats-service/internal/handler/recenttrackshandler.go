@@ -2,8 +2,8 @@ package handler
 
 import (
 	"net/http"
-	"strconv"
 
+	"drone-stats-service/internal/logic"
 	"drone-stats-service/internal/svc"
 	"drone-stats-service/internal/types"
 
@@ -12,69 +12,18 @@ import (
 
 func RecentTracksHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// 支持 ?orderID=xxx 查询指定轨迹，否则查最近n条
-		orderID := r.URL.Query().Get("orderID")
-		var orderIDs []string
-
-		if orderID != "" {
-			orderIDs = []string{orderID}
-		} else {
-			n := 3
-			if val := r.URL.Query().Get("n"); val != "" {
-				if num, err := strconv.Atoi(val); err == nil && num > 0 {
-					n = num
-				}
-			}
-			// 查询最近n条飞行记录
-			records, err := svcCtx.MySQLDao.QueryFlightRecords("", "", "", "")
-			if err != nil {
-				httpx.Error(w, err)
-				return
-			}
-			for i := 0; i < n && i < len(records); i++ {
-				if oid, ok := records[i]["OrderID"].(string); ok {
-					orderIDs = append(orderIDs, oid)
-				}
-			}
+		var req types.RecentTracksReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+			return
 		}
 
-		var allPoints []types.TrackPoints
-		for _, oid := range orderIDs {
-			points, err := svcCtx.MySQLDao.GetTrackPointsByRecordId(oid)
-			if err != nil {
-				httpx.Error(w, err)
-				return
-			}
-			for _, pt := range points {
-				allPoints = append(allPoints, types.TrackPoints{
-					OrderID:      pt["orderID"].(string),
-					FlightStatus: pt["flightStatus"].(string),
-					TimeStamp:    pt["timeStamp"].(string),
-					Longitude:    pt["longitude"].(int64),
-					Latitude:     pt["latitude"].(int64),
-					HeightType:   pt["heightType"].(int),
-					Height:       pt["height"].(int),
-					Altitude:     pt["altitude"].(int),
-					VS:           pt["VS"].(int),
-					GS:           pt["GS"].(int),
-					Course:       pt["course"].(int),
-					SOC:          pt["SOC"].(int),
-					RM:           pt["RM"].(int),
-					Voltage:      pt["voltage"].(int),
-					Current:      pt["current"].(int),
-					WindSpeed:    pt["windSpeed"].(int),
-					WindDirect:   pt["windDirect"].(int),
-					Temperture:   pt["temperture"].(int),
-					Humidity:     pt["humidity"].(int),
-				})
-			}
-		}
-		if allPoints == nil {
-			allPoints = []types.TrackPoints{}
-		}
-		resp := types.TrackResponse{
-			Track: allPoints,
+		l := logic.NewRecentTracksLogic(r.Context(), svcCtx)
+		resp, err := l.RecentTracks(&req)
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+		} else {
+			httpx.OkJsonCtx(r.Context(), w, resp)
 		}
-		httpx.OkJson(w, resp)
 	}
 }
@@ -14,6 +14,91 @@ import (
 func RegisterHandlers(server *rest.Server, serverCtx *svc.ServiceContext) {
 	server.AddRoutes(
 		[]rest.Route{
+			{
+				Method:  http.MethodGet,
+				Path:    "/audit/logs",
+				Handler: AuditLogsHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/audit/logs/:id/revert",
+				Handler: RevertAuditHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodDelete,
+				Path:    "/backup/:id",
+				Handler: DeleteBackupHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/backup/:id/download",
+				Handler: BackupDownloadHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/backup/list",
+				Handler: BackupListHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/backup/status",
+				Handler: BackupStatusHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/backup/trigger",
+				Handler: TriggerBackupHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/health",
+				Handler: HealthHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/maintenance/plan",
+				Handler: MaintenancePlanHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/maintenance/plan",
+				Handler: SetMaintenancePlanHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/maintenance/record",
+				Handler: MaintenanceRecordHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/maintenance/uas/:uasID",
+				Handler: MaintenanceStatusHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/maintenance/upcoming",
+				Handler: MaintenanceUpcomingHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/queue/batches",
+				Handler: QueueListHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/queue/batches/:state/:key",
+				Handler: QueueBatchHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/queue/purge",
+				Handler: QueuePurgeHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/queue/requeue",
+				Handler: QueueRequeueHandler(serverCtx),
+			},
 			{
 				Method:  http.MethodGet,
 				Path:    "/record/SOCUsage",
@@ -24,6 +109,16 @@ func RegisterHandlers(server *rest.Server, serverCtx *svc.ServiceContext) {
 				Path:    "/record/avgStats",
 				Handler: AvgStatsHandler(serverCtx),
 			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/record/battery/warnings",
+				Handler: BatteryWarningsHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/record/uas/:uasID/battery",
+				Handler: BatteryHealthHandler(serverCtx),
+			},
 			{
 				Method:  http.MethodPost,
 				Path:    "/record/export",
@@ -44,11 +139,41 @@ func RegisterHandlers(server *rest.Server, serverCtx *svc.ServiceContext) {
 				Path:    "/record/exportDownload",
 				Handler: ExportDownloadHandler(serverCtx),
 			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/record/exportDownloads",
+				Handler: ExportDownloadsHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/record/exportTasks",
+				Handler: ListExportTasksHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodDelete,
+				Path:    "/record/exportTasks",
+				Handler: CancelExportTaskHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/record/flightReport",
+				Handler: FlightReportHandler(serverCtx),
+			},
 			{
 				Method:  http.MethodPost,
 				Path:    "/record/get",
 				Handler: GetFlightRecordsHandler(serverCtx),
 			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/record/history",
+				Handler: FlightHistoryHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/record/payloadImport",
+				Handler: PayloadImportHandler(serverCtx),
+			},
 			{
 				Method:  http.MethodGet,
 				Path:    "/record/payloadStats",
@@ -64,6 +189,11 @@ func RegisterHandlers(server *rest.Server, serverCtx *svc.ServiceContext) {
 				Path:    "/record/recentTracks",
 				Handler: RecentTracksHandler(serverCtx),
 			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/record/replay",
+				Handler: ReplayHandler(serverCtx),
+			},
 			{
 				Method:  http.MethodPost,
 				Path:    "/record/stats",
@@ -74,16 +204,66 @@ func RegisterHandlers(server *rest.Server, serverCtx *svc.ServiceContext) {
 				Path:    "/record/timeSeries",
 				Handler: TimeSeriesStatsHandler(serverCtx),
 			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/record/trackQuality",
+				Handler: TrackQualityHandler(serverCtx),
+			},
 			{
 				Method:  http.MethodGet,
 				Path:    "/record/uas",
 				Handler: GetUasStatsHandler(serverCtx),
 			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/record/uas/:uasID/stats",
+				Handler: UasDetailStatsHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/record/uas/compare",
+				Handler: UasCompareHandler(serverCtx),
+			},
 			{
 				Method:  http.MethodPost,
 				Path:    "/record/updatePayload",
 				Handler: UpdatePayloadHandler(serverCtx),
 			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/report/runs",
+				Handler: ReportRunsHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/report/schedules",
+				Handler: ListReportSchedulesHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/report/schedules",
+				Handler: CreateReportScheduleHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodPut,
+				Path:    "/report/schedules/:id",
+				Handler: UpdateReportScheduleHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodDelete,
+				Path:    "/report/schedules/:id",
+				Handler: DeleteReportScheduleHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/report/schedules/:id/run",
+				Handler: RunReportScheduleHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/telemetry/series",
+				Handler: TelemetrySeriesHandler(serverCtx),
+			},
 		},
 	)
 }
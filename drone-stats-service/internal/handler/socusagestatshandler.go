@@ -5,15 +5,28 @@ import (
 
 	"drone-stats-service/internal/logic"
 	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/types"
 
 	"github.com/zeromicro/go-zero/rest/httpx"
 )
 
 func SOCUsageStatsHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		mode := r.URL.Query().Get("mode")
+		var req types.StatsQueryReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+			return
+		}
+		if !logic.IsLegacyStatsReq(&req) {
+			metrics := logic.SOCUsageStatsMetrics
+			if req.Mode == "avg" {
+				metrics = logic.SOCUsageAvgMetrics
+			}
+			serveStatsSeries(w, r, svcCtx, &req, metrics)
+			return
+		}
 		l := logic.NewSOCUsageStatsLogic(r.Context(), svcCtx)
-		resp, err := l.SOCUsageStats(mode)
+		resp, err := l.SOCUsageStats(req.Mode)
 		if err != nil {
 			httpx.ErrorCtx(r.Context(), w, err)
 		} else {
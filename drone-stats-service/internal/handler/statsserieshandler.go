@@ -0,0 +1,22 @@
+package handler
+
+import (
+	"net/http"
+
+	"drone-stats-service/internal/logic"
+	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/types"
+
+	"github.com/zeromicro/go-zero/rest/httpx"
+)
+
+// serveStatsSeries 统计类接口的参数化（序列格式）响应
+func serveStatsSeries(w http.ResponseWriter, r *http.Request, svcCtx *svc.ServiceContext, req *types.StatsQueryReq, metrics []string) {
+	l := logic.NewStatsSeriesLogic(r.Context(), svcCtx)
+	resp, err := l.StatsSeries(req, metrics)
+	if err != nil {
+		httpx.ErrorCtx(r.Context(), w, err)
+	} else {
+		httpx.OkJsonCtx(r.Context(), w, resp)
+	}
+}
@@ -5,11 +5,22 @@ import (
 
 	"drone-stats-service/internal/logic"
 	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/types"
+
 	"github.com/zeromicro/go-zero/rest/httpx"
 )
 
 func TimeSeriesStatsHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		var req types.StatsQueryReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+			return
+		}
+		if !logic.IsLegacyStatsReq(&req) {
+			serveStatsSeries(w, r, svcCtx, &req, logic.TimeSeriesStatsMetrics)
+			return
+		}
 		l := logic.NewTimeSeriesStatsLogic(r.Context(), svcCtx)
 		resp, err := l.TimeSeriesStats()
 		if err != nil {
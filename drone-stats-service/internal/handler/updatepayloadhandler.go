@@ -3,6 +3,9 @@ package handler
 import (
 	"net/http"
 
+	"drone-stats-service/internal/audit"
+	"drone-stats-service/internal/dao"
+	"drone-stats-service/internal/model"
 	"drone-stats-service/internal/svc"
 	"drone-stats-service/internal/types"
 
@@ -16,7 +19,8 @@ func UpdatePayloadHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 			httpx.Error(w, err)
 			return
 		}
-		err := svcCtx.MySQLDao.UpdateFlightPayload(req.OrderID, req.Payload, req.ExpressCount)
+		err := svcCtx.Records.UpdateFlightPayload(req.OrderID, req.Payload, req.ExpressCount,
+			dao.AuditMeta{Actor: audit.Actor(r.Context()), Source: model.AuditSourceAPI})
 		if err != nil {
 			httpx.OkJson(w, types.UpdatePayloadResp{
 				Code:     1,
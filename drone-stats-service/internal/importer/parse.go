@@ -0,0 +1,235 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Row 导入文件中的一行数据
+type Row struct {
+	Line         int // 文件中的行号（从 1 开始，含表头）
+	OrderID      string
+	UasID        string
+	Time         time.Time // 未填写时为零值
+	TimeText     string
+	Payload      *int // 千克乘 10，与 flight_records.payload 一致；未填写时为 nil
+	ExpressCount *int
+	Err          string // 解析失败原因
+}
+
+// columns 表头别名（比较前统一转小写并去掉空格、下划线、连字符）
+var columns = map[string][]string{
+	"orderID":      {"orderid", "order", "架次编号", "架次号", "架次"},
+	"uasID":        {"uasid", "uas", "droneid", "无人机编号", "无人机"},
+	"time":         {"time", "starttime", "takeofftime", "flighttime", "起飞时间", "时间"},
+	"payload":      {"payload", "payloadkg", "weight", "载货量", "载重", "重量"},
+	"expressCount": {"expresscount", "parcels", "parcelcount", "票数", "件数"},
+}
+
+// timeLayouts 支持的时间格式，按 MySQL 连接时区解析
+var timeLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006/01/02 15:04:05",
+	"2006/01/02 15:04",
+	"2006/1/2 15:04:05",
+	"2006/1/2 15:04",
+	"2006-01-02T15:04:05",
+	"20060102150405",
+}
+
+// ParseFile 读取 xlsx 或 csv（按文件扩展名区分）的首个工作表，首个非空行为表头。
+// 必须包含 payload 或 expressCount 列，且包含 orderID 列或 uasID + time 列。
+func ParseFile(r io.Reader, name string, loc *time.Location) ([]Row, error) {
+	var (
+		records [][]string
+		xlsx    bool
+		err     error
+	)
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".xlsx":
+		xlsx = true
+		records, err = readXLSX(r)
+	case ".csv":
+		records, err = readCSV(r)
+	default:
+		return nil, fmt.Errorf("仅支持 xlsx、csv 文件")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	header := -1
+	for i, rec := range records {
+		if !blank(rec) {
+			header = i
+			break
+		}
+	}
+	if header < 0 {
+		return nil, fmt.Errorf("文件为空")
+	}
+	idx := map[string]int{}
+	for i, h := range records[header] {
+		key := normalizeHeader(h)
+		for col, aliases := range columns {
+			if _, ok := idx[col]; ok {
+				continue
+			}
+			for _, a := range aliases {
+				if key == a {
+					idx[col] = i
+				}
+			}
+		}
+	}
+	_, hasPayload := idx["payload"]
+	_, hasCount := idx["expressCount"]
+	if !hasPayload && !hasCount {
+		return nil, fmt.Errorf("缺少 payload 或 expressCount 列")
+	}
+	_, hasOrder := idx["orderID"]
+	_, hasUas := idx["uasID"]
+	_, hasTime := idx["time"]
+	if !hasOrder && !(hasUas && hasTime) {
+		return nil, fmt.Errorf("缺少 orderID 列或 uasID + time 列")
+	}
+	cell := func(rec []string, col string) string {
+		i, ok := idx[col]
+		if !ok || i >= len(rec) {
+			return ""
+		}
+		return strings.TrimSpace(rec[i])
+	}
+
+	var rows []Row
+	for i := header + 1; i < len(records); i++ {
+		rec := records[i]
+		if blank(rec) {
+			continue
+		}
+		row := Row{
+			Line:     i + 1,
+			OrderID:  cell(rec, "orderID"),
+			UasID:    cell(rec, "uasID"),
+			TimeText: cell(rec, "time"),
+		}
+		var errs []string
+		if row.TimeText != "" {
+			t, err := parseTime(row.TimeText, xlsx, loc)
+			if err != nil {
+				errs = append(errs, err.Error())
+			}
+			row.Time = t
+		}
+		if row.OrderID == "" && (row.UasID == "" || row.TimeText == "") {
+			errs = append(errs, "需要 orderID 或 uasID + time")
+		}
+		if v := cell(rec, "payload"); v != "" {
+			kg, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(strings.ToLower(v), "kg")), 64)
+			if err != nil || kg < 0 || kg > 100000 || math.IsNaN(kg) {
+				errs = append(errs, "payload 无效: "+v)
+			} else {
+				p := int(math.Round(kg * 10))
+				row.Payload = &p
+			}
+		}
+		if v := cell(rec, "expressCount"); v != "" {
+			n, err := strconv.ParseFloat(v, 64)
+			if err != nil || n < 0 || n != math.Trunc(n) || n > math.MaxInt32 {
+				errs = append(errs, "expressCount 无效: "+v)
+			} else {
+				c := int(n)
+				row.ExpressCount = &c
+			}
+		}
+		if row.Payload == nil && row.ExpressCount == nil && len(errs) == 0 {
+			errs = append(errs, "payload 与 expressCount 均为空")
+		}
+		row.Err = strings.Join(errs, "; ")
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func readXLSX(r io.Reader) ([][]string, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("读取 xlsx 失败: %v", err)
+	}
+	defer f.Close()
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, fmt.Errorf("xlsx 中没有工作表")
+	}
+	// 使用原始值，日期单元格得到 Excel 序列号，避免受单元格显示格式影响
+	return f.GetRows(sheets[0], excelize.Options{RawCellValue: true})
+}
+
+func readCSV(r io.Reader) ([][]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	cr := csv.NewReader(bytes.NewReader(data))
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	cr.TrimLeadingSpace = true
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("读取 csv 失败: %v", err)
+	}
+	return records, nil
+}
+
+// parseTime 解析时间；xlsx 中的数字按 Excel 日期序列号处理，得到的墙上时间按 loc 解释
+func parseTime(s string, xlsx bool, loc *time.Location) (time.Time, error) {
+	if xlsx {
+		if f, err := strconv.ParseFloat(s, 64); err == nil && f > 0 && f < 1e6 {
+			t, err := excelize.ExcelDateToTime(f, false)
+			if err == nil {
+				t = t.Round(time.Second)
+				return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, loc), nil
+			}
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.In(loc), nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("时间格式无效: %s", s)
+}
+
+func normalizeHeader(h string) string {
+	h = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+	// 去掉单位等括号内容，如 "payload (kg)"、"载货量（kg）"
+	for _, open := range []string{"(", "（"} {
+		if i := strings.Index(h, open); i > 0 {
+			h = h[:i]
+		}
+	}
+	return strings.NewReplacer(" ", "", "_", "", "-", "").Replace(h)
+}
+
+func blank(rec []string) bool {
+	for _, v := range rec {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
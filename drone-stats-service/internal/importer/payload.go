@@ -0,0 +1,346 @@
+package importer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"drone-stats-service/internal/dao"
+	"drone-stats-service/internal/model"
+)
+
+// 行处理结果
+const (
+	StatusUpdate    = "update"    // 将更新（已应用时为已更新）
+	StatusUnchanged = "unchanged" // 与库中数据一致或与前面的行重复
+	StatusUnmatched = "unmatched" // 未找到对应架次
+	StatusAmbiguous = "ambiguous" // 容差内匹配到多个架次
+	StatusConflict  = "conflict"  // 与库中已录入的值或其它行冲突
+	StatusInvalid   = "invalid"   // 解析失败
+)
+
+// DefaultTolerance 按 uasID + time 匹配时起飞时间的默认容差
+const DefaultTolerance = 5 * time.Minute
+
+// Options 匹配参数
+type Options struct {
+	Tolerance time.Duration // 时间容差，0 使用 DefaultTolerance
+	Overwrite bool          // 为 true 时覆盖库中已录入的不同值，否则视为冲突
+}
+
+// Match 行匹配到的飞行记录
+type Match struct {
+	RecordID        int     `json:"recordID"`
+	OrderID         string  `json:"orderID"`
+	UasID           string  `json:"uasID"`
+	StartTime       string  `json:"startTime"`
+	TimeDiffSeconds float64 `json:"timeDiffSeconds,omitempty"` // 文件时间与起飞时间之差（秒），仅按时间匹配时给出
+	OldPayload      float64 `json:"oldPayload"`                // 库中载货量（kg）
+	OldExpressCount int     `json:"oldExpressCount"`
+}
+
+// RowResult 单行的匹配与处理结果
+type RowResult struct {
+	Line         int      `json:"line"`
+	OrderID      string   `json:"orderID,omitempty"`
+	UasID        string   `json:"uasID,omitempty"`
+	Time         string   `json:"time,omitempty"`
+	Payload      *float64 `json:"payload,omitempty"` // kg
+	ExpressCount *int     `json:"expressCount,omitempty"`
+	Status       string   `json:"status"`
+	Message      string   `json:"message,omitempty"`
+	Matches      []Match  `json:"matches,omitempty"`
+}
+
+// Summary 各状态行数
+type Summary struct {
+	Total     int `json:"total"`
+	Update    int `json:"update"`
+	Unchanged int `json:"unchanged"`
+	Unmatched int `json:"unmatched"`
+	Ambiguous int `json:"ambiguous"`
+	Conflict  int `json:"conflict"`
+	Invalid   int `json:"invalid"`
+	Records   int `json:"records"` // 将更新的飞行记录条数
+}
+
+// Result 导入预览/结果
+type Result struct {
+	DryRun  bool        `json:"dryRun"`
+	Applied bool        `json:"applied"`
+	BatchID string      `json:"batchID,omitempty"` // 审计日志中本次导入的批次号，仅实际导入时给出
+	Summary Summary     `json:"summary"`
+	Rows    []RowResult `json:"rows"`
+
+	updates []dao.PayloadUpdate
+}
+
+// Plan 将解析出的行与 flight_records 匹配，计算每行状态及需要执行的更新，不修改数据库
+func Plan(repo dao.PayloadImportRepo, rows []Row, opts Options) (*Result, error) {
+	if opts.Tolerance <= 0 {
+		opts.Tolerance = DefaultTolerance
+	}
+	loc := repo.Location()
+
+	// 批量加载候选记录：按 OrderID 一次查询；按 uasID 查询该机所有行时间范围（含容差）内的记录
+	var orderIDs []string
+	seenOrder := map[string]bool{}
+	uasRange := map[string][2]time.Time{}
+	for _, r := range rows {
+		switch {
+		case r.Err != "":
+		case r.OrderID != "":
+			if !seenOrder[r.OrderID] {
+				seenOrder[r.OrderID] = true
+				orderIDs = append(orderIDs, r.OrderID)
+			}
+		default:
+			rg, ok := uasRange[r.UasID]
+			if !ok || r.Time.Before(rg[0]) {
+				rg[0] = r.Time
+			}
+			if !ok || r.Time.After(rg[1]) {
+				rg[1] = r.Time
+			}
+			uasRange[r.UasID] = rg
+		}
+	}
+	byOrder := map[string][]dao.PayloadCandidate{}
+	if len(orderIDs) > 0 {
+		list, err := repo.FindPayloadCandidatesByOrderIDs(orderIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range list {
+			byOrder[c.OrderID] = append(byOrder[c.OrderID], c)
+		}
+	}
+	byUas := map[string][]dao.PayloadCandidate{}
+	for uas, rg := range uasRange {
+		list, err := repo.FindPayloadCandidatesByUas(uas, rg[0].Add(-opts.Tolerance), rg[1].Add(opts.Tolerance))
+		if err != nil {
+			return nil, err
+		}
+		byUas[uas] = list
+	}
+
+	res := &Result{Rows: make([]RowResult, len(rows))}
+	matched := make([][]dao.PayloadCandidate, len(rows))
+	targets := map[int][]int{} // 记录 id -> 匹配到该记录的行（rows 下标）
+	var targetOrder []int
+	for i, r := range rows {
+		out := RowResult{Line: r.Line, OrderID: r.OrderID, UasID: r.UasID, Time: r.TimeText, ExpressCount: r.ExpressCount}
+		if !r.Time.IsZero() {
+			// xlsx 日期单元格的原始值为序列号，统一输出解析后的时间
+			out.Time = r.Time.In(loc).Format("2006-01-02 15:04:05")
+		}
+		if r.Payload != nil {
+			kg := float64(*r.Payload) / 10
+			out.Payload = &kg
+		}
+		if r.Err != "" {
+			out.Status, out.Message = StatusInvalid, r.Err
+			res.Rows[i] = out
+			continue
+		}
+		var (
+			cands  []dao.PayloadCandidate
+			byTime bool
+		)
+		if r.OrderID != "" {
+			cands = byOrder[r.OrderID]
+			if len(cands) == 0 {
+				out.Status, out.Message = StatusUnmatched, "未找到该 OrderID 的飞行记录"
+			} else if !r.Time.IsZero() && len(cands) > 1 {
+				// 同一 OrderID 有多个架次时按时间选取；未给出时间则与 /record/updatePayload 一致更新全部架次
+				cands, byTime = matchByTime(cands, r.Time, opts.Tolerance), true
+			}
+		} else {
+			cands, byTime = matchByTime(byUas[r.UasID], r.Time, opts.Tolerance), true
+		}
+		if out.Status == "" && len(cands) == 0 {
+			out.Status, out.Message = StatusUnmatched, fmt.Sprintf("±%s 内没有起飞或正在飞行的架次", opts.Tolerance)
+		}
+		for _, c := range cands {
+			m := Match{
+				RecordID:        c.ID,
+				OrderID:         c.OrderID,
+				UasID:           c.UasID,
+				StartTime:       c.StartTime.In(loc).Format("2006-01-02 15:04:05"),
+				OldPayload:      float64(c.Payload) / 10,
+				OldExpressCount: c.ExpressCount,
+			}
+			if byTime {
+				m.TimeDiffSeconds = r.Time.Sub(c.StartTime).Seconds()
+			}
+			out.Matches = append(out.Matches, m)
+		}
+		if out.Status == "" && byTime && len(cands) > 1 {
+			out.Status, out.Message = StatusAmbiguous, fmt.Sprintf("容差内匹配到 %d 个架次", len(cands))
+		}
+		res.Rows[i] = out
+		if out.Status != "" {
+			continue
+		}
+		matched[i] = cands
+		for _, c := range cands {
+			if _, ok := targets[c.ID]; !ok {
+				targetOrder = append(targetOrder, c.ID)
+			}
+			targets[c.ID] = append(targets[c.ID], i)
+		}
+	}
+
+	// 同一记录被多行设置为不同值时，这些行均视为冲突
+	conflictMsg := map[int]string{}
+	dupOf := map[int]int{}
+	for _, id := range targetOrder {
+		lines := targets[id]
+		first := rows[lines[0]]
+		for _, li := range lines[1:] {
+			if sameValues(first, rows[li]) {
+				if _, ok := dupOf[li]; !ok {
+					dupOf[li] = first.Line
+				}
+				continue
+			}
+			for _, lj := range lines {
+				if _, ok := conflictMsg[lj]; !ok {
+					conflictMsg[lj] = fmt.Sprintf("记录 %d 被多行设置为不同的值", id)
+				}
+			}
+		}
+	}
+
+	updated := map[int]bool{}
+	for i, r := range rows {
+		out := &res.Rows[i]
+		if out.Status != "" {
+			continue
+		}
+		if msg, ok := conflictMsg[i]; ok {
+			out.Status, out.Message = StatusConflict, msg
+			continue
+		}
+		var (
+			ups       []dao.PayloadUpdate
+			conflicts []string
+		)
+		for _, c := range matched[i] {
+			u := dao.PayloadUpdate{ID: c.ID}
+			if r.Payload != nil && *r.Payload != c.Payload {
+				if c.Payload != 0 && !opts.Overwrite {
+					conflicts = append(conflicts, fmt.Sprintf("记录 %d 已录入载货量 %.1fkg", c.ID, float64(c.Payload)/10))
+				}
+				u.Payload = r.Payload
+			}
+			if r.ExpressCount != nil && *r.ExpressCount != c.ExpressCount {
+				if c.ExpressCount != 0 && !opts.Overwrite {
+					conflicts = append(conflicts, fmt.Sprintf("记录 %d 已录入票数 %d", c.ID, c.ExpressCount))
+				}
+				u.ExpressCount = r.ExpressCount
+			}
+			if u.Payload != nil || u.ExpressCount != nil {
+				ups = append(ups, u)
+			}
+		}
+		switch {
+		case len(conflicts) > 0:
+			out.Status, out.Message = StatusConflict, strings.Join(conflicts, "; ")+"（可使用 overwrite 覆盖）"
+		case len(ups) == 0:
+			out.Status = StatusUnchanged
+			if line, ok := dupOf[i]; ok {
+				out.Message = fmt.Sprintf("与第 %d 行重复", line)
+			}
+		case dupOf[i] != 0:
+			// 与前面的行设置了相同的值，更新由前面的行执行
+			out.Status, out.Message = StatusUnchanged, fmt.Sprintf("与第 %d 行重复", dupOf[i])
+		default:
+			out.Status = StatusUpdate
+			for _, u := range ups {
+				if !updated[u.ID] {
+					updated[u.ID] = true
+					res.updates = append(res.updates, u)
+				}
+			}
+		}
+	}
+	res.Summary = summarize(res.Rows)
+	res.Summary.Records = len(res.updates)
+	return res, nil
+}
+
+// Apply 在一个事务内执行 Plan 计算出的全部更新，审计日志记录发起人 actor 及本次导入的批次号
+func Apply(repo dao.PayloadImportRepo, res *Result, actor string) error {
+	if len(res.updates) > 0 {
+		b := make([]byte, 4)
+		if _, err := rand.Read(b); err != nil {
+			return err
+		}
+		batchID := "import-" + time.Now().Format("20060102150405") + "-" + hex.EncodeToString(b)
+		if err := repo.ApplyPayloadUpdates(res.updates, dao.AuditMeta{Actor: actor, Source: model.AuditSourceImport, BatchID: batchID}); err != nil {
+			return err
+		}
+		res.BatchID = batchID
+	}
+	res.Applied = true
+	return nil
+}
+
+// matchByTime 选出起飞时间在 t±tolerance 内或 t 时刻正在飞行的架次；
+// 若恰有一个架次在 t 时刻正在飞行则只返回该架次，否则返回全部候选（多于一个即为歧义）
+func matchByTime(cands []dao.PayloadCandidate, t time.Time, tolerance time.Duration) []dao.PayloadCandidate {
+	var near, inFlight []dao.PayloadCandidate
+	for _, c := range cands {
+		flying := !c.StartTime.After(t) && !c.EndTime.IsZero() && !c.EndTime.Before(t)
+		if flying {
+			inFlight = append(inFlight, c)
+		}
+		if d := t.Sub(c.StartTime); flying || (d <= tolerance && d >= -tolerance) {
+			near = append(near, c)
+		}
+	}
+	if len(inFlight) == 1 {
+		return inFlight
+	}
+	sort.Slice(near, func(i, j int) bool {
+		return absDuration(t.Sub(near[i].StartTime)) < absDuration(t.Sub(near[j].StartTime))
+	})
+	return near
+}
+
+func sameValues(a, b Row) bool {
+	eq := func(x, y *int) bool { return (x == nil && y == nil) || (x != nil && y != nil && *x == *y) }
+	return eq(a.Payload, b.Payload) && eq(a.ExpressCount, b.ExpressCount)
+}
+
+func summarize(rows []RowResult) Summary {
+	s := Summary{Total: len(rows)}
+	for _, r := range rows {
+		switch r.Status {
+		case StatusUpdate:
+			s.Update++
+		case StatusUnchanged:
+			s.Unchanged++
+		case StatusUnmatched:
+			s.Unmatched++
+		case StatusAmbiguous:
+			s.Ambiguous++
+		case StatusConflict:
+			s.Conflict++
+		case StatusInvalid:
+			s.Invalid++
+		}
+	}
+	return s
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,183 @@
+package importer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"drone-stats-service/internal/config"
+	"drone-stats-service/internal/dao"
+)
+
+var importBase = time.Date(2025, 6, 20, 8, 0, 0, 0, time.UTC)
+
+// at 返回 importBase 之后 h 时 m 分
+func at(h, m int) time.Time {
+	return importBase.Add(time.Duration(h)*time.Hour + time.Duration(m)*time.Minute)
+}
+
+func intPtr(v int) *int { return &v }
+
+// newImportDao 内存 SQLite 中的飞行记录，id 按插入顺序从 1 开始：
+//  1. O-1 / U1 08:00-08:20
+//  2. O-1 / U1 09:00-09:20（同一 OrderID 的第二个架次）
+//  3. O-2 / U2 10:00-10:40
+//  4. O-3 / U3 11:00-11:10，已录入载货量 5kg
+//  5. O-4 / U4 13:00-13:01
+//  6. O-5 / U4 13:04-13:10
+func newImportDao(t *testing.T) *dao.SQLDao {
+	t.Helper()
+	d, err := dao.NewSQLiteDao(config.StorageConf{SQLitePath: ":memory:"}, config.MySQLConf{QueuePath: t.TempDir() + "/queue.jsonl"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := []struct {
+		orderID, uasID string
+		start, end     time.Time
+	}{
+		{"O-1", "U1", at(0, 0), at(0, 20)},
+		{"O-1", "U1", at(1, 0), at(1, 20)},
+		{"O-2", "U2", at(2, 0), at(2, 40)},
+		{"O-3", "U3", at(3, 0), at(3, 10)},
+		{"O-4", "U4", at(5, 0), at(5, 1)},
+		{"O-5", "U4", at(5, 4), at(5, 10)},
+	}
+	for _, r := range records {
+		if err := d.SaveFlightRecord(r.orderID, r.uasID, r.start, r.end, 0, 0, 0, 0, 0, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := d.DB.Exec(`UPDATE flight_records SET payload = 50 WHERE id = 4`); err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestPlan(t *testing.T) {
+	d := newImportDao(t)
+	tests := []struct {
+		name     string
+		rows     []Row
+		opts     Options
+		statuses []string
+		matches  [][]int // 各行匹配到的记录 id
+		records  int     // 将更新的记录数
+	}{
+		{
+			name:     "OrderID 多架次按时间选取",
+			rows:     []Row{{Line: 2, OrderID: "O-1", Time: at(1, 2), Payload: intPtr(25)}},
+			statuses: []string{StatusUpdate},
+			matches:  [][]int{{2}},
+			records:  1,
+		},
+		{
+			name:     "OrderID 多架次未给时间",
+			rows:     []Row{{Line: 2, OrderID: "O-1", Payload: intPtr(25)}},
+			statuses: []string{StatusUpdate},
+			matches:  [][]int{{1, 2}},
+			records:  2,
+		},
+		{
+			name:     "OrderID 不存在",
+			rows:     []Row{{Line: 2, OrderID: "O-9", Payload: intPtr(25)}},
+			statuses: []string{StatusUnmatched},
+			matches:  [][]int{nil},
+		},
+		{
+			name:     "uasID 容差内",
+			rows:     []Row{{Line: 2, UasID: "U2", Time: at(1, 57), ExpressCount: intPtr(3)}},
+			statuses: []string{StatusUpdate},
+			matches:  [][]int{{3}},
+			records:  1,
+		},
+		{
+			name:     "uasID 超出容差",
+			rows:     []Row{{Line: 2, UasID: "U2", Time: at(1, 50), ExpressCount: intPtr(3)}},
+			statuses: []string{StatusUnmatched},
+			matches:  [][]int{nil},
+		},
+		{
+			name:     "uasID 正在飞行",
+			rows:     []Row{{Line: 2, UasID: "U2", Time: at(2, 30), Payload: intPtr(25)}},
+			statuses: []string{StatusUpdate},
+			matches:  [][]int{{3}},
+			records:  1,
+		},
+		{
+			name:     "容差内多个架次",
+			rows:     []Row{{Line: 2, UasID: "U4", Time: at(5, 3), Payload: intPtr(25)}},
+			statuses: []string{StatusAmbiguous},
+			matches:  [][]int{{6, 5}}, // 按时间差由近到远
+		},
+		{
+			name: "重复行",
+			rows: []Row{
+				{Line: 2, OrderID: "O-2", Payload: intPtr(30)},
+				{Line: 3, UasID: "U2", Time: at(2, 1), Payload: intPtr(30)},
+			},
+			statuses: []string{StatusUpdate, StatusUnchanged},
+			matches:  [][]int{{3}, {3}},
+			records:  1,
+		},
+		{
+			name: "多行设置不同值",
+			rows: []Row{
+				{Line: 2, OrderID: "O-2", Payload: intPtr(30)},
+				{Line: 3, OrderID: "O-2", Payload: intPtr(40)},
+				{Line: 4, OrderID: "O-4", Payload: intPtr(40)},
+			},
+			statuses: []string{StatusConflict, StatusConflict, StatusUpdate},
+			matches:  [][]int{{3}, {3}, {5}},
+			records:  1,
+		},
+		{
+			name:     "与已录入值冲突",
+			rows:     []Row{{Line: 2, OrderID: "O-3", Payload: intPtr(60)}},
+			statuses: []string{StatusConflict},
+			matches:  [][]int{{4}},
+		},
+		{
+			name:     "覆盖已录入值",
+			rows:     []Row{{Line: 2, OrderID: "O-3", Payload: intPtr(60)}},
+			opts:     Options{Overwrite: true},
+			statuses: []string{StatusUpdate},
+			matches:  [][]int{{4}},
+			records:  1,
+		},
+		{
+			name:     "与已录入值相同",
+			rows:     []Row{{Line: 2, OrderID: "O-3", Payload: intPtr(50)}},
+			statuses: []string{StatusUnchanged},
+			matches:  [][]int{{4}},
+		},
+		{
+			name:     "解析失败",
+			rows:     []Row{{Line: 2, Err: "载货量无效"}},
+			statuses: []string{StatusInvalid},
+			matches:  [][]int{nil},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Plan(d, tt.rows, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, r := range res.Rows {
+				if r.Status != tt.statuses[i] {
+					t.Errorf("line %d status = %s (%s), want %s", r.Line, r.Status, r.Message, tt.statuses[i])
+				}
+				var ids []int
+				for _, m := range r.Matches {
+					ids = append(ids, m.RecordID)
+				}
+				if !reflect.DeepEqual(ids, tt.matches[i]) {
+					t.Errorf("line %d matches = %v, want %v", r.Line, ids, tt.matches[i])
+				}
+			}
+			if res.Summary.Records != tt.records {
+				t.Errorf("records = %d, want %d", res.Summary.Records, tt.records)
+			}
+		})
+	}
+}
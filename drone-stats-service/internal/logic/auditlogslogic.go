@@ -0,0 +1,110 @@
+package logic
+
+import (
+	"context"
+	"encoding/json"
+
+	"drone-stats-service/internal/audit"
+	"drone-stats-service/internal/dao"
+	"drone-stats-service/internal/model"
+	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type AuditLogsLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewAuditLogsLogic(ctx context.Context, svcCtx *svc.ServiceContext) *AuditLogsLogic {
+	return &AuditLogsLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *AuditLogsLogic) AuditLogs(req *types.AuditLogsReq) (resp *types.AuditLogsResp, err error) {
+	logs, err := l.svcCtx.Audits.GetAuditLogs(dao.AuditFilter{
+		Entity:   req.Entity,
+		EntityID: req.EntityID,
+		Actor:    req.Actor,
+		Source:   req.Source,
+		BatchID:  req.BatchID,
+		Limit:    auditLimit(req.Limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toAuditLogsResp(logs), nil
+}
+
+func auditLimit(limit int) int {
+	if limit <= 0 {
+		return 50
+	}
+	if limit > 500 {
+		return 500
+	}
+	return limit
+}
+
+func toAuditLogsResp(logs []model.AuditLog) *types.AuditLogsResp {
+	resp := &types.AuditLogsResp{Logs: []types.AuditLog{}}
+	for _, e := range logs {
+		resp.Logs = append(resp.Logs, toAuditLog(e))
+	}
+	return resp
+}
+
+func toAuditLog(e model.AuditLog) types.AuditLog {
+	return types.AuditLog{
+		ID:         e.ID,
+		Entity:     e.Entity,
+		EntityID:   e.EntityID,
+		OrderID:    e.OrderID,
+		Field:      e.Field,
+		OldValue:   e.OldValue,
+		NewValue:   e.NewValue,
+		Actor:      e.Actor,
+		Source:     e.Source,
+		BatchID:    e.BatchID,
+		RevertOf:   e.RevertOf,
+		RevertedBy: e.RevertedBy,
+		Revertible: e.Entity == model.AuditEntityFlightRecord && dao.FlightAuditRevertible(e.Field) && e.RevertedBy == 0,
+		CreatedAt:  formatReportTime(e.CreatedAt),
+	}
+}
+
+// recordAudit 以 JSON 记录维保、报表计划等整条数据的修改，old 为 nil 表示新建，new 为 nil 表示删除。
+// 修改已经生效，写审计日志失败只记录错误日志，不影响接口结果。
+func recordAudit(ctx context.Context, svcCtx *svc.ServiceContext, entity, entityID, field string, old, new interface{}) {
+	e := model.AuditLog{
+		Entity:   entity,
+		EntityID: entityID,
+		Field:    field,
+		Actor:    audit.Actor(ctx),
+		Source:   model.AuditSourceAPI,
+	}
+	var err error
+	if e.OldValue, err = auditJSON(old); err == nil {
+		e.NewValue, err = auditJSON(new)
+	}
+	if err == nil {
+		err = svcCtx.Audits.SaveAuditLog(e)
+	}
+	if err != nil {
+		logx.WithContext(ctx).Errorf("写入审计日志失败: entity=%s id=%s err=%v", entity, entityID, err)
+	}
+}
+
+func auditJSON(v interface{}) (string, error) {
+	if v == nil {
+		return "", nil
+	}
+	b, err := json.Marshal(v)
+	return string(b), err
+}
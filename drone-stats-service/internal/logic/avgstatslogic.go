@@ -24,7 +24,7 @@ func NewAvgStatsLogic(ctx context.Context, svcCtx *svc.ServiceContext) *AvgStats
 }
 
 func (l *AvgStatsLogic) AvgStats() (resp *types.AvgStatsResp, err error) {
-	avgTime, avgBattery, avgPayload, avgGS, err := l.svcCtx.MySQLDao.GetAvgStats()
+	avgTime, avgBattery, avgPayload, avgGS, err := l.svcCtx.Records.GetAvgStats()
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,95 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+
+	"drone-stats-service/internal/audit"
+	"drone-stats-service/internal/backup"
+	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type BackupListLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewBackupListLogic(ctx context.Context, svcCtx *svc.ServiceContext) *BackupListLogic {
+	return &BackupListLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// BackupList 列出备份目录下的备份及其清单、大小和最近一次恢复演练结果，需管理员令牌
+func (l *BackupListLogic) BackupList(req *types.BackupListReq) (resp *types.BackupListResp, err error) {
+	if err := audit.CheckAdminToken(l.svcCtx.Config.Audit.AdminToken, req.AdminToken); err != nil {
+		return nil, err
+	}
+	if req.Type != "" && req.Type != backup.TypeFull && req.Type != backup.TypeIncremental {
+		return nil, fmt.Errorf("type must be full or incremental")
+	}
+	list, err := l.svcCtx.Backup.ListBackups()
+	if err != nil {
+		return nil, fmt.Errorf("list backups failed: %w", err)
+	}
+	resp = &types.BackupListResp{Backups: []types.BackupInfo{}}
+	for _, b := range list {
+		if req.Type != "" && (b.Manifest == nil || b.Manifest.Type != req.Type) {
+			continue
+		}
+		resp.Backups = append(resp.Backups, toBackupInfo(b))
+		resp.TotalSize += b.Size
+	}
+	return resp, nil
+}
+
+func toBackupInfo(b backup.BackupInfo) types.BackupInfo {
+	out := types.BackupInfo{
+		ID:       b.ID,
+		Size:     b.Size,
+		Complete: b.Manifest != nil,
+		Running:  b.Running,
+	}
+	if mf := b.Manifest; mf != nil {
+		out.Type = mf.Type
+		out.Base = mf.Base
+		out.Parent = mf.Parent
+		out.Compression = mf.Compression
+		out.StartedAt = formatReportTime(mf.StartedAt)
+		out.FinishedAt = formatReportTime(mf.FinishedAt)
+		for _, t := range mf.MySQL.Tables {
+			out.Tables = append(out.Tables, types.BackupTable{
+				Name:        t.Name,
+				Mode:        t.Mode,
+				Rows:        t.Rows,
+				UpdatedRows: t.UpdatedRows,
+			})
+		}
+		if mf.Influx.File != "" {
+			out.Influx = &types.BackupInflux{
+				Bucket: mf.Influx.Bucket,
+				Start:  formatReportTime(mf.Influx.Start),
+				Stop:   formatReportTime(mf.Influx.Stop),
+				Points: mf.Influx.Points,
+				Fields: mf.Influx.Fields,
+			}
+		}
+		for _, f := range mf.Files {
+			out.Files = append(out.Files, types.BackupFile{Name: f.Name, Size: f.Size, SHA256: f.SHA256})
+		}
+	}
+	if rt := b.RestoreTest; rt != nil {
+		out.RestoreTest = &types.BackupRestoreTest{
+			FinishedAt: formatReportTime(rt.FinishedAt),
+			OK:         rt.OK,
+			Problems:   rt.Problems(),
+		}
+	}
+	return out
+}
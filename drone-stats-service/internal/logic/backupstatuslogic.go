@@ -0,0 +1,60 @@
+package logic
+
+import (
+	"context"
+
+	"drone-stats-service/internal/audit"
+	"drone-stats-service/internal/backup"
+	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type BackupStatusLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewBackupStatusLogic(ctx context.Context, svcCtx *svc.ServiceContext) *BackupStatusLogic {
+	return &BackupStatusLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// BackupStatus 返回正在执行及最近一次结束的备份，需管理员令牌（含库名、表名、行数等信息）
+func (l *BackupStatusLogic) BackupStatus(req *types.BackupStatusReq) (resp *types.BackupStatusResp, err error) {
+	if err := audit.CheckAdminToken(l.svcCtx.Config.Audit.AdminToken, req.AdminToken); err != nil {
+		return nil, err
+	}
+	current, last := l.svcCtx.Backup.Status()
+	resp = &types.BackupStatusResp{Running: current != nil}
+	if current != nil {
+		run := toBackupRun(*current)
+		resp.Current = &run
+	}
+	if last != nil {
+		run := toBackupRun(*last)
+		resp.Last = &run
+	}
+	return resp, nil
+}
+
+func toBackupRun(st backup.RunStatus) types.BackupRun {
+	out := types.BackupRun{
+		Trigger:      st.Trigger,
+		Type:         st.Type,
+		BackupID:     st.BackupID,
+		Running:      st.Running,
+		StartedAt:    formatReportTime(st.StartedAt),
+		Error:        st.Error,
+		UploadErrors: st.UploadErrors,
+	}
+	if st.FinishedAt != nil {
+		out.FinishedAt = formatReportTime(*st.FinishedAt)
+	}
+	return out
+}
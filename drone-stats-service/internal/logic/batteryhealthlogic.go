@@ -0,0 +1,254 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"drone-stats-service/internal/battery"
+	"drone-stats-service/internal/model"
+	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+const (
+	defaultWarnCapacityPercent = 80
+	defaultBatteryTrendWindow  = 10
+	// batteryBackfillBatch 后台每轮最多补算多少个历史架次的电池指标
+	batteryBackfillBatch = 500
+	// batteryBackfillMaxFailures 同一架次补算连续失败该次数后写入 method=none 的指标，不再重试
+	batteryBackfillMaxFailures = 3
+)
+
+// batteryBackfillFailures 各架次（OrderID + 起飞时间）补算连续失败的次数
+var batteryBackfillFailures = struct {
+	sync.Mutex
+	m map[string]int
+}{m: map[string]int{}}
+
+type BatteryHealthLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewBatteryHealthLogic(ctx context.Context, svcCtx *svc.ServiceContext) *BatteryHealthLogic {
+	return &BatteryHealthLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *BatteryHealthLogic) BatteryHealth(req *types.BatteryHealthReq) (resp *types.BatteryHealthResp, err error) {
+	loc := l.svcCtx.Records.Location()
+	start, err := parseStatsTime(req.Start, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start: %w", err)
+	}
+	end, err := parseStatsTime(req.End, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end: %w", err)
+	}
+	// 累计循环次数与基准容量需要全部历史，区间仅用于过滤趋势输出
+	metrics, err := l.svcCtx.Battery.GetBatteryFlightMetrics(req.UasID, time.Time{}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	resp = summarizeBattery(l.svcCtx, req.UasID, metrics)
+	var cycles float64
+	resp.Trend = []types.BatteryTrendPoint{}
+	for _, m := range metrics {
+		cycles += m.SOCUsed / 100
+		if (!start.IsZero() && m.StartTime.Before(start)) || (!end.IsZero() && !m.StartTime.Before(end)) {
+			continue
+		}
+		resp.Trend = append(resp.Trend, types.BatteryTrendPoint{
+			OrderID:             m.OrderID,
+			StartTime:           m.StartTime.Format("2006-01-02 15:04:05"),
+			SOCUsed:             m.SOCUsed,
+			CapacityUsedAh:      m.CapacityUsedAh,
+			CapacityMethod:      m.CapacityMethod,
+			EffectiveCapacityAh: m.EffectiveCapacityAh,
+			ResistanceMOhm:      m.ResistanceMOhm,
+			Cycles:              cycles,
+		})
+	}
+	return resp, nil
+}
+
+// summarizeBattery 根据某架无人机的全部架次指标（按起飞时间升序）计算当前健康度与告警
+func summarizeBattery(svcCtx *svc.ServiceContext, uasID string, metrics []model.BatteryFlightMetrics) *types.BatteryHealthResp {
+	conf := svcCtx.Config.BatteryConf
+	window := conf.TrendWindow
+	if window <= 0 {
+		window = defaultBatteryTrendWindow
+	}
+	warnPercent := conf.WarnCapacityPercent
+	if warnPercent <= 0 {
+		warnPercent = defaultWarnCapacityPercent
+	}
+
+	resp := &types.BatteryHealthResp{UasID: uasID, Flights: len(metrics)}
+	var capacities, resistances []float64
+	for _, m := range metrics {
+		resp.CycleCount += m.SOCUsed / 100
+		if m.EffectiveCapacityAh > 0 {
+			capacities = append(capacities, m.EffectiveCapacityAh)
+		}
+		if m.ResistanceMOhm > 0 {
+			resistances = append(resistances, m.ResistanceMOhm)
+		}
+	}
+	resp.CapacityAh = mean(tail(capacities, window))
+	resp.ResistanceMOhm = mean(tail(resistances, window))
+
+	// 标称容量：机型配置 > 全局配置 > 最早若干架次有效容量的中位数
+	resp.NominalCapacityAh, resp.NominalSource = configuredNominalCapacity(svcCtx, uasID)
+	if resp.NominalCapacityAh == 0 && len(capacities) > 0 {
+		resp.NominalCapacityAh = baselineCapacityAh(capacities, window)
+		resp.NominalSource = "baseline"
+	}
+
+	if resp.NominalCapacityAh > 0 && resp.CapacityAh > 0 {
+		resp.HealthPercent = resp.CapacityAh / resp.NominalCapacityAh * 100
+		if resp.HealthPercent < warnPercent {
+			resp.Warning = true
+			resp.WarningMsg = fmt.Sprintf("有效容量 %.2fAh 低于标称容量 %.2fAh 的 %.0f%%", resp.CapacityAh, resp.NominalCapacityAh, warnPercent)
+		}
+	}
+	return resp
+}
+
+// configuredNominalCapacity 返回配置的标称容量（A.h）及来源：机型配置 model > 全局配置 config，未配置时返回 0
+func configuredNominalCapacity(svcCtx *svc.ServiceContext, uasID string) (float64, string) {
+	conf := svcCtx.Config.BatteryConf
+	if uasModel, err := svcCtx.Sorties.GetUasModel(uasID); err == nil && uasModel != "" {
+		if v, ok := conf.ModelNominalCapacityAh[uasModel]; ok && v > 0 {
+			return v, "model"
+		}
+	}
+	if conf.NominalCapacityAh > 0 {
+		return conf.NominalCapacityAh, "config"
+	}
+	return 0, ""
+}
+
+// baselineCapacityAh 最早 window 个架次有效容量（按起飞时间升序）的中位数
+func baselineCapacityAh(capacities []float64, window int) float64 {
+	head := append([]float64(nil), capacities[:min(window, len(capacities))]...)
+	sort.Float64s(head)
+	return head[len(head)/2]
+}
+
+// nominalCapacityAh 计算耗电时使用的标称容量（A.h）：配置优先，未配置时取该无人机已保存架次有效容量的基准值，仍无数据时返回 0
+func nominalCapacityAh(svcCtx *svc.ServiceContext, uasID string) float64 {
+	if v, _ := configuredNominalCapacity(svcCtx, uasID); v > 0 {
+		return v
+	}
+	metrics, err := svcCtx.Battery.GetBatteryFlightMetrics(uasID, time.Time{}, time.Time{})
+	if err != nil {
+		logx.Errorf("查询电池指标失败: uasID=%s, err=%v", uasID, err)
+		return 0
+	}
+	var capacities []float64
+	for _, m := range metrics {
+		if m.EffectiveCapacityAh > 0 {
+			capacities = append(capacities, m.EffectiveCapacityAh)
+		}
+	}
+	if len(capacities) == 0 {
+		return 0
+	}
+	window := svcCtx.Config.BatteryConf.TrendWindow
+	if window <= 0 {
+		window = defaultBatteryTrendWindow
+	}
+	return baselineCapacityAh(capacities, window)
+}
+
+// BackfillBatteryMetrics 为尚未计算电池指标的历史架次补算一批，返回补算的架次数及各失败架次的错误。
+// 新架次在保存飞行记录时即计算电池指标，该方法由后台定时任务调用，查询接口只读已保存的指标。
+// 失败的架次跳过，不影响其后的架次；连续失败 batteryBackfillMaxFailures 次后写入 method=none 的指标
+func BackfillBatteryMetrics(svcCtx *svc.ServiceContext) (int, error) {
+	records, err := svcCtx.Battery.ListRecordsWithoutBatteryMetrics("", batteryBackfillBatch)
+	if err != nil {
+		return 0, err
+	}
+	var (
+		n    int
+		errs []error
+	)
+	for _, r := range records {
+		key := r.OrderID + "@" + r.StartTime.UTC().Format(time.RFC3339Nano)
+		points, err := svcCtx.TrackPoints.GetTrackPoints(r.OrderID)
+		if err == nil {
+			// 无轨迹点的架次也写入一条（method=none），避免重复补算
+			err = saveBatteryMetrics(svcCtx, r.OrderID, r.UasID, r.StartTime, points)
+		}
+		batteryBackfillFailures.Lock()
+		if err == nil {
+			delete(batteryBackfillFailures.m, key)
+			batteryBackfillFailures.Unlock()
+			n++
+			continue
+		}
+		batteryBackfillFailures.m[key]++
+		giveUp := batteryBackfillFailures.m[key] >= batteryBackfillMaxFailures
+		if giveUp {
+			delete(batteryBackfillFailures.m, key)
+		}
+		batteryBackfillFailures.Unlock()
+
+		logx.Errorf("补算电池指标失败: OrderID=%s, start=%v, err=%v", r.OrderID, r.StartTime, err)
+		errs = append(errs, fmt.Errorf("OrderID=%s: %w", r.OrderID, err))
+		if giveUp {
+			if err := saveBatteryMetrics(svcCtx, r.OrderID, r.UasID, r.StartTime, nil); err != nil {
+				errs = append(errs, fmt.Errorf("OrderID=%s 写入 method=none 失败: %w", r.OrderID, err))
+			}
+		}
+	}
+	return n, errors.Join(errs...)
+}
+
+// saveBatteryMetrics 计算并保存单架次电池指标
+func saveBatteryMetrics(svcCtx *svc.ServiceContext, orderID, uasID string, startTime time.Time, points []model.FlightTrackPoint) error {
+	m := battery.Analyze(points)
+	return svcCtx.Battery.SaveBatteryFlightMetrics(model.BatteryFlightMetrics{
+		OrderID:             orderID,
+		UasID:               uasID,
+		StartTime:           startTime,
+		SOCStart:            m.SOCStart,
+		SOCEnd:              m.SOCEnd,
+		SOCUsed:             m.SOCUsed,
+		CapacityUsedAh:      m.CapacityUsedAh,
+		CapacityMethod:      m.CapacityMethod,
+		EffectiveCapacityAh: m.EffectiveCapacityAh,
+		ResistanceMOhm:      m.ResistanceMOhm,
+		MinVoltage:          m.MinVoltage,
+		MaxCurrent:          m.MaxCurrent,
+	})
+}
+
+func tail(vals []float64, n int) []float64 {
+	if len(vals) > n {
+		return vals[len(vals)-n:]
+	}
+	return vals
+}
+
+func mean(vals []float64) float64 {
+	if len(vals) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range vals {
+		sum += v
+	}
+	return sum / float64(len(vals))
+}
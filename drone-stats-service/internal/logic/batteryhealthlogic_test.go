@@ -0,0 +1,62 @@
+package logic
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"drone-stats-service/internal/battery"
+	"drone-stats-service/internal/dao"
+	"drone-stats-service/internal/model"
+)
+
+// failingTrackPoints 读取指定架次的轨迹点总是失败
+type failingTrackPoints struct {
+	dao.TrackPointRepo
+	orderID string
+}
+
+func (f failingTrackPoints) GetTrackPoints(orderID string) ([]model.FlightTrackPoint, error) {
+	if orderID == f.orderID {
+		return nil, errors.New("读取轨迹点失败")
+	}
+	return f.TrackPointRepo.GetTrackPoints(orderID)
+}
+
+func TestBackfillBatteryMetricsSkipsFailingRecord(t *testing.T) {
+	svcCtx, d, _ := newTestServiceContext(t)
+	svcCtx.TrackPoints = failingTrackPoints{TrackPointRepo: d, orderID: "O-BAD"}
+	// 失败的架次起飞最早，每轮都排在第一个
+	for i, orderID := range []string{"O-BAD", "O-2", "O-3"} {
+		start := flightBase.Add(time.Duration(i) * time.Hour)
+		if err := d.SaveFlightRecord(orderID, "U1", start, start.Add(20*time.Minute), 0, 0, 0, 0, 0, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for round := 1; round <= batteryBackfillMaxFailures; round++ {
+		n, err := BackfillBatteryMetrics(svcCtx)
+		if err == nil {
+			t.Fatalf("round %d: want error for O-BAD", round)
+		}
+		want := 0 // 其余架次在第一轮已补算
+		if round == 1 {
+			want = 2
+		}
+		if n != want {
+			t.Errorf("round %d: backfilled = %d, want %d", round, n, want)
+		}
+	}
+	// 连续失败后写入 method=none，不再重试
+	n, err := BackfillBatteryMetrics(svcCtx)
+	if n != 0 || err != nil {
+		t.Errorf("after give up: backfilled = %d, err = %v", n, err)
+	}
+	metrics, err := d.GetBatteryFlightMetrics("U1", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(metrics) != 3 || metrics[0].OrderID != "O-BAD" || metrics[0].CapacityMethod != battery.CapacityMethodNone {
+		t.Errorf("metrics = %+v", metrics)
+	}
+}
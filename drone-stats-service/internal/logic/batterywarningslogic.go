@@ -0,0 +1,54 @@
+package logic
+
+import (
+	"context"
+	"time"
+
+	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type BatteryWarningsLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewBatteryWarningsLogic(ctx context.Context, svcCtx *svc.ServiceContext) *BatteryWarningsLogic {
+	return &BatteryWarningsLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// BatteryWarnings 返回所有有效容量低于告警阈值的无人机
+func (l *BatteryWarningsLogic) BatteryWarnings() (resp *types.BatteryWarningsResp, err error) {
+	metrics, err := l.svcCtx.Battery.GetBatteryFlightMetrics("", time.Time{}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	warnPercent := l.svcCtx.Config.BatteryConf.WarnCapacityPercent
+	if warnPercent <= 0 {
+		warnPercent = defaultWarnCapacityPercent
+	}
+	resp = &types.BatteryWarningsResp{
+		WarnCapacityPercent: warnPercent,
+		Items:               []types.BatteryHealthResp{},
+	}
+	// 结果已按 uasID、起飞时间排序，逐段汇总
+	for i := 0; i < len(metrics); {
+		j := i
+		for j < len(metrics) && metrics[j].UasID == metrics[i].UasID {
+			j++
+		}
+		summary := summarizeBattery(l.svcCtx, metrics[i].UasID, metrics[i:j:j])
+		if summary.Warning {
+			resp.Items = append(resp.Items, *summary)
+		}
+		i = j
+	}
+	return resp, nil
+}
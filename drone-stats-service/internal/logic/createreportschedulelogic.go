@@ -0,0 +1,180 @@
+package logic
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"drone-stats-service/internal/audit"
+	"drone-stats-service/internal/export"
+	"drone-stats-service/internal/model"
+	"drone-stats-service/internal/report"
+	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type CreateReportScheduleLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewCreateReportScheduleLogic(ctx context.Context, svcCtx *svc.ServiceContext) *CreateReportScheduleLogic {
+	return &CreateReportScheduleLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *CreateReportScheduleLogic) CreateReportSchedule(req *types.ReportScheduleReq) (resp *types.ReportSchedule, err error) {
+	if err := audit.CheckAdminToken(l.svcCtx.Config.Audit.AdminToken, req.AdminToken); err != nil {
+		return nil, err
+	}
+	sc, err := reportScheduleFromReq(l.svcCtx, req)
+	if err != nil {
+		return nil, err
+	}
+	sc.Enabled = req.Enabled == nil || *req.Enabled
+	if sc.Enabled {
+		if sc.NextRunAt, err = l.svcCtx.ReportScheduler.NextRun(sc.Cron, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+	if sc.ID, err = l.svcCtx.Reports.CreateReportSchedule(sc); err != nil {
+		return nil, err
+	}
+	// 返回数据库中的记录（含 created_at）
+	if sc, err = l.svcCtx.Reports.GetReportSchedule(sc.ID); err != nil {
+		return nil, err
+	}
+	out := toReportSchedule(sc)
+	recordAudit(l.ctx, l.svcCtx, model.AuditEntityReportSchedule, strconv.FormatInt(sc.ID, 10), "schedule", nil, out)
+	return &out, nil
+}
+
+// reportScheduleFromReq 校验请求并补全默认值，不处理 Enabled 与执行时间
+func reportScheduleFromReq(svcCtx *svc.ServiceContext, req *types.ReportScheduleReq) (model.ReportSchedule, error) {
+	sc := model.ReportSchedule{
+		ID:          req.ID,
+		Name:        strings.TrimSpace(req.Name),
+		Cron:        strings.TrimSpace(req.Cron),
+		Target:      req.Target,
+		Format:      strings.ToLower(req.Format),
+		PartitionBy: strings.ToLower(req.PartitionBy),
+		UasID:       req.UasID,
+		RangeHours:  req.RangeHours,
+		Delivery:    strings.ToLower(req.Delivery),
+		DeliveryTo:  strings.TrimSpace(req.DeliveryTo),
+		RetainDays:  req.RetainDays,
+	}
+	if svcCtx.ReportScheduler == nil {
+		return sc, fmt.Errorf("report scheduler is not enabled")
+	}
+	if sc.Name == "" {
+		return sc, fmt.Errorf("name is required")
+	}
+	if _, err := report.ParseCron(sc.Cron); err != nil {
+		return sc, err
+	}
+	if sc.Target == "" {
+		sc.Target = "records"
+	}
+	if sc.Target != "records" && sc.Target != "trajectory" && sc.Target != "both" {
+		return sc, fmt.Errorf("target must be records, trajectory or both")
+	}
+	if sc.Format == "" {
+		sc.Format = "xlsx"
+	}
+	switch {
+	case sc.Format == "xlsx", sc.Format == "csv", sc.Format == export.FormatParquet:
+	case export.IsGeoFormat(sc.Format):
+		if sc.Target == "records" {
+			return sc, fmt.Errorf("format %s is not supported for records", sc.Format)
+		}
+	default:
+		return sc, fmt.Errorf("unsupported format: %s", sc.Format)
+	}
+	if sc.PartitionBy != "" && sc.Format != export.FormatParquet {
+		return sc, fmt.Errorf("partitionBy is only supported for parquet")
+	}
+	if !export.IsParquetPartition(sc.PartitionBy) {
+		return sc, fmt.Errorf("partitionBy must be day or uas")
+	}
+	if sc.RangeHours == 0 {
+		sc.RangeHours = 24
+	}
+	if sc.RangeHours < 0 || sc.RangeHours > 24*366 {
+		return sc, fmt.Errorf("rangeHours must be between 1 and %d", 24*366)
+	}
+	if sc.RetainDays < 0 {
+		return sc, fmt.Errorf("retainDays must not be negative")
+	}
+	switch sc.Delivery {
+	case report.DeliveryLocal:
+		if !svcCtx.ReportScheduler.DeliveryDirConfigured() {
+			return sc, fmt.Errorf("Report.DeliveryDir is not configured")
+		}
+		if sc.DeliveryTo != filepath.Base(sc.DeliveryTo) && sc.DeliveryTo != "" {
+			return sc, fmt.Errorf("deliveryTo must be a single directory name for local delivery")
+		}
+	case report.DeliverySMTP:
+		if !svcCtx.ReportScheduler.SMTPConfigured() {
+			return sc, fmt.Errorf("Report.SMTP is not configured")
+		}
+		if sc.DeliveryTo == "" {
+			return sc, fmt.Errorf("deliveryTo (recipients) is required for smtp delivery")
+		}
+	case report.DeliveryWebhook:
+		if err := svcCtx.ReportScheduler.CheckWebhookURL(sc.DeliveryTo); err != nil {
+			return sc, err
+		}
+	default:
+		return sc, fmt.Errorf("delivery must be local, smtp or webhook")
+	}
+	return sc, nil
+}
+
+// getReportSchedule 查询报表计划，不存在时返回可读错误
+func getReportSchedule(svcCtx *svc.ServiceContext, id int64) (model.ReportSchedule, error) {
+	sc, err := svcCtx.Reports.GetReportSchedule(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return sc, fmt.Errorf("report schedule %d not found", id)
+	}
+	return sc, err
+}
+
+func toReportSchedule(s model.ReportSchedule) types.ReportSchedule {
+	return types.ReportSchedule{
+		ID:          s.ID,
+		Name:        s.Name,
+		Cron:        s.Cron,
+		Target:      s.Target,
+		Format:      s.Format,
+		PartitionBy: s.PartitionBy,
+		UasID:       s.UasID,
+		RangeHours:  s.RangeHours,
+		Delivery:    s.Delivery,
+		DeliveryTo:  s.DeliveryTo,
+		RetainDays:  s.RetainDays,
+		Enabled:     s.Enabled,
+		LastRunAt:   formatReportTime(s.LastRunAt),
+		NextRunAt:   formatReportTime(s.NextRunAt),
+		CreatedAt:   formatReportTime(s.CreatedAt),
+	}
+}
+
+// formatReportTime 零值时间返回空串
+func formatReportTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
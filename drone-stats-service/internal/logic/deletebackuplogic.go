@@ -0,0 +1,51 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"drone-stats-service/internal/audit"
+	"drone-stats-service/internal/backup"
+	"drone-stats-service/internal/model"
+	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type DeleteBackupLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewDeleteBackupLogic(ctx context.Context, svcCtx *svc.ServiceContext) *DeleteBackupLogic {
+	return &DeleteBackupLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// DeleteBackup 删除备份；被后续增量备份依赖时需指定 cascade，一并删除整条依赖链，避免留下无法恢复的增量备份
+func (l *DeleteBackupLogic) DeleteBackup(req *types.DeleteBackupReq) (resp *types.DeleteBackupResp, err error) {
+	if err := audit.CheckAdminToken(l.svcCtx.Config.Audit.AdminToken, req.AdminToken); err != nil {
+		return nil, err
+	}
+	deleted, err := l.svcCtx.Backup.Delete(req.ID, req.Cascade)
+	switch {
+	case errors.Is(err, backup.ErrBackupRunning):
+		return nil, fmt.Errorf("cannot delete while a backup is running")
+	case errors.Is(err, backup.ErrBackupNotFound):
+		return nil, fmt.Errorf("backup %s not found", req.ID)
+	case errors.Is(err, backup.ErrBackupHasDependents):
+		return nil, fmt.Errorf("backup %s is required by later incremental backups, retry with cascade=true to delete them too: %w", req.ID, err)
+	case err != nil:
+		return nil, fmt.Errorf("delete backup %s failed: %w", req.ID, err)
+	}
+	l.Infof("删除备份 %v actor=%s", deleted, audit.Actor(l.ctx))
+	// 旧值为被删除的备份（cascade 时含依赖它的增量备份）
+	recordAudit(l.ctx, l.svcCtx, model.AuditEntityBackup, req.ID, "backup", deleted, nil)
+	return &types.DeleteBackupResp{Deleted: deleted}, nil
+}
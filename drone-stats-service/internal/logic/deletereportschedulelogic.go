@@ -0,0 +1,43 @@
+package logic
+
+import (
+	"context"
+	"strconv"
+
+	"drone-stats-service/internal/audit"
+	"drone-stats-service/internal/model"
+	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type DeleteReportScheduleLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewDeleteReportScheduleLogic(ctx context.Context, svcCtx *svc.ServiceContext) *DeleteReportScheduleLogic {
+	return &DeleteReportScheduleLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// DeleteReportSchedule 删除计划；已有执行记录及存档保留，到期后照常清理
+func (l *DeleteReportScheduleLogic) DeleteReportSchedule(req *types.ReportScheduleIDReq) error {
+	if err := audit.CheckAdminToken(l.svcCtx.Config.Audit.AdminToken, req.AdminToken); err != nil {
+		return err
+	}
+	old, err := getReportSchedule(l.svcCtx, req.ID)
+	if err != nil {
+		return err
+	}
+	if err := l.svcCtx.Reports.DeleteReportSchedule(req.ID); err != nil {
+		return err
+	}
+	recordAudit(l.ctx, l.svcCtx, model.AuditEntityReportSchedule, strconv.FormatInt(req.ID, 10), "schedule", toReportSchedule(old), nil)
+	return nil
+}
@@ -0,0 +1,46 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"drone-stats-service/internal/dao"
+	"drone-stats-service/internal/model"
+	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type FlightHistoryLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewFlightHistoryLogic(ctx context.Context, svcCtx *svc.ServiceContext) *FlightHistoryLogic {
+	return &FlightHistoryLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// FlightHistory 返回单个架次载货量、票数的修改历史，按时间倒序
+func (l *FlightHistoryLogic) FlightHistory(req *types.FlightHistoryReq) (resp *types.AuditLogsResp, err error) {
+	f := dao.AuditFilter{Entity: model.AuditEntityFlightRecord, Limit: auditLimit(req.Limit)}
+	switch {
+	case req.ID > 0:
+		f.EntityID = strconv.Itoa(req.ID)
+	case req.OrderID != "":
+		f.OrderID = req.OrderID
+	default:
+		return nil, fmt.Errorf("id or orderID is required")
+	}
+	logs, err := l.svcCtx.Audits.GetAuditLogs(f)
+	if err != nil {
+		return nil, err
+	}
+	return toAuditLogsResp(logs), nil
+}
@@ -0,0 +1,79 @@
+package logic
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"drone-stats-service/internal/report"
+	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/track"
+	"drone-stats-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type FlightReportLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewFlightReportLogic(ctx context.Context, svcCtx *svc.ServiceContext) *FlightReportLogic {
+	return &FlightReportLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// FlightReport 生成单架次 PDF 报告，返回文件内容及下载文件名
+func (l *FlightReportLogic) FlightReport(req *types.FlightReportReq) (data []byte, fileName string, err error) {
+	if req.ID <= 0 && req.OrderID == "" {
+		return nil, "", fmt.Errorf("id or orderID is required")
+	}
+	rec, err := l.svcCtx.Records.GetFlightReportRecord(req.ID, req.OrderID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, "", fmt.Errorf("flight record not found")
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	// 未记录降落时间时取起飞后 24 小时内的轨迹点
+	end := rec.EndTime
+	if !rec.HasEndTime {
+		end = rec.StartTime.Add(24 * time.Hour)
+	}
+	points, err := l.svcCtx.TrackPoints.GetTrackPointsInRange([]string{rec.OrderID}, rec.StartTime, end)
+	if err != nil {
+		return nil, "", err
+	}
+	cleaned := track.Clean(points, trackCleanOptions(l.svcCtx))
+
+	c := l.svcCtx.Config.FlightReport
+	var buf bytes.Buffer
+	err = report.WriteFlightPDF(&buf, report.FlightReport{
+		Record:      rec,
+		Points:      cleaned.Points,
+		Gaps:        cleaned.Gaps,
+		TotalPoints: len(points),
+	}, report.FlightReportOptions{
+		LowSOCPercent: c.LowSOCPercent,
+		HighWindMps:   c.HighWindMps,
+		MaxHeightM:    c.MaxHeightM,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	name := strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e || strings.ContainsRune(`/\:*?"<>| `, r) {
+			return '_'
+		}
+		return r
+	}, rec.OrderID)
+	return buf.Bytes(), fmt.Sprintf("flight-report-%s-%s.pdf", name, rec.StartTime.Format("20060102150405")), nil
+}
@@ -2,11 +2,12 @@ package logic
 
 import (
 	"context"
+	"drone-stats-service/internal/battery"
 	"drone-stats-service/internal/model"
 	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/track"
 	"drone-stats-service/internal/types"
 	"fmt"
-	"math"
 	"time"
 
 	"github.com/zeromicro/go-zero/core/logx"
@@ -26,6 +27,8 @@ func NewGetFlightRecordsLogic(ctx context.Context, svcCtx *svc.ServiceContext) *
 	}
 }
 
+// GetFlightRecords 由原始遥测识别一个架次并保存飞行记录与轨迹点。起降判定与轨迹点依赖原始采样，
+// 降采样级别每个窗口只保留最后的 flightStatus，无法还原起降，因此该接口只读取原始数据
 func (l *GetFlightRecordsLogic) GetFlightRecords(req *types.FlightRecordReq) (resp *types.TrackResponse, err error) {
 	start, _ := time.Parse(time.RFC3339, req.StartTime)
 	end, _ := time.Parse(time.RFC3339, req.EndTime)
@@ -33,7 +36,11 @@ func (l *GetFlightRecordsLogic) GetFlightRecords(req *types.FlightRecordReq) (re
 	start = start.UTC()
 	end = end.UTC()
 
-	records, err := l.svcCtx.InfluxDao.QueryFlightRecords(req.OrderID, start, end)
+	// 该接口读取原始遥测，超出原始数据保留期时明确报错
+	if err := l.svcCtx.Downsample.CheckRaw(start); err != nil {
+		return nil, err
+	}
+	records, err := l.svcCtx.Telemetry.QueryFlightRecords(req.OrderID, start, end)
 	if err != nil {
 		return nil, err
 	}
@@ -108,85 +115,84 @@ func (l *GetFlightRecordsLogic) GetFlightRecords(req *types.FlightRecordReq) (re
 	startPoint := flightPoints[0]
 	endPoint := flightPoints[len(flightPoints)-1]
 
-	energyUsed := calcEnergyKWh(flightPoints)
-
-	// 计算距离：
-	// 1) 水平距离使用球面距离（基于经纬度）
-	// 2) 垂直距离为全过程上下移动距离（每段海拔变化的绝对值之和）
-	// 最终距离 = 水平距离 + 垂直移动距离（按用户要求将两者相加）
-	var totalDistance, horizontal, verticalMovement float64
-
-	for i := 1; i < len(flightPoints); i++ {
-		// 水平球面距离
-		lat1 := float64(getInt64(flightPoints[i-1], "latitude")) / 1e7
-		lng1 := float64(getInt64(flightPoints[i-1], "longitude")) / 1e7
-		lat2 := float64(getInt64(flightPoints[i], "latitude")) / 1e7
-		lng2 := float64(getInt64(flightPoints[i], "longitude")) / 1e7
-		horizontal += haversine(lat1, lng1, lat2, lng2)
-
-		// 全过程垂直移动距离（海拔单位按原代码除以10）
-		altPrev := float64(getInt64(flightPoints[i-1], "altitude")) / 10
-		altCurr := float64(getInt64(flightPoints[i], "altitude")) / 10
-		verticalMovement += math.Abs(altCurr - altPrev)
+	// 批量构造轨迹点，结构与flight_record.go同步
+	var trackPoints []model.FlightTrackPoint
+	for _, r := range flightPoints {
+		point := model.FlightTrackPoint{
+			OrderID:      req.OrderID,
+			FlightStatus: getString(r, "flightStatus"),
+			TimeStamp:    r["_time"].(time.Time),
+			Longitude:    getInt64(r, "longitude"),
+			Latitude:     getInt64(r, "latitude"),
+			HeightType:   int(getInt64(r, "heightType")),
+			Height:       int(getInt64(r, "height")),
+			Altitude:     int(getInt64(r, "altitude")),
+			VS:           int(getInt64(r, "VS")),
+			GS:           int(getInt64(r, "GS")),
+			Course:       int(getInt64(r, "course")),
+			SOC:          int(getInt64(r, "SOC")),
+			RM:           int(getInt64(r, "RM")),
+			Voltage:      int(getInt64(r, "voltage")),
+			Current:      int(getInt64(r, "current")),
+			WindSpeed:    int(getInt64(r, "windSpeed")),
+			WindDirect:   int(getInt64(r, "windDirect")),
+			Temperture:   int(getInt64(r, "temperture")),
+			Humidity:     int(getInt64(r, "humidity")),
+		}
+		trackPoints = append(trackPoints, point)
 	}
 
-	totalDistance = horizontal + verticalMovement
+	// 轨迹清洗：剔除跳点/零点/冻结点后再计算航程与耗电，清洗前的指标一并保存
+	cleaned := track.Clean(trackPoints, trackCleanOptions(l.svcCtx))
+	energyOpts := battery.EnergyOptions{
+		NominalCapacityAh: nominalCapacityAh(l.svcCtx, getString(startPoint, "uasID")),
+		NominalVoltageV:   l.svcCtx.Config.BatteryConf.NominalVoltageV,
+	}
+	rawDistance, _, _ := track.Distance(trackPoints)
+	rawEnergy := battery.Energy(trackPoints, energyOpts)
+	totalDistance, _, _ := track.Distance(cleaned.Points)
+	energy := rawEnergy
+	if len(cleaned.Points) >= 2 {
+		// 按真实采样间隔积分功率计算耗电
+		energy = battery.Energy(cleaned.Points, energyOpts)
+	}
 
 	// 存储到flight_records主表，注意经纬度/高度转换
 	fr := model.FlightRecord{
-		OrderID:     req.OrderID,
-		UasID:       getString(startPoint, "uasID"), // 新增，确保从influx数据中获取uasID
-		StartTime:   startPoint["_time"].(time.Time),
-		EndTime:     endPoint["_time"].(time.Time),
-		StartLat:    getInt64(startPoint, "latitude"),
-		StartLng:    getInt64(startPoint, "longitude"),
-		EndLat:      getInt64(endPoint, "latitude"),
-		EndLng:      getInt64(endPoint, "longitude"),
-		Distance:    totalDistance,
-		BatteryUsed: energyUsed,
-		Payload:     getFloat64(endPoint, "payload"),
+		OrderID:      req.OrderID,
+		UasID:        getString(startPoint, "uasID"), // 新增，确保从influx数据中获取uasID
+		StartTime:    startPoint["_time"].(time.Time),
+		EndTime:      endPoint["_time"].(time.Time),
+		StartLat:     getInt64(startPoint, "latitude"),
+		StartLng:     getInt64(startPoint, "longitude"),
+		EndLat:       getInt64(endPoint, "latitude"),
+		EndLng:       getInt64(endPoint, "longitude"),
+		Distance:     totalDistance,
+		BatteryUsed:  energy.KWh,
+		EnergyMethod: energy.Method,
+		Payload:      getFloat64(endPoint, "payload"),
+	}
+	if n := len(cleaned.Points); n > 0 {
+		// 起降点取清洗后的首尾点，避免起降时刻的 (0,0) 或跳点
+		fr.StartLat, fr.StartLng = cleaned.Points[0].Latitude, cleaned.Points[0].Longitude
+		fr.EndLat, fr.EndLng = cleaned.Points[n-1].Latitude, cleaned.Points[n-1].Longitude
 	}
 
 	// 新增：插入前判断是否已存在
-	exists, err := l.svcCtx.MySQLDao.FlightRecordExists(fr.OrderID, fr.StartTime, fr.EndTime)
+	exists, err := l.svcCtx.Records.FlightRecordExists(fr.OrderID, fr.StartTime, fr.EndTime)
 	if err != nil {
 		return nil, err
 	}
 	if exists {
 		l.Logger.Infof("该飞行架次已存在: uav_id=%s, start=%v, end=%v", fr.OrderID, fr.StartTime, fr.EndTime)
 		// 如果主表存在，但轨迹点为空，则尝试回填轨迹点
-		pts, err := l.svcCtx.MySQLDao.GetTrackPointsByRecordId(fr.OrderID)
+		pts, err := l.svcCtx.TrackPoints.GetTrackPointsByRecordId(fr.OrderID)
 		if err != nil {
 			return nil, err
 		}
 		if len(pts) == 0 {
 			// 构造并保存轨迹点（使用字符串 OrderID）
-			var trackPoints []model.FlightTrackPoint
-			for _, r := range flightPoints {
-				point := model.FlightTrackPoint{
-					OrderID:      fr.OrderID,
-					FlightStatus: getString(r, "flightStatus"),
-					TimeStamp:    r["_time"].(time.Time),
-					Longitude:    getInt64(r, "longitude"),
-					Latitude:     getInt64(r, "latitude"),
-					HeightType:   int(getInt64(r, "heightType")),
-					Height:       int(getInt64(r, "height")),
-					Altitude:     int(getInt64(r, "altitude")),
-					VS:           int(getInt64(r, "VS")),
-					GS:           int(getInt64(r, "GS")),
-					Course:       int(getInt64(r, "course")),
-					SOC:          int(getInt64(r, "SOC")),
-					RM:           int(getInt64(r, "RM")),
-					Voltage:      int(getInt64(r, "voltage")),
-					Current:      int(getInt64(r, "current")),
-					WindSpeed:    int(getInt64(r, "windSpeed")),
-					WindDirect:   int(getInt64(r, "windDirect")),
-					Temperture:   int(getInt64(r, "temperture")),
-					Humidity:     int(getInt64(r, "humidity")),
-				}
-				trackPoints = append(trackPoints, point)
-			}
-			if err := l.svcCtx.MySQLDao.SaveTrackPoints(trackPoints); err != nil {
+			if err := l.svcCtx.TrackPoints.SaveTrackPoints(trackPoints); err != nil {
 				fmt.Println("回填轨迹点失败:", err)
 			} else {
 				l.Logger.Infof("已为存在的飞行架次回填轨迹点: %s", fr.OrderID)
@@ -195,87 +201,33 @@ func (l *GetFlightRecordsLogic) GetFlightRecords(req *types.FlightRecordReq) (re
 		return &types.TrackResponse{}, nil
 	}
 
-	orderID, err := l.svcCtx.MySQLDao.SaveFlightRecordAndGetOrderID(fr)
+	orderID, err := l.svcCtx.Records.SaveFlightRecordAndGetOrderID(fr)
 	if err != nil {
 		return nil, err
 	} //else {
 	// 	l.Logger.Infof("当前架次: %s, 最终距离=%.2f, 水平距离=%.2f, 垂直移动距离=%.2f", fr.OrderID, totalDistance, horizontal, verticalMovement)
 	// }
 
-	// 批量构造轨迹点，结构与flight_record.go同步
-	var trackPoints []model.FlightTrackPoint
-	for _, r := range flightPoints {
-		point := model.FlightTrackPoint{
-			OrderID:      orderID,
-			FlightStatus: getString(r, "flightStatus"),
-			TimeStamp:    r["_time"].(time.Time),
-			Longitude:    getInt64(r, "longitude"),
-			Latitude:     getInt64(r, "latitude"),
-			HeightType:   int(getInt64(r, "heightType")),
-			Height:       int(getInt64(r, "height")),
-			Altitude:     int(getInt64(r, "altitude")),
-			VS:           int(getInt64(r, "VS")),
-			GS:           int(getInt64(r, "GS")),
-			Course:       int(getInt64(r, "course")),
-			SOC:          int(getInt64(r, "SOC")),
-			RM:           int(getInt64(r, "RM")),
-			Voltage:      int(getInt64(r, "voltage")),
-			Current:      int(getInt64(r, "current")),
-			WindSpeed:    int(getInt64(r, "windSpeed")),
-			WindDirect:   int(getInt64(r, "windDirect")),
-			Temperture:   int(getInt64(r, "temperture")),
-			Humidity:     int(getInt64(r, "humidity")),
-		}
-		trackPoints = append(trackPoints, point)
-	}
-
 	// 一次性批量插入
-	err = l.svcCtx.MySQLDao.SaveTrackPoints(trackPoints)
+	err = l.svcCtx.TrackPoints.SaveTrackPoints(trackPoints)
 	if err != nil {
 		fmt.Println("批量插入轨迹点失败:", err)
 	}
 
-	return
-}
+	// 保存轨迹清洗统计
+	if err := saveTrackCleaning(l.svcCtx, fr, cleaned, len(trackPoints), rawDistance, rawEnergy.KWh); err != nil {
+		fmt.Println("保存轨迹清洗统计失败:", err)
+	}
 
-// 计算两点间球面距离（单位：米）
-func haversine(lat1, lng1, lat2, lng2 float64) float64 {
-	const R = 6371000 // 地球半径，单位米
-	dLat := (lat2 - lat1) * math.Pi / 180
-	dLng := (lng2 - lng1) * math.Pi / 180
-	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
-		math.Cos(lat1*math.Pi/180)*math.Cos(lat2*math.Pi/180)*
-			math.Sin(dLng/2)*math.Sin(dLng/2)
-	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
-	return R * c
-}
+	// 计算并保存单架次电池指标
+	if err := saveBatteryMetrics(l.svcCtx, orderID, fr.UasID, fr.StartTime, trackPoints); err != nil {
+		fmt.Println("保存电池指标失败:", err)
+	}
 
-// 计算所有轨迹点消耗的电能（单位：kWh）
-func calcEnergyKWh(points []map[string]interface{}) float64 {
-	var totalEnergyWh float64
-	for _, p := range points {
-		voltage := 0.0
-		current := 0.0
-		// 支持多种类型
-		switch v := p["voltage"].(type) {
-		case int:
-			voltage = float64(v)
-		case int64:
-			voltage = float64(v)
-		case float64:
-			voltage = v
-		}
-		switch c := p["current"].(type) {
-		case int:
-			current = float64(c)
-		case int64:
-			current = float64(c)
-		case float64:
-			current = c
-		}
-		voltageV := voltage / 1000.0
-		currentA := current / 1000.0
-		totalEnergyWh += voltageV * currentA / 3600.0
+	// 检查起飞时是否已超期维保
+	if err := checkMaintenanceOnTakeoff(l.svcCtx, fr); err != nil {
+		fmt.Println("检查维保状态失败:", err)
 	}
-	return totalEnergyWh / 1000.0
+
+	return
 }
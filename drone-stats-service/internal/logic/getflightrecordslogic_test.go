@@ -0,0 +1,138 @@
+package logic
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"drone-stats-service/internal/config"
+	"drone-stats-service/internal/dao"
+	"drone-stats-service/internal/model"
+	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/types"
+)
+
+var flightBase = time.Date(2025, 6, 20, 8, 0, 0, 0, time.UTC)
+
+// newTestServiceContext 以内存 SQLite 与内存遥测构造的服务上下文
+func newTestServiceContext(t *testing.T) (*svc.ServiceContext, *dao.SQLDao, *dao.MemoryTelemetry) {
+	t.Helper()
+	d, err := dao.NewSQLiteDao(config.StorageConf{SQLitePath: ":memory:"}, config.MySQLConf{QueuePath: t.TempDir() + "/queue.jsonl"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mem := dao.NewMemoryTelemetry()
+	return &svc.ServiceContext{
+		Records:     d,
+		TrackPoints: d,
+		Sorties:     d,
+		Exports:     d,
+		Telemetry:   mem,
+		Battery:     d,
+		Maintenance: d,
+		Audits:      d,
+		Reports:     d,
+		Payloads:    d,
+		Store:       d,
+	}, d, mem
+}
+
+// writeFlight 写入一个架次的遥测：起飞、count-2 个飞行中点与降落，每 10 秒一个点
+func writeFlight(mem *dao.MemoryTelemetry, orderID, uasID string, start time.Time, count int) {
+	for i := 0; i < count; i++ {
+		status := "Inflight"
+		switch i {
+		case 0:
+			status = "TakeOff"
+		case count - 1:
+			status = "Land"
+		}
+		mem.Write("drone_status", map[string]string{"orderID": orderID}, map[string]interface{}{
+			"flightStatus": status,
+			"uasID":        uasID,
+			"longitude":    int64(1134000000 + i*1000),
+			"latitude":     int64(225000000 + i*1000),
+			"height":       int64(500),
+			"GS":           int64(100),
+			"SOC":          int64(90 - i),
+			"voltage":      int64(48000),
+			"current":      int64(20000),
+		}, start.Add(time.Duration(i)*10*time.Second))
+	}
+}
+
+func TestGetFlightRecords(t *testing.T) {
+	svcCtx, d, mem := newTestServiceContext(t)
+	// 每架次都需维保，此前已飞过一个架次，新架次起飞时即超期
+	if err := d.ReplaceMaintenancePlan(dao.DefaultMaintenanceModel, []model.MaintenancePlan{{Item: "桨叶", IntervalCycles: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SaveFlightRecord("O-0", "U1", flightBase.Add(-2*time.Hour), flightBase.Add(-100*time.Minute), 0, 0, 0, 0, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	writeFlight(mem, "O-1", "U1", flightBase, 6)
+	writeFlight(mem, "O-2", "U2", flightBase, 6)
+
+	req := &types.FlightRecordReq{
+		OrderID:   "O-1",
+		StartTime: flightBase.Add(-time.Minute).Format(time.RFC3339),
+		EndTime:   flightBase.Add(time.Hour).Format(time.RFC3339),
+	}
+	// 重复处理同一架次不重复入库
+	for i := 0; i < 2; i++ {
+		if _, err := NewGetFlightRecordsLogic(context.Background(), svcCtx).GetFlightRecords(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	page, err := d.QueryFlightRecordsPage(dao.FlightRecordQuery{OrderID: "O-1", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Records) != 1 {
+		t.Fatalf("records = %v, want 1", page.Records)
+	}
+	if r := page.Records[0]; r["uasID"] != "U1" || r["distance"].(float64) <= 0 {
+		t.Errorf("record = %v", r)
+	}
+	points, err := d.GetTrackPoints("O-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 6 || points[0].FlightStatus != "TakeOff" || points[5].FlightStatus != "Land" {
+		t.Errorf("track points = %+v", points)
+	}
+
+	logs, err := d.GetAuditLogs(dao.AuditFilter{OrderID: "O-1", Source: model.AuditSourceSystem, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 1 || logs[0].Field != "maintenance_overdue_items" || logs[0].NewValue != "桨叶" {
+		t.Errorf("audit logs = %+v, want one overdue flag", logs)
+	}
+}
+
+func TestGetFlightRecordsIncomplete(t *testing.T) {
+	svcCtx, d, mem := newTestServiceContext(t)
+	// 只有起飞与飞行中点，尚未降落
+	writeFlight(mem, "O-1", "U1", flightBase, 6)
+	req := &types.FlightRecordReq{
+		OrderID:   "O-1",
+		StartTime: flightBase.Add(-time.Minute).Format(time.RFC3339),
+		EndTime:   flightBase.Add(45 * time.Second).Format(time.RFC3339),
+	}
+	if _, err := NewGetFlightRecordsLogic(context.Background(), svcCtx).GetFlightRecords(req); err != nil {
+		t.Fatal(err)
+	}
+	exists, err := d.FlightRecordExists("O-1", flightBase, flightBase.Add(50*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	points, err := d.GetTrackPoints("O-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exists || len(points) != 0 {
+		t.Errorf("incomplete flight saved: exists = %v, points = %d", exists, len(points))
+	}
+}
@@ -0,0 +1,67 @@
+package logic
+
+import (
+	"context"
+	"time"
+
+	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type HealthLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewHealthLogic(ctx context.Context, svcCtx *svc.ServiceContext) *HealthLogic {
+	return &HealthLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// Health 检查 MySQL 连通性并返回本地重放队列统计；MySQL 不可用或存在死信批次时状态为 degraded
+func (l *HealthLogic) Health() (resp *types.HealthResp, err error) {
+	now := time.Now()
+	resp = &types.HealthResp{Status: "ok", Time: formatReportTime(now)}
+
+	ctx, cancel := context.WithTimeout(l.ctx, 2*time.Second)
+	defer cancel()
+	if err := l.svcCtx.Store.Ping(ctx); err != nil {
+		resp.MySQL.Error = err.Error()
+	} else {
+		resp.MySQL.OK = true
+	}
+
+	if q := l.svcCtx.Store.Queue(); q != nil {
+		s, err := q.Stats()
+		if err != nil {
+			resp.Queue.Error = err.Error()
+		}
+		resp.Queue = types.QueueHealth{
+			Pending:         s.Pending,
+			PendingBytes:    s.PendingBytes,
+			Dead:            s.Dead,
+			DeadBytes:       s.DeadBytes,
+			OldestPendingAt: formatReportTime(s.OldestPendingAt),
+			MaxAttempts:     s.MaxAttempts,
+			Replayed:        s.Replayed,
+			Failed:          s.Failed,
+			DeadLettered:    s.DeadLettered,
+			FileSize:        s.FileSize,
+			Error:           resp.Queue.Error,
+		}
+		if !s.OldestPendingAt.IsZero() {
+			resp.Queue.OldestPendingAgeSec = int64(now.Sub(s.OldestPendingAt).Seconds())
+		}
+	}
+
+	if !resp.MySQL.OK || resp.Queue.Dead > 0 || resp.Queue.Error != "" {
+		resp.Status = "degraded"
+	}
+	return resp, nil
+}
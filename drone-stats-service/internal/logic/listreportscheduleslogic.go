@@ -0,0 +1,36 @@
+package logic
+
+import (
+	"context"
+
+	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type ListReportSchedulesLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewListReportSchedulesLogic(ctx context.Context, svcCtx *svc.ServiceContext) *ListReportSchedulesLogic {
+	return &ListReportSchedulesLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *ListReportSchedulesLogic) ListReportSchedules() (resp *types.ReportSchedulesResp, err error) {
+	list, err := l.svcCtx.Reports.ListReportSchedules(false)
+	if err != nil {
+		return nil, err
+	}
+	resp = &types.ReportSchedulesResp{Schedules: []types.ReportSchedule{}}
+	for _, s := range list {
+		resp.Schedules = append(resp.Schedules, toReportSchedule(s))
+	}
+	return resp, nil
+}
@@ -0,0 +1,51 @@
+package logic
+
+import (
+	"context"
+
+	"drone-stats-service/internal/model"
+	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type MaintenancePlanLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewMaintenancePlanLogic(ctx context.Context, svcCtx *svc.ServiceContext) *MaintenancePlanLogic {
+	return &MaintenancePlanLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *MaintenancePlanLogic) MaintenancePlan(req *types.MaintenancePlanReq) (resp *types.MaintenancePlanResp, err error) {
+	plans, err := l.svcCtx.Maintenance.GetMaintenancePlans(req.Model)
+	if err != nil {
+		return nil, err
+	}
+	resp = &types.MaintenancePlanResp{Plans: []types.MaintenancePlan{}}
+	// 结果已按机型排序，逐段分组
+	for _, p := range plans {
+		if n := len(resp.Plans); n == 0 || resp.Plans[n-1].Model != p.Model {
+			resp.Plans = append(resp.Plans, types.MaintenancePlan{Model: p.Model})
+		}
+		last := &resp.Plans[len(resp.Plans)-1]
+		last.Items = append(last.Items, toMaintenancePlanItem(p))
+	}
+	return resp, nil
+}
+
+func toMaintenancePlanItem(p model.MaintenancePlan) types.MaintenancePlanItem {
+	return types.MaintenancePlanItem{
+		Item:           p.Item,
+		IntervalHours:  p.IntervalHours,
+		IntervalCycles: p.IntervalCycles,
+		Description:    p.Description,
+	}
+}
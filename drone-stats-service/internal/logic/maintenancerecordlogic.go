@@ -0,0 +1,92 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"drone-stats-service/internal/model"
+	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type MaintenanceRecordLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewMaintenanceRecordLogic(ctx context.Context, svcCtx *svc.ServiceContext) *MaintenanceRecordLogic {
+	return &MaintenanceRecordLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// MaintenanceRecord 登记维保完成，记录完成时的累计飞行小时与架次作为下次到期的起点
+func (l *MaintenanceRecordLogic) MaintenanceRecord(req *types.MaintenanceRecordReq) (resp *types.MaintenanceRecord, err error) {
+	if req.UasID == "" || req.Item == "" {
+		return nil, fmt.Errorf("uasID and item are required")
+	}
+	_, planModel, plans, err := uasMaintenancePlan(l.svcCtx, req.UasID)
+	if err != nil {
+		return nil, err
+	}
+	found := false
+	for _, p := range plans {
+		if p.Item == req.Item {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("item %s is not in maintenance plan %s", req.Item, planModel)
+	}
+
+	loc := l.svcCtx.Records.Location()
+	performedAt, err := parseStatsTime(req.PerformedAt, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid performedAt: %w", err)
+	}
+	if performedAt.IsZero() {
+		performedAt = time.Now()
+	}
+	if performedAt.After(time.Now()) {
+		return nil, fmt.Errorf("performedAt is in the future")
+	}
+	totals, err := l.svcCtx.Records.GetUasFlightTotals([]string{req.UasID}, time.Time{}, performedAt)
+	if err != nil {
+		return nil, err
+	}
+	r := model.MaintenanceRecord{
+		UasID:       req.UasID,
+		Item:        req.Item,
+		PerformedAt: performedAt.In(loc),
+		Technician:  req.Technician,
+		Note:        req.Note,
+	}
+	if t, ok := totals[req.UasID]; ok {
+		r.FlightHours = t.FlightSeconds / 3600
+		r.Cycles = t.Flights
+	}
+	r.ID, err = l.svcCtx.Maintenance.SaveMaintenanceRecord(r)
+	if err != nil {
+		return nil, err
+	}
+	resp = &types.MaintenanceRecord{
+		ID:          r.ID,
+		UasID:       r.UasID,
+		Item:        r.Item,
+		PerformedAt: r.PerformedAt.Format("2006-01-02 15:04:05"),
+		FlightHours: r.FlightHours,
+		Cycles:      r.Cycles,
+		Technician:  r.Technician,
+		Note:        r.Note,
+	}
+	recordAudit(l.ctx, l.svcCtx, model.AuditEntityMaintenanceRecord, strconv.Itoa(r.ID), "record", nil, resp)
+	return resp, nil
+}
@@ -0,0 +1,198 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"drone-stats-service/internal/dao"
+	"drone-stats-service/internal/model"
+	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// 维保项状态，按严重程度递增
+const (
+	MaintenanceStatusOK      = "ok"
+	MaintenanceStatusDue     = "due"
+	MaintenanceStatusOverdue = "overdue"
+)
+
+const defaultDueSoonPercent = 10
+
+type MaintenanceStatusLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewMaintenanceStatusLogic(ctx context.Context, svcCtx *svc.ServiceContext) *MaintenanceStatusLogic {
+	return &MaintenanceStatusLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *MaintenanceStatusLogic) MaintenanceStatus(req *types.MaintenanceStatusReq) (resp *types.MaintenanceStatusResp, err error) {
+	return buildMaintenanceStatus(l.svcCtx, req.UasID, time.Time{})
+}
+
+// uasMaintenancePlan 返回无人机适用的维保计划：优先按机型，机型未登记或无计划时使用 default 计划
+func uasMaintenancePlan(svcCtx *svc.ServiceContext, uasID string) (uasModel, planModel string, plans []model.MaintenancePlan, err error) {
+	uasModel, err = svcCtx.Sorties.GetUasModel(uasID)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if uasModel != "" {
+		plans, err = svcCtx.Maintenance.GetMaintenancePlans(uasModel)
+		if err != nil {
+			return "", "", nil, err
+		}
+		if len(plans) > 0 {
+			return uasModel, uasModel, plans, nil
+		}
+	}
+	plans, err = svcCtx.Maintenance.GetMaintenancePlans(dao.DefaultMaintenanceModel)
+	return uasModel, dao.DefaultMaintenanceModel, plans, err
+}
+
+// buildMaintenanceStatus 计算无人机在 asOf 时刻（零值表示当前）各维保项的状态，
+// 累计小时/架次只统计 asOf 之前起飞的架次
+func buildMaintenanceStatus(svcCtx *svc.ServiceContext, uasID string, asOf time.Time) (*types.MaintenanceStatusResp, error) {
+	if uasID == "" {
+		return nil, fmt.Errorf("uasID is required")
+	}
+	uasModel, planModel, plans, err := uasMaintenancePlan(svcCtx, uasID)
+	if err != nil {
+		return nil, err
+	}
+	totals, err := svcCtx.Records.GetUasFlightTotals([]string{uasID}, time.Time{}, asOf)
+	if err != nil {
+		return nil, err
+	}
+	records, err := svcCtx.Maintenance.GetLatestMaintenanceRecords(uasID, asOf)
+	if err != nil {
+		return nil, err
+	}
+	resp, lastMaintenance := computeMaintenanceStatus(svcCtx, uasID, uasModel, planModel, plans, totals[uasID], records)
+	if asOf.IsZero() {
+		resp.OverdueFlights, err = svcCtx.Maintenance.CountMaintenanceOverdueFlights(uasID, lastMaintenance)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// computeMaintenanceStatus 根据已查询的计划、累计量与最近维保记录计算各维保项状态（不访问数据库），
+// 同时返回最近一次维保时间；t 为 nil 表示无飞行记录
+func computeMaintenanceStatus(svcCtx *svc.ServiceContext, uasID, uasModel, planModel string, plans []model.MaintenancePlan,
+	t *dao.UasFlightTotals, records map[string]model.MaintenanceRecord) (*types.MaintenanceStatusResp, time.Time) {
+	if t == nil {
+		t = &dao.UasFlightTotals{UasID: uasID}
+	}
+	dueSoon := svcCtx.Config.Maintenance.DueSoonPercent
+	if dueSoon <= 0 {
+		dueSoon = defaultDueSoonPercent
+	}
+	hours := t.FlightSeconds / 3600
+	resp := &types.MaintenanceStatusResp{
+		UasID:       uasID,
+		Model:       uasModel,
+		PlanModel:   planModel,
+		FlightHours: hours,
+		Cycles:      t.Flights,
+		Status:      MaintenanceStatusOK,
+		Items:       make([]types.MaintenanceItemStatus, 0, len(plans)),
+	}
+	var lastMaintenance time.Time
+	for _, p := range plans {
+		item := types.MaintenanceItemStatus{
+			UasID:          uasID,
+			Item:           p.Item,
+			IntervalHours:  p.IntervalHours,
+			IntervalCycles: p.IntervalCycles,
+			HoursSince:     hours,
+			CyclesSince:    t.Flights,
+		}
+		if r, ok := records[p.Item]; ok {
+			item.LastPerformedAt = r.PerformedAt.Format("2006-01-02 15:04:05")
+			item.HoursSince = hours - r.FlightHours
+			item.CyclesSince = t.Flights - r.Cycles
+			if r.PerformedAt.After(lastMaintenance) {
+				lastMaintenance = r.PerformedAt
+			}
+		}
+		item.RemainingHours = p.IntervalHours - item.HoursSince
+		item.RemainingCycles = p.IntervalCycles - item.CyclesSince
+		item.Status = maintenanceItemStatus(p, item, dueSoon)
+		if maintenanceSeverity(item.Status) > maintenanceSeverity(resp.Status) {
+			resp.Status = item.Status
+		}
+		resp.Items = append(resp.Items, item)
+	}
+	// 仅为提示标记：本服务不参与调度，不会拦截起飞；超期起飞的架次在入库时标记 maintenance_overdue
+	resp.Blocked = resp.Status == MaintenanceStatusOverdue && svcCtx.Config.Maintenance.BlockOverdue
+	return resp, lastMaintenance
+}
+
+// maintenanceItemStatus 任一间隔用尽即为 overdue，剩余量低于间隔的 dueSoon% 为 due；间隔为 0 的维度不参与判断
+func maintenanceItemStatus(p model.MaintenancePlan, item types.MaintenanceItemStatus, dueSoon float64) string {
+	status := MaintenanceStatusOK
+	if p.IntervalHours > 0 {
+		if item.RemainingHours <= 0 {
+			return MaintenanceStatusOverdue
+		}
+		if item.RemainingHours <= p.IntervalHours*dueSoon/100 {
+			status = MaintenanceStatusDue
+		}
+	}
+	if p.IntervalCycles > 0 {
+		if item.RemainingCycles <= 0 {
+			return MaintenanceStatusOverdue
+		}
+		if float64(item.RemainingCycles) <= float64(p.IntervalCycles)*dueSoon/100 {
+			status = MaintenanceStatusDue
+		}
+	}
+	return status
+}
+
+func maintenanceSeverity(status string) int {
+	switch status {
+	case MaintenanceStatusOverdue:
+		return 2
+	case MaintenanceStatusDue:
+		return 1
+	}
+	return 0
+}
+
+// checkMaintenanceOnTakeoff 新架次入库后检查起飞时是否已超期维保，超期则标记该架次
+func checkMaintenanceOnTakeoff(svcCtx *svc.ServiceContext, fr model.FlightRecord) error {
+	status, err := buildMaintenanceStatus(svcCtx, fr.UasID, fr.StartTime)
+	if err != nil {
+		return err
+	}
+	if status.Status != MaintenanceStatusOverdue {
+		return nil
+	}
+	var items string
+	for _, it := range status.Items {
+		if it.Status != MaintenanceStatusOverdue {
+			continue
+		}
+		if items != "" {
+			items += ","
+		}
+		items += it.Item
+	}
+	if len(items) > 255 {
+		items = items[:255]
+	}
+	logx.Errorf("无人机 %s 超期未维保仍在飞行: OrderID=%s, start=%v, items=%s", fr.UasID, fr.OrderID, fr.StartTime, items)
+	return svcCtx.Maintenance.FlagMaintenanceOverdue(fr.OrderID, fr.StartTime, items)
+}
@@ -0,0 +1,218 @@
+package logic
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"drone-stats-service/internal/config"
+	"drone-stats-service/internal/dao"
+	"drone-stats-service/internal/model"
+	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/types"
+)
+
+func TestMaintenanceItemStatus(t *testing.T) {
+	hours := model.MaintenancePlan{IntervalHours: 100}
+	cycles := model.MaintenancePlan{IntervalCycles: 50}
+	both := model.MaintenancePlan{IntervalHours: 100, IntervalCycles: 50}
+	tests := []struct {
+		name      string
+		plan      model.MaintenancePlan
+		remHours  float64
+		remCycles int
+		want      string
+	}{
+		{name: "按小时，恰好到期", plan: hours, remHours: 0, want: MaintenanceStatusOverdue},
+		{name: "按小时，已超期", plan: hours, remHours: -0.5, want: MaintenanceStatusOverdue},
+		{name: "按小时，剩余恰为 10%", plan: hours, remHours: 10, want: MaintenanceStatusDue},
+		{name: "按小时，剩余略多于 10%", plan: hours, remHours: 10.01, want: MaintenanceStatusOK},
+		{name: "按架次，恰好到期", plan: cycles, remCycles: 0, want: MaintenanceStatusOverdue},
+		{name: "按架次，剩余恰为 10%", plan: cycles, remCycles: 5, want: MaintenanceStatusDue},
+		{name: "按架次，剩余多于 10%", plan: cycles, remCycles: 6, want: MaintenanceStatusOK},
+		{name: "按架次计划忽略小时", plan: cycles, remHours: -10, remCycles: 20, want: MaintenanceStatusOK},
+		{name: "按小时计划忽略架次", plan: hours, remHours: 50, remCycles: -3, want: MaintenanceStatusOK},
+		{name: "双维度，架次到期", plan: both, remHours: 50, remCycles: 0, want: MaintenanceStatusOverdue},
+		{name: "双维度，小时即将到期", plan: both, remHours: 5, remCycles: 40, want: MaintenanceStatusDue},
+		{name: "双维度，小时即将到期且架次超期", plan: both, remHours: 5, remCycles: -1, want: MaintenanceStatusOverdue},
+		{name: "未配置间隔", remHours: -1, remCycles: -1, want: MaintenanceStatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := types.MaintenanceItemStatus{RemainingHours: tt.remHours, RemainingCycles: tt.remCycles}
+			if got := maintenanceItemStatus(tt.plan, item, defaultDueSoonPercent); got != tt.want {
+				t.Errorf("status = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComputeMaintenanceStatus(t *testing.T) {
+	plans := []model.MaintenancePlan{
+		{Item: "桨叶", IntervalCycles: 10},
+		{Item: "电机", IntervalHours: 20},
+	}
+	performed := flightBase.Add(-24 * time.Hour)
+	tests := []struct {
+		name    string
+		conf    config.MaintenanceConf
+		totals  *dao.UasFlightTotals
+		records map[string]model.MaintenanceRecord
+		want    []string // 各维保项状态，顺序同 plans
+		status  string
+		blocked bool
+	}{
+		{
+			name:   "无飞行记录",
+			want:   []string{MaintenanceStatusOK, MaintenanceStatusOK},
+			status: MaintenanceStatusOK,
+		},
+		{
+			// 无维保记录时从首个架次起累计：10 架次恰好到期，19 小时剩余 1 小时（5%）
+			name:   "无维保记录",
+			totals: &dao.UasFlightTotals{Flights: 10, FlightSeconds: 19 * 3600},
+			want:   []string{MaintenanceStatusOverdue, MaintenanceStatusDue},
+			status: MaintenanceStatusOverdue,
+		},
+		{
+			// 自上次维保起 9 架次、10 小时
+			name:   "从上次维保起累计",
+			totals: &dao.UasFlightTotals{Flights: 12, FlightSeconds: 25 * 3600},
+			records: map[string]model.MaintenanceRecord{
+				"桨叶": {Item: "桨叶", PerformedAt: performed, Cycles: 3},
+				"电机": {Item: "电机", PerformedAt: performed, FlightHours: 15},
+			},
+			want:   []string{MaintenanceStatusDue, MaintenanceStatusOK},
+			status: MaintenanceStatusDue,
+		},
+		{
+			// 即将到期阈值调为 50%：剩余 6 架次仍为 ok，剩余 10 小时为 due
+			name:   "自定义即将到期比例",
+			conf:   config.MaintenanceConf{DueSoonPercent: 50},
+			totals: &dao.UasFlightTotals{Flights: 4, FlightSeconds: 10 * 3600},
+			want:   []string{MaintenanceStatusOK, MaintenanceStatusDue},
+			status: MaintenanceStatusDue,
+		},
+		{
+			name:    "超期且配置 BlockOverdue",
+			conf:    config.MaintenanceConf{BlockOverdue: true},
+			totals:  &dao.UasFlightTotals{Flights: 11, FlightSeconds: 3600},
+			want:    []string{MaintenanceStatusOverdue, MaintenanceStatusOK},
+			status:  MaintenanceStatusOverdue,
+			blocked: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svcCtx := &svc.ServiceContext{Config: config.Config{Maintenance: tt.conf}}
+			resp, last := computeMaintenanceStatus(svcCtx, "U1", "", dao.DefaultMaintenanceModel, plans, tt.totals, tt.records)
+			if len(resp.Items) != len(plans) {
+				t.Fatalf("items = %+v", resp.Items)
+			}
+			for i, it := range resp.Items {
+				if it.Status != tt.want[i] {
+					t.Errorf("%s status = %s, want %s (remaining %v h / %d cycles)", it.Item, it.Status, tt.want[i], it.RemainingHours, it.RemainingCycles)
+				}
+			}
+			if resp.Status != tt.status || resp.Blocked != tt.blocked {
+				t.Errorf("status = %s, blocked = %v, want %s, %v", resp.Status, resp.Blocked, tt.status, tt.blocked)
+			}
+			if wantLast := (tt.records != nil); wantLast != last.Equal(performed) {
+				t.Errorf("last maintenance = %v", last)
+			}
+		})
+	}
+}
+
+func TestBuildMaintenanceStatusAsOf(t *testing.T) {
+	svcCtx, d, _ := newTestServiceContext(t)
+	if err := d.ReplaceMaintenancePlan(dao.DefaultMaintenanceModel, []model.MaintenancePlan{{Item: "桨叶", IntervalCycles: 2}}); err != nil {
+		t.Fatal(err)
+	}
+	// 每小时起飞一个 30 分钟的架次
+	for i, orderID := range []string{"O-1", "O-2", "O-3"} {
+		start := flightBase.Add(time.Duration(i) * time.Hour)
+		if err := d.SaveFlightRecord(orderID, "U1", start, start.Add(30*time.Minute), 0, 0, 0, 0, 0, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// O-1 之后维保，完成时累计 1 架次
+	if _, err := d.SaveMaintenanceRecord(model.MaintenanceRecord{UasID: "U1", Item: "桨叶", PerformedAt: flightBase.Add(time.Hour), Cycles: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		asOf   time.Time
+		cycles int // 自上次维保（或首个架次）起的架次
+		status string
+	}{
+		// asOf 时刻起飞的架次与完成的维保均不计入
+		{name: "O-2 起飞时", asOf: flightBase.Add(time.Hour), cycles: 1, status: MaintenanceStatusOK},
+		{name: "O-3 起飞时", asOf: flightBase.Add(2 * time.Hour), cycles: 1, status: MaintenanceStatusOK},
+		{name: "O-3 之后", asOf: flightBase.Add(3 * time.Hour), cycles: 2, status: MaintenanceStatusOverdue},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := buildMaintenanceStatus(svcCtx, "U1", tt.asOf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Items) != 1 || resp.Items[0].CyclesSince != tt.cycles || resp.Status != tt.status {
+				t.Errorf("resp = %+v, want %d cycles since, %s", resp, tt.cycles, tt.status)
+			}
+		})
+	}
+}
+
+func TestMaintenanceUpcoming(t *testing.T) {
+	svcCtx, d, _ := newTestServiceContext(t)
+	if err := d.ReplaceMaintenancePlan(dao.DefaultMaintenanceModel, []model.MaintenancePlan{
+		{Item: "桨叶", IntervalCycles: 10},
+		{Item: "电机", IntervalHours: 1},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// U1 飞 9 架次共 0.75 小时：桨叶 due；U2 飞 10 架次共 0.95 小时：桨叶 overdue、电机 due；U3 无维保到期
+	for uasID, n := range map[string]int{"U1": 9, "U2": 10, "U3": 1} {
+		for i := 0; i < n; i++ {
+			start := flightBase.Add(time.Duration(i) * time.Hour)
+			dur := 5 * time.Minute
+			if uasID == "U2" {
+				dur = 5*time.Minute + 42*time.Second
+			}
+			if err := d.SaveFlightRecord(uasID+"-"+start.Format("15"), uasID, start, start.Add(dur), 0, 0, 0, 0, 0, 0); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	tests := []struct {
+		status string
+		want   []string // uasID/维保项/状态
+	}{
+		// 默认只返回需关注的项，先 overdue，同级按剩余比例升序
+		{status: "", want: []string{"U2/桨叶/overdue", "U2/电机/due", "U1/桨叶/due"}},
+		{status: MaintenanceStatusDue, want: []string{"U2/电机/due", "U1/桨叶/due"}},
+		{status: MaintenanceStatusOK, want: []string{"U1/电机/ok", "U3/桨叶/ok", "U3/电机/ok"}},
+	}
+	for _, tt := range tests {
+		t.Run("status="+tt.status, func(t *testing.T) {
+			resp, err := NewMaintenanceUpcomingLogic(context.Background(), svcCtx).MaintenanceUpcoming(&types.MaintenanceUpcomingReq{Status: tt.status})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, it := range resp.Items {
+				got = append(got, it.UasID+"/"+it.Item+"/"+it.Status)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("items = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if _, err := NewMaintenanceUpcomingLogic(context.Background(), svcCtx).MaintenanceUpcoming(&types.MaintenanceUpcomingReq{Status: "unknown"}); err == nil {
+		t.Error("unknown status accepted")
+	}
+}
@@ -0,0 +1,103 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"drone-stats-service/internal/dao"
+	"drone-stats-service/internal/model"
+	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type MaintenanceUpcomingLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewMaintenanceUpcomingLogic(ctx context.Context, svcCtx *svc.ServiceContext) *MaintenanceUpcomingLogic {
+	return &MaintenanceUpcomingLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// MaintenanceUpcoming 汇总全部无人机的维保项，按严重程度、剩余量排序
+func (l *MaintenanceUpcomingLogic) MaintenanceUpcoming(req *types.MaintenanceUpcomingReq) (resp *types.MaintenanceUpcomingResp, err error) {
+	switch req.Status {
+	case "", MaintenanceStatusOK, MaintenanceStatusDue, MaintenanceStatusOverdue:
+	default:
+		return nil, fmt.Errorf("unsupported status: %s", req.Status)
+	}
+	// 一次性查询全部无人机的机型、维保计划、累计量与维保记录，避免逐架查询
+	ids, err := l.svcCtx.Sorties.ListUasIDs()
+	if err != nil {
+		return nil, err
+	}
+	models, err := l.svcCtx.Sorties.GetUasModels()
+	if err != nil {
+		return nil, err
+	}
+	allPlans, err := l.svcCtx.Maintenance.GetMaintenancePlans("")
+	if err != nil {
+		return nil, err
+	}
+	plansByModel := make(map[string][]model.MaintenancePlan)
+	for _, p := range allPlans {
+		plansByModel[p.Model] = append(plansByModel[p.Model], p)
+	}
+	totals, err := l.svcCtx.Records.GetUasFlightTotals(ids, time.Time{}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	records, err := l.svcCtx.Maintenance.GetAllLatestMaintenanceRecords(time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	resp = &types.MaintenanceUpcomingResp{Items: []types.MaintenanceItemStatus{}}
+	for _, id := range ids {
+		// 与 uasMaintenancePlan 一致：机型无专属计划时使用 default 计划
+		uasModel, planModel := models[id], dao.DefaultMaintenanceModel
+		if uasModel != "" && len(plansByModel[uasModel]) > 0 {
+			planModel = uasModel
+		}
+		status, _ := computeMaintenanceStatus(l.svcCtx, id, uasModel, planModel, plansByModel[planModel], totals[id], records[id])
+		for _, it := range status.Items {
+			if req.Status == "" && it.Status == MaintenanceStatusOK {
+				continue
+			}
+			if req.Status != "" && it.Status != req.Status {
+				continue
+			}
+			resp.Items = append(resp.Items, it)
+		}
+	}
+	sort.SliceStable(resp.Items, func(i, j int) bool {
+		a, b := resp.Items[i], resp.Items[j]
+		if sa, sb := maintenanceSeverity(a.Status), maintenanceSeverity(b.Status); sa != sb {
+			return sa > sb
+		}
+		return remainingFraction(a) < remainingFraction(b)
+	})
+	return resp, nil
+}
+
+// remainingFraction 剩余量占间隔的比例，取小时与架次中较小者
+func remainingFraction(it types.MaintenanceItemStatus) float64 {
+	f := 1.0
+	if it.IntervalHours > 0 {
+		f = it.RemainingHours / it.IntervalHours
+	}
+	if it.IntervalCycles > 0 {
+		if c := float64(it.RemainingCycles) / float64(it.IntervalCycles); c < f {
+			f = c
+		}
+	}
+	return f
+}
@@ -3,6 +3,7 @@ package logic
 import (
 	"context"
 
+	"drone-stats-service/internal/dao"
 	"drone-stats-service/internal/svc"
 	"drone-stats-service/internal/types"
 
@@ -23,14 +24,52 @@ func NewQueryFlightRecordsLogic(ctx context.Context, svcCtx *svc.ServiceContext)
 	}
 }
 
-func (l *QueryFlightRecordsLogic) QueryFlightRecords(req *types.FlightRecordReq) (resp *types.FlightRecordsResponse, err error) {
-	// 字段名已变更：OrderID, StartTime, EndTime
-	records, err := l.svcCtx.MySQLDao.QueryFlightRecords(req.OrderID, req.UasID, req.StartTime, req.EndTime)
+// 兼容旧接口：仅传单个 uasID 时，默认只返回起飞点位于该区域内的架次
+var legacyUasStartArea = dao.BoundingBox{
+	MinLat: -900000000,
+	MaxLat: 228000000 - 1,
+	MinLng: 1139430000 + 1,
+	MaxLng: 1800000000,
+}
+
+func (l *QueryFlightRecordsLogic) QueryFlightRecords(req *types.FlightRecordQueryReq) (resp *types.FlightRecordsResponse, err error) {
+	q := dao.FlightRecordQuery{
+		OrderID:        req.OrderID,
+		UasIDs:         req.UasIDs,
+		Model:          req.Model,
+		StartTime:      req.StartTime,
+		EndTime:        req.EndTime,
+		MinDistance:    req.MinDistance,
+		MaxDistance:    req.MaxDistance,
+		MinDuration:    req.MinDuration,
+		MaxDuration:    req.MaxDuration,
+		MinPayload:     req.MinPayload,
+		MaxPayload:     req.MaxPayload,
+		StartBBox:      toDaoBBox(req.StartBBox),
+		EndBBox:        toDaoBBox(req.EndBBox),
+		IncompleteOnly: req.IncompleteOnly,
+		SortBy:         req.SortBy,
+		SortOrder:      req.SortOrder,
+		Limit:          req.Limit,
+		Offset:         req.Offset,
+		Cursor:         req.Cursor,
+	}
+	if req.UasID != "" {
+		q.UasIDs = append(q.UasIDs, req.UasID)
+		if q.StartBBox == nil {
+			area := legacyUasStartArea
+			q.StartBBox = &area
+		}
+	}
+	page, err := l.svcCtx.Records.QueryFlightRecordsPage(q)
 	if err != nil {
 		return nil, err
 	}
-	resp = &types.FlightRecordsResponse{}
-	for _, r := range records {
+	resp = &types.FlightRecordsResponse{
+		Total:      page.Total,
+		NextCursor: page.NextCursor,
+	}
+	for _, r := range page.Records {
 		resp.Flightrecords = append(resp.Flightrecords, types.FlightRecord{
 			ID:           r["id"].(int),
 			OrderID:      r["OrderID"].(string),
@@ -50,3 +89,10 @@ func (l *QueryFlightRecordsLogic) QueryFlightRecords(req *types.FlightRecordReq)
 	}
 	return resp, nil
 }
+
+func toDaoBBox(b *types.BoundingBox) *dao.BoundingBox {
+	if b == nil {
+		return nil
+	}
+	return &dao.BoundingBox{MinLat: b.MinLat, MinLng: b.MinLng, MaxLat: b.MaxLat, MaxLng: b.MaxLng}
+}
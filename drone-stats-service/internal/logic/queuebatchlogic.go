@@ -0,0 +1,51 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"drone-stats-service/internal/audit"
+	"drone-stats-service/internal/dao"
+	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type QueueBatchLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewQueueBatchLogic(ctx context.Context, svcCtx *svc.ServiceContext) *QueueBatchLogic {
+	return &QueueBatchLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// QueueBatch 返回单个队列批次及其轨迹点，便于判断被拒绝的原因
+func (l *QueueBatchLogic) QueueBatch(req *types.QueueBatchReq) (resp *types.QueueBatchResp, err error) {
+	if err := audit.CheckAdminToken(l.svcCtx.Config.Audit.AdminToken, req.AdminToken); err != nil {
+		return nil, err
+	}
+	q, err := replayQueue(l.svcCtx)
+	if err != nil {
+		return nil, err
+	}
+	state, err := queueState(req.State)
+	if err != nil {
+		return nil, err
+	}
+	e, points, err := q.Get(state, req.Key)
+	switch {
+	case errors.Is(err, dao.ErrQueueBatchNotFound):
+		return nil, fmt.Errorf("%s batch %s not found", state, req.Key)
+	case err != nil:
+		return nil, fmt.Errorf("get queue batch failed: %w", err)
+	}
+	return &types.QueueBatchResp{Batch: toQueueBatch(e), Points: toTrackPoints(points, nil)}, nil
+}
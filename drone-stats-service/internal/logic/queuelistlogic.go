@@ -0,0 +1,101 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"drone-stats-service/internal/audit"
+	"drone-stats-service/internal/dao"
+	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type QueueListLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewQueueListLogic(ctx context.Context, svcCtx *svc.ServiceContext) *QueueListLogic {
+	return &QueueListLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// QueueList 分页列出 MySQL 重放队列中待重放或死信的批次
+func (l *QueueListLogic) QueueList(req *types.QueueListReq) (resp *types.QueueListResp, err error) {
+	if err := audit.CheckAdminToken(l.svcCtx.Config.Audit.AdminToken, req.AdminToken); err != nil {
+		return nil, err
+	}
+	q, err := replayQueue(l.svcCtx)
+	if err != nil {
+		return nil, err
+	}
+	state, err := queueState(req.State)
+	if err != nil {
+		return nil, err
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 500 {
+		limit = 500
+	}
+	entries, total, err := q.List(state, req.Offset, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list queue failed: %w", err)
+	}
+	resp = &types.QueueListResp{State: state, Total: total, Batches: []types.QueueBatch{}}
+	for _, e := range entries {
+		resp.Batches = append(resp.Batches, toQueueBatch(e))
+	}
+	return resp, nil
+}
+
+func replayQueue(svcCtx *svc.ServiceContext) (*dao.Queue, error) {
+	q := svcCtx.Store.Queue()
+	if q == nil {
+		return nil, fmt.Errorf("replay queue is not enabled")
+	}
+	return q, nil
+}
+
+// queueState 校验队列状态参数，为空时为 pending
+func queueState(state string) (string, error) {
+	switch state {
+	case "":
+		return dao.QueuePending, nil
+	case dao.QueuePending, dao.QueueDead:
+		return state, nil
+	}
+	return "", fmt.Errorf("state must be %s or %s", dao.QueuePending, dao.QueueDead)
+}
+
+func toQueueBatch(e dao.QueueEntry) types.QueueBatch {
+	return types.QueueBatch{
+		Key:           e.Key,
+		State:         e.State,
+		EnqueuedAt:    formatReportTime(e.EnqueuedAt),
+		Points:        e.Points,
+		Bytes:         e.Bytes,
+		Attempts:      e.Attempts,
+		LastError:     e.LastError,
+		LastAttemptAt: formatOptionalTime(e.LastAttemptAt),
+		DeadAt:        formatOptionalTime(e.DeadAt),
+		DeadReason:    e.DeadReason,
+		DecodeError:   e.DecodeError,
+	}
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return formatReportTime(*t)
+}
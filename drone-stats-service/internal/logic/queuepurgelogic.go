@@ -0,0 +1,58 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"drone-stats-service/internal/audit"
+	"drone-stats-service/internal/dao"
+	"drone-stats-service/internal/model"
+	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type QueuePurgeLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewQueuePurgeLogic(ctx context.Context, svcCtx *svc.ServiceContext) *QueuePurgeLogic {
+	return &QueuePurgeLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// QueuePurge 删除待重放或死信批次，删除后数据不可恢复
+func (l *QueuePurgeLogic) QueuePurge(req *types.QueuePurgeReq) (resp *types.QueueActionResp, err error) {
+	if err := audit.CheckAdminToken(l.svcCtx.Config.Audit.AdminToken, req.AdminToken); err != nil {
+		return nil, err
+	}
+	if req.State != dao.QueuePending && req.State != dao.QueueDead {
+		return nil, fmt.Errorf("state must be %s or %s", dao.QueuePending, dao.QueueDead)
+	}
+	if len(req.Keys) == 0 && !req.All {
+		return nil, fmt.Errorf("keys is required, or set all=true to purge every %s batch", req.State)
+	}
+	q, err := replayQueue(l.svcCtx)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := q.Purge(req.State, req.Keys)
+	switch {
+	case errors.Is(err, dao.ErrQueueBatchNotFound):
+		return nil, fmt.Errorf("%s batch not found, nothing purged: %w", req.State, err)
+	case err != nil:
+		return nil, fmt.Errorf("purge failed: %w", err)
+	}
+	l.Infof("删除%s队列批次 %d 个 keys=%v actor=%s", req.State, len(keys), keys, audit.Actor(l.ctx))
+	if len(keys) > 0 {
+		recordAudit(l.ctx, l.svcCtx, model.AuditEntityReplayQueue, req.State, "purge", keys, nil)
+	}
+	return &types.QueueActionResp{Affected: len(keys)}, nil
+}
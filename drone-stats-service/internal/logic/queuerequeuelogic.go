@@ -0,0 +1,55 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"drone-stats-service/internal/audit"
+	"drone-stats-service/internal/dao"
+	"drone-stats-service/internal/model"
+	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type QueueRequeueLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewQueueRequeueLogic(ctx context.Context, svcCtx *svc.ServiceContext) *QueueRequeueLogic {
+	return &QueueRequeueLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// QueueRequeue 将死信批次移回待重放队列，重放次数清零；通常在修复数据或表结构后使用
+func (l *QueueRequeueLogic) QueueRequeue(req *types.QueueRequeueReq) (resp *types.QueueActionResp, err error) {
+	if err := audit.CheckAdminToken(l.svcCtx.Config.Audit.AdminToken, req.AdminToken); err != nil {
+		return nil, err
+	}
+	if len(req.Keys) == 0 && !req.All {
+		return nil, fmt.Errorf("keys is required, or set all=true to requeue every dead batch")
+	}
+	q, err := replayQueue(l.svcCtx)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := q.Requeue(req.Keys)
+	switch {
+	case errors.Is(err, dao.ErrQueueBatchNotFound):
+		return nil, fmt.Errorf("dead batch not found, nothing requeued: %w", err)
+	case err != nil:
+		return nil, fmt.Errorf("requeue failed: %w", err)
+	}
+	l.Infof("死信批次重新入队 %d 个 keys=%v actor=%s", len(keys), keys, audit.Actor(l.ctx))
+	if len(keys) > 0 {
+		recordAudit(l.ctx, l.svcCtx, model.AuditEntityReplayQueue, dao.QueueDead, "requeue", keys, nil)
+	}
+	return &types.QueueActionResp{Affected: len(keys)}, nil
+}
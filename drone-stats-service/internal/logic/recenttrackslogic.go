@@ -2,13 +2,18 @@ package logic
 
 import (
 	"context"
+	"fmt"
 
+	"drone-stats-service/internal/model"
 	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/track"
 	"drone-stats-service/internal/types"
 
 	"github.com/zeromicro/go-zero/core/logx"
 )
 
+const defaultRecentTracks = 3
+
 type RecentTracksLogic struct {
 	logx.Logger
 	ctx    context.Context
@@ -23,8 +28,119 @@ func NewRecentTracksLogic(ctx context.Context, svcCtx *svc.ServiceContext) *Rece
 	}
 }
 
-func (l *RecentTracksLogic) RecentTracks() (resp *types.TrackResponse, err error) {
-	// todo: add your logic here and delete this line
+// RecentTracks 支持 ?orderID=xxx 查询指定轨迹，否则查最近 n 条；带简化参数时按轨迹逐条简化并缓存
+func (l *RecentTracksLogic) RecentTracks(req *types.RecentTracksReq) (resp *types.TrackResponse, err error) {
+	switch req.Algorithm {
+	case "", track.AlgorithmDouglasPeucker, track.AlgorithmVisvalingam:
+	default:
+		return nil, fmt.Errorf("unsupported algorithm: %s", req.Algorithm)
+	}
+	if req.Tolerance < 0 || req.Zoom < 0 || req.MaxPoints < 0 {
+		return nil, fmt.Errorf("tolerance, zoom and maxPoints must not be negative")
+	}
+
+	var orderIDs []string
+	if req.OrderID != "" {
+		orderIDs = []string{req.OrderID}
+	} else {
+		n := req.N
+		if n <= 0 {
+			n = defaultRecentTracks
+		}
+		// 查询最近n条飞行记录
+		orderIDs, err = l.svcCtx.Records.RecentOrderIDs(n)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	return
+	resp = &types.TrackResponse{Track: []types.TrackPoints{}}
+	for _, oid := range orderIDs {
+		points, err := l.simplifiedTrack(oid, req)
+		if err != nil {
+			return nil, err
+		}
+		resp.Track = append(resp.Track, points...)
+	}
+	return resp, nil
+}
+
+// simplifiedTrack 返回单条轨迹（未带简化参数时为全部点），结果按轨迹点版本缓存
+func (l *RecentTracksLogic) simplifiedTrack(orderID string, req *types.RecentTracksReq) ([]types.TrackPoints, error) {
+	simplify := req.Tolerance > 0 || req.Zoom > 0 || req.MaxPoints > 0
+	if !simplify {
+		points, err := l.svcCtx.TrackPoints.GetTrackPoints(orderID)
+		if err != nil {
+			return nil, err
+		}
+		return toTrackPoints(points, nil), nil
+	}
+
+	count, maxID, err := l.svcCtx.TrackPoints.TrackPointsVersion(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, nil
+	}
+	key := fmt.Sprintf("%s|%d|%d|%s|%g|%d|%d", orderID, count, maxID, req.Algorithm, req.Tolerance, req.Zoom, req.MaxPoints)
+	v, err := l.svcCtx.TrackCache.Take(key, func() (any, error) {
+		points, err := l.svcCtx.TrackPoints.GetTrackPoints(orderID)
+		if err != nil {
+			return nil, err
+		}
+		if len(points) == 0 {
+			return []types.TrackPoints{}, nil
+		}
+		tol := req.Tolerance
+		if tol <= 0 && req.Zoom > 0 {
+			tol = track.ToleranceForZoom(req.Zoom, float64(points[0].Latitude)/1e7)
+		}
+		idx := track.Simplify(points, track.SimplifyOptions{
+			Algorithm:  req.Algorithm,
+			ToleranceM: tol,
+			MaxPoints:  req.MaxPoints,
+		})
+		return toTrackPoints(points, idx), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]types.TrackPoints), nil
+}
+
+// toTrackPoints 转换为接口格式，idx 为 nil 时转换全部点
+func toTrackPoints(points []model.FlightTrackPoint, idx []int) []types.TrackPoints {
+	if idx == nil {
+		idx = make([]int, len(points))
+		for i := range idx {
+			idx[i] = i
+		}
+	}
+	out := make([]types.TrackPoints, 0, len(idx))
+	for _, i := range idx {
+		pt := points[i]
+		out = append(out, types.TrackPoints{
+			OrderID:      pt.OrderID,
+			FlightStatus: pt.FlightStatus,
+			TimeStamp:    pt.TimeStamp.Format("2006-01-02 15:04:05"),
+			Longitude:    pt.Longitude,
+			Latitude:     pt.Latitude,
+			HeightType:   pt.HeightType,
+			Height:       pt.Height,
+			Altitude:     pt.Altitude,
+			VS:           pt.VS,
+			GS:           pt.GS,
+			Course:       pt.Course,
+			SOC:          pt.SOC,
+			RM:           pt.RM,
+			Voltage:      pt.Voltage,
+			Current:      pt.Current,
+			WindSpeed:    pt.WindSpeed,
+			WindDirect:   pt.WindDirect,
+			Temperture:   pt.Temperture,
+			Humidity:     pt.Humidity,
+		})
+	}
+	return out
 }
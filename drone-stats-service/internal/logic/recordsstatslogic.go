@@ -24,7 +24,7 @@ func NewRecordsStatsLogic(ctx context.Context, svcCtx *svc.ServiceContext) *Reco
 }
 
 func (l *RecordsStatsLogic) RecordsStats() (resp *types.RecordsStatsResp, err error) {
-	totalCount, totalDistance, totalTime, err := l.svcCtx.MySQLDao.GetFlightStats()
+	totalCount, totalDistance, totalTime, err := l.svcCtx.Records.GetFlightStats()
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,152 @@
+package logic
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"drone-stats-service/internal/model"
+	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/track"
+	"drone-stats-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+const (
+	// maxReplayFrames 单次回放最多返回的帧数
+	maxReplayFrames = 3600
+	// replayMaxGap 相邻轨迹点间隔超过该值时不插值，视为该时段无数据
+	replayMaxGap = 30 * time.Second
+)
+
+type ReplayLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewReplayLogic(ctx context.Context, svcCtx *svc.ServiceContext) *ReplayLogic {
+	return &ReplayLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// Replay 按帧返回各时刻在飞无人机的插值状态，用于回放任意历史时刻的空域态势
+func (l *ReplayLogic) Replay(req *types.ReplayReq) (resp *types.ReplayResp, err error) {
+	loc := l.svcCtx.Records.Location()
+	var orderIDs []string
+	for _, id := range strings.Split(req.OrderID, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			orderIDs = append(orderIDs, id)
+		}
+	}
+	at, err := parseStatsTime(req.Time, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time: %w", err)
+	}
+	start, err := parseStatsTime(req.Start, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start: %w", err)
+	}
+	end, err := parseStatsTime(req.End, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end: %w", err)
+	}
+	step := req.Step
+	if step < 0 {
+		return nil, fmt.Errorf("step must be positive")
+	}
+	if step == 0 {
+		step = 1
+	}
+
+	switch {
+	case !at.IsZero():
+		start, end = at, at
+	case start.IsZero() && end.IsZero():
+		if len(orderIDs) == 0 {
+			return nil, fmt.Errorf("orderID, time or start/end is required")
+		}
+		start, end, err = l.svcCtx.Records.GetFlightWindow(orderIDs[0], time.Time{})
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("no flight found for orderID %s", orderIDs[0])
+		}
+		if err != nil {
+			return nil, err
+		}
+	case start.IsZero() || end.IsZero():
+		return nil, fmt.Errorf("start and end must be given together")
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("end must not be before start")
+	}
+	stepDur := time.Duration(step * float64(time.Second))
+	if stepDur < time.Millisecond {
+		return nil, fmt.Errorf("step must be at least 0.001s")
+	}
+	if frames := end.Sub(start)/stepDur + 1; frames > maxReplayFrames {
+		return nil, fmt.Errorf("too many frames (> %d), narrow the range or use a larger step", maxReplayFrames)
+	}
+
+	filter := orderIDs
+	if req.Airspace {
+		filter = nil
+	}
+	// 前后各多取一个插值间隔，保证区间端点也能插值
+	points, err := l.svcCtx.TrackPoints.GetTrackPointsInRange(filter, start.Add(-replayMaxGap), end.Add(replayMaxGap))
+	if err != nil {
+		return nil, err
+	}
+	var series [][]model.FlightTrackPoint
+	for i := 0; i < len(points); {
+		j := i
+		for j < len(points) && points[j].OrderID == points[i].OrderID {
+			j++
+		}
+		series = append(series, points[i:j])
+		i = j
+	}
+
+	resp = &types.ReplayResp{
+		Start:  start.In(loc).Format(time.RFC3339),
+		End:    end.In(loc).Format(time.RFC3339),
+		Step:   step,
+		Frames: []types.ReplayFrame{},
+	}
+	for t := start; !t.After(end); t = t.Add(stepDur) {
+		frame := types.ReplayFrame{
+			Time:   t.In(loc).Format(time.RFC3339Nano),
+			Drones: []types.ReplayDroneState{},
+		}
+		for _, s := range series {
+			st, ok := track.Interpolate(s, t, replayMaxGap)
+			if !ok {
+				continue
+			}
+			frame.Drones = append(frame.Drones, types.ReplayDroneState{
+				OrderID:      st.OrderID,
+				Latitude:     st.Latitude,
+				Longitude:    st.Longitude,
+				Height:       round2(st.Height),
+				Altitude:     round2(st.Altitude),
+				Heading:      round2(st.Heading),
+				GS:           round2(st.GS),
+				SOC:          round2(st.SOC),
+				FlightStatus: st.Status,
+			})
+		}
+		resp.Frames = append(resp.Frames, frame)
+	}
+	return resp, nil
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,65 @@
+package logic
+
+import (
+	"context"
+	"path/filepath"
+
+	"drone-stats-service/internal/model"
+	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type ReportRunsLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewReportRunsLogic(ctx context.Context, svcCtx *svc.ServiceContext) *ReportRunsLogic {
+	return &ReportRunsLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *ReportRunsLogic) ReportRuns(req *types.ReportRunsReq) (resp *types.ReportRunsResp, err error) {
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 500 {
+		limit = 500
+	}
+	runs, err := l.svcCtx.Reports.ListReportRuns(req.ScheduleID, limit)
+	if err != nil {
+		return nil, err
+	}
+	resp = &types.ReportRunsResp{Runs: []types.ReportRun{}}
+	for _, r := range runs {
+		resp.Runs = append(resp.Runs, toReportRun(r))
+	}
+	return resp, nil
+}
+
+func toReportRun(r model.ReportRun) types.ReportRun {
+	out := types.ReportRun{
+		ID:          r.ID,
+		ScheduleID:  r.ScheduleID,
+		TaskID:      r.TaskID,
+		Trigger:     r.Trigger,
+		PeriodStart: formatReportTime(r.PeriodStart),
+		PeriodEnd:   formatReportTime(r.PeriodEnd),
+		Status:      r.Status,
+		FileSize:    r.FileSize,
+		Error:       r.Error,
+		StartedAt:   formatReportTime(r.StartedAt),
+		FinishedAt:  formatReportTime(r.FinishedAt),
+	}
+	if r.FilePath != "" {
+		out.FileName = filepath.Base(r.FilePath)
+	}
+	return out
+}
@@ -0,0 +1,46 @@
+package logic
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"drone-stats-service/internal/audit"
+	"drone-stats-service/internal/dao"
+	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type RevertAuditLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewRevertAuditLogic(ctx context.Context, svcCtx *svc.ServiceContext) *RevertAuditLogic {
+	return &RevertAuditLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// RevertAudit 管理员撤销一次飞行记录字段修改，恢复为修改前的值；字段之后又被修改过时拒绝撤销
+func (l *RevertAuditLogic) RevertAudit(req *types.RevertAuditReq) (resp *types.AuditLog, err error) {
+	if err := audit.CheckAdminToken(l.svcCtx.Config.Audit.AdminToken, req.AdminToken); err != nil {
+		return nil, err
+	}
+	e, err := l.svcCtx.Audits.RevertFlightAudit(req.ID, dao.AuditMeta{Actor: audit.Actor(l.ctx)})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("audit log %d not found", req.ID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot revert audit log %d: %w", req.ID, err)
+	}
+	l.Infof("撤销审计条目 %d: record=%s field=%s %s -> %s actor=%s", req.ID, e.EntityID, e.Field, e.OldValue, e.NewValue, e.Actor)
+	out := toAuditLog(e)
+	return &out, nil
+}
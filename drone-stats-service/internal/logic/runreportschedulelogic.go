@@ -0,0 +1,51 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"drone-stats-service/internal/audit"
+	"drone-stats-service/internal/model"
+	"drone-stats-service/internal/report"
+	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type RunReportScheduleLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewRunReportScheduleLogic(ctx context.Context, svcCtx *svc.ServiceContext) *RunReportScheduleLogic {
+	return &RunReportScheduleLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// RunReportSchedule 立即执行一次计划（报表截止时刻为当前时间），不影响 cron 的下次执行时间
+func (l *RunReportScheduleLogic) RunReportSchedule(req *types.ReportScheduleIDReq) (resp *types.ReportRun, err error) {
+	if err := audit.CheckAdminToken(l.svcCtx.Config.Audit.AdminToken, req.AdminToken); err != nil {
+		return nil, err
+	}
+	if l.svcCtx.ReportScheduler == nil {
+		return nil, fmt.Errorf("report scheduler is not enabled")
+	}
+	sc, err := getReportSchedule(l.svcCtx, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	run, err := l.svcCtx.ReportScheduler.Trigger(sc, time.Now(), report.TriggerManual)
+	if err != nil {
+		return nil, err
+	}
+	out := toReportRun(run)
+	recordAudit(l.ctx, l.svcCtx, model.AuditEntityReportSchedule, strconv.FormatInt(sc.ID, 10), "run", nil, out)
+	return &out, nil
+}
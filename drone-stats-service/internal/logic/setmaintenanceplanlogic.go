@@ -0,0 +1,74 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+
+	"drone-stats-service/internal/model"
+	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type SetMaintenancePlanLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewSetMaintenancePlanLogic(ctx context.Context, svcCtx *svc.ServiceContext) *SetMaintenancePlanLogic {
+	return &SetMaintenancePlanLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *SetMaintenancePlanLogic) SetMaintenancePlan(req *types.SetMaintenancePlanReq) (resp *types.MaintenancePlan, err error) {
+	if req.Model == "" {
+		return nil, fmt.Errorf("model is required")
+	}
+	seen := make(map[string]bool)
+	plans := make([]model.MaintenancePlan, 0, len(req.Items))
+	for _, it := range req.Items {
+		if it.Item == "" {
+			return nil, fmt.Errorf("item is required")
+		}
+		if seen[it.Item] {
+			return nil, fmt.Errorf("duplicate item: %s", it.Item)
+		}
+		seen[it.Item] = true
+		if it.IntervalHours < 0 || it.IntervalCycles < 0 || (it.IntervalHours == 0 && it.IntervalCycles == 0) {
+			return nil, fmt.Errorf("item %s: intervalHours or intervalCycles must be positive", it.Item)
+		}
+		plans = append(plans, model.MaintenancePlan{
+			Model:          req.Model,
+			Item:           it.Item,
+			IntervalHours:  it.IntervalHours,
+			IntervalCycles: it.IntervalCycles,
+			Description:    it.Description,
+		})
+	}
+	oldPlans, err := l.svcCtx.Maintenance.GetMaintenancePlans(req.Model)
+	if err != nil {
+		return nil, err
+	}
+	if err := l.svcCtx.Maintenance.ReplaceMaintenancePlan(req.Model, plans); err != nil {
+		return nil, err
+	}
+	resp = &types.MaintenancePlan{Model: req.Model, Items: []types.MaintenancePlanItem{}}
+	for _, p := range plans {
+		resp.Items = append(resp.Items, toMaintenancePlanItem(p))
+	}
+	var old interface{}
+	if len(oldPlans) > 0 {
+		items := make([]types.MaintenancePlanItem, 0, len(oldPlans))
+		for _, p := range oldPlans {
+			items = append(items, toMaintenancePlanItem(p))
+		}
+		old = items
+	}
+	recordAudit(l.ctx, l.svcCtx, model.AuditEntityMaintenancePlan, req.Model, "items", old, resp.Items)
+	return resp, nil
+}
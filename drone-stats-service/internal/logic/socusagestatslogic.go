@@ -29,7 +29,7 @@ func (l *SOCUsageStatsLogic) SOCUsageStats(mode string) (resp *types.SOCUsageSta
 	)
 	switch mode {
 	case "avg":
-		yearStats, monthStats, dayStats, err = l.svcCtx.MySQLDao.GetAvgSOCPerDistancePayloadStats()
+		yearStats, monthStats, dayStats, err = l.svcCtx.Records.GetAvgSOCPerDistancePayloadStats()
 		if err != nil {
 			return nil, err
 		}
@@ -53,7 +53,7 @@ func (l *SOCUsageStatsLogic) SOCUsageStats(mode string) (resp *types.SOCUsageSta
 			})
 		}
 	default:
-		yearStats, monthStats, dayStats, err = l.svcCtx.MySQLDao.GetSOCUsageStats()
+		yearStats, monthStats, dayStats, err = l.svcCtx.Records.GetSOCUsageStats()
 		if err != nil {
 			return nil, err
 		}
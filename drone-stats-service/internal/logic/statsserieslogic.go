@@ -0,0 +1,321 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"drone-stats-service/internal/dao"
+	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// 统计粒度
+const (
+	GranularityHour  = "hour"
+	GranularityDay   = "day"
+	GranularityWeek  = "week"
+	GranularityMonth = "month"
+	GranularityYear  = "year"
+)
+
+// 序列指标
+const (
+	MetricCount          = "count"          // 架次数
+	MetricDistance       = "distance"       // 总航程（m）
+	MetricDuration       = "duration"       // 总飞行时长（s）
+	MetricBattery        = "battery"        // 总耗电（kWh）
+	MetricBatteryPerKmKg = "batteryPerKmKg" // 单位耗电（kWh/km/kg）
+	MetricPayload        = "payload"        // 总载货量（kg）
+	MetricExpressCount   = "expressCount"   // 总票数
+	MetricAvgDuration    = "avgDuration"    // 平均飞行时长（s）
+	MetricAvgBattery     = "avgBattery"     // 平均耗电（kWh）
+	MetricAvgPayload     = "avgPayload"     // 平均载货量（kg）
+	MetricAvgGS          = "avgGS"          // 平均地速（m/s）
+)
+
+// 各统计接口对应的指标
+var (
+	RecordsStatsMetrics    = []string{MetricCount, MetricDistance, MetricDuration}
+	TimeSeriesStatsMetrics = []string{MetricCount}
+	SOCUsageStatsMetrics   = []string{MetricBattery}
+	SOCUsageAvgMetrics     = []string{MetricBatteryPerKmKg}
+	PayloadStatsMetrics    = []string{MetricPayload, MetricExpressCount}
+	AvgStatsMetrics        = []string{MetricAvgDuration, MetricAvgBattery, MetricAvgPayload, MetricAvgGS}
+)
+
+// maxStatsBuckets 单次返回的最大桶数，防止小粒度 + 大区间时响应过大
+const maxStatsBuckets = 5000
+
+type StatsSeriesLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewStatsSeriesLogic(ctx context.Context, svcCtx *svc.ServiceContext) *StatsSeriesLogic {
+	return &StatsSeriesLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// 统计接口响应格式
+const (
+	StatsFormatSeries = "series"
+	StatsFormatLegacy = "legacy" // 原有的年/月/日统计，已弃用
+)
+
+// IsLegacyStatsReq 仅在显式指定 format=legacy 时返回原有响应格式，默认均为序列格式
+func IsLegacyStatsReq(req *types.StatsQueryReq) bool {
+	return req.Format == StatsFormatLegacy
+}
+
+// StatsSeries 按时区与粒度分桶、按维度分组统计指定指标
+func (l *StatsSeriesLogic) StatsSeries(req *types.StatsQueryReq, metrics []string) (*types.StatsSeriesResp, error) {
+	if req.Format != "" && req.Format != StatsFormatSeries {
+		return nil, fmt.Errorf("unsupported format: %s", req.Format)
+	}
+	loc := l.svcCtx.Records.Location()
+	if req.Timezone != "" {
+		tz, err := time.LoadLocation(req.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tz: %w", err)
+		}
+		loc = tz
+	}
+	gran := req.Granularity
+	if gran == "" {
+		gran = GranularityDay
+	}
+	switch gran {
+	case GranularityHour, GranularityDay, GranularityWeek, GranularityMonth, GranularityYear:
+	default:
+		return nil, fmt.Errorf("unsupported granularity: %s", gran)
+	}
+	start, err := parseStatsTime(req.Start, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start: %w", err)
+	}
+	end, err := parseStatsTime(req.End, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end: %w", err)
+	}
+	if end.IsZero() {
+		end = time.Now()
+	}
+	if !start.IsZero() && !end.After(start) {
+		return nil, fmt.Errorf("end must be after start")
+	}
+
+	needGS := false
+	for _, m := range metrics {
+		if m == MetricAvgGS {
+			needGS = true
+		}
+	}
+	slots, err := l.svcCtx.Records.AggregateFlightRecordSlots(dao.StatsAggQuery{
+		Start:     start,
+		End:       end,
+		GroupBy:   req.GroupBy,
+		IncludeGS: needGS,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if start.IsZero() {
+		// 未指定起点时从最早的数据开始
+		start = end
+		if len(slots) > 0 {
+			start = slots[0].Slot
+		}
+	}
+
+	// 生成完整的桶序列（包含无数据的桶），便于前端直接作图
+	var buckets []time.Time
+	index := make(map[int64]int)
+	for b := truncateBucket(start.In(loc), gran); b.Before(end); b = nextBucket(b, gran) {
+		if len(buckets) >= maxStatsBuckets {
+			return nil, fmt.Errorf("too many buckets (> %d), narrow the range or use a coarser granularity", maxStatsBuckets)
+		}
+		index[b.Unix()] = len(buckets)
+		buckets = append(buckets, b)
+	}
+
+	// 按分组、桶累加预聚合结果
+	acc := make(map[string][]dao.StatsSlot)
+	for _, s := range slots {
+		i, ok := index[truncateBucket(s.Slot.In(loc), gran).Unix()]
+		if !ok {
+			continue
+		}
+		group := s.Group
+		if req.GroupBy != "" && group == "" {
+			group = "unknown"
+		}
+		if _, ok := acc[group]; !ok {
+			acc[group] = make([]dao.StatsSlot, len(buckets))
+		}
+		addStatsSlot(&acc[group][i], s)
+	}
+	groups := make([]string, 0, len(acc))
+	for g := range acc {
+		groups = append(groups, g)
+	}
+	sort.Strings(groups)
+	if len(groups) == 0 && req.GroupBy == "" {
+		// 未分组时即使无数据也返回全零序列
+		groups = []string{""}
+		acc[""] = make([]dao.StatsSlot, len(buckets))
+	}
+
+	resp := &types.StatsSeriesResp{
+		Granularity: gran,
+		Timezone:    loc.String(),
+		Start:       start.In(loc).Format(time.RFC3339),
+		End:         end.In(loc).Format(time.RFC3339),
+		GroupBy:     req.GroupBy,
+		Buckets:     make([]string, len(buckets)),
+		Series:      []types.StatsSeries{},
+	}
+	for i, b := range buckets {
+		resp.Buckets[i] = bucketLabel(b, gran)
+	}
+	for _, g := range groups {
+		for _, m := range metrics {
+			data := make([]float64, len(buckets))
+			for i := range buckets {
+				data[i] = metricValue(m, acc[g][i])
+			}
+			resp.Series = append(resp.Series, types.StatsSeries{
+				Metric: m,
+				Group:  g,
+				Data:   data,
+			})
+		}
+	}
+	return resp, nil
+}
+
+// parseStatsTime 解析 RFC3339，或按 loc 解析 "yyyy-MM-dd HH:mm:ss" / "yyyy-MM-dd"；空字符串返回零值
+func parseStatsTime(s string, loc *time.Location) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04:05", s, loc); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", s, loc)
+}
+
+// truncateBucket 返回 t 所在桶的起点（按 t 的时区），周以周一为起点
+func truncateBucket(t time.Time, gran string) time.Time {
+	loc := t.Location()
+	switch gran {
+	case GranularityHour:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+	case GranularityWeek:
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, loc)
+	case GranularityMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+	case GranularityYear:
+		return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, loc)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	}
+}
+
+// nextBucket 返回下一个桶的起点
+func nextBucket(b time.Time, gran string) time.Time {
+	switch gran {
+	case GranularityHour:
+		next := b.Add(time.Hour)
+		// 夏令时回拨时同一本地小时出现两次，避免死循环
+		if nb := truncateBucket(next, gran); nb.After(b) {
+			return nb
+		}
+		return next
+	case GranularityWeek:
+		return b.AddDate(0, 0, 7)
+	case GranularityMonth:
+		return b.AddDate(0, 1, 0)
+	case GranularityYear:
+		return b.AddDate(1, 0, 0)
+	default:
+		return b.AddDate(0, 0, 1)
+	}
+}
+
+// bucketLabel 桶标签，年/月/日格式与原有统计接口保持一致
+func bucketLabel(b time.Time, gran string) string {
+	switch gran {
+	case GranularityHour:
+		return b.Format("2006-01-02 15:00")
+	case GranularityMonth:
+		return b.Format("2006-01")
+	case GranularityYear:
+		return b.Format("2006")
+	default:
+		return b.Format("2006-01-02")
+	}
+}
+
+func addStatsSlot(dst *dao.StatsSlot, s dao.StatsSlot) {
+	dst.Count += s.Count
+	dst.Finished += s.Finished
+	dst.Distance += s.Distance
+	dst.Duration += s.Duration
+	dst.Battery += s.Battery
+	dst.BatteryCount += s.BatteryCount
+	dst.Payload += s.Payload
+	dst.PayloadCount += s.PayloadCount
+	dst.Express += s.Express
+	dst.GSSum += s.GSSum
+	dst.GSCount += s.GSCount
+}
+
+func metricValue(metric string, s dao.StatsSlot) float64 {
+	ratio := func(a float64, b int) float64 {
+		if b == 0 {
+			return 0
+		}
+		return a / float64(b)
+	}
+	switch metric {
+	case MetricCount:
+		return float64(s.Count)
+	case MetricDistance:
+		return s.Distance
+	case MetricDuration:
+		return s.Duration
+	case MetricBattery:
+		return s.Battery
+	case MetricBatteryPerKmKg:
+		// 与原 mode=avg 一致：总电能 / 总距离(km) / 总载重(kg)
+		if s.Distance == 0 || s.Payload == 0 {
+			return 0
+		}
+		return s.Battery / (s.Distance / 1000) / s.Payload
+	case MetricPayload:
+		return s.Payload
+	case MetricExpressCount:
+		return float64(s.Express)
+	case MetricAvgDuration:
+		return ratio(s.Duration, s.Finished)
+	case MetricAvgBattery:
+		return ratio(s.Battery, s.BatteryCount)
+	case MetricAvgPayload:
+		return ratio(s.Payload, s.PayloadCount)
+	case MetricAvgGS:
+		return ratio(s.GSSum, s.GSCount)
+	}
+	return 0
+}
@@ -0,0 +1,97 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"drone-stats-service/internal/downsample"
+	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type TelemetrySeriesLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewTelemetrySeriesLogic(ctx context.Context, svcCtx *svc.ServiceContext) *TelemetrySeriesLogic {
+	return &TelemetrySeriesLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// TelemetrySeries 查询原始遥测或降采样数据，resolution 为 auto 时按时间范围选择分辨率
+func (l *TelemetrySeriesLogic) TelemetrySeries(req *types.TelemetrySeriesReq) (resp *types.TelemetrySeriesResp, err error) {
+	measurement := req.Measurement
+	if measurement == "" {
+		measurement = "drone_status"
+	}
+	spec, ok := downsample.FindSpec(measurement)
+	if !ok {
+		return nil, fmt.Errorf("invalid measurement: %s", measurement)
+	}
+	filter := map[string]string{}
+	for k, v := range map[string]string{"sn": req.Sn, "flightCode": req.FlightCode, "orderID": req.OrderID, "vin": req.Vin} {
+		if v == "" {
+			continue
+		}
+		if !hasSpecKey(spec, k) {
+			return nil, fmt.Errorf("%s is not applicable to %s", k, measurement)
+		}
+		filter[k] = v
+	}
+
+	loc := l.svcCtx.Records.Location()
+	start, err := parseStatsTime(req.Start, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start: %w", err)
+	}
+	end, err := parseStatsTime(req.End, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end: %w", err)
+	}
+	if end.IsZero() {
+		end = time.Now()
+	}
+	if !end.After(start) {
+		return nil, fmt.Errorf("end must be after start")
+	}
+
+	series, err := l.svcCtx.Downsample.Query(l.ctx, measurement, filter, start, end, req.Resolution, req.MaxPoints)
+	if err != nil {
+		return nil, err
+	}
+	resp = &types.TelemetrySeriesResp{
+		Measurement: measurement,
+		Resolution:  series.Resolution,
+		Start:       formatReportTime(start.In(loc)),
+		End:         formatReportTime(end.In(loc)),
+		Points:      make([]types.TelemetryPoint, 0, len(series.Points)),
+	}
+	for _, p := range series.Points {
+		resp.Points = append(resp.Points, types.TelemetryPoint{
+			Time:   p.Time.In(loc).Format(time.RFC3339Nano),
+			Tags:   p.Tags,
+			Fields: p.Fields,
+		})
+	}
+	return resp, nil
+}
+
+// hasSpecKey 判断 key 是否为 measurement 的 tag 或字段
+func hasSpecKey(spec downsample.Spec, key string) bool {
+	for _, keys := range [][]string{spec.Tags, spec.Stats, spec.Last} {
+		for _, k := range keys {
+			if k == key {
+				return true
+			}
+		}
+	}
+	return false
+}
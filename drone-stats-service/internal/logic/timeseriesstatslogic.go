@@ -24,7 +24,7 @@ func NewTimeSeriesStatsLogic(ctx context.Context, svcCtx *svc.ServiceContext) *T
 }
 
 func (l *TimeSeriesStatsLogic) TimeSeriesStats() (resp *types.TimeSeriesStatsResp, err error) {
-	yearStats, monthStats, dayStats, err := l.svcCtx.MySQLDao.GetFlightRecordsStats()
+	yearStats, monthStats, dayStats, err := l.svcCtx.Records.GetFlightRecordsStats()
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,121 @@
+package logic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"drone-stats-service/internal/model"
+	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/track"
+	"drone-stats-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type TrackQualityLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewTrackQualityLogic(ctx context.Context, svcCtx *svc.ServiceContext) *TrackQualityLogic {
+	return &TrackQualityLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// TrackQuality 返回某 OrderID 各架次的轨迹清洗统计及清洗前后的航程/耗电
+func (l *TrackQualityLogic) TrackQuality(req *types.TrackQualityReq) (resp *types.TrackQualityResp, err error) {
+	if req.OrderID == "" {
+		return nil, fmt.Errorf("OrderID is required")
+	}
+	items, err := l.svcCtx.TrackPoints.GetTrackCleaning(req.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	resp = &types.TrackQualityResp{Items: []types.TrackQuality{}}
+	for _, c := range items {
+		q := types.TrackQuality{
+			OrderID:        c.OrderID,
+			UasID:          c.UasID,
+			StartTime:      c.StartTime.Format("2006-01-02 15:04:05"),
+			PointsTotal:    c.PointsTotal,
+			PointsKept:     c.PointsKept,
+			DroppedZero:    c.DroppedZero,
+			DroppedTime:    c.DroppedTime,
+			DroppedSpeed:   c.DroppedSpeed,
+			DroppedAccel:   c.DroppedAccel,
+			DroppedFrozen:  c.DroppedFrozen,
+			GapCount:       c.GapCount,
+			GapSeconds:     c.GapSeconds,
+			Gaps:           []types.TrackGap{},
+			Smoothing:      c.Smoothing,
+			RawDistance:    c.RawDistance,
+			CleanDistance:  c.CleanDistance,
+			RawEnergyKWh:   c.RawEnergyKWh,
+			CleanEnergyKWh: c.CleanEnergyKWh,
+		}
+		var gaps []track.Gap
+		if err := json.Unmarshal([]byte(c.Gaps), &gaps); err != nil {
+			l.Logger.Errorf("解析轨迹缺口失败: OrderID=%s, err=%v", c.OrderID, err)
+		}
+		for _, g := range gaps {
+			q.Gaps = append(q.Gaps, types.TrackGap{
+				Start:   g.Start.Format("2006-01-02 15:04:05"),
+				End:     g.End.Format("2006-01-02 15:04:05"),
+				Seconds: g.Seconds,
+			})
+		}
+		resp.Items = append(resp.Items, q)
+	}
+	return resp, nil
+}
+
+func trackCleanOptions(svcCtx *svc.ServiceContext) track.Options {
+	c := svcCtx.Config.TrackClean
+	return track.Options{
+		MaxSpeedMps:     c.MaxSpeedMps,
+		MaxAccelMps2:    c.MaxAccelMps2,
+		FrozenMinGS:     c.FrozenMinGS,
+		GapSeconds:      c.GapSeconds,
+		Smoothing:       c.Smoothing,
+		SmoothingWindow: c.SmoothingWindow,
+		KalmanNoiseM:    c.KalmanNoiseM,
+		KalmanAccelMps2: c.KalmanAccelMps2,
+	}
+}
+
+// saveTrackCleaning 保存单架次轨迹清洗统计，清洗后的航程/耗电取自已入库的飞行记录
+func saveTrackCleaning(svcCtx *svc.ServiceContext, fr model.FlightRecord, cleaned track.Result, total int, rawDistance, rawEnergy float64) error {
+	gaps := cleaned.Gaps
+	if gaps == nil {
+		gaps = []track.Gap{}
+	}
+	gapsJSON, err := json.Marshal(gaps)
+	if err != nil {
+		return err
+	}
+	return svcCtx.TrackPoints.SaveTrackCleaning(model.TrackCleaning{
+		OrderID:        fr.OrderID,
+		UasID:          fr.UasID,
+		StartTime:      fr.StartTime,
+		PointsTotal:    total,
+		PointsKept:     len(cleaned.Points),
+		DroppedZero:    cleaned.DroppedZero,
+		DroppedTime:    cleaned.DroppedTime,
+		DroppedSpeed:   cleaned.DroppedSpeed,
+		DroppedAccel:   cleaned.DroppedAccel,
+		DroppedFrozen:  cleaned.DroppedFrozen,
+		GapCount:       len(cleaned.Gaps),
+		GapSeconds:     cleaned.GapSeconds,
+		Gaps:           string(gapsJSON),
+		Smoothing:      cleaned.Smoothing,
+		RawDistance:    rawDistance,
+		CleanDistance:  fr.Distance,
+		RawEnergyKWh:   rawEnergy,
+		CleanEnergyKWh: fr.BatteryUsed,
+	})
+}
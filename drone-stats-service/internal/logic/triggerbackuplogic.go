@@ -0,0 +1,52 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"drone-stats-service/internal/audit"
+	"drone-stats-service/internal/backup"
+	"drone-stats-service/internal/model"
+	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type TriggerBackupLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewTriggerBackupLogic(ctx context.Context, svcCtx *svc.ServiceContext) *TriggerBackupLogic {
+	return &TriggerBackupLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// TriggerBackup 立即在后台执行一次备份，进度通过 /backup/status 查询；已有备份正在执行时拒绝
+func (l *TriggerBackupLogic) TriggerBackup(req *types.TriggerBackupReq) (resp *types.BackupRun, err error) {
+	if err := audit.CheckAdminToken(l.svcCtx.Config.Audit.AdminToken, req.AdminToken); err != nil {
+		return nil, err
+	}
+	if req.Type != "" && req.Type != backup.TypeFull && req.Type != backup.TypeIncremental {
+		return nil, fmt.Errorf("type must be full or incremental")
+	}
+	st, err := l.svcCtx.Backup.StartBackup(req.Type, backup.TriggerManual)
+	if errors.Is(err, backup.ErrBackupRunning) {
+		return nil, fmt.Errorf("a backup is already running")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("start backup failed: %w", err)
+	}
+	l.Infof("手动触发备份: type=%s actor=%s", req.Type, audit.Actor(l.ctx))
+	run := toBackupRun(st)
+	// 备份 ID 在执行后才确定，以开始时间标识本次手动触发
+	recordAudit(l.ctx, l.svcCtx, model.AuditEntityBackup, st.StartedAt.Format(time.RFC3339), "trigger", nil, run)
+	return &run, nil
+}
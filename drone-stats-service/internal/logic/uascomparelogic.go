@@ -0,0 +1,42 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+
+	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// maxCompareUas 对比接口单次最多支持的无人机数量
+const maxCompareUas = 50
+
+type UasCompareLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewUasCompareLogic(ctx context.Context, svcCtx *svc.ServiceContext) *UasCompareLogic {
+	return &UasCompareLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *UasCompareLogic) UasCompare(req *types.UasCompareReq) (resp *types.UasCompareResp, err error) {
+	if len(req.UasIDs) == 0 {
+		return nil, fmt.Errorf("uasIDs is required")
+	}
+	if len(req.UasIDs) > maxCompareUas {
+		return nil, fmt.Errorf("at most %d uasIDs can be compared", maxCompareUas)
+	}
+	items, err := buildUasStats(l.svcCtx, req.UasIDs, req.Start, req.End)
+	if err != nil {
+		return nil, err
+	}
+	return &types.UasCompareResp{Items: items}, nil
+}
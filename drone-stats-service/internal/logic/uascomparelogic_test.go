@@ -0,0 +1,111 @@
+package logic
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"drone-stats-service/internal/dao"
+	"drone-stats-service/internal/model"
+	"drone-stats-service/internal/types"
+)
+
+func TestUasCompare(t *testing.T) {
+	svcCtx, d, _ := newTestServiceContext(t)
+	// U1：30 分钟 1km 0.2kWh 载货 5kg，SOC 90 -> 60；60 分钟 2km 0.4kWh，SOC 70 -> 55
+	flights := []struct {
+		orderID string
+		start   time.Time
+		dur     time.Duration
+		dist    float64
+		kwh     float64
+		socs    []int
+	}{
+		{"O-1", flightBase, 30 * time.Minute, 1000, 0.2, []int{90, 75, 60}},
+		{"O-2", flightBase.Add(time.Hour), time.Hour, 2000, 0.4, []int{70, 0, 55}},
+	}
+	for _, f := range flights {
+		if err := d.SaveFlightRecord(f.orderID, "U1", f.start, f.start.Add(f.dur), 0, 0, 0, 0, f.dist, f.kwh); err != nil {
+			t.Fatal(err)
+		}
+		points := make([]model.FlightTrackPoint, len(f.socs))
+		for i, soc := range f.socs {
+			points[i] = model.FlightTrackPoint{OrderID: f.orderID, TimeStamp: f.start.Add(time.Duration(i) * time.Minute), SOC: soc}
+		}
+		if err := d.SaveTrackPoints(points); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.UpdateFlightPayload("O-1", 50, 3, dao.AuditMeta{Actor: "test"}); err != nil {
+		t.Fatal(err)
+	}
+	loc := svcCtx.Records.Location()
+	logic := NewUasCompareLogic(context.Background(), svcCtx)
+
+	// 统计区间 6 小时
+	resp, err := logic.UasCompare(&types.UasCompareReq{
+		UasIDs: []string{"U1", "U2"},
+		Start:  flightBase.Format(time.RFC3339),
+		End:    flightBase.Add(6 * time.Hour).Format(time.RFC3339),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Items) != 2 || resp.Items[0].UasID != "U1" || resp.Items[1].UasID != "U2" {
+		t.Fatalf("items = %+v", resp.Items)
+	}
+	u1 := resp.Items[0]
+	if u1.TotalFlights != 2 || u1.TotalHours != 1.5 || u1.TotalDistance != 3000 || !approx(u1.TotalEnergy, 0.6) ||
+		u1.TotalPayload != 5 || u1.TotalExpress != 3 {
+		t.Errorf("U1 totals = %+v", u1)
+	}
+	// 每公里 0.6kWh / 3km，每千克只计有载货的 O-1：0.2kWh / 5kg，利用率 1.5h / 6h
+	if !approx(u1.EnergyPerKm, 0.2) || !approx(u1.EnergyPerKg, 0.04) || !approx(u1.UtilisationRate, 0.25) {
+		t.Errorf("U1 rates = %v kWh/km, %v kWh/kg, utilisation %v", u1.EnergyPerKm, u1.EnergyPerKg, u1.UtilisationRate)
+	}
+	soc := u1.SOCDrop
+	if soc.Flights != 2 || soc.Avg != 22.5 || soc.P50 != 15 || soc.P90 != 30 || soc.Max != 30 || len(soc.Buckets) != 10 {
+		t.Errorf("U1 SOC drop = %+v", soc)
+	}
+	for _, b := range soc.Buckets {
+		want := 0
+		if b.Range == "10-20" || b.Range == "30-40" {
+			want = 1
+		}
+		if b.Count != want {
+			t.Errorf("bucket %s = %d, want %d", b.Range, b.Count, want)
+		}
+	}
+	// 无记录的无人机返回零值，统计区间为请求区间
+	u2 := resp.Items[1]
+	if u2.TotalFlights != 0 || u2.UtilisationRate != 0 || u2.SOCDrop.Flights != 0 || u2.Start != flightBase.In(loc).Format(time.RFC3339) {
+		t.Errorf("U2 = %+v", u2)
+	}
+
+	// 未指定起点时从首次起飞算起：1.5h / 2h
+	resp, err = logic.UasCompare(&types.UasCompareReq{UasIDs: []string{"U1"}, End: flightBase.Add(2 * time.Hour).Format(time.RFC3339)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u1 := resp.Items[0]; u1.Start != flightBase.In(loc).Format(time.RFC3339) || !approx(u1.UtilisationRate, 0.75) {
+		t.Errorf("U1 without start = %+v", u1)
+	}
+
+	tooMany := make([]string, maxCompareUas+1)
+	for i := range tooMany {
+		tooMany[i] = "U1"
+	}
+	for name, req := range map[string]*types.UasCompareReq{
+		"未指定无人机": {},
+		"无人机过多":  {UasIDs: tooMany},
+		"终点早于起点": {UasIDs: []string{"U1"}, Start: "2025-06-20", End: "2025-06-19"},
+		"时间格式错误": {UasIDs: []string{"U1"}, Start: "20/06/2025"},
+	} {
+		if _, err := logic.UasCompare(req); err == nil {
+			t.Errorf("%s: want error", name)
+		}
+	}
+}
+
+func approx(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
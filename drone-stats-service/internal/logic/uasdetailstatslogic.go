@@ -0,0 +1,150 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"drone-stats-service/internal/dao"
+	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// socDropBucketWidth SOC 降幅分布的区间宽度（百分点）
+const socDropBucketWidth = 10
+
+type UasDetailStatsLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewUasDetailStatsLogic(ctx context.Context, svcCtx *svc.ServiceContext) *UasDetailStatsLogic {
+	return &UasDetailStatsLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *UasDetailStatsLogic) UasDetailStats(req *types.UasDetailStatsReq) (resp *types.UasDetailStatsResp, err error) {
+	items, err := buildUasStats(l.svcCtx, []string{req.UasID}, req.Start, req.End)
+	if err != nil {
+		return nil, err
+	}
+	return &items[0], nil
+}
+
+// buildUasStats 按请求顺序返回每架无人机的统计，无记录的无人机返回全零统计
+func buildUasStats(svcCtx *svc.ServiceContext, uasIDs []string, startStr, endStr string) ([]types.UasDetailStatsResp, error) {
+	loc := svcCtx.Records.Location()
+	start, err := parseStatsTime(startStr, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start: %w", err)
+	}
+	end, err := parseStatsTime(endStr, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end: %w", err)
+	}
+	if end.IsZero() {
+		end = time.Now()
+	}
+	if !start.IsZero() && !end.After(start) {
+		return nil, fmt.Errorf("end must be after start")
+	}
+
+	totals, err := svcCtx.Records.GetUasFlightTotals(uasIDs, start, end)
+	if err != nil {
+		return nil, err
+	}
+	drops, err := svcCtx.Records.GetUasSOCDrops(uasIDs, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]types.UasDetailStatsResp, 0, len(uasIDs))
+	for _, id := range uasIDs {
+		t, ok := totals[id]
+		if !ok {
+			t = &dao.UasFlightTotals{UasID: id}
+		}
+		periodStart := start
+		if periodStart.IsZero() {
+			periodStart = t.FirstStart
+		}
+		item := types.UasDetailStatsResp{
+			UasID:         id,
+			End:           end.In(loc).Format(time.RFC3339),
+			TotalFlights:  t.Flights,
+			TotalHours:    t.FlightSeconds / 3600,
+			TotalDistance: t.Distance,
+			TotalPayload:  t.Payload,
+			TotalExpress:  t.ExpressCount,
+			TotalEnergy:   t.Battery,
+			SOCDrop:       socDropStats(drops[id]),
+		}
+		if !periodStart.IsZero() {
+			item.Start = periodStart.In(loc).Format(time.RFC3339)
+			if period := end.Sub(periodStart).Seconds(); period > 0 {
+				item.UtilisationRate = math.Min(t.FlightSeconds/period, 1)
+			}
+		}
+		if t.Distance > 0 {
+			item.EnergyPerKm = t.Battery / (t.Distance / 1000)
+		}
+		if t.Payload > 0 {
+			item.EnergyPerKg = t.PayloadBattery / t.Payload
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// socDropStats 计算 SOC 降幅的均值、分位数与按 10 个百分点分段的分布
+func socDropStats(drops []float64) types.SOCDropStats {
+	stats := types.SOCDropStats{
+		Flights: len(drops),
+		Buckets: make([]types.SOCDropBucket, 0, 100/socDropBucketWidth),
+	}
+	for lo := 0; lo < 100; lo += socDropBucketWidth {
+		stats.Buckets = append(stats.Buckets, types.SOCDropBucket{Range: fmt.Sprintf("%d-%d", lo, lo+socDropBucketWidth)})
+	}
+	if len(drops) == 0 {
+		return stats
+	}
+	sorted := append([]float64(nil), drops...)
+	sort.Float64s(sorted)
+	var sum float64
+	for _, d := range sorted {
+		sum += d
+		i := int(d) / socDropBucketWidth
+		if i >= len(stats.Buckets) {
+			i = len(stats.Buckets) - 1
+		}
+		if i < 0 {
+			i = 0
+		}
+		stats.Buckets[i].Count++
+	}
+	stats.Avg = sum / float64(len(sorted))
+	stats.P50 = percentile(sorted, 0.5)
+	stats.P90 = percentile(sorted, 0.9)
+	stats.Max = sorted[len(sorted)-1]
+	return stats
+}
+
+// percentile 最近秩法分位数，sorted 需升序
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
@@ -0,0 +1,61 @@
+package logic
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"drone-stats-service/internal/audit"
+	"drone-stats-service/internal/model"
+	"drone-stats-service/internal/svc"
+	"drone-stats-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type UpdateReportScheduleLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewUpdateReportScheduleLogic(ctx context.Context, svcCtx *svc.ServiceContext) *UpdateReportScheduleLogic {
+	return &UpdateReportScheduleLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *UpdateReportScheduleLogic) UpdateReportSchedule(req *types.ReportScheduleReq) (resp *types.ReportSchedule, err error) {
+	if err := audit.CheckAdminToken(l.svcCtx.Config.Audit.AdminToken, req.AdminToken); err != nil {
+		return nil, err
+	}
+	old, err := getReportSchedule(l.svcCtx, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	sc, err := reportScheduleFromReq(l.svcCtx, req)
+	if err != nil {
+		return nil, err
+	}
+	sc.Enabled = old.Enabled
+	if req.Enabled != nil {
+		sc.Enabled = *req.Enabled
+	}
+	// 修改 cron 或重新启用后从当前时刻重新计算，停用期间错过的触发不再补执行
+	if sc.Enabled {
+		if sc.NextRunAt, err = l.svcCtx.ReportScheduler.NextRun(sc.Cron, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+	if err := l.svcCtx.Reports.UpdateReportSchedule(sc); err != nil {
+		return nil, err
+	}
+	if sc, err = l.svcCtx.Reports.GetReportSchedule(sc.ID); err != nil {
+		return nil, err
+	}
+	out := toReportSchedule(sc)
+	recordAudit(l.ctx, l.svcCtx, model.AuditEntityReportSchedule, strconv.FormatInt(sc.ID, 10), "schedule", toReportSchedule(old), out)
+	return &out, nil
+}
@@ -0,0 +1,39 @@
+package model
+
+import "time"
+
+// 审计日志实体
+const (
+	AuditEntityFlightRecord      = "flight_record"
+	AuditEntityMaintenancePlan   = "maintenance_plan"
+	AuditEntityMaintenanceRecord = "maintenance_record"
+	AuditEntityReportSchedule    = "report_schedule"
+	AuditEntityBackup            = "backup"
+	AuditEntityReplayQueue       = "replay_queue" // entity_id 为队列状态 pending | dead
+)
+
+// 审计日志来源
+const (
+	AuditSourceAPI    = "api"    // 接口手工修改
+	AuditSourceImport = "import" // 批量导入
+	AuditSourceRevert = "revert" // 管理员撤销
+	AuditSourceSystem = "system" // 服务自动修改（如超期维保标记）
+)
+
+// AuditLog 手工修改审计日志（audit_logs 表）。
+// 飞行记录按字段逐条记录，值为库中原始值（payload 为千克乘 10）；其它实体记录整条数据的 JSON。
+type AuditLog struct {
+	ID         int64     `db:"id"`
+	Entity     string    `db:"entity"`
+	EntityID   string    `db:"entity_id"` // 飞行记录 id、机型、维保记录 id 或报表计划 id
+	OrderID    string    `db:"order_id"`  // 仅飞行记录
+	Field      string    `db:"field"`
+	OldValue   string    `db:"old_value"` // 新建时为空
+	NewValue   string    `db:"new_value"` // 删除时为空
+	Actor      string    `db:"actor"`     // 客户端地址，Audit.TrustUserHeader 开启时为 X-User-ID 请求头
+	Source     string    `db:"source"`    // api | import | revert | system
+	BatchID    string    `db:"batch_id"`  // 同一次批量导入的条目相同
+	RevertOf   int64     `db:"revert_of"` // 撤销条目对应的原条目 id
+	RevertedBy int64     `db:"reverted_by"`
+	CreatedAt  time.Time `db:"created_at"`
+}
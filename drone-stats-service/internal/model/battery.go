@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// BatteryFlightMetrics 单架次电池指标（flight_battery_metrics 表）
+type BatteryFlightMetrics struct {
+	ID                  int       `db:"id"`
+	OrderID             string    `db:"OrderID"`
+	UasID               string    `db:"uasID"`
+	StartTime           time.Time `db:"start_time"`
+	SOCStart            int       `db:"soc_start"`
+	SOCEnd              int       `db:"soc_end"`
+	SOCUsed             float64   `db:"soc_used"`
+	CapacityUsedAh      float64   `db:"capacity_used_ah"`
+	CapacityMethod      string    `db:"capacity_method"` // current | rm | none
+	EffectiveCapacityAh float64   `db:"effective_capacity_ah"`
+	ResistanceMOhm      float64   `db:"resistance_mohm"`
+	MinVoltage          int       `db:"min_voltage"`
+	MaxCurrent          int       `db:"max_current"`
+}
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// ExportDownload 导出结果下载记录（export_downloads 表）
+type ExportDownload struct {
+	ID           int64     `db:"id"`
+	TaskID       string    `db:"task_id"`
+	FileName     string    `db:"file_name"`
+	User         string    `db:"user"` // 客户端地址，Audit.TrustUserHeader 开启时为 X-User-ID 请求头
+	RemoteAddr   string    `db:"remote_addr"`
+	UserAgent    string    `db:"user_agent"`
+	RangeHeader  string    `db:"range_header"` // 断点续传时的 Range 请求头
+	Status       int       `db:"status"`       // HTTP 状态码
+	BytesSent    int64     `db:"bytes_sent"`
+	DownloadedAt time.Time `db:"downloaded_at"`
+}
@@ -14,8 +14,11 @@ type FlightRecord struct {
 	EndLng      int64     `db:"end_lng"`
 	Distance    float64   `db:"distance"`
 	BatteryUsed float64   `db:"battery_used"`
-	CreatedAt   time.Time `db:"created_at"`
-	Payload     float64   `db:"payload"`
+	// EnergyMethod 耗电计算方式：power | soc | rm | none，旧数据为空（按每点 1 秒累加）
+	EnergyMethod string    `db:"energy_method"`
+	CreatedAt    time.Time `db:"created_at"`
+	Payload      float64   `db:"payload"`
+	ExpressCount int       `db:"expressCount"`
 }
 
 type FlightTrackPoint struct {
@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// MaintenancePlan 机型维保计划中的单个维保项（maintenance_plans 表）
+type MaintenancePlan struct {
+	ID             int     `db:"id"`
+	Model          string  `db:"model"` // 机型，default 表示未登记机型或无专属计划的机型
+	Item           string  `db:"item"`
+	IntervalHours  float64 `db:"interval_hours"`  // 按飞行小时的间隔，0 表示不按小时
+	IntervalCycles int     `db:"interval_cycles"` // 按起降架次的间隔，0 表示不按架次
+	Description    string  `db:"description"`
+}
+
+// MaintenanceRecord 维保完成记录（maintenance_records 表）
+type MaintenanceRecord struct {
+	ID          int       `db:"id"`
+	UasID       string    `db:"uasID"`
+	Item        string    `db:"item"`
+	PerformedAt time.Time `db:"performed_at"`
+	FlightHours float64   `db:"flight_hours"` // 完成维保时的累计飞行小时
+	Cycles      int       `db:"cycles"`       // 完成维保时的累计架次
+	Technician  string    `db:"technician"`
+	Note        string    `db:"note"`
+}
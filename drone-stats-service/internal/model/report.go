@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+// ReportSchedule 定时报表计划（report_schedules 表）
+type ReportSchedule struct {
+	ID          int64     `db:"id"`
+	Name        string    `db:"name"`
+	Cron        string    `db:"cron"`   // 标准 5 段 cron 表达式，按 Report.Timezone 解释
+	Target      string    `db:"target"` // records | trajectory | both
+	Format      string    `db:"format"`
+	PartitionBy string    `db:"partition_by"`
+	UasID       string    `db:"uasID"`
+	RangeHours  int       `db:"range_hours"` // 报表覆盖触发时刻之前多少小时的数据
+	Delivery    string    `db:"delivery"`    // local | smtp | webhook
+	DeliveryTo  string    `db:"delivery_to"` // local：子目录；smtp：逗号分隔的收件人；webhook：URL
+	RetainDays  int       `db:"retain_days"` // 存档保留天数，0 表示使用默认值
+	Enabled     bool      `db:"enabled"`
+	LastRunAt   time.Time `db:"last_run_at"`
+	NextRunAt   time.Time `db:"next_run_at"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+// ReportRun 报表执行记录（report_runs 表）
+type ReportRun struct {
+	ID          int64     `db:"id"`
+	ScheduleID  int64     `db:"schedule_id"`
+	TaskID      string    `db:"task_id"`
+	Trigger     string    `db:"trigger_type"` // cron | manual
+	PeriodStart time.Time `db:"period_start"`
+	PeriodEnd   time.Time `db:"period_end"`
+	Status      string    `db:"status"`    // pending | delivered | failed
+	FilePath    string    `db:"file_path"` // 存档文件路径，超过保留期删除后为空
+	FileSize    int64     `db:"file_size"`
+	Error       string    `db:"error"`
+	StartedAt   time.Time `db:"started_at"`
+	FinishedAt  time.Time `db:"finished_at"`
+}
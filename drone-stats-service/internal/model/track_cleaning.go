@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// TrackCleaning 单架次轨迹清洗统计及清洗前后的航程/耗电（flight_track_cleaning 表）
+type TrackCleaning struct {
+	ID             int       `db:"id"`
+	OrderID        string    `db:"OrderID"`
+	UasID          string    `db:"uasID"`
+	StartTime      time.Time `db:"start_time"`
+	PointsTotal    int       `db:"points_total"`
+	PointsKept     int       `db:"points_kept"`
+	DroppedZero    int       `db:"dropped_zero"`
+	DroppedTime    int       `db:"dropped_time"`
+	DroppedSpeed   int       `db:"dropped_speed"`
+	DroppedAccel   int       `db:"dropped_accel"`
+	DroppedFrozen  int       `db:"dropped_frozen"`
+	GapCount       int       `db:"gap_count"`
+	GapSeconds     float64   `db:"gap_seconds"`
+	Gaps           string    `db:"gaps"` // JSON 数组：[{start,end,seconds}]
+	Smoothing      string    `db:"smoothing"`
+	RawDistance    float64   `db:"raw_distance"`
+	CleanDistance  float64   `db:"clean_distance"`
+	RawEnergyKWh   float64   `db:"raw_energy_kwh"`
+	CleanEnergyKWh float64   `db:"clean_energy_kwh"`
+}
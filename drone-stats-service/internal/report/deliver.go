@@ -0,0 +1,274 @@
+package report
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"drone-stats-service/internal/model"
+)
+
+const (
+	defaultWebhookTimeout  = 60 * time.Second
+	defaultMaxAttachmentMB = 20
+)
+
+// webhookMeta webhook 投递时 meta 字段的内容
+type webhookMeta struct {
+	ScheduleID  int64  `json:"scheduleID"`
+	Schedule    string `json:"schedule"`
+	RunID       int64  `json:"runID"`
+	Trigger     string `json:"trigger"`
+	PeriodStart string `json:"periodStart"`
+	PeriodEnd   string `json:"periodEnd"`
+	FileName    string `json:"fileName"`
+	FileSize    int64  `json:"fileSize"`
+}
+
+// deliver 按计划的投递方式发送存档后的报表
+func (s *Scheduler) deliver(sc model.ReportSchedule, run model.ReportRun) error {
+	switch sc.Delivery {
+	case DeliveryLocal:
+		return s.deliverLocal(sc, run)
+	case DeliverySMTP:
+		return s.deliverSMTP(sc, run)
+	case DeliveryWebhook:
+		return s.deliverWebhook(sc, run)
+	}
+	return fmt.Errorf("不支持的投递方式 %s", sc.Delivery)
+}
+
+// deliverLocal 复制到 DeliveryDir 下的子目录
+func (s *Scheduler) deliverLocal(sc model.ReportSchedule, run model.ReportRun) error {
+	if s.conf.DeliveryDir == "" {
+		return fmt.Errorf("未配置 Report.DeliveryDir")
+	}
+	dir := s.conf.DeliveryDir
+	if sub := sanitizeName(sc.DeliveryTo); sub != "" && sub != "." && sub != ".." {
+		dir = filepath.Join(dir, sub)
+	}
+	_, err := copyFile(run.FilePath, filepath.Join(dir, filepath.Base(run.FilePath)))
+	return err
+}
+
+// deliverWebhook 以 multipart/form-data 上传报表：meta 为 JSON 元信息，file 为报表文件
+func (s *Scheduler) deliverWebhook(sc model.ReportSchedule, run model.ReportRun) error {
+	// 投递时再次校验，配置收紧后已有计划同样受限
+	if err := s.CheckWebhookURL(sc.DeliveryTo); err != nil {
+		return err
+	}
+	meta, err := json.Marshal(webhookMeta{
+		ScheduleID:  sc.ID,
+		Schedule:    sc.Name,
+		RunID:       run.ID,
+		Trigger:     run.Trigger,
+		PeriodStart: run.PeriodStart.In(s.loc).Format(time.RFC3339),
+		PeriodEnd:   run.PeriodEnd.In(s.loc).Format(time.RFC3339),
+		FileName:    filepath.Base(run.FilePath),
+		FileSize:    run.FileSize,
+	})
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(run.FilePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// 流式写出请求体，避免大文件整体载入内存
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		err := mw.WriteField("meta", string(meta))
+		if err == nil {
+			var part io.Writer
+			if part, err = mw.CreateFormFile("file", filepath.Base(run.FilePath)); err == nil {
+				if _, err = io.Copy(part, f); err == nil {
+					err = mw.Close()
+				}
+			}
+		}
+		pw.CloseWithError(err)
+	}()
+
+	req, err := http.NewRequest(http.MethodPost, sc.DeliveryTo, pr)
+	if err != nil {
+		pr.Close()
+		return err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	if s.conf.WebhookSecret != "" {
+		mac := hmac.New(sha256.New, []byte(s.conf.WebhookSecret))
+		mac.Write(meta)
+		req.Header.Set("X-Report-Signature", hex.EncodeToString(mac.Sum(nil)))
+	}
+	timeout := time.Duration(s.conf.WebhookTimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = defaultWebhookTimeout
+	}
+	client := &http.Client{
+		Timeout: timeout,
+		// 不跟随重定向，避免被重定向到白名单以外的地址
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook 返回 %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// deliverSMTP 以附件形式发送报表邮件
+func (s *Scheduler) deliverSMTP(sc model.ReportSchedule, run model.ReportRun) error {
+	c := s.conf.SMTP
+	if c.Host == "" || c.From == "" {
+		return fmt.Errorf("未配置 Report.SMTP")
+	}
+	var to []string
+	for _, addr := range strings.Split(sc.DeliveryTo, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			to = append(to, addr)
+		}
+	}
+	if len(to) == 0 {
+		return fmt.Errorf("未指定收件人")
+	}
+	maxMB := c.MaxAttachmentMB
+	if maxMB <= 0 {
+		maxMB = defaultMaxAttachmentMB
+	}
+	if run.FileSize > int64(maxMB)<<20 {
+		return fmt.Errorf("报表 %d 字节超过附件上限 %dMB", run.FileSize, maxMB)
+	}
+	data, err := os.ReadFile(run.FilePath)
+	if err != nil {
+		return err
+	}
+	subject := fmt.Sprintf("%s %s ~ %s", sc.Name,
+		run.PeriodStart.In(s.loc).Format("2006-01-02 15:04"), run.PeriodEnd.In(s.loc).Format("2006-01-02 15:04"))
+	body := fmt.Sprintf("报表计划：%s\n统计区间：%s ~ %s\n附件：%s（%d 字节）\n",
+		sc.Name, run.PeriodStart.In(s.loc).Format("2006-01-02 15:04:05"), run.PeriodEnd.In(s.loc).Format("2006-01-02 15:04:05"),
+		filepath.Base(run.FilePath), run.FileSize)
+	msg, err := buildMail(c.From, to, subject, body, filepath.Base(run.FilePath), data)
+	if err != nil {
+		return err
+	}
+	port := c.Port
+	if port == 0 {
+		port = 25
+	}
+	addr := net.JoinHostPort(c.Host, strconv.Itoa(port))
+	var auth smtp.Auth
+	if c.Username != "" {
+		auth = smtp.PlainAuth("", c.Username, c.Password, c.Host)
+	}
+	if port != 465 {
+		// smtp.SendMail 在服务器支持时自动使用 STARTTLS
+		return smtp.SendMail(addr, auth, c.From, to, msg)
+	}
+	// 465 端口为隐式 TLS
+	conn, err := tls.Dial("tcp", addr, &tls.Config{ServerName: c.Host})
+	if err != nil {
+		return err
+	}
+	client, err := smtp.NewClient(conn, c.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(c.From); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := client.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// buildMail 构造带单个附件的 MIME 邮件
+func buildMail(from string, to []string, subject, body, fileName string, attachment []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	header := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nMIME-Version: 1.0\r\nContent-Type: multipart/mixed; boundary=%s\r\n\r\n",
+		from, strings.Join(to, ", "), mime.BEncoding.Encode("UTF-8", subject), time.Now().Format(time.RFC1123Z), mw.Boundary())
+
+	text, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=UTF-8"},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := writeBase64(text, []byte(body)); err != nil {
+		return nil, err
+	}
+	att, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {mime.FormatMediaType("application/octet-stream", map[string]string{"name": fileName})},
+		"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": fileName})},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := writeBase64(att, attachment); err != nil {
+		return nil, err
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return append([]byte(header), buf.Bytes()...), nil
+}
+
+// writeBase64 按每行 76 字符写出 base64 编码内容
+func writeBase64(w io.Writer, data []byte) error {
+	enc := base64.StdEncoding.EncodeToString(data)
+	for len(enc) > 76 {
+		if _, err := io.WriteString(w, enc[:76]+"\r\n"); err != nil {
+			return err
+		}
+		enc = enc[76:]
+	}
+	_, err := io.WriteString(w, enc+"\r\n")
+	return err
+}
@@ -0,0 +1,583 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"drone-stats-service/internal/dao"
+	"drone-stats-service/internal/model"
+	"drone-stats-service/internal/track"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// 单架次报告告警阈值默认值
+const (
+	defaultLowSOCPercent = 20
+	defaultHighWindMps   = 10
+	defaultMaxHeightM    = 120
+)
+
+const (
+	// mapMaxPoints 航线图最多绘制的点数，超出时按 Douglas-Peucker 简化
+	mapMaxPoints = 1500
+	// profileMaxPoints 剖面图最多绘制的点数，超出时等间隔抽样
+	profileMaxPoints = 1000
+	// pageMargin A4 页边距（mm）
+	pageMargin = 15.0
+)
+
+// FlightReportOptions 告警阈值，0 值使用默认值
+type FlightReportOptions struct {
+	LowSOCPercent float64
+	HighWindMps   float64
+	MaxHeightM    float64
+}
+
+// FlightReport 单架次 PDF 报告的输入数据
+type FlightReport struct {
+	Record      dao.FlightReportRecord
+	Points      []model.FlightTrackPoint // 清洗后的轨迹点，按时间升序
+	Gaps        []track.Gap
+	TotalPoints int // 清洗前的轨迹点数
+}
+
+// FlightAlert 报告中列出的告警
+type FlightAlert struct {
+	Level   string // WARN | INFO
+	Message string
+}
+
+// weather 轨迹点气象数据的平均值
+type weather struct {
+	WindSpeed   float64 // m/s
+	WindDirect  float64 // °，按矢量平均
+	Temperature float64 // ℃
+	Humidity    float64 // %
+}
+
+// WriteFlightPDF 生成单架次 PDF 报告：概要、气象均值、告警、航线图及高度/速度/SOC 剖面，全部在服务端绘制
+func WriteFlightPDF(w io.Writer, rep FlightReport, opts FlightReportOptions) error {
+	opts = opts.withDefaults()
+	rec := rep.Record
+	loc := rec.StartTime.Location()
+
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(pageMargin, pageMargin, pageMargin)
+	pdf.SetAutoPageBreak(false, pageMargin)
+	pdf.SetTitle("Flight Report "+rec.OrderID, true)
+	pdf.SetCreator("drone-stats-service", true)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pageW, pageH := pdf.GetPageSize()
+	contentW := pageW - 2*pageMargin
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-10)
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.SetTextColor(120, 120, 120)
+		pdf.CellFormat(0, 5, fmt.Sprintf("Generated %s  -  page %d", time.Now().In(loc).Format("2006-01-02 15:04:05"), pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+
+	// 第 1 页：概要、气象、告警、航线图
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.CellFormat(contentW, 9, "Flight Report", "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.SetTextColor(80, 80, 80)
+	pdf.CellFormat(contentW, 6, tr("Order "+rec.OrderID), "", 1, "L", false, 0, "")
+	pdf.Ln(3)
+
+	colW := (contentW - 6) / 2
+	top := pdf.GetY()
+	left := drawTable(pdf, tr, pageMargin, top, colW, "Flight", flightRows(rep))
+	right := pageMargin + colW + 6
+	y := drawTable(pdf, tr, right, top, colW, "Weather (average)", weatherRows(averageWeather(rep.Points)))
+	y = drawTable(pdf, tr, right, y+4, colW, "Energy & load", []kv{
+		{"Energy used", fmt.Sprintf("%.3f kWh", rec.BatteryUsed)},
+		{"Energy method", orDash(rec.EnergyMethod)},
+		{"Payload", fmt.Sprintf("%.1f kg", rec.Payload/10)},
+		{"Parcels", fmt.Sprintf("%d", rec.ExpressCount)},
+	})
+	y = math.Max(y, left) + 4
+
+	alerts := flightAlerts(rep, opts)
+	y = drawAlerts(pdf, tr, pageMargin, y, contentW, alerts) + 4
+
+	pdf.SetXY(pageMargin, y)
+	sectionTitle(pdf, contentW, "Route")
+	mapY := pdf.GetY() + 1
+	drawRouteMap(pdf, tr, pageMargin, mapY, contentW, pageH-pageMargin-5-mapY, rep)
+
+	// 第 2 页：剖面图
+	pdf.AddPage()
+	sectionTitle(pdf, contentW, "Flight profiles")
+	times, height, speed, soc := profileSeries(rep.Points)
+	chartH := 72.0
+	y = pdf.GetY() + 2
+	drawProfile(pdf, tr, pageMargin, y, contentW, chartH, "Height above ground (m)", times, height, [3]int{31, 119, 180}, false)
+	y += chartH + 10
+	drawProfile(pdf, tr, pageMargin, y, contentW, chartH, "Ground speed (m/s)", times, speed, [3]int{44, 160, 44}, false)
+	y += chartH + 10
+	drawProfile(pdf, tr, pageMargin, y, contentW, chartH, "Battery SOC (%)", times, soc, [3]int{214, 39, 40}, true)
+
+	if err := pdf.Error(); err != nil {
+		return err
+	}
+	return pdf.Output(w)
+}
+
+func (o FlightReportOptions) withDefaults() FlightReportOptions {
+	if o.LowSOCPercent <= 0 {
+		o.LowSOCPercent = defaultLowSOCPercent
+	}
+	if o.HighWindMps <= 0 {
+		o.HighWindMps = defaultHighWindMps
+	}
+	if o.MaxHeightM <= 0 {
+		o.MaxHeightM = defaultMaxHeightM
+	}
+	return o
+}
+
+type kv struct{ k, v string }
+
+func flightRows(rep FlightReport) []kv {
+	rec := rep.Record
+	landing := rec.EndTime.Format("2006-01-02 15:04:05")
+	duration := formatDuration(rec.EndTime.Sub(rec.StartTime))
+	if !rec.HasEndTime {
+		landing, duration = "-", "-"
+	}
+	return []kv{
+		{"Record ID", fmt.Sprintf("%d", rec.ID)},
+		{"UAS ID", orDash(rec.UasID)},
+		{"Takeoff time", rec.StartTime.Format("2006-01-02 15:04:05")},
+		{"Landing time", landing},
+		{"Duration", duration},
+		{"Takeoff position", formatPosition(rec.StartLat, rec.StartLng)},
+		{"Landing position", formatPosition(rec.EndLat, rec.EndLng)},
+		{"Distance", fmt.Sprintf("%.3f km", rec.Distance/1000)},
+		{"Track points", fmt.Sprintf("%d (%d after cleaning)", rep.TotalPoints, len(rep.Points))},
+	}
+}
+
+func weatherRows(wx *weather) []kv {
+	if wx == nil {
+		return []kv{{"Weather", "no data"}}
+	}
+	return []kv{
+		{"Wind speed", fmt.Sprintf("%.1f m/s", wx.WindSpeed)},
+		{"Wind direction", fmt.Sprintf("%.0f° (%s)", wx.WindDirect, compassPoint(wx.WindDirect))},
+		{"Temperature", fmt.Sprintf("%.1f °C", wx.Temperature)},
+		{"Humidity", fmt.Sprintf("%.0f %%", wx.Humidity)},
+	}
+}
+
+// averageWeather 计算气象均值，风向按单位矢量平均以正确处理 0°/360° 附近的取值
+func averageWeather(points []model.FlightTrackPoint) *weather {
+	if len(points) == 0 {
+		return nil
+	}
+	var (
+		wx         weather
+		sinD, cosD float64
+	)
+	for _, p := range points {
+		wx.WindSpeed += float64(p.WindSpeed) / 10
+		wx.Temperature += float64(p.Temperture)
+		wx.Humidity += float64(p.Humidity)
+		rad := float64(p.WindDirect) / 10 * math.Pi / 180
+		sinD += math.Sin(rad)
+		cosD += math.Cos(rad)
+	}
+	n := float64(len(points))
+	wx.WindSpeed /= n
+	wx.Temperature /= n
+	wx.Humidity /= n
+	wx.WindDirect = math.Mod(math.Atan2(sinD, cosD)*180/math.Pi+360, 360)
+	return &wx
+}
+
+// flightAlerts 根据飞行记录与轨迹点生成告警
+func flightAlerts(rep FlightReport, opts FlightReportOptions) []FlightAlert {
+	rec := rep.Record
+	loc := rec.StartTime.Location()
+	var alerts []FlightAlert
+	if rec.MaintenanceOverdue {
+		alerts = append(alerts, FlightAlert{"WARN", "Maintenance overdue at takeoff: " + orDash(rec.MaintenanceOverdueItems)})
+	}
+	if !rec.HasEndTime {
+		alerts = append(alerts, FlightAlert{"WARN", "Landing time not recorded"})
+	}
+	if len(rep.Points) == 0 {
+		return append(alerts, FlightAlert{"WARN", "No track points recorded for this flight"})
+	}
+	var minSOC, maxHeight, maxWind *model.FlightTrackPoint
+	for i := range rep.Points {
+		p := &rep.Points[i]
+		if p.SOC > 0 && (minSOC == nil || p.SOC < minSOC.SOC) {
+			minSOC = p
+		}
+		if maxHeight == nil || p.Height > maxHeight.Height {
+			maxHeight = p
+		}
+		if maxWind == nil || p.WindSpeed > maxWind.WindSpeed {
+			maxWind = p
+		}
+	}
+	at := func(p *model.FlightTrackPoint) string { return p.TimeStamp.In(loc).Format("15:04:05") }
+	if minSOC != nil && float64(minSOC.SOC) < opts.LowSOCPercent {
+		alerts = append(alerts, FlightAlert{"WARN", fmt.Sprintf("Battery SOC dropped to %d%% at %s (threshold %.0f%%)", minSOC.SOC, at(minSOC), opts.LowSOCPercent)})
+	}
+	if h := float64(maxHeight.Height) / 10; h > opts.MaxHeightM {
+		alerts = append(alerts, FlightAlert{"WARN", fmt.Sprintf("Height reached %.1f m at %s (limit %.0f m)", h, at(maxHeight), opts.MaxHeightM)})
+	}
+	if ws := float64(maxWind.WindSpeed) / 10; ws > opts.HighWindMps {
+		alerts = append(alerts, FlightAlert{"WARN", fmt.Sprintf("Wind speed reached %.1f m/s at %s (threshold %.0f m/s)", ws, at(maxWind), opts.HighWindMps)})
+	}
+	if len(rep.Gaps) > 0 {
+		var total float64
+		longest := rep.Gaps[0]
+		for _, g := range rep.Gaps {
+			total += g.Seconds
+			if g.Seconds > longest.Seconds {
+				longest = g
+			}
+		}
+		alerts = append(alerts, FlightAlert{"INFO", fmt.Sprintf("%d telemetry gap(s), %.0f s in total; longest %.0f s at %s",
+			len(rep.Gaps), total, longest.Seconds, longest.Start.In(loc).Format("15:04:05"))})
+	}
+	if dropped := rep.TotalPoints - len(rep.Points); dropped > 0 {
+		alerts = append(alerts, FlightAlert{"INFO", fmt.Sprintf("%d invalid track point(s) removed before analysis", dropped)})
+	}
+	return alerts
+}
+
+func sectionTitle(pdf *fpdf.Fpdf, w float64, title string) {
+	pdf.SetFont("Helvetica", "B", 12)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.CellFormat(w, 7, title, "B", 1, "L", false, 0, "")
+}
+
+// drawTable 绘制带标题的两列键值表，返回表格底部的 y 坐标
+func drawTable(pdf *fpdf.Fpdf, tr func(string) string, x, y, w float64, title string, rows []kv) float64 {
+	pdf.SetXY(x, y)
+	sectionTitle(pdf, w, title)
+	keyW := w * 0.42
+	for _, r := range rows {
+		pdf.SetX(x)
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.SetTextColor(90, 90, 90)
+		pdf.CellFormat(keyW, 6, tr(r.k), "", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.SetTextColor(0, 0, 0)
+		pdf.CellFormat(w-keyW, 6, tr(r.v), "", 1, "L", false, 0, "")
+	}
+	return pdf.GetY()
+}
+
+func drawAlerts(pdf *fpdf.Fpdf, tr func(string) string, x, y, w float64, alerts []FlightAlert) float64 {
+	pdf.SetXY(x, y)
+	sectionTitle(pdf, w, "Alerts")
+	pdf.SetFont("Helvetica", "", 9)
+	if len(alerts) == 0 {
+		pdf.SetTextColor(44, 160, 44)
+		pdf.CellFormat(w, 6, "No alerts", "", 1, "L", false, 0, "")
+		return pdf.GetY()
+	}
+	for _, a := range alerts {
+		pdf.SetX(x)
+		if a.Level == "WARN" {
+			pdf.SetTextColor(200, 30, 30)
+		} else {
+			pdf.SetTextColor(200, 120, 0)
+		}
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.CellFormat(14, 6, a.Level, "", 0, "L", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.MultiCell(w-14, 6, tr(a.Message), "", "L", false)
+	}
+	return pdf.GetY()
+}
+
+// drawRouteMap 将轨迹投影到局部平面后绘制航线，含网格、比例尺、指北针及起降点，缺口处以虚线连接
+func drawRouteMap(pdf *fpdf.Fpdf, tr func(string) string, x, y, w, h float64, rep FlightReport) {
+	pdf.SetDrawColor(180, 180, 180)
+	pdf.SetFillColor(246, 248, 250)
+	pdf.SetLineWidth(0.2)
+	pdf.Rect(x, y, w, h, "FD")
+
+	points := rep.Points
+	if len(points) == 0 {
+		rec := rep.Record
+		if rec.StartLat == 0 && rec.StartLng == 0 {
+			pdf.SetFont("Helvetica", "", 10)
+			pdf.SetTextColor(120, 120, 120)
+			pdf.SetXY(x, y+h/2-3)
+			pdf.CellFormat(w, 6, "No position data", "", 0, "C", false, 0, "")
+			return
+		}
+		points = []model.FlightTrackPoint{
+			{Latitude: rec.StartLat, Longitude: rec.StartLng, FlightStatus: "TakeOff"},
+			{Latitude: rec.EndLat, Longitude: rec.EndLng, FlightStatus: "Land"},
+		}
+	}
+	idx := track.Simplify(points, track.SimplifyOptions{MaxPoints: mapMaxPoints})
+
+	lat0 := float64(points[0].Latitude) / 1e7
+	lng0 := float64(points[0].Longitude) / 1e7
+	cosLat := math.Cos(lat0 * math.Pi / 180)
+	const mPerDeg = math.Pi / 180 * 6371000
+	xs := make([]float64, len(idx))
+	ys := make([]float64, len(idx))
+	minX, maxX, minY, maxY := math.Inf(1), math.Inf(-1), math.Inf(1), math.Inf(-1)
+	for k, i := range idx {
+		xs[k] = (float64(points[i].Longitude)/1e7 - lng0) * cosLat * mPerDeg
+		ys[k] = (float64(points[i].Latitude)/1e7 - lat0) * mPerDeg
+		minX, maxX = math.Min(minX, xs[k]), math.Max(maxX, xs[k])
+		minY, maxY = math.Min(minY, ys[k]), math.Max(maxY, ys[k])
+	}
+	// 留白并保证最小显示范围，避免悬停架次被放大到失真
+	spanX, spanY := math.Max(maxX-minX, 100), math.Max(maxY-minY, 100)
+	pad := 8.0
+	scale := math.Min((w-2*pad)/spanX, (h-2*pad)/spanY) // mm / m
+	cx, cy := (minX+maxX)/2, (minY+maxY)/2
+	px := func(mx float64) float64 { return x + w/2 + (mx-cx)*scale }
+	py := func(my float64) float64 { return y + h/2 - (my-cy)*scale }
+
+	// 网格
+	step := niceStep(math.Max(w, h)/scale, 8)
+	pdf.SetDrawColor(225, 228, 232)
+	pdf.SetLineWidth(0.1)
+	for gx := math.Ceil((cx-w/2/scale)/step) * step; px(gx) < x+w; gx += step {
+		pdf.Line(px(gx), y, px(gx), y+h)
+	}
+	for gy := math.Ceil((cy-h/2/scale)/step) * step; py(gy) > y; gy += step {
+		pdf.Line(x, py(gy), x+w, py(gy))
+	}
+
+	// 航线
+	gapStart := map[int64]bool{}
+	for _, g := range rep.Gaps {
+		gapStart[g.Start.UnixNano()] = true
+	}
+	pdf.SetDrawColor(31, 119, 180)
+	pdf.SetLineWidth(0.5)
+	pdf.SetLineCapStyle("round")
+	pdf.SetLineJoinStyle("round")
+	for k := 1; k < len(idx); k++ {
+		// 简化后相邻下标之间若有缺口起点，则该段以虚线绘制
+		dashed := false
+		for i := idx[k-1]; i < idx[k] && len(gapStart) > 0; i++ {
+			if gapStart[points[i].TimeStamp.UnixNano()] {
+				dashed = true
+				break
+			}
+		}
+		if dashed {
+			pdf.SetDashPattern([]float64{1.2, 1.2}, 0)
+		}
+		pdf.Line(px(xs[k-1]), py(ys[k-1]), px(xs[k]), py(ys[k]))
+		if dashed {
+			pdf.SetDashPattern([]float64{}, 0)
+		}
+	}
+
+	// 起降点
+	last := len(idx) - 1
+	pdf.SetLineWidth(0.3)
+	pdf.SetDrawColor(255, 255, 255)
+	pdf.SetFillColor(44, 160, 44)
+	pdf.Circle(px(xs[0]), py(ys[0]), 1.8, "FD")
+	pdf.SetFillColor(214, 39, 40)
+	pdf.Circle(px(xs[last]), py(ys[last]), 1.8, "FD")
+	pdf.SetFont("Helvetica", "B", 8)
+	pdf.SetTextColor(44, 120, 44)
+	pdf.Text(px(xs[0])+2.5, py(ys[0])-2, "Takeoff")
+	pdf.SetTextColor(214, 39, 40)
+	pdf.Text(px(xs[last])+2.5, py(ys[last])+4, "Landing")
+
+	// 比例尺
+	barM := niceStep(w/scale, 5)
+	barW := barM * scale
+	bx, by := x+5, y+h-5
+	pdf.SetDrawColor(40, 40, 40)
+	pdf.SetLineWidth(0.4)
+	pdf.Line(bx, by, bx+barW, by)
+	pdf.Line(bx, by-1.2, bx, by)
+	pdf.Line(bx+barW, by-1.2, bx+barW, by)
+	pdf.SetFont("Helvetica", "", 8)
+	pdf.SetTextColor(40, 40, 40)
+	pdf.Text(bx, by-2, formatMeters(barM))
+
+	// 指北针
+	nx, ny := x+w-8, y+6
+	pdf.SetFillColor(40, 40, 40)
+	pdf.Polygon([]fpdf.PointType{{X: nx, Y: ny}, {X: nx - 2, Y: ny + 6}, {X: nx, Y: ny + 4.5}, {X: nx + 2, Y: ny + 6}}, "F")
+	pdf.SetFont("Helvetica", "B", 8)
+	pdf.Text(nx-1.2, ny+10, "N")
+
+	pdf.SetFont("Helvetica", "", 7)
+	pdf.SetTextColor(120, 120, 120)
+	pdf.Text(x+w-62, y+h-2, tr(fmt.Sprintf("Grid %s  -  centre %.5f, %.5f", formatMeters(step),
+		lat0+cy/mPerDeg, lng0+cx/(cosLat*mPerDeg))))
+}
+
+// profileSeries 提取剖面数据：横轴为起飞后分钟数，超出 profileMaxPoints 时等间隔抽样
+func profileSeries(points []model.FlightTrackPoint) (times, height, speed, soc []float64) {
+	if len(points) == 0 {
+		return
+	}
+	stride := (len(points) + profileMaxPoints - 1) / profileMaxPoints
+	t0 := points[0].TimeStamp
+	for i := 0; i < len(points); i += stride {
+		p := points[i]
+		times = append(times, p.TimeStamp.Sub(t0).Minutes())
+		height = append(height, float64(p.Height)/10)
+		speed = append(speed, float64(p.GS)/10)
+		soc = append(soc, float64(p.SOC))
+	}
+	return
+}
+
+// drawProfile 绘制折线剖面图；skipZero 为 true 时 0 值视为无数据（如非智能电池的 SOC）
+func drawProfile(pdf *fpdf.Fpdf, tr func(string) string, x, y, w, h float64, title string, times, values []float64, color [3]int, skipZero bool) {
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.Text(x, y+4, tr(title))
+	const axisW, axisH = 14.0, 9.0
+	plotX, plotY := x+axisW, y+7
+	plotW, plotH := w-axisW-2, h-7-axisH
+
+	pdf.SetDrawColor(180, 180, 180)
+	pdf.SetLineWidth(0.2)
+	pdf.Rect(plotX, plotY, plotW, plotH, "D")
+
+	minV, maxV := math.Inf(1), math.Inf(-1)
+	n := 0
+	for _, v := range values {
+		if skipZero && v == 0 {
+			continue
+		}
+		minV, maxV = math.Min(minV, v), math.Max(maxV, v)
+		n++
+	}
+	if n == 0 {
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.SetTextColor(120, 120, 120)
+		pdf.SetXY(plotX, plotY+plotH/2-3)
+		pdf.CellFormat(plotW, 6, "No data", "", 0, "C", false, 0, "")
+		return
+	}
+	if minV > 0 {
+		minV = 0
+	}
+	if maxV-minV < 1 {
+		maxV = minV + 1
+	}
+	yStep := niceStep(maxV-minV, 5)
+	minV = math.Floor(minV/yStep) * yStep
+	maxV = math.Ceil(maxV/yStep) * yStep
+	maxT := math.Max(times[len(times)-1], 1.0/60)
+	xStep := niceStep(maxT, 8)
+	tx := func(t float64) float64 { return plotX + t/maxT*plotW }
+	vy := func(v float64) float64 { return plotY + plotH - (v-minV)/(maxV-minV)*plotH }
+
+	pdf.SetFont("Helvetica", "", 7)
+	pdf.SetTextColor(90, 90, 90)
+	pdf.SetDrawColor(230, 230, 230)
+	pdf.SetLineWidth(0.1)
+	for v := minV; v <= maxV+yStep/2; v += yStep {
+		pdf.Line(plotX, vy(v), plotX+plotW, vy(v))
+		label := formatTick(v, yStep)
+		pdf.Text(plotX-1.5-pdf.GetStringWidth(label), vy(v)+1, label)
+	}
+	for t := 0.0; t <= maxT+xStep/100; t += xStep {
+		pdf.Line(tx(t), plotY, tx(t), plotY+plotH)
+		label := formatTick(t, xStep)
+		pdf.Text(tx(t)-pdf.GetStringWidth(label)/2, plotY+plotH+4, label)
+	}
+	pdf.Text(plotX+plotW-pdf.GetStringWidth("minutes since takeoff"), plotY+plotH+8, "minutes since takeoff")
+
+	pdf.SetDrawColor(color[0], color[1], color[2])
+	pdf.SetLineWidth(0.4)
+	started := false
+	for i, v := range values {
+		if skipZero && v == 0 {
+			continue
+		}
+		if !started {
+			pdf.MoveTo(tx(times[i]), vy(v))
+			started = true
+			continue
+		}
+		pdf.LineTo(tx(times[i]), vy(v))
+	}
+	pdf.DrawPath("D")
+}
+
+// niceStep 返回把 span 大致分成 n 份的 1/2/5×10^k 步长
+func niceStep(span float64, n int) float64 {
+	if span <= 0 || n <= 0 {
+		return 1
+	}
+	raw := span / float64(n)
+	mag := math.Pow(10, math.Floor(math.Log10(raw)))
+	switch f := raw / mag; {
+	case f <= 1:
+		return mag
+	case f <= 2:
+		return 2 * mag
+	case f <= 5:
+		return 5 * mag
+	}
+	return 10 * mag
+}
+
+func formatTick(v, step float64) string {
+	if step >= 1 {
+		return fmt.Sprintf("%.0f", v)
+	}
+	decimals := int(math.Ceil(-math.Log10(step)))
+	return fmt.Sprintf("%.*f", decimals, v)
+}
+
+func formatMeters(m float64) string {
+	if m >= 1000 {
+		return strings.TrimSuffix(fmt.Sprintf("%.1f", m/1000), ".0") + " km"
+	}
+	return fmt.Sprintf("%.0f m", m)
+}
+
+func formatDuration(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	d = d.Round(time.Second)
+	return fmt.Sprintf("%02d:%02d:%02d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+}
+
+func formatPosition(lat, lng int64) string {
+	if lat == 0 && lng == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.7f, %.7f", float64(lat)/1e7, float64(lng)/1e7)
+}
+
+// compassPoint 将方位角转换为 16 方位
+func compassPoint(deg float64) string {
+	names := []string{"N", "NNE", "NE", "ENE", "E", "ESE", "SE", "SSE", "S", "SSW", "SW", "WSW", "W", "WNW", "NW", "NNW"}
+	return names[int(math.Round(deg/22.5))%16]
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
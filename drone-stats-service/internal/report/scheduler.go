@@ -0,0 +1,374 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"drone-stats-service/internal/config"
+	"drone-stats-service/internal/dao"
+	"drone-stats-service/internal/export"
+	"drone-stats-service/internal/model"
+
+	"github.com/robfig/cron/v3"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// 投递方式
+const (
+	DeliveryLocal   = "local"
+	DeliverySMTP    = "smtp"
+	DeliveryWebhook = "webhook"
+)
+
+// 执行记录状态与触发方式
+const (
+	RunStatusPending   = "pending"
+	RunStatusDelivered = "delivered"
+	RunStatusFailed    = "failed"
+
+	TriggerCron   = "cron"
+	TriggerManual = "manual"
+)
+
+const (
+	defaultReportDir        = "/app/reports"
+	defaultReportRetainDays = 30
+	defaultRangeHours       = 24
+	// tickInterval 检查到期计划的间隔，cron 最小粒度为分钟
+	tickInterval = 30 * time.Second
+	// maxErrorLen 与 report_runs.error 列长度一致
+	maxErrorLen = 1000
+)
+
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// ParseCron 解析标准 5 段 cron 表达式（支持 @daily、@weekly 等描述符）
+func ParseCron(expr string) (cron.Schedule, error) {
+	sched, err := cronParser.Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("cron 表达式无效: %v", err)
+	}
+	return sched, nil
+}
+
+// Scheduler 按 cron 触发报表计划：通过 TaskManager 生成导出文件，完成后存档并投递
+type Scheduler struct {
+	mysql dao.ReportRepo
+	tm    *export.TaskManager
+	conf  config.ReportConf
+	loc   *time.Location
+	dir   string
+
+	mu   sync.Mutex
+	runs map[string]int64 // task id -> report run id，任务结束回调时据此找到执行记录
+}
+
+// NewScheduler 创建报表调度器，需调用 Start 启动
+func NewScheduler(mysql dao.ReportRepo, tm *export.TaskManager, c config.ReportConf) *Scheduler {
+	loc := time.FixedZone("UTC+8", 8*3600)
+	tz := c.Timezone
+	if tz == "" {
+		tz = "Asia/Shanghai"
+	}
+	if l, err := time.LoadLocation(tz); err == nil {
+		loc = l
+	} else {
+		logx.Errorf("加载报表时区 %s 失败，使用 UTC+8: %v", tz, err)
+	}
+	dir := c.Dir
+	if dir == "" {
+		dir = defaultReportDir
+	}
+	if c.RetainDays <= 0 {
+		c.RetainDays = defaultReportRetainDays
+	}
+	return &Scheduler{mysql: mysql, tm: tm, conf: c, loc: loc, dir: dir, runs: map[string]int64{}}
+}
+
+// Start 恢复未结束的执行记录并启动调度与存档清理
+func (s *Scheduler) Start() {
+	s.tm.OnFinish(s.onTaskFinished)
+	if runs, err := s.mysql.ListPendingReportRuns(); err != nil {
+		logx.Errorf("加载未完成的报表执行记录失败: %v", err)
+	} else {
+		for _, r := range runs {
+			s.resume(r)
+		}
+	}
+	go s.loop()
+}
+
+// NextRun 计算 cron 表达式在 from 之后的下一次触发时间
+func (s *Scheduler) NextRun(expr string, from time.Time) (time.Time, error) {
+	sched, err := ParseCron(expr)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return sched.Next(from.In(s.loc)), nil
+}
+
+// DeliveryDirConfigured 是否配置了 local 投递根目录
+func (s *Scheduler) DeliveryDirConfigured() bool {
+	return s.conf.DeliveryDir != ""
+}
+
+// CheckWebhookURL 校验 webhook 地址：必须为 http(s) URL，且主机名在 Report.WebhookAllowedHosts 中
+func (s *Scheduler) CheckWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("webhook 地址必须为 http(s) URL")
+	}
+	if len(s.conf.WebhookAllowedHosts) == 0 {
+		return fmt.Errorf("未配置 Report.WebhookAllowedHosts，不支持 webhook 投递")
+	}
+	for _, h := range s.conf.WebhookAllowedHosts {
+		if strings.EqualFold(strings.TrimSpace(h), u.Hostname()) {
+			return nil
+		}
+	}
+	return fmt.Errorf("webhook 主机 %s 不在 Report.WebhookAllowedHosts 中", u.Hostname())
+}
+
+// SMTPConfigured 是否配置了 SMTP 服务器
+func (s *Scheduler) SMTPConfigured() bool {
+	return s.conf.SMTP.Host != "" && s.conf.SMTP.From != ""
+}
+
+func (s *Scheduler) loop() {
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+	lastClean := time.Time{}
+	for now := range ticker.C {
+		s.tick(now)
+		if now.Sub(lastClean) >= time.Hour {
+			s.cleanExpired(now)
+			lastClean = now
+		}
+	}
+}
+
+// tick 触发所有到期的计划；停机期间错过的多次触发只补执行一次
+func (s *Scheduler) tick(now time.Time) {
+	list, err := s.mysql.ListReportSchedules(true)
+	if err != nil {
+		logx.Errorf("查询报表计划失败: %v", err)
+		return
+	}
+	for _, sc := range list {
+		next, err := s.NextRun(sc.Cron, now)
+		if err != nil {
+			logx.Errorf("报表计划 %d: %v", sc.ID, err)
+			continue
+		}
+		if sc.NextRunAt.IsZero() {
+			if err := s.mysql.SetReportScheduleRunTimes(sc.ID, sc.LastRunAt, next); err != nil {
+				logx.Errorf("更新报表计划 %d 下次执行时间失败: %v", sc.ID, err)
+			}
+			continue
+		}
+		if sc.NextRunAt.After(now) {
+			continue
+		}
+		if _, err := s.Trigger(sc, sc.NextRunAt, TriggerCron); err != nil {
+			logx.Errorf("触发报表计划 %d 失败: %v", sc.ID, err)
+		}
+		if err := s.mysql.SetReportScheduleRunTimes(sc.ID, now, next); err != nil {
+			logx.Errorf("更新报表计划 %d 执行时间失败: %v", sc.ID, err)
+		}
+	}
+}
+
+// Trigger 以 at 为报表截止时刻执行一次计划，返回执行记录；导出任务异步执行
+func (s *Scheduler) Trigger(sc model.ReportSchedule, at time.Time, trigger string) (model.ReportRun, error) {
+	rangeHours := sc.RangeHours
+	if rangeHours <= 0 {
+		rangeHours = defaultRangeHours
+	}
+	run := model.ReportRun{
+		ScheduleID:  sc.ID,
+		Trigger:     trigger,
+		PeriodStart: at.Add(-time.Duration(rangeHours) * time.Hour),
+		PeriodEnd:   at,
+		Status:      RunStatusPending,
+		StartedAt:   time.Now(),
+	}
+	id, err := s.mysql.CreateReportRun(run)
+	if err != nil {
+		return run, err
+	}
+	run.ID = id
+
+	// 持锁创建任务并登记映射，避免任务在登记前结束而丢失回调
+	s.mu.Lock()
+	taskID, err := s.tm.CreateTask(export.TaskRequest{
+		Target:      sc.Target,
+		UasID:       sc.UasID,
+		StartTime:   run.PeriodStart.UTC().Format(time.RFC3339),
+		EndTime:     run.PeriodEnd.UTC().Format(time.RFC3339),
+		Format:      sc.Format,
+		PartitionBy: sc.PartitionBy,
+		User:        fmt.Sprintf("report:%d", sc.ID),
+		Priority:    export.PriorityLow,
+	})
+	if err == nil {
+		s.runs[taskID] = run.ID
+	}
+	s.mu.Unlock()
+	if err != nil {
+		s.finish(&run, err)
+		return run, err
+	}
+	run.TaskID = taskID
+	if err := s.mysql.UpdateReportRun(run); err != nil {
+		logx.Errorf("更新报表执行记录 %d 失败: %v", run.ID, err)
+	}
+	return run, nil
+}
+
+// resume 服务重启后恢复跟踪未结束的执行记录：任务已结束的直接处理，否则等待回调
+func (s *Scheduler) resume(r model.ReportRun) {
+	t, ok := s.tm.GetTask(r.TaskID)
+	if r.TaskID == "" || !ok {
+		s.finish(&r, fmt.Errorf("导出任务已丢失"))
+		return
+	}
+	s.mu.Lock()
+	s.runs[r.TaskID] = r.ID
+	s.mu.Unlock()
+	switch t.Status {
+	case export.TaskStatusDone, export.TaskStatusFailed, export.TaskStatusCanceled:
+		s.onTaskFinished(*t)
+	}
+}
+
+// onTaskFinished TaskManager 任务结束回调：存档并投递报表
+func (s *Scheduler) onTaskFinished(t export.Task) {
+	s.mu.Lock()
+	runID, ok := s.runs[t.ID]
+	delete(s.runs, t.ID)
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+	go s.complete(runID, t)
+}
+
+func (s *Scheduler) complete(runID int64, t export.Task) {
+	run, err := s.mysql.GetReportRun(runID)
+	if err != nil {
+		logx.Errorf("查询报表执行记录 %d 失败: %v", runID, err)
+		return
+	}
+	if t.Status != export.TaskStatusDone {
+		msg := t.Error
+		if msg == "" {
+			msg = "导出任务" + t.Status
+		}
+		s.finish(&run, fmt.Errorf("%s", msg))
+		return
+	}
+	sc, err := s.mysql.GetReportSchedule(run.ScheduleID)
+	if err != nil {
+		s.finish(&run, fmt.Errorf("查询报表计划失败: %v", err))
+		return
+	}
+	path, size, err := s.archive(sc, run, t.ResultFile)
+	if err != nil {
+		s.finish(&run, fmt.Errorf("存档报表失败: %v", err))
+		return
+	}
+	run.FilePath, run.FileSize = path, size
+	s.finish(&run, s.deliver(sc, run))
+}
+
+// finish 记录执行结果，err 为空时状态为 delivered
+func (s *Scheduler) finish(run *model.ReportRun, err error) {
+	run.Status = RunStatusDelivered
+	run.Error = ""
+	if err != nil {
+		run.Status = RunStatusFailed
+		run.Error = err.Error()
+		if len(run.Error) > maxErrorLen {
+			run.Error = run.Error[:maxErrorLen]
+		}
+		logx.Errorf("报表执行 %d（计划 %d）失败: %v", run.ID, run.ScheduleID, err)
+	}
+	run.FinishedAt = time.Now()
+	if e := s.mysql.UpdateReportRun(*run); e != nil {
+		logx.Errorf("更新报表执行记录 %d 失败: %v", run.ID, e)
+	}
+}
+
+// reportFileName 存档文件名：计划名_截止时刻.扩展名
+func (s *Scheduler) reportFileName(sc model.ReportSchedule, run model.ReportRun, src string) string {
+	name := sanitizeName(sc.Name)
+	if name == "" {
+		name = fmt.Sprintf("report-%d", sc.ID)
+	}
+	return name + "_" + run.PeriodEnd.In(s.loc).Format("20060102-1504") + filepath.Ext(src)
+}
+
+// archive 将导出结果复制到报表存档目录（不受导出任务保留期影响）
+func (s *Scheduler) archive(sc model.ReportSchedule, run model.ReportRun, src string) (string, int64, error) {
+	dir := filepath.Join(s.dir, fmt.Sprintf("schedule-%d", sc.ID))
+	dst := filepath.Join(dir, s.reportFileName(sc, run, src))
+	size, err := copyFile(src, dst)
+	return dst, size, err
+}
+
+// cleanExpired 删除超过保留期的报表存档
+func (s *Scheduler) cleanExpired(now time.Time) {
+	runs, err := s.mysql.ListExpiredReportRuns(s.conf.RetainDays, now)
+	if err != nil {
+		logx.Errorf("查询过期报表失败: %v", err)
+		return
+	}
+	for _, r := range runs {
+		if err := os.Remove(r.FilePath); err != nil && !os.IsNotExist(err) {
+			logx.Errorf("删除过期报表 %s 失败: %v", r.FilePath, err)
+			continue
+		}
+		r.FilePath = ""
+		if err := s.mysql.UpdateReportRun(r); err != nil {
+			logx.Errorf("更新报表执行记录 %d 失败: %v", r.ID, err)
+		}
+	}
+}
+
+// sanitizeName 去除不适合作为文件/目录名的字符
+func sanitizeName(v string) string {
+	v = strings.TrimSpace(v)
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|', ' ':
+			return '_'
+		}
+		return r
+	}, v)
+}
+
+func copyFile(src, dst string) (int64, error) {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return 0, err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(out, in)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	return n, err
+}
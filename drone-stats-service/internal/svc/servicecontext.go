@@ -1,35 +1,213 @@
 package svc
 
 import (
+	"context"
+	"database/sql"
+	"drone-stats-service/internal/backup"
 	"drone-stats-service/internal/config"
 	"drone-stats-service/internal/dao"
+	"drone-stats-service/internal/downsample"
 	"drone-stats-service/internal/export"
+	"drone-stats-service/internal/report"
 	"fmt"
+	"os"
+	"time"
 
 	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/zeromicro/go-zero/core/collection"
 )
 
 type ServiceContext struct {
-	Config      config.Config
-	InfluxDao   *dao.InfluxDao
-	MySQLDao    *dao.MySQLDao
+	Config config.Config
+	// 仓储接口，按 Storage 配置由 MySQL/SQLite 与 InfluxDB/内存遥测实现
+	Records     dao.FlightRecordRepo
+	TrackPoints dao.TrackPointRepo
+	Sorties     dao.SortieRepo
+	Exports     dao.ExportRepo
+	Telemetry   dao.TelemetrySource
+	Battery     dao.BatteryRepo
+	Maintenance dao.MaintenanceRepo
+	Audits      dao.AuditRepo
+	Reports     dao.ReportRepo
+	Payloads    dao.PayloadImportRepo
+	Store       dao.StoreStatus
+	// SQLDao 关系库，仅供建表迁移、启动时队列重放等后台任务使用，logic 与 handler 通过上面的接口访问
+	SQLDao      *dao.SQLDao
 	TaskManager *export.TaskManager
+	TrackCache  *collection.Cache // 简化轨迹缓存，键含轨迹点版本，轨迹点变化后自动失效
+	// ReportScheduler 定时报表调度，依赖 TaskManager，TaskManager 未启用时为 nil
+	ReportScheduler *report.Scheduler
+	// Backup 备份管理器，定时备份、退出前备份与备份管理 API 共用，保证备份互斥执行
+	Backup *backup.Manager
+	// Downsample 降采样任务，未启用时仅用于查询原始数据
+	Downsample *downsample.Job
 }
 
 func NewServiceContext(c config.Config) *ServiceContext {
-	influxClient := influxdb2.NewClient("http://"+c.InfluxDBConfig.Host+":"+c.InfluxDBConfig.Port, c.InfluxDBConfig.Token)
-	influxDao := dao.NewInfluxDao(influxClient, c.InfluxDBConfig.Org)
-	mysqlDao, err := dao.NewMySQLDao(c.MySQL)
+	sqlDao, err := newSQLDao(c)
 	if err != nil {
 		panic(err)
 	}
-	// 初始化 TaskManager，使用系统临时目录存放任务及输出
+	telemetry, influxClient, err := newTelemetry(c)
+	if err != nil {
+		panic(err)
+	}
+	// 初始化 TaskManager，默认使用系统临时目录存放任务及输出
 	baseURL := fmt.Sprintf("http://%s:%d", c.Host, c.Port)
-	taskMgr, _ := export.NewTaskManager(mysqlDao, influxDao, "", baseURL)
+	taskMgr, _ := export.NewTaskManager(sqlDao, telemetry, c.Export, baseURL)
+	var scheduler *report.Scheduler
+	if taskMgr != nil {
+		scheduler = report.NewScheduler(sqlDao, taskMgr, c.Report)
+		scheduler.Start()
+	}
+	expire := c.TrackCache.ExpireSeconds
+	if expire <= 0 {
+		expire = 600
+	}
+	limit := c.TrackCache.Limit
+	if limit <= 0 {
+		limit = 1000
+	}
+	trackCache, err := collection.NewCache(time.Duration(expire)*time.Second, collection.WithLimit(limit))
+	if err != nil {
+		panic(err)
+	}
+	downsampleJob, err := downsample.NewJob(influxClient, c.InfluxDBConfig.Org, c.InfluxDBConfig.Bucket, c.Downsample)
+	if err != nil {
+		panic(err)
+	}
+	downsampleJob.Start()
 	return &ServiceContext{
-		Config:      c,
-		InfluxDao:   influxDao,
-		MySQLDao:    mysqlDao,
-		TaskManager: taskMgr,
+		Config:          c,
+		Records:         sqlDao,
+		TrackPoints:     sqlDao,
+		Sorties:         sqlDao,
+		Exports:         sqlDao,
+		Telemetry:       telemetry,
+		Battery:         sqlDao,
+		Maintenance:     sqlDao,
+		Audits:          sqlDao,
+		Reports:         sqlDao,
+		Payloads:        sqlDao,
+		Store:           sqlDao,
+		SQLDao:          sqlDao,
+		TaskManager:     taskMgr,
+		TrackCache:      trackCache,
+		ReportScheduler: scheduler,
+		Backup:          newBackupManager(c, sqlDao, influxClient),
+		Downsample:      downsampleJob,
+	}
+}
+
+// newSQLDao 按 Storage.Driver 连接 MySQL 或打开嵌入式 SQLite
+func newSQLDao(c config.Config) (*dao.SQLDao, error) {
+	switch c.Storage.Driver {
+	case "", dao.DialectMySQL:
+		return dao.NewMySQLDao(c.MySQL)
+	case dao.DialectSQLite:
+		return dao.NewSQLiteDao(c.Storage, c.MySQL)
+	}
+	return nil, fmt.Errorf("不支持的存储驱动 %q", c.Storage.Driver)
+}
+
+// newTelemetry 按 Storage.Telemetry 创建遥测来源；使用内存遥测时 influxClient 为 nil
+func newTelemetry(c config.Config) (dao.TelemetrySource, influxdb2.Client, error) {
+	switch c.Storage.Telemetry {
+	case "", "influx":
+		client := influxdb2.NewClient("http://"+c.InfluxDBConfig.Host+":"+c.InfluxDBConfig.Port, c.InfluxDBConfig.Token)
+		return dao.NewInfluxDao(client, c.InfluxDBConfig.Org), client, nil
+	case "memory":
+		mem := dao.NewMemoryTelemetry()
+		if c.Storage.TelemetryFile != "" {
+			n, err := mem.LoadFile(c.Storage.TelemetryFile)
+			if err != nil {
+				return nil, nil, fmt.Errorf("导入遥测文件 %s 失败: %w", c.Storage.TelemetryFile, err)
+			}
+			fmt.Printf("已导入遥测文件 %s：%d 个点\n", c.Storage.TelemetryFile, n)
+		}
+		return mem, nil, nil
+	}
+	return nil, nil, fmt.Errorf("不支持的遥测来源 %q", c.Storage.Telemetry)
+}
+
+// newBackupManager 按 BackupConf 创建备份管理器，未配置的项使用默认值
+func newBackupManager(c config.Config, sqlDao *dao.SQLDao, influxClient influxdb2.Client) *backup.Manager {
+	backupDir := "/droneMonitor/backups"
+	retention := 7
+	influxBucket := c.InfluxDBConfig.Bucket
+	if c.BackupConf.BackupDir != "" {
+		backupDir = c.BackupConf.BackupDir
+	}
+	if c.BackupConf.RetentionDays > 0 {
+		retention = c.BackupConf.RetentionDays
+	}
+	if c.BackupConf.InfluxBucket != "" {
+		influxBucket = c.BackupConf.InfluxBucket
+	}
+
+	// 确保备份目录存在
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		fmt.Println("创建备份目录失败:", err)
+	}
+
+	// 备份导出 MySQL 与 InfluxDB，嵌入式存储不支持备份
+	var mysqlDB *sql.DB
+	if sqlDao.Dialect() == dao.DialectMySQL {
+		mysqlDB = sqlDao.DB
+	}
+	bm := backup.NewManager(mysqlDB, influxClient, c.InfluxDBConfig.Org, influxBucket, backupDir, retention)
+	if c.BackupConf.FullIntervalDays > 0 {
+		bm.FullIntervalDays = c.BackupConf.FullIntervalDays
+	}
+	if c.BackupConf.Compression != "" {
+		bm.Compression = c.BackupConf.Compression
+	}
+	bm.RewriteTables = c.BackupConf.RewriteTables
+	bm.IDOverlap = c.BackupConf.IDOverlap
+	for _, tc := range c.BackupConf.Targets {
+		st, err := newBackupStorage(tc)
+		if err != nil {
+			// 存储目标不可用时只保留本地备份，不影响服务启动
+			fmt.Printf("备份存储目标 %s 初始化失败: %v\n", tc.Name, err)
+			continue
+		}
+		bm.Targets = append(bm.Targets, backup.Target{Storage: st, RetentionDays: tc.RetentionDays})
+	}
+	return bm
+}
+
+func newBackupStorage(tc config.StorageTargetConf) (backup.Storage, error) {
+	name := tc.Name
+	if name == "" {
+		name = tc.Type
+	}
+	switch tc.Type {
+	case backup.StorageLocal:
+		return backup.NewLocalStorage(name, tc.Path)
+	case backup.StorageS3:
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		return backup.NewS3Storage(ctx, backup.S3Options{
+			Name:      name,
+			Endpoint:  tc.Endpoint,
+			Bucket:    tc.Bucket,
+			Prefix:    tc.Prefix,
+			Region:    tc.Region,
+			AccessKey: tc.AccessKey,
+			SecretKey: tc.SecretKey,
+			UseSSL:    tc.UseSSL,
+		})
+	case backup.StorageSFTP:
+		return backup.NewSFTPStorage(backup.SFTPOptions{
+			Name:               name,
+			Host:               tc.Host,
+			User:               tc.User,
+			Password:           tc.Password,
+			PrivateKeyFile:     tc.PrivateKeyFile,
+			KnownHostsFile:     tc.KnownHostsFile,
+			HostKeyFingerprint: tc.HostKeyFingerprint,
+			Path:               tc.Path,
+		})
 	}
+	return nil, fmt.Errorf("不支持的存储类型 %q", tc.Type)
 }
@@ -0,0 +1,253 @@
+package track
+
+import (
+	"math"
+	"time"
+
+	"drone-stats-service/internal/model"
+)
+
+// 平滑方式
+const (
+	SmoothingNone          = "none"
+	SmoothingMovingAverage = "moving_average"
+	SmoothingKalman        = "kalman"
+)
+
+// Options 轨迹清洗参数，0 值使用默认值
+type Options struct {
+	MaxSpeedMps     float64 // 相邻点隐含水平速度上限（m/s），默认 40
+	MaxAccelMps2    float64 // 相邻区间隐含加速度上限（m/s²），默认 20
+	FrozenMinGS     float64 // 上报地速（m/s）不低于该值而坐标不变时视为位置冻结，默认 1
+	GapSeconds      float64 // 相邻保留点间隔超过该值（秒）时记为缺口，默认 10
+	Smoothing       string  // none | moving_average | kalman，默认 none
+	SmoothingWindow int     // 滑动平均窗口（点数，奇数），默认 5
+	KalmanNoiseM    float64 // 卡尔曼滤波观测噪声标准差（m），默认 5
+	KalmanAccelMps2 float64 // 卡尔曼滤波过程噪声（m/s²），默认 3
+}
+
+func (o Options) withDefaults() Options {
+	if o.MaxSpeedMps <= 0 {
+		o.MaxSpeedMps = 40
+	}
+	if o.MaxAccelMps2 <= 0 {
+		o.MaxAccelMps2 = 20
+	}
+	if o.FrozenMinGS <= 0 {
+		o.FrozenMinGS = 1
+	}
+	if o.GapSeconds <= 0 {
+		o.GapSeconds = 10
+	}
+	if o.Smoothing == "" {
+		o.Smoothing = SmoothingNone
+	}
+	if o.SmoothingWindow <= 1 {
+		o.SmoothingWindow = 5
+	}
+	if o.KalmanNoiseM <= 0 {
+		o.KalmanNoiseM = 5
+	}
+	if o.KalmanAccelMps2 <= 0 {
+		o.KalmanAccelMps2 = 3
+	}
+	return o
+}
+
+// maxConsecutiveRejects 连续剔除达到该点数时不再以当前参照点为准：参照点为跳点时剔除参照点，否则视为位置跳变并重新定位
+const maxConsecutiveRejects = 5
+
+// Gap 采样缺口
+type Gap struct {
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Seconds float64   `json:"seconds"`
+}
+
+// Result 清洗结果
+type Result struct {
+	Points        []model.FlightTrackPoint // 保留（并按需平滑）的轨迹点
+	DroppedZero   int                      // (0,0) 或超出经纬度范围的点
+	DroppedTime   int                      // 时间戳重复或倒序的点
+	DroppedSpeed  int                      // 隐含速度超限的点
+	DroppedAccel  int                      // 隐含加速度超限的点
+	DroppedFrozen int                      // 位置冻结的点
+	Gaps          []Gap
+	GapSeconds    float64
+	Smoothing     string
+}
+
+// Clean 剔除无效坐标、时间错乱、速度/加速度跳变与位置冻结的点，按需平滑并标记采样缺口。
+// points 需按时间升序，返回的点为副本，不修改入参。
+func Clean(points []model.FlightTrackPoint, opts Options) Result {
+	opts = opts.withDefaults()
+	res := Result{Smoothing: opts.Smoothing}
+	kept := make([]model.FlightTrackPoint, 0, len(points))
+	var (
+		lastSpeed float64
+		rejects   int
+		streak    int    // 本轮连续剔除的第一个点的下标
+		snap      Result // 本轮连续剔除开始前的统计，重新定位时恢复
+		reanchor  bool   // 下一个点不做速度/加速度校验，直接作为新的参照点
+	)
+	for i := 0; i < len(points); i++ {
+		p := points[i]
+		if !validCoord(p) {
+			res.DroppedZero++
+			continue
+		}
+		if len(kept) == 0 {
+			kept = append(kept, p)
+			continue
+		}
+		prev := kept[len(kept)-1]
+		dt := p.TimeStamp.Sub(prev.TimeStamp).Seconds()
+		if dt <= 0 {
+			res.DroppedTime++
+			continue
+		}
+		d := horizontalDistance(prev, p)
+		if d == 0 && float64(p.GS)/10 >= opts.FrozenMinGS && float64(prev.GS)/10 >= opts.FrozenMinGS {
+			res.DroppedFrozen++
+			continue
+		}
+		speed := d / dt
+		if reanchor {
+			reanchor = false
+			speed = 0
+		} else {
+			var dropped *int
+			if speed > opts.MaxSpeedMps {
+				dropped = &res.DroppedSpeed
+			} else if len(kept) > 1 && dt <= opts.GapSeconds && math.Abs(speed-lastSpeed)/dt > opts.MaxAccelMps2 {
+				// 缺口两侧的速度变化不代表真实加速度，不做加速度校验
+				dropped = &res.DroppedAccel
+			}
+			if dropped != nil {
+				if rejects == 0 {
+					streak, snap = i, res
+				}
+				*dropped++
+				rejects++
+				if rejects < maxConsecutiveRejects {
+					continue
+				}
+				// 连续剔除过多：撤销本轮剔除，从本轮第一个被剔除的点重新校验。
+				// 参照点是首点或本轮首点与参照点之前的保留点相符时，参照点本身是跳点，将其剔除；
+				// 否则视为真实的位置跳变，保留参照点，以本轮首点重新定位
+				res, rejects = snap, 0
+				if n := len(kept); n == 1 || impliedSpeed(kept[n-2], points[streak]) <= opts.MaxSpeedMps {
+					kept = kept[:n-1]
+					res.DroppedSpeed++
+				}
+				reanchor = len(kept) > 0
+				i = streak - 1
+				continue
+			}
+		}
+		rejects = 0
+		if dt > opts.GapSeconds {
+			res.Gaps = append(res.Gaps, Gap{Start: prev.TimeStamp, End: p.TimeStamp, Seconds: dt})
+			res.GapSeconds += dt
+		}
+		lastSpeed = speed
+		kept = append(kept, p)
+	}
+
+	switch opts.Smoothing {
+	case SmoothingMovingAverage:
+		movingAverage(kept, opts.SmoothingWindow)
+	case SmoothingKalman:
+		kalman(kept, opts.KalmanNoiseM, opts.KalmanAccelMps2)
+	default:
+		res.Smoothing = SmoothingNone
+	}
+	res.Points = kept
+	return res
+}
+
+// Dropped 剔除的点总数
+func (r Result) Dropped() int {
+	return r.DroppedZero + r.DroppedTime + r.DroppedSpeed + r.DroppedAccel + r.DroppedFrozen
+}
+
+// impliedSpeed 两点间的隐含水平速度（m/s），a 需早于 b
+func impliedSpeed(a, b model.FlightTrackPoint) float64 {
+	dt := b.TimeStamp.Sub(a.TimeStamp).Seconds()
+	if dt <= 0 {
+		return math.Inf(1)
+	}
+	return horizontalDistance(a, b) / dt
+}
+
+func validCoord(p model.FlightTrackPoint) bool {
+	if p.Latitude == 0 && p.Longitude == 0 {
+		return false
+	}
+	return p.Latitude >= -90e7 && p.Latitude <= 90e7 && p.Longitude >= -180e7 && p.Longitude <= 180e7
+}
+
+// movingAverage 对经纬度与海拔做居中滑动平均，首尾点保持不变以保留起降位置
+func movingAverage(points []model.FlightTrackPoint, window int) {
+	if len(points) < 3 {
+		return
+	}
+	half := window / 2
+	lat := make([]float64, len(points))
+	lng := make([]float64, len(points))
+	alt := make([]float64, len(points))
+	for i := 1; i < len(points)-1; i++ {
+		lo, hi := max(i-half, 0), min(i+half, len(points)-1)
+		var sLat, sLng, sAlt float64
+		for j := lo; j <= hi; j++ {
+			sLat += float64(points[j].Latitude)
+			sLng += float64(points[j].Longitude)
+			sAlt += float64(points[j].Altitude)
+		}
+		n := float64(hi - lo + 1)
+		lat[i], lng[i], alt[i] = sLat/n, sLng/n, sAlt/n
+	}
+	for i := 1; i < len(points)-1; i++ {
+		points[i].Latitude = int64(math.Round(lat[i]))
+		points[i].Longitude = int64(math.Round(lng[i]))
+		points[i].Altitude = int(math.Round(alt[i]))
+	}
+}
+
+// kalman 在以首点为原点的局部平面坐标（m）上，对东向、北向、海拔分别做随机游走模型的一维卡尔曼滤波，
+// 过程噪声随采样间隔增长，适配不等间隔采样
+func kalman(points []model.FlightTrackPoint, noiseM, accel float64) {
+	if len(points) < 2 {
+		return
+	}
+	lat0 := float64(points[0].Latitude) / 1e7
+	cosLat := math.Cos(lat0 * math.Pi / 180)
+	const mPerDeg = math.Pi / 180 * earthRadius
+	r := noiseM * noiseM
+	type filter struct{ x, p float64 }
+	east := filter{x: 0, p: r}
+	north := filter{x: 0, p: r}
+	up := filter{x: float64(points[0].Altitude) / 10, p: r}
+	lng0 := float64(points[0].Longitude) / 1e7
+	step := func(f *filter, z, q float64) {
+		f.p += q
+		k := f.p / (f.p + r)
+		f.x += k * (z - f.x)
+		f.p *= 1 - k
+	}
+	for i := 1; i < len(points); i++ {
+		dt := points[i].TimeStamp.Sub(points[i-1].TimeStamp).Seconds()
+		// 位置方差按 (a·dt²/2)² 增长
+		q := math.Pow(accel*dt*dt/2, 2)
+		zE := (float64(points[i].Longitude)/1e7 - lng0) * mPerDeg * cosLat
+		zN := (float64(points[i].Latitude)/1e7 - lat0) * mPerDeg
+		step(&east, zE, q)
+		step(&north, zN, q)
+		step(&up, float64(points[i].Altitude)/10, q)
+		points[i].Latitude = int64(math.Round((lat0 + north.x/mPerDeg) * 1e7))
+		if cosLat != 0 {
+			points[i].Longitude = int64(math.Round((lng0 + east.x/(mPerDeg*cosLat)) * 1e7))
+		}
+		points[i].Altitude = int(math.Round(up.x * 10))
+	}
+}
@@ -0,0 +1,105 @@
+package track
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"drone-stats-service/internal/model"
+)
+
+var cleanBase = time.Date(2025, 6, 20, 8, 0, 0, 0, time.UTC)
+
+// northMeters 沿经线向北移动 m 米对应的纬度增量（1e-7 度）
+func northMeters(m float64) int64 {
+	return int64(math.Round(m / (math.Pi / 180 * earthRadius) * 1e7))
+}
+
+// straightTrack 从 (lat, 114°) 出发每秒向北 speed 米的 n 个点
+func straightTrack(start time.Time, lat int64, n int, speed float64) []model.FlightTrackPoint {
+	points := make([]model.FlightTrackPoint, n)
+	for i := range points {
+		points[i] = model.FlightTrackPoint{
+			TimeStamp: start.Add(time.Duration(i) * time.Second),
+			Latitude:  lat + northMeters(speed*float64(i)),
+			Longitude: 1140000000,
+			GS:        int(speed * 10),
+		}
+	}
+	return points
+}
+
+func TestClean(t *testing.T) {
+	const lat0 = 225000000
+	join := func(parts ...[]model.FlightTrackPoint) []model.FlightTrackPoint {
+		var out []model.FlightTrackPoint
+		for _, p := range parts {
+			out = append(out, p...)
+		}
+		return out
+	}
+	// 首点为约 1.1km 外的过期定位，随后 20 个点以 5m/s 飞行
+	firstSpike := join(
+		[]model.FlightTrackPoint{{TimeStamp: cleanBase.Add(-time.Second), Latitude: lat0 - northMeters(1100), Longitude: 1140000000}},
+		straightTrack(cleanBase, lat0, 20, 5),
+	)
+	// 中途单点跳到 2km 外
+	midSpike := straightTrack(cleanBase, lat0, 20, 5)
+	midSpike[10].Latitude += northMeters(2000)
+	// 中途连续 3 个跳点
+	midBurst := straightTrack(cleanBase, lat0, 20, 5)
+	for i := 8; i < 11; i++ {
+		midBurst[i].Latitude += northMeters(2000)
+	}
+	// 缺口后位置真实跳变约 3km，跳变后的点不应被剔除（跨缺口的距离仍计入）
+	shifted := join(
+		straightTrack(cleanBase, lat0, 10, 5),
+		straightTrack(cleanBase.Add(20*time.Second), lat0+northMeters(3000), 10, 5),
+	)
+	// 坐标为 (0,0) 与时间戳重复的点
+	invalid := straightTrack(cleanBase, lat0, 10, 5)
+	invalid[3].Latitude, invalid[3].Longitude = 0, 0
+	invalid = append(invalid[:6], append([]model.FlightTrackPoint{invalid[5]}, invalid[6:]...)...)
+
+	tests := []struct {
+		name         string
+		points       []model.FlightTrackPoint
+		kept         int
+		droppedSpeed int
+		droppedZero  int
+		droppedTime  int
+		distance     float64 // 清洗后水平距离（米）
+		gaps         int
+	}{
+		{name: "首点跳点", points: firstSpike, kept: 20, droppedSpeed: 1, distance: 95},
+		{name: "中途单点跳点", points: midSpike, kept: 19, droppedSpeed: 1, distance: 95},
+		{name: "中途连续跳点", points: midBurst, kept: 17, droppedSpeed: 3, distance: 95},
+		{name: "缺口后位置跳变", points: shifted, kept: 20, droppedSpeed: 0, distance: 3045, gaps: 1},
+		{name: "无效坐标与重复时间", points: invalid, kept: 9, droppedZero: 1, droppedTime: 1, distance: 45},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := Clean(tt.points, Options{})
+			if len(res.Points) != tt.kept {
+				t.Errorf("kept = %d, want %d", len(res.Points), tt.kept)
+			}
+			if res.DroppedSpeed != tt.droppedSpeed || res.DroppedZero != tt.droppedZero || res.DroppedTime != tt.droppedTime {
+				t.Errorf("dropped speed/zero/time = %d/%d/%d, want %d/%d/%d",
+					res.DroppedSpeed, res.DroppedZero, res.DroppedTime, tt.droppedSpeed, tt.droppedZero, tt.droppedTime)
+			}
+			if got := res.Dropped(); got != len(tt.points)-len(res.Points) {
+				t.Errorf("Dropped() = %d, want %d", got, len(tt.points)-len(res.Points))
+			}
+			if len(res.Gaps) != tt.gaps {
+				t.Errorf("gaps = %d, want %d", len(res.Gaps), tt.gaps)
+			}
+			var horizontal float64
+			for i := 1; i < len(res.Points); i++ {
+				horizontal += horizontalDistance(res.Points[i-1], res.Points[i])
+			}
+			if math.Abs(horizontal-tt.distance) > 0.5 {
+				t.Errorf("distance = %.2f, want %.2f", horizontal, tt.distance)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package track
+
+import (
+	"math"
+
+	"drone-stats-service/internal/model"
+)
+
+const earthRadius = 6371000 // 地球半径，单位米
+
+// Distance 计算航程（m）：
+// 1) 水平距离使用球面距离（基于经纬度）
+// 2) 垂直距离为全过程上下移动距离（每段海拔变化的绝对值之和，海拔单位除以 10）
+// 最终距离 = 水平距离 + 垂直移动距离
+func Distance(points []model.FlightTrackPoint) (total, horizontal, vertical float64) {
+	for i := 1; i < len(points); i++ {
+		horizontal += horizontalDistance(points[i-1], points[i])
+		vertical += math.Abs(float64(points[i].Altitude)/10 - float64(points[i-1].Altitude)/10)
+	}
+	return horizontal + vertical, horizontal, vertical
+}
+
+// Haversine 计算两点间球面距离（单位：米）
+func Haversine(lat1, lng1, lat2, lng2 float64) float64 {
+	dLat := (lat2 - lat1) * math.Pi / 180
+	dLng := (lng2 - lng1) * math.Pi / 180
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*math.Pi/180)*math.Cos(lat2*math.Pi/180)*
+			math.Sin(dLng/2)*math.Sin(dLng/2)
+	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+	return earthRadius * c
+}
+
+func horizontalDistance(a, b model.FlightTrackPoint) float64 {
+	return Haversine(float64(a.Latitude)/1e7, float64(a.Longitude)/1e7, float64(b.Latitude)/1e7, float64(b.Longitude)/1e7)
+}
@@ -0,0 +1,85 @@
+package track
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"drone-stats-service/internal/model"
+)
+
+// courseUnavailable 协议约定暂不具备航迹角测算能力时上报 999.0（乘 10 后为 9990）
+const courseUnavailable = 9990
+
+// State 某一时刻插值得到的飞行状态
+type State struct {
+	OrderID   string
+	Latitude  int64   // 度 × 1e7
+	Longitude int64   // 度 × 1e7
+	Height    float64 // 真高（m）
+	Altitude  float64 // 海拔（m）
+	Heading   float64 // 航向（°，真北顺时针 [0, 360)）
+	GS        float64 // 地速（m/s）
+	SOC       float64 // 电量百分比
+	Status    string  // 前一个轨迹点的飞行状态
+}
+
+// Interpolate 在按时间升序的单架无人机轨迹点中插值 t 时刻的状态。
+// t 不在某次起飞到降落之间，或两侧采样间隔超过 maxGap 时返回 false。
+func Interpolate(points []model.FlightTrackPoint, t time.Time, maxGap time.Duration) (State, bool) {
+	i := sort.Search(len(points), func(i int) bool { return points[i].TimeStamp.After(t) }) - 1
+	if i < 0 {
+		return State{}, false
+	}
+	a := points[i]
+	if a.TimeStamp.Equal(t) {
+		return stateAt(a, a, 0), true
+	}
+	if i+1 >= len(points) || a.FlightStatus == "Land" {
+		return State{}, false
+	}
+	b := points[i+1]
+	if b.FlightStatus == "TakeOff" {
+		// 下一个点是新架次的起飞点，说明当前架次未正常上报 Land
+		return State{}, false
+	}
+	span := b.TimeStamp.Sub(a.TimeStamp)
+	if span > maxGap {
+		return State{}, false
+	}
+	return stateAt(a, b, float64(t.Sub(a.TimeStamp))/float64(span)), true
+}
+
+func stateAt(a, b model.FlightTrackPoint, f float64) State {
+	lerp := func(x, y float64) float64 { return x + (y-x)*f }
+	s := State{
+		OrderID:   a.OrderID,
+		Latitude:  int64(math.Round(lerp(float64(a.Latitude), float64(b.Latitude)))),
+		Longitude: int64(math.Round(lerp(float64(a.Longitude), float64(b.Longitude)))),
+		Height:    lerp(float64(a.Height), float64(b.Height)) / 10,
+		Altitude:  lerp(float64(a.Altitude), float64(b.Altitude)) / 10,
+		GS:        lerp(float64(a.GS), float64(b.GS)) / 10,
+		SOC:       lerp(float64(a.SOC), float64(b.SOC)),
+		Status:    a.FlightStatus,
+	}
+	switch {
+	case a.Course != courseUnavailable && b.Course != courseUnavailable:
+		// 航向按最短角度插值，避免 359° → 1° 时绕一圈
+		ca, cb := float64(a.Course)/10, float64(b.Course)/10
+		d := math.Mod(cb-ca+540, 360) - 180
+		s.Heading = math.Mod(ca+d*f+360, 360)
+	case a.Latitude != b.Latitude || a.Longitude != b.Longitude:
+		s.Heading = bearing(a, b)
+	}
+	return s
+}
+
+// bearing 由 a 指向 b 的初始方位角（°）
+func bearing(a, b model.FlightTrackPoint) float64 {
+	lat1 := float64(a.Latitude) / 1e7 * math.Pi / 180
+	lat2 := float64(b.Latitude) / 1e7 * math.Pi / 180
+	dLng := float64(b.Longitude-a.Longitude) / 1e7 * math.Pi / 180
+	y := math.Sin(dLng) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dLng)
+	return math.Mod(math.Atan2(y, x)*180/math.Pi+360, 360)
+}
@@ -0,0 +1,102 @@
+package track
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"drone-stats-service/internal/model"
+)
+
+func TestInterpolate(t *testing.T) {
+	at := func(sec int) time.Time { return cleanBase.Add(time.Duration(sec) * time.Second) }
+	pt := func(sec int, status string, lat int64, height, soc, course int) model.FlightTrackPoint {
+		return model.FlightTrackPoint{
+			OrderID: "O-1", FlightStatus: status, TimeStamp: at(sec),
+			Latitude: lat, Longitude: 1140000000, Height: height, Altitude: height + 1000, GS: 50, SOC: soc, Course: course,
+		}
+	}
+	// 第一个架次：0-30 秒每 10 秒一个点，30-90 秒间隔 60 秒，100 秒降落；
+	// 第二个架次 200 秒起飞，210 秒后未上报 Land，300 秒又有新架次起飞
+	points := []model.FlightTrackPoint{
+		pt(0, "TakeOff", 225000000, 0, 90, 3580),
+		pt(10, "Inflight", 225001000, 100, 88, 20),
+		pt(20, "Inflight", 225002000, 200, 86, 20),
+		pt(30, "Inflight", 225003000, 200, 84, courseUnavailable),
+		pt(90, "Inflight", 225009000, 200, 72, courseUnavailable),
+		pt(100, "Land", 225010000, 0, 70, 0),
+		pt(200, "TakeOff", 225000000, 0, 95, 0),
+		pt(210, "Inflight", 225001000, 100, 94, 0),
+		pt(300, "TakeOff", 225000000, 0, 99, 0),
+	}
+
+	tests := []struct {
+		name   string
+		t      time.Time
+		maxGap time.Duration
+		ok     bool
+		want   State
+	}{
+		{
+			name: "恰好命中起飞点", t: at(0), maxGap: 30 * time.Second, ok: true,
+			want: State{Latitude: 225000000, Height: 0, Altitude: 100, Heading: 358, GS: 5, SOC: 90, Status: "TakeOff"},
+		},
+		{
+			name: "恰好命中降落点", t: at(100), maxGap: 30 * time.Second, ok: true,
+			want: State{Latitude: 225010000, Height: 0, Altitude: 100, Heading: 0, GS: 5, SOC: 70, Status: "Land"},
+		},
+		{
+			// 航向 358° → 2° 按最短角度插值为 0°
+			name: "中点", t: at(5), maxGap: 30 * time.Second, ok: true,
+			want: State{Latitude: 225000500, Height: 5, Altitude: 105, Heading: 0, GS: 5, SOC: 89, Status: "TakeOff"},
+		},
+		{
+			name: "四分之一处", t: at(12).Add(500 * time.Millisecond), maxGap: 30 * time.Second, ok: true,
+			want: State{Latitude: 225001250, Height: 12.5, Altitude: 112.5, Heading: 2, GS: 5, SOC: 87.5, Status: "Inflight"},
+		},
+		{
+			// 航向不可用时取两点连线的方位角（正北）
+			name: "间隔恰为 maxGap", t: at(60), maxGap: time.Minute, ok: true,
+			want: State{Latitude: 225006000, Height: 20, Altitude: 120, Heading: 0, GS: 5, SOC: 78, Status: "Inflight"},
+		},
+		{name: "间隔超过 maxGap", t: at(60), maxGap: 30 * time.Second},
+		{name: "早于首个点", t: at(-1), maxGap: time.Minute},
+		{name: "降落与下次起飞之间", t: at(150), maxGap: time.Hour},
+		{name: "未上报降落即开始新架次", t: at(250), maxGap: time.Hour},
+		{name: "晚于最后一个点", t: at(301), maxGap: time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Interpolate(points, tt.t, tt.maxGap)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v (state %+v)", ok, tt.ok, got)
+			}
+			if !ok {
+				return
+			}
+			tt.want.OrderID, tt.want.Longitude = "O-1", 1140000000
+			if got.OrderID != tt.want.OrderID || got.Latitude != tt.want.Latitude || got.Longitude != tt.want.Longitude ||
+				got.Status != tt.want.Status || !closeTo(got.Height, tt.want.Height) || !closeTo(got.Altitude, tt.want.Altitude) ||
+				!closeTo(got.GS, tt.want.GS) || !closeTo(got.SOC, tt.want.SOC) || !closeTo(math.Mod(got.Heading+180, 360), math.Mod(tt.want.Heading+180, 360)) {
+				t.Errorf("state = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInterpolateHeadingFromBearing(t *testing.T) {
+	// 航向不可用时按位置变化计算：向东移动为 90°
+	points := []model.FlightTrackPoint{
+		{TimeStamp: cleanBase, FlightStatus: "Inflight", Latitude: 225000000, Longitude: 1140000000, Course: courseUnavailable},
+		{TimeStamp: cleanBase.Add(10 * time.Second), FlightStatus: "Inflight", Latitude: 225000000, Longitude: 1140010000, Course: courseUnavailable},
+	}
+	got, ok := Interpolate(points, cleanBase.Add(5*time.Second), time.Minute)
+	if !ok || math.Abs(got.Heading-90) > 0.01 {
+		t.Errorf("heading = %v, ok = %v, want 90", got.Heading, ok)
+	}
+	if _, ok := Interpolate(nil, cleanBase, time.Minute); ok {
+		t.Error("interpolated empty track")
+	}
+}
+
+func closeTo(a, b float64) bool { return math.Abs(a-b) < 1e-6 }
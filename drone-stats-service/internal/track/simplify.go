@@ -0,0 +1,234 @@
+package track
+
+import (
+	"container/heap"
+	"math"
+
+	"drone-stats-service/internal/model"
+)
+
+// 简化算法
+const (
+	AlgorithmDouglasPeucker = "dp"
+	AlgorithmVisvalingam    = "vw"
+)
+
+// SimplifyOptions 轨迹简化参数
+type SimplifyOptions struct {
+	Algorithm  string  // dp | vw，默认 dp
+	ToleranceM float64 // 容差（m）：dp 为点到弦的距离，vw 为以容差为边长的正方形面积；0 表示仅按 MaxPoints 简化
+	MaxPoints  int     // 最多保留点数，0 表示不限；必保留点数超过该值时仍全部保留
+	AltChangeM float64 // 相邻点海拔变化不小于该值（m）的点必保留，默认 5
+}
+
+// ToleranceForZoom 返回 Web Mercator 下指定缩放级别一个像素对应的地面距离（m），lat 为纬度（°）
+func ToleranceForZoom(zoom int, lat float64) float64 {
+	return 156543.03392 * math.Cos(lat*math.Pi/180) / math.Pow(2, float64(zoom))
+}
+
+// Simplify 返回简化后保留的点下标（升序）。首尾点、TakeOff/Land 点与海拔突变点始终保留。
+func Simplify(points []model.FlightTrackPoint, opts SimplifyOptions) []int {
+	n := len(points)
+	if n <= 2 || (opts.ToleranceM <= 0 && (opts.MaxPoints <= 0 || n <= opts.MaxPoints)) {
+		return allIndexes(n)
+	}
+	if opts.AltChangeM <= 0 {
+		opts.AltChangeM = 5
+	}
+	xy := projectLocal(points)
+	forced := make([]bool, n)
+	forced[0], forced[n-1] = true, true
+	for i, p := range points {
+		if p.FlightStatus == "TakeOff" || p.FlightStatus == "Land" {
+			forced[i] = true
+		}
+		if i > 0 && math.Abs(float64(p.Altitude-points[i-1].Altitude))/10 >= opts.AltChangeM {
+			forced[i-1], forced[i] = true, true
+		}
+	}
+	if opts.Algorithm == AlgorithmVisvalingam {
+		return visvalingam(xy, forced, opts.ToleranceM*opts.ToleranceM, opts.MaxPoints)
+	}
+	return douglasPeucker(xy, forced, opts.ToleranceM, opts.MaxPoints)
+}
+
+type xyPoint struct{ x, y float64 }
+
+// projectLocal 以首点为原点投影到局部平面（m），用于短距离几何计算
+func projectLocal(points []model.FlightTrackPoint) []xyPoint {
+	lat0 := float64(points[0].Latitude) / 1e7
+	lng0 := float64(points[0].Longitude) / 1e7
+	cosLat := math.Cos(lat0 * math.Pi / 180)
+	const mPerDeg = math.Pi / 180 * earthRadius
+	out := make([]xyPoint, len(points))
+	for i, p := range points {
+		out[i] = xyPoint{
+			x: (float64(p.Longitude)/1e7 - lng0) * mPerDeg * cosLat,
+			y: (float64(p.Latitude)/1e7 - lat0) * mPerDeg,
+		}
+	}
+	return out
+}
+
+func allIndexes(n int) []int {
+	out := make([]int, n)
+	for i := range out {
+		out[i] = i
+	}
+	return out
+}
+
+func collectKept(keep []bool) []int {
+	var out []int
+	for i, k := range keep {
+		if k {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+// segmentDistance 点 p 到线段 ab 的距离
+func segmentDistance(p, a, b xyPoint) float64 {
+	dx, dy := b.x-a.x, b.y-a.y
+	if dx == 0 && dy == 0 {
+		return math.Hypot(p.x-a.x, p.y-a.y)
+	}
+	t := ((p.x-a.x)*dx + (p.y-a.y)*dy) / (dx*dx + dy*dy)
+	t = math.Max(0, math.Min(1, t))
+	return math.Hypot(p.x-(a.x+t*dx), p.y-(a.y+t*dy))
+}
+
+// dpSegment 待细分的区间及其中偏离弦最远的点
+type dpSegment struct {
+	lo, hi, far int
+	dist        float64
+}
+
+type dpQueue []dpSegment
+
+func (q dpQueue) Len() int            { return len(q) }
+func (q dpQueue) Less(i, j int) bool  { return q[i].dist > q[j].dist }
+func (q dpQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *dpQueue) Push(x interface{}) { *q = append(*q, x.(dpSegment)) }
+func (q *dpQueue) Pop() interface{} {
+	old := *q
+	x := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return x
+}
+
+// douglasPeucker 以必保留点为锚点的优先队列版 Douglas–Peucker：每次细分偏离最大的区间，
+// 直到最大偏离不超过容差或达到 maxPoints
+func douglasPeucker(xy []xyPoint, forced []bool, tol float64, maxPoints int) []int {
+	keep := append([]bool(nil), forced...)
+	count := 0
+	for _, k := range keep {
+		if k {
+			count++
+		}
+	}
+	q := &dpQueue{}
+	push := func(lo, hi int) {
+		if hi-lo < 2 {
+			return
+		}
+		seg := dpSegment{lo: lo, hi: hi, far: -1}
+		for i := lo + 1; i < hi; i++ {
+			if d := segmentDistance(xy[i], xy[lo], xy[hi]); d > seg.dist || seg.far < 0 {
+				seg.dist, seg.far = d, i
+			}
+		}
+		heap.Push(q, seg)
+	}
+	prev := 0
+	for i := 1; i < len(xy); i++ {
+		if keep[i] {
+			push(prev, i)
+			prev = i
+		}
+	}
+	for q.Len() > 0 {
+		if maxPoints > 0 && count >= maxPoints {
+			break
+		}
+		seg := heap.Pop(q).(dpSegment)
+		if tol > 0 && seg.dist <= tol {
+			break
+		}
+		keep[seg.far] = true
+		count++
+		push(seg.lo, seg.far)
+		push(seg.far, seg.hi)
+	}
+	return collectKept(keep)
+}
+
+// vwItem 可删除点及其与相邻保留点构成的三角形面积
+type vwItem struct {
+	idx   int
+	area  float64
+	index int // 在堆中的位置
+}
+
+type vwQueue []*vwItem
+
+func (q vwQueue) Len() int            { return len(q) }
+func (q vwQueue) Less(i, j int) bool  { return q[i].area < q[j].area }
+func (q vwQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i]; q[i].index = i; q[j].index = j }
+func (q *vwQueue) Push(x interface{}) { it := x.(*vwItem); it.index = len(*q); *q = append(*q, it) }
+func (q *vwQueue) Pop() interface{} {
+	old := *q
+	it := old[len(old)-1]
+	*q = old[:len(old)-1]
+	it.index = -1
+	return it
+}
+
+// visvalingam Visvalingam–Whyatt：反复删除有效面积最小的点，直到最小面积不小于 minArea 且点数不超过 maxPoints
+func visvalingam(xy []xyPoint, forced []bool, minArea float64, maxPoints int) []int {
+	n := len(xy)
+	prev := make([]int, n)
+	next := make([]int, n)
+	for i := range xy {
+		prev[i], next[i] = i-1, i+1
+	}
+	area := func(i int) float64 {
+		a, b, c := xy[prev[i]], xy[i], xy[next[i]]
+		return math.Abs((b.x-a.x)*(c.y-a.y)-(c.x-a.x)*(b.y-a.y)) / 2
+	}
+	items := make([]*vwItem, n)
+	q := &vwQueue{}
+	for i := 1; i < n-1; i++ {
+		if forced[i] {
+			continue
+		}
+		items[i] = &vwItem{idx: i, area: area(i)}
+		heap.Push(q, items[i])
+	}
+	keep := make([]bool, n)
+	for i := range keep {
+		keep[i] = true
+	}
+	count := n
+	for q.Len() > 0 {
+		top := (*q)[0]
+		if top.area >= minArea && (maxPoints <= 0 || count <= maxPoints) {
+			break
+		}
+		heap.Pop(q)
+		i := top.idx
+		keep[i] = false
+		count--
+		p, nx := prev[i], next[i]
+		next[p], prev[nx] = nx, p
+		// 删除后的面积不小于被删点的面积，保证删除顺序单调
+		for _, j := range []int{p, nx} {
+			if it := items[j]; it != nil && it.index >= 0 {
+				it.area = math.Max(area(j), top.area)
+				heap.Fix(q, it.index)
+			}
+		}
+	}
+	return collectKept(keep)
+}
@@ -0,0 +1,137 @@
+package track
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"drone-stats-service/internal/model"
+)
+
+// localTrack 以 (22.5°, 114°) 为原点、按局部平面坐标（m）构造的轨迹点
+func localTrack(xy ...[2]float64) []model.FlightTrackPoint {
+	const lat0 = 225000000
+	cosLat := math.Cos(22.5 * math.Pi / 180)
+	points := make([]model.FlightTrackPoint, len(xy))
+	for i, p := range xy {
+		points[i] = model.FlightTrackPoint{
+			Latitude:  lat0 + northMeters(p[1]),
+			Longitude: 1140000000 + int64(math.Round(float64(northMeters(p[0]))/cosLat)),
+		}
+	}
+	return points
+}
+
+// northLine 向北每 step 米一个点的 n 个共线点
+func northLine(n int, step float64) [][2]float64 {
+	out := make([][2]float64, n)
+	for i := range out {
+		out[i] = [2]float64{0, step * float64(i)}
+	}
+	return out
+}
+
+func TestSimplify(t *testing.T) {
+	// 向北的直线，中间的第 4 个点向东偏出 50m
+	spike := northLine(9, 10)
+	spike[4][0] = 50
+	// 两段直线构成的折线：先向北 50m，再向东 50m
+	corner := append(northLine(6, 10), [][2]float64{{10, 50}, {20, 50}, {30, 50}, {40, 50}, {50, 50}}...)
+	// 海拔突变与 TakeOff/Land 点必保留
+	forcedTrack := localTrack(northLine(10, 10)...)
+	forcedTrack[2].FlightStatus = "TakeOff"
+	for i := 5; i < len(forcedTrack); i++ {
+		forcedTrack[i].Altitude = 100 // 0.1m 单位，第 5 个点较前一点升高 10m
+	}
+	forcedTrack[8].FlightStatus = "Land"
+	// 曲线：半径 500m 的四分之一圆弧上 50 个点
+	var arc [][2]float64
+	for i := 0; i < 50; i++ {
+		a := math.Pi / 2 * float64(i) / 49
+		arc = append(arc, [2]float64{500 * math.Cos(a), 500 * math.Sin(a)})
+	}
+
+	tests := []struct {
+		name   string
+		points []model.FlightTrackPoint
+		opts   SimplifyOptions
+		dp, vw []int // nil 表示只检查点数与首尾
+		count  int
+	}{
+		{name: "空轨迹", opts: SimplifyOptions{ToleranceM: 5}, dp: []int{}, vw: []int{}},
+		{name: "单点", points: localTrack([2]float64{0, 0}), opts: SimplifyOptions{ToleranceM: 5}, dp: []int{0}, vw: []int{0}},
+		{name: "两点", points: localTrack([2]float64{0, 0}, [2]float64{0, 100}), opts: SimplifyOptions{ToleranceM: 5, MaxPoints: 1}, dp: []int{0, 1}, vw: []int{0, 1}},
+		{name: "共线点", points: localTrack(northLine(10, 10)...), opts: SimplifyOptions{ToleranceM: 1}, dp: []int{0, 9}, vw: []int{0, 9}},
+		{name: "重复点", points: localTrack(make([][2]float64, 10)...), opts: SimplifyOptions{ToleranceM: 1}, dp: []int{0, 9}, vw: []int{0, 9}},
+		{name: "重复点，按点数", points: localTrack(make([][2]float64, 10)...), opts: SimplifyOptions{MaxPoints: 3}, count: 3},
+		// 与尖峰相邻的点到新弦的距离（dp）、与相邻保留点构成的面积（vw）均超过容差
+		{name: "单个尖峰", points: localTrack(spike...), opts: SimplifyOptions{ToleranceM: 5}, dp: []int{0, 3, 4, 5, 8}, vw: []int{0, 3, 4, 5, 8}},
+		{name: "拐角", points: localTrack(corner...), opts: SimplifyOptions{ToleranceM: 1}, dp: []int{0, 5, 10}, vw: []int{0, 5, 10}},
+		{name: "容差为 0 且点数不超限时不简化", points: localTrack(northLine(5, 10)...), opts: SimplifyOptions{MaxPoints: 5}, dp: []int{0, 1, 2, 3, 4}, vw: []int{0, 1, 2, 3, 4}},
+		{name: "按点数简化", points: localTrack(arc...), opts: SimplifyOptions{MaxPoints: 10}, count: 10},
+		{name: "必保留点", points: forcedTrack, opts: SimplifyOptions{ToleranceM: 1}, dp: []int{0, 2, 4, 5, 8, 9}, vw: []int{0, 2, 4, 5, 8, 9}},
+		{name: "必保留点多于 MaxPoints", points: forcedTrack, opts: SimplifyOptions{MaxPoints: 3}, dp: []int{0, 2, 4, 5, 8, 9}, vw: []int{0, 2, 4, 5, 8, 9}},
+	}
+	for _, alg := range []string{AlgorithmDouglasPeucker, AlgorithmVisvalingam} {
+		for _, tt := range tests {
+			t.Run(alg+"/"+tt.name, func(t *testing.T) {
+				opts := tt.opts
+				opts.Algorithm = alg
+				got := Simplify(tt.points, opts)
+				want := tt.dp
+				if alg == AlgorithmVisvalingam {
+					want = tt.vw
+				}
+				if want != nil && !reflect.DeepEqual(got, want) {
+					t.Errorf("Simplify = %v, want %v", got, want)
+				}
+				if tt.count > 0 && len(got) != tt.count {
+					t.Errorf("Simplify kept %d points (%v), want %d", len(got), got, tt.count)
+				}
+				// 首尾点始终保留，下标升序
+				if n := len(tt.points); n > 0 && (len(got) == 0 || got[0] != 0 || got[len(got)-1] != n-1) {
+					t.Errorf("endpoints not kept: %v", got)
+				}
+				for i := 1; i < len(got); i++ {
+					if got[i] <= got[i-1] {
+						t.Errorf("indexes not ascending: %v", got)
+						break
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestSimplifyMaxDeviation(t *testing.T) {
+	// dp 简化后每个被删除的点到所在保留区间的弦的距离不超过容差
+	var xy [][2]float64
+	for i := 0; i < 200; i++ {
+		xy = append(xy, [2]float64{30 * math.Sin(float64(i)/15), 5 * float64(i)})
+	}
+	points := localTrack(xy...)
+	local := projectLocal(points)
+	const tol = 2.0
+	kept := Simplify(points, SimplifyOptions{ToleranceM: tol})
+	if len(kept) >= len(points) || len(kept) < 3 {
+		t.Fatalf("kept %d of %d points", len(kept), len(points))
+	}
+	for k := 1; k < len(kept); k++ {
+		lo, hi := kept[k-1], kept[k]
+		for i := lo + 1; i < hi; i++ {
+			if d := segmentDistance(local[i], local[lo], local[hi]); d > tol {
+				t.Errorf("point %d is %.2fm from chord %d-%d, tolerance %.0fm", i, d, lo, hi, tol)
+			}
+		}
+	}
+}
+
+func TestToleranceForZoom(t *testing.T) {
+	if got := ToleranceForZoom(0, 0); math.Abs(got-156543.03392) > 1e-6 {
+		t.Errorf("zoom 0 at equator = %v", got)
+	}
+	// 每放大一级减半，纬度 60° 处为赤道的一半
+	if got, want := ToleranceForZoom(10, 60), 156543.03392/1024/2; math.Abs(got-want) > 1e-6 {
+		t.Errorf("zoom 10 at 60° = %v, want %v", got, want)
+	}
+}
@@ -3,6 +3,36 @@
 
 package types
 
+type AuditLog struct {
+	ID         int64  `json:"id"`
+	Entity     string `json:"entity"`   // flight_record | maintenance_plan | maintenance_record | report_schedule | backup | replay_queue
+	EntityID   string `json:"entityID"` // 飞行记录 id、机型、维保记录 id 或报表计划 id
+	OrderID    string `json:"orderID,omitempty"`
+	Field      string `json:"field"`
+	OldValue   string `json:"oldValue"` // 飞行记录为库中原始值（payload 为千克乘 10），其它实体为整条数据的 JSON
+	NewValue   string `json:"newValue"`
+	Actor      string `json:"actor"`
+	Source     string `json:"source"` // api | import | revert | system
+	BatchID    string `json:"batchID,omitempty"`
+	RevertOf   int64  `json:"revertOf,omitempty"`   // 撤销条目对应的原条目 id
+	RevertedBy int64  `json:"revertedBy,omitempty"` // 已被撤销时为撤销条目的 id
+	Revertible bool   `json:"revertible"`
+	CreatedAt  string `json:"createdAt"`
+}
+
+type AuditLogsReq struct {
+	Entity   string `form:"entity,optional"`
+	EntityID string `form:"entityID,optional"`
+	Actor    string `form:"actor,optional"`
+	Source   string `form:"source,optional"`
+	BatchID  string `form:"batchID,optional"`
+	Limit    int    `form:"limit,optional"` // 默认 50，最大 500
+}
+
+type AuditLogsResp struct {
+	Logs []AuditLog `json:"logs"`
+}
+
 type AvgStatsResp struct {
 	AvgFlightTime  float64 `json:"avgFlightTime"`  // 单位：秒
 	AvgBatteryUsed float64 `json:"avgBatteryUsed"` // 单位：百分比
@@ -10,28 +40,187 @@ type AvgStatsResp struct {
 	AvgGS          float64 `json:"avgGS"`
 }
 
+type BackupFile struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+type BackupInflux struct {
+	Bucket string `json:"bucket"`
+	Start  string `json:"start"`
+	Stop   string `json:"stop"`
+	Points int64  `json:"points"`
+	Fields int64  `json:"fields"` // 字段值个数，一个点可包含多个字段
+}
+
+type BackupInfo struct {
+	ID          string             `json:"id"`
+	Type        string             `json:"type,omitempty"` // full | incremental，没有清单时为空
+	Base        string             `json:"base,omitempty"` // 所在备份链的完整备份
+	Parent      string             `json:"parent,omitempty"`
+	Compression string             `json:"compression,omitempty"`
+	StartedAt   string             `json:"startedAt,omitempty"`
+	FinishedAt  string             `json:"finishedAt,omitempty"`
+	Size        int64              `json:"size"`     // 目录内文件总字节数
+	Complete    bool               `json:"complete"` // 是否有清单（备份成功完成）
+	Running     bool               `json:"running"`  // 正在执行的备份
+	Tables      []BackupTable      `json:"tables,omitempty"`
+	Influx      *BackupInflux      `json:"influx,omitempty"`
+	Files       []BackupFile       `json:"files,omitempty"`
+	RestoreTest *BackupRestoreTest `json:"restoreTest,omitempty"` // 最近一次恢复演练结果
+}
+
+type BackupListReq struct {
+	Type       string `form:"type,optional"` // full | incremental，为空返回全部（含没有清单的目录）
+	AdminToken string `header:"X-Admin-Token,optional"`
+}
+
+type BackupListResp struct {
+	Backups   []BackupInfo `json:"backups"` // 按时间倒序
+	TotalSize int64        `json:"totalSize"`
+}
+
+type BackupRestoreTest struct {
+	FinishedAt string   `json:"finishedAt"`
+	OK         bool     `json:"ok"`
+	Problems   []string `json:"problems,omitempty"`
+}
+
+type BackupRun struct {
+	Trigger    string `json:"trigger"` // schedule | manual | shutdown
+	Type       string `json:"type,omitempty"`
+	BackupID   string `json:"backupID,omitempty"`
+	Running    bool   `json:"running"`
+	StartedAt  string `json:"startedAt"`
+	FinishedAt string `json:"finishedAt,omitempty"`
+	Error      string `json:"error,omitempty"`
+	// UploadErrors 上传到存储目标失败的信息，未上传完成的备份在下一次备份后继续上传
+	UploadErrors []string `json:"uploadErrors,omitempty"`
+}
+
+type BackupStatusReq struct {
+	AdminToken string `header:"X-Admin-Token,optional"`
+}
+
+type BackupStatusResp struct {
+	Running bool       `json:"running"`
+	Current *BackupRun `json:"current,omitempty"` // 正在执行的备份
+	Last    *BackupRun `json:"last,omitempty"`    // 最近一次结束的备份（服务重启后为空）
+}
+
+type BackupTable struct {
+	Name        string `json:"name"`
+	Mode        string `json:"mode"` // full | incremental | rewrite
+	Rows        int64  `json:"rows"`
+	UpdatedRows int64  `json:"updatedRows,omitempty"`
+}
+
+type BatteryHealthReq struct {
+	UasID string `path:"uasID"`
+	Start string `form:"start,optional"` // 趋势起始时间（含），RFC3339 或 "yyyy-MM-dd HH:mm:ss"/"yyyy-MM-dd"
+	End   string `form:"end,optional"`   // 趋势结束时间（不含）
+}
+
+type BatteryHealthResp struct {
+	UasID             string              `json:"uasID"`
+	NominalCapacityAh float64             `json:"nominalCapacityAh"` // 标称容量（A.h）
+	NominalSource     string              `json:"nominalSource"`     // model：按机型配置 config：全局配置 baseline：最早若干架次的有效容量
+	CapacityAh        float64             `json:"capacityAh"`        // 最近若干架次的平均有效容量（A.h）
+	HealthPercent     float64             `json:"healthPercent"`     // 有效容量 / 标称容量 * 100
+	ResistanceMOhm    float64             `json:"resistanceMOhm"`    // 最近若干架次的平均内阻估算（毫欧）
+	CycleCount        float64             `json:"cycleCount"`        // 累计等效循环次数（SOC 降幅之和 / 100）。遥测不含电池包编号，按无人机累计，换装电池包后不重置
+	Flights           int                 `json:"flights"`
+	Warning           bool                `json:"warning"`
+	WarningMsg        string              `json:"warningMsg"`
+	Trend             []BatteryTrendPoint `json:"trend"`
+}
+
+type BatteryTrendPoint struct {
+	OrderID             string  `json:"orderID"`
+	StartTime           string  `json:"startTime"`
+	SOCUsed             float64 `json:"socUsed"`             // SOC 降幅（百分点）
+	CapacityUsedAh      float64 `json:"capacityUsedAh"`      // 本架次消耗容量（A.h）
+	CapacityMethod      string  `json:"capacityMethod"`      // current：电流积分 rm：剩余容量差 none：无数据
+	EffectiveCapacityAh float64 `json:"effectiveCapacityAh"` // 折算满电有效容量（A.h），数据不足时为 0
+	ResistanceMOhm      float64 `json:"resistanceMOhm"`      // 内阻估算（毫欧），数据不足时为 0
+	Cycles              float64 `json:"cycles"`              // 截至本架次的累计等效循环次数
+}
+
+type BatteryWarningsResp struct {
+	WarnCapacityPercent float64             `json:"warnCapacityPercent"`
+	Items               []BatteryHealthResp `json:"items"`
+}
+
+type BoundingBox struct {
+	MinLat int64 `json:"minLat"` // 单位：度（°）乘 10 的 7 次方，与记录中的经纬度一致
+	MinLng int64 `json:"minLng"`
+	MaxLat int64 `json:"maxLat"`
+	MaxLng int64 `json:"maxLng"`
+}
+
 type DateCount struct {
 	Date  string `json:"date"`
 	Count int    `json:"count"`
 }
 
+type DeleteBackupReq struct {
+	ID         string `path:"id"`
+	Cascade    bool   `form:"cascade,optional"` // 同时删除依赖该备份的增量备份
+	AdminToken string `header:"X-Admin-Token,optional"`
+}
+
+type DeleteBackupResp struct {
+	Deleted []string `json:"deleted"`
+}
+
+type FlightHistoryReq struct {
+	ID      int    `form:"id,optional"`      // 飞行记录 id
+	OrderID string `form:"orderID,optional"` // 未指定 id 时返回该 OrderID 下所有架次的修改记录
+	Limit   int    `form:"limit,optional"`   // 默认 50，最大 500
+}
+
 type FlightRecord struct {
 	ID           int     `json:"id"`
-	OrderID      string  `json:"OrderID"`      // 架次编号：厂商的无人机生产序列号（sn）－8位起飞日期（YYYYMMDD）－8 位随机码（数字或字母均可）如：1581F5FHD25G100C1SDN-20240320-owvGyLqe
-	UasID        string  `json:"uasID"`        // 对应无人机编号，UAS04028624 == 5197, UAS04143500 == 5210, UAS04028648 == 5203
-	StartTime    string  `json:"start_time"`   // 起飞时间，格式"yyyyMMddHHmmss"，例：2024012409500
-	EndTime      string  `json:"end_time"`     // 降落时间
-	StartLat     int64   `json:"start_lat"`    // 起飞纬度，单位：度（°）精确到小数点后 7 位，乘 10 的 7 次方后传输
-	StartLng     int64   `json:"start_lng"`    // 起飞经度
-	EndLat       int64   `json:"end_lat"`      // 降落纬度
-	EndLng       int64   `json:"end_lng"`      // 降落经度
-	Distance     float64 `json:"distance"`     // 飞行距离，单位：米（m）
-	BatteryUsed  float64 `json:"battery_used"` // 耗电量，单位：安时（A.h）
+	OrderID      string  `json:"OrderID"`       // 架次编号：厂商的无人机生产序列号（sn）－8位起飞日期（YYYYMMDD）－8 位随机码（数字或字母均可）如：1581F5FHD25G100C1SDN-20240320-owvGyLqe
+	UasID        string  `json:"uasID"`         // 对应无人机编号，UAS04028624 == 5197, UAS04143500 == 5210, UAS04028648 == 5203
+	StartTime    string  `json:"start_time"`    // 起飞时间，格式"yyyyMMddHHmmss"，例：2024012409500
+	EndTime      string  `json:"end_time"`      // 降落时间
+	StartLat     int64   `json:"start_lat"`     // 起飞纬度，单位：度（°）精确到小数点后 7 位，乘 10 的 7 次方后传输
+	StartLng     int64   `json:"start_lng"`     // 起飞经度
+	EndLat       int64   `json:"end_lat"`       // 降落纬度
+	EndLng       int64   `json:"end_lng"`       // 降落经度
+	Distance     float64 `json:"distance"`      // 飞行距离，单位：米（m）
+	BatteryUsed  float64 `json:"battery_used"`  // 耗电量，单位：千瓦时（kWh）
+	EnergyMethod string  `json:"energy_method"` // 耗电计算方式：power 功率积分 soc SOC×标称容量 rm 剩余容量差 none 无数据，旧数据为空
 	CreatedAt    string  `json:"created_at"`
 	Payload      int     `json:"payload"`      // 载货量，单位：千克（kg）精确到小数点后 1 位，乘 10 后传输
 	ExpressCount int     `json:"expressCount"` // 票数，单位：票
 }
 
+type FlightRecordQueryReq struct {
+	OrderID        string       `json:"OrderID,optional"`
+	UasID          string       `json:"uasID,optional"`       // 兼容旧接口：单个无人机编号（附带默认起飞区域限制）
+	UasIDs         []string     `json:"uasIDs,optional"`      // 无人机编号列表
+	Model          string       `json:"model,optional"`       // 机型（flight_sorties.model）
+	StartTime      string       `json:"startTime,optional"`   // 起飞时间下限，格式"yyyy-MM-dd HH:mm:ss"
+	EndTime        string       `json:"endTime,optional"`     // 降落时间上限
+	MinDistance    float64      `json:"minDistance,optional"` // 单位：米（m）
+	MaxDistance    float64      `json:"maxDistance,optional"`
+	MinDuration    int64        `json:"minDuration,optional"` // 单位：秒（s）
+	MaxDuration    int64        `json:"maxDuration,optional"`
+	MinPayload     int          `json:"minPayload,optional"` // 与 payload 同单位（kg 乘 10）
+	MaxPayload     int          `json:"maxPayload,optional"`
+	StartBBox      *BoundingBox `json:"startBBox,optional"`      // 起飞点范围
+	EndBBox        *BoundingBox `json:"endBBox,optional"`        // 降落点范围
+	IncompleteOnly bool         `json:"incompleteOnly,optional"` // 仅返回缺少降落时间或未录入载货量/票数的架次
+	SortBy         string       `json:"sortBy,optional"`         // start_time | end_time | distance | battery_used | duration | payload | expressCount | id
+	SortOrder      string       `json:"sortOrder,optional"`      // asc | desc，默认 desc
+	Limit          int          `json:"limit,optional"`          // 每页条数，默认 100，最大 1000
+	Offset         int          `json:"offset,optional"`         // 偏移量（未使用 cursor 时生效）
+	Cursor         string       `json:"cursor,optional"`         // 上一页返回的 nextCursor
+}
+
 type FlightRecordReq struct {
 	OrderID   string `json:"OrderID"` // 架次编号：厂商的无人机生产序列号（sn）－8位起飞日期（YYYYMMDD）－8 位随机码（数字或字母均可）如：1581F5FHD25G100C1SDN-20240320-owvGyLqe
 	UasID     string `json:"uasID"`
@@ -41,6 +230,101 @@ type FlightRecordReq struct {
 
 type FlightRecordsResponse struct {
 	Flightrecords []FlightRecord `json:"flightRecords"`
+	Total         int            `json:"total"`      // 满足条件的总条数，按游标翻页时不统计，为 -1
+	NextCursor    string         `json:"nextCursor"` // 下一页游标，为空表示没有更多数据
+}
+
+type FlightReportReq struct {
+	ID      int    `form:"id,optional"`      // flight_records.id，优先于 orderID
+	OrderID string `form:"orderID,optional"` // 未指定 id 时取该 OrderID 最近一次架次
+}
+
+type HealthComponent struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+type HealthResp struct {
+	Status string          `json:"status"` // ok | degraded（MySQL 不可用或有死信批次）
+	Time   string          `json:"time"`
+	MySQL  HealthComponent `json:"mysql"`
+	Queue  QueueHealth     `json:"queue"`
+}
+
+type MaintenanceItemStatus struct {
+	UasID           string  `json:"uasID"`
+	Item            string  `json:"item"`
+	IntervalHours   float64 `json:"intervalHours"`
+	IntervalCycles  int     `json:"intervalCycles"`
+	LastPerformedAt string  `json:"lastPerformedAt"` // 从未维保时为空，自首次飞行起算
+	HoursSince      float64 `json:"hoursSince"`      // 上次维保后的飞行小时
+	CyclesSince     int     `json:"cyclesSince"`     // 上次维保后的架次
+	RemainingHours  float64 `json:"remainingHours"`  // 距下次维保的剩余飞行小时，负数表示已超出
+	RemainingCycles int     `json:"remainingCycles"` // 距下次维保的剩余架次，负数表示已超出
+	Status          string  `json:"status"`          // ok | due：即将到期 | overdue：已到期
+}
+
+type MaintenancePlan struct {
+	Model string                `json:"model"` // 机型，default 表示未登记机型或无专属计划的机型
+	Items []MaintenancePlanItem `json:"items"`
+}
+
+type MaintenancePlanItem struct {
+	Item           string  `json:"item"`
+	IntervalHours  float64 `json:"intervalHours,optional"`  // 按飞行小时的间隔，0 表示不按小时
+	IntervalCycles int     `json:"intervalCycles,optional"` // 按起降架次的间隔，0 表示不按架次
+	Description    string  `json:"description,optional"`
+}
+
+type MaintenancePlanReq struct {
+	Model string `form:"model,optional"` // 为空时返回全部机型
+}
+
+type MaintenancePlanResp struct {
+	Plans []MaintenancePlan `json:"plans"`
+}
+
+type MaintenanceRecord struct {
+	ID          int     `json:"id"`
+	UasID       string  `json:"uasID"`
+	Item        string  `json:"item"`
+	PerformedAt string  `json:"performedAt"`
+	FlightHours float64 `json:"flightHours"` // 完成维保时的累计飞行小时
+	Cycles      int     `json:"cycles"`      // 完成维保时的累计架次
+	Technician  string  `json:"technician"`
+	Note        string  `json:"note"`
+}
+
+type MaintenanceRecordReq struct {
+	UasID       string `json:"uasID"`
+	Item        string `json:"item"`
+	PerformedAt string `json:"performedAt,optional"` // 完成时间，RFC3339 或 "yyyy-MM-dd HH:mm:ss"，默认当前时间
+	Technician  string `json:"technician,optional"`
+	Note        string `json:"note,optional"`
+}
+
+type MaintenanceStatusReq struct {
+	UasID string `path:"uasID"`
+}
+
+type MaintenanceStatusResp struct {
+	UasID          string                  `json:"uasID"`
+	Model          string                  `json:"model"`
+	PlanModel      string                  `json:"planModel"` // 实际采用的维保计划机型
+	FlightHours    float64                 `json:"flightHours"`
+	Cycles         int                     `json:"cycles"`
+	Status         string                  `json:"status"`         // 各维保项中最严重的状态
+	Blocked        bool                    `json:"blocked"`        // 开启 BlockOverdue 且存在超期项时为 true。仅为提示，由调度侧据此禁飞
+	OverdueFlights int                     `json:"overdueFlights"` // 最近一次维保后超期仍飞行的架次数
+	Items          []MaintenanceItemStatus `json:"items"`
+}
+
+type MaintenanceUpcomingReq struct {
+	Status string `form:"status,optional"` // ok | due | overdue，默认返回 due 与 overdue
+}
+
+type MaintenanceUpcomingResp struct {
+	Items []MaintenanceItemStatus `json:"items"`
 }
 
 type PayloadStats struct {
@@ -54,12 +338,210 @@ type PayloadStatsResp struct {
 	DayStats   []PayloadStats `json:"dayStats"`
 }
 
+type QueueActionResp struct {
+	Affected int `json:"affected"`
+}
+
+type QueueBatch struct {
+	Key           string `json:"key"`
+	State         string `json:"state"` // pending | dead
+	EnqueuedAt    string `json:"enqueuedAt"`
+	Points        int    `json:"points"`
+	Bytes         int    `json:"bytes"`
+	Attempts      int    `json:"attempts"`
+	LastError     string `json:"lastError,omitempty"`
+	LastAttemptAt string `json:"lastAttemptAt,omitempty"`
+	DeadAt        string `json:"deadAt,omitempty"`
+	DeadReason    string `json:"deadReason,omitempty"`  // rejected：多次被 MySQL 拒绝，decode：记录无法解析
+	DecodeError   string `json:"decodeError,omitempty"` // 记录无法解析时的错误
+}
+
+type QueueBatchReq struct {
+	State      string `path:"state"`
+	Key        string `path:"key"`
+	AdminToken string `header:"X-Admin-Token,optional"`
+}
+
+type QueueBatchResp struct {
+	Batch  QueueBatch    `json:"batch"`
+	Points []TrackPoints `json:"points"`
+}
+
+type QueueHealth struct {
+	Pending             int    `json:"pending"` // 待重放批次数
+	PendingBytes        int64  `json:"pendingBytes"`
+	Dead                int    `json:"dead"` // 死信批次数
+	DeadBytes           int64  `json:"deadBytes"`
+	OldestPendingAt     string `json:"oldestPendingAt,omitempty"`
+	OldestPendingAgeSec int64  `json:"oldestPendingAgeSec"`
+	MaxAttempts         int    `json:"maxAttempts"`
+	Replayed            int64  `json:"replayed"` // 以下为进程启动以来的计数：重放成功的批次
+	Failed              int64  `json:"failed"`   // 被 MySQL 拒绝的重放次数
+	DeadLettered        int64  `json:"deadLettered"`
+	FileSize            int64  `json:"fileSize"`
+	Error               string `json:"error,omitempty"`
+}
+
+type QueueListReq struct {
+	State      string `form:"state,optional"` // pending | dead，默认 pending
+	Offset     int    `form:"offset,optional"`
+	Limit      int    `form:"limit,optional"` // 默认 50，最大 500
+	AdminToken string `header:"X-Admin-Token,optional"`
+}
+
+type QueueListResp struct {
+	State   string       `json:"state"`
+	Total   int          `json:"total"`
+	Batches []QueueBatch `json:"batches"` // 按入队顺序
+}
+
+type QueuePurgeReq struct {
+	State      string   `json:"state"` // pending | dead
+	Keys       []string `json:"keys,optional"`
+	All        bool     `json:"all,optional"` // 删除该状态的全部批次，Keys 为空时必须指定
+	AdminToken string   `header:"X-Admin-Token,optional"`
+}
+
+type QueueRequeueReq struct {
+	Keys       []string `json:"keys,optional"` // 要移回待重放队列的死信批次
+	All        bool     `json:"all,optional"`  // 移回全部死信，Keys 为空时必须指定
+	AdminToken string   `header:"X-Admin-Token,optional"`
+}
+
+type RecentTracksReq struct {
+	OrderID   string  `form:"orderID,optional"`   // 指定 OrderID 时只返回该轨迹
+	N         int     `form:"n,optional"`         // 未指定 orderID 时返回最近 n 条，默认 3
+	Algorithm string  `form:"algorithm,optional"` // 简化算法：dp（Douglas–Peucker，默认）| vw（Visvalingam–Whyatt）
+	Tolerance float64 `form:"tolerance,optional"` // 简化容差（m）
+	Zoom      int     `form:"zoom,optional"`      // 地图缩放级别，未指定 tolerance 时按一个像素的地面距离作为容差
+	MaxPoints int     `form:"maxPoints,optional"` // 每条轨迹最多返回的点数
+}
+
 type RecordsStatsResp struct {
 	TotalCount    int     `json:"totalCount"`
 	TotalDistance float64 `json:"totalDistance"`
 	TotalTime     int64   `json:"totalTime"`
 }
 
+type ReplayDroneState struct {
+	OrderID      string  `json:"orderID"`
+	Latitude     int64   `json:"latitude"`  // 单位：度（°）乘 10 的 7 次方
+	Longitude    int64   `json:"longitude"` // 单位：度（°）乘 10 的 7 次方
+	Height       float64 `json:"height"`    // 真高（m）
+	Altitude     float64 `json:"altitude"`  // 海拔（m）
+	Heading      float64 `json:"heading"`   // 航向（°），上报航迹角不可用时按位置变化推算
+	GS           float64 `json:"GS"`        // 地速（m/s）
+	SOC          float64 `json:"SOC"`
+	FlightStatus string  `json:"flightStatus"` // 前一个轨迹点的飞行状态
+}
+
+type ReplayFrame struct {
+	Time   string             `json:"time"`
+	Drones []ReplayDroneState `json:"drones"`
+}
+
+type ReplayReq struct {
+	OrderID  string  `form:"orderID,optional"`  // 回放的 OrderID，多个以逗号分隔；未指定区间时取该 OrderID 最近一次（或包含 time 的）架次的起降时间
+	Time     string  `form:"time,optional"`     // 单个回放时刻，RFC3339 或 "yyyy-MM-dd HH:mm:ss"
+	Start    string  `form:"start,optional"`    // 回放起始时刻
+	End      string  `form:"end,optional"`      // 回放结束时刻（含）
+	Step     float64 `form:"step,optional"`     // 帧间隔（秒），默认 1
+	Airspace bool    `form:"airspace,optional"` // 为 true 时返回该时段内所有在飞的无人机，而不仅是 orderID 指定的
+}
+
+type ReplayResp struct {
+	Start  string        `json:"start"`
+	End    string        `json:"end"`
+	Step   float64       `json:"step"`
+	Frames []ReplayFrame `json:"frames"`
+}
+
+type ReportRun struct {
+	ID          int64  `json:"id"`
+	ScheduleID  int64  `json:"scheduleID"`
+	TaskID      string `json:"taskID"`
+	Trigger     string `json:"trigger"` // cron | manual
+	PeriodStart string `json:"periodStart"`
+	PeriodEnd   string `json:"periodEnd"`
+	Status      string `json:"status"`   // pending | delivered | failed
+	FileName    string `json:"fileName"` // 存档文件名，超过保留期被清理后为空
+	FileSize    int64  `json:"fileSize"`
+	Error       string `json:"error"`
+	StartedAt   string `json:"startedAt"`
+	FinishedAt  string `json:"finishedAt"`
+}
+
+type ReportRunsReq struct {
+	ScheduleID int64 `form:"scheduleID,optional"` // 不指定时返回全部计划的执行记录
+	Limit      int   `form:"limit,optional"`      // 默认 50，最大 500
+}
+
+type ReportRunsResp struct {
+	Runs []ReportRun `json:"runs"`
+}
+
+type ReportSchedule struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Cron        string `json:"cron"`
+	Target      string `json:"target"`
+	Format      string `json:"format"`
+	PartitionBy string `json:"partitionBy"`
+	UasID       string `json:"uasID"`
+	RangeHours  int    `json:"rangeHours"`
+	Delivery    string `json:"delivery"`
+	DeliveryTo  string `json:"deliveryTo"`
+	RetainDays  int    `json:"retainDays"`
+	Enabled     bool   `json:"enabled"`
+	LastRunAt   string `json:"lastRunAt"`
+	NextRunAt   string `json:"nextRunAt"`
+	CreatedAt   string `json:"createdAt"`
+}
+
+type ReportScheduleIDReq struct {
+	ID         int64  `path:"id"`
+	AdminToken string `header:"X-Admin-Token,optional"`
+}
+
+type ReportScheduleReq struct {
+	ID          int64  `path:"id,optional"`
+	Name        string `json:"name"`
+	Cron        string `json:"cron"`                 // 5 段 cron 表达式（分 时 日 月 周），支持 @daily 等描述符，按 Report.Timezone 解释
+	Target      string `json:"target,optional"`      // records | trajectory | both，默认 records
+	Format      string `json:"format,optional"`      // xlsx | csv | geojson | kml | gpx | parquet，默认 xlsx
+	PartitionBy string `json:"partitionBy,optional"` // 仅 parquet：day | uas
+	UasID       string `json:"uasID,optional"`
+	RangeHours  int    `json:"rangeHours,optional"`      // 报表覆盖触发时刻之前多少小时，默认 24
+	Delivery    string `json:"delivery"`                 // local | smtp | webhook
+	DeliveryTo  string `json:"deliveryTo,optional"`      // local：DeliveryDir 下的子目录；smtp：收件人，逗号分隔；webhook：URL
+	RetainDays  int    `json:"retainDays,optional"`      // 存档保留天数，0 表示使用 Report.RetainDays
+	Enabled     *bool  `json:"enabled,optional"`         // 新建时默认启用，更新时不传则保持不变
+	AdminToken  string `header:"X-Admin-Token,optional"` // 新建、修改、删除与手动触发计划均需管理员令牌
+}
+
+type ReportSchedulesResp struct {
+	Schedules []ReportSchedule `json:"schedules"`
+}
+
+type RevertAuditReq struct {
+	ID         int64  `path:"id"`
+	AdminToken string `header:"X-Admin-Token,optional"`
+}
+
+type SOCDropBucket struct {
+	Range string `json:"range"` // 区间，如 "10-20"（百分点，左闭右开）
+	Count int    `json:"count"`
+}
+
+type SOCDropStats struct {
+	Flights int             `json:"flights"` // 有 SOC 数据的架次数
+	Avg     float64         `json:"avg"`
+	P50     float64         `json:"p50"`
+	P90     float64         `json:"p90"`
+	Max     float64         `json:"max"`
+	Buckets []SOCDropBucket `json:"buckets"`
+}
+
 type SOCUsage struct {
 	Date  string  `json:"date"`
 	Usage float64 `json:"usage"`
@@ -71,12 +553,75 @@ type SOCUsageStatsResp struct {
 	DayStats   []SOCUsage `json:"dayStats"`
 }
 
+type SetMaintenancePlanReq struct {
+	Model string                `json:"model"`
+	Items []MaintenancePlanItem `json:"items"` // 整体替换该机型的维保计划，为空时删除
+}
+
+type StatsQueryReq struct {
+	Start       string `form:"start,optional"`       // 统计起始时间（含），RFC3339 或 "yyyy-MM-dd HH:mm:ss"/"yyyy-MM-dd"（按 tz 解析）
+	End         string `form:"end,optional"`         // 统计结束时间（不含），默认当前时间
+	Granularity string `form:"granularity,optional"` // hour | day | week | month | year，默认 day
+	Timezone    string `form:"tz,optional"`          // IANA 时区，如 Asia/Shanghai，默认与数据库一致
+	GroupBy     string `form:"groupBy,optional"`     // uasID | model | manufacturer，为空不分组
+	Mode        string `form:"mode,optional"`        // 仅 /record/SOCUsage 使用：avg 表示单位耗电（kWh/km/kg）
+	Format      string `form:"format,optional"`      // series（默认）| legacy：legacy 返回原有的年/月/日统计，已弃用
+}
+
+type StatsSeries struct {
+	Metric string    `json:"metric"` // 指标名
+	Group  string    `json:"group"`  // 分组值，未分组时为空
+	Data   []float64 `json:"data"`   // 与 buckets 一一对应，无数据的桶为 0
+}
+
+type StatsSeriesResp struct {
+	Granularity string        `json:"granularity"`
+	Timezone    string        `json:"timezone"`
+	Start       string        `json:"start"` // 实际统计区间（RFC3339）
+	End         string        `json:"end"`
+	GroupBy     string        `json:"groupBy"`
+	Buckets     []string      `json:"buckets"` // 各桶起点标签（按 tz）
+	Series      []StatsSeries `json:"series"`
+}
+
+type TelemetryPoint struct {
+	Time   string                 `json:"time"` // 降采样数据为窗口起点
+	Tags   map[string]string      `json:"tags"`
+	Fields map[string]interface{} `json:"fields"` // 降采样数据为 count、<字段>_mean/_min/_max 及最后位置
+}
+
+type TelemetrySeriesReq struct {
+	Measurement string `form:"measurement,optional"` // drone_status | vehicle_info，默认 drone_status
+	Sn          string `form:"sn,optional"`
+	FlightCode  string `form:"flightCode,optional"`
+	OrderID     string `form:"orderID,optional"`
+	Vin         string `form:"vin,optional"`
+	Start       string `form:"start"`
+	End         string `form:"end,optional"`        // 默认当前时间
+	Resolution  string `form:"resolution,optional"` // auto | raw | 降采样级别（如 10s、1m），默认 auto
+	MaxPoints   int    `form:"maxPoints,optional"`  // auto 时单条序列的目标点数上限，默认取配置
+}
+
+type TelemetrySeriesResp struct {
+	Measurement string           `json:"measurement"`
+	Resolution  string           `json:"resolution"` // 实际使用的分辨率
+	Start       string           `json:"start"`
+	End         string           `json:"end"`
+	Points      []TelemetryPoint `json:"points"`
+}
+
 type TimeSeriesStatsResp struct {
 	YearStats  []DateCount `json:"yearStats"`
 	MonthStats []DateCount `json:"monthStats"`
 	DayStats   []DateCount `json:"dayStats"`
 }
 
+type TrackGap struct {
+	Start   string  `json:"start"`
+	End     string  `json:"end"`
+	Seconds float64 `json:"seconds"`
+}
+
 type TrackPoints struct {
 	OrderID      string `json:"orderID"`      // 架次编号：厂商的无人机生产序列号（sn）－8位起飞日期（YYYYMMDD）－8 位随机码（数字或字母均可）如：1581F5FHD25G100C1SDN-20240320-owvGyLqe
 	FlightStatus string `json:"flightStatus"` // TakeOff：代表当前架次飞行的首个轨迹点 Inflight：代表当前架次飞行中除首尾以外的其它轨迹点 Land：代表当前架次飞行的最后一个轨迹点
@@ -99,10 +644,76 @@ type TrackPoints struct {
 	Humidity     int    `json:"humidity"`     // 湿度值，百分比，0-100 整数
 }
 
+type TrackQuality struct {
+	OrderID        string     `json:"OrderID"`
+	UasID          string     `json:"uasID"`
+	StartTime      string     `json:"start_time"`
+	PointsTotal    int        `json:"pointsTotal"`
+	PointsKept     int        `json:"pointsKept"`
+	DroppedZero    int        `json:"droppedZero"`   // (0,0) 或超出范围的坐标
+	DroppedTime    int        `json:"droppedTime"`   // 时间戳重复或倒序
+	DroppedSpeed   int        `json:"droppedSpeed"`  // 隐含速度超限
+	DroppedAccel   int        `json:"droppedAccel"`  // 隐含加速度超限
+	DroppedFrozen  int        `json:"droppedFrozen"` // 位置冻结
+	GapCount       int        `json:"gapCount"`
+	GapSeconds     float64    `json:"gapSeconds"`
+	Gaps           []TrackGap `json:"gaps"`
+	Smoothing      string     `json:"smoothing"`      // none | moving_average | kalman
+	RawDistance    float64    `json:"rawDistance"`    // 清洗前航程（m）
+	CleanDistance  float64    `json:"cleanDistance"`  // 清洗后航程（m），即 flight_records.distance
+	RawEnergyKWh   float64    `json:"rawEnergyKWh"`   // 清洗前耗电（kWh）
+	CleanEnergyKWh float64    `json:"cleanEnergyKWh"` // 清洗后耗电（kWh），即 flight_records.battery_used
+}
+
+type TrackQualityReq struct {
+	OrderID string `form:"OrderID"`
+}
+
+type TrackQualityResp struct {
+	Items []TrackQuality `json:"items"`
+}
+
 type TrackResponse struct {
 	Track []TrackPoints `json:"track"`
 }
 
+type TriggerBackupReq struct {
+	Type       string `json:"type,optional"` // full | incremental，为空时按 FullIntervalDays 自动选择
+	AdminToken string `header:"X-Admin-Token,optional"`
+}
+
+type UasCompareReq struct {
+	UasIDs []string `json:"uasIDs"`
+	Start  string   `json:"start,optional"`
+	End    string   `json:"end,optional"`
+}
+
+type UasCompareResp struct {
+	Items []UasDetailStatsResp `json:"items"`
+}
+
+type UasDetailStatsReq struct {
+	UasID string `path:"uasID"`
+	Start string `form:"start,optional"` // 统计起始时间（含），RFC3339 或 "yyyy-MM-dd HH:mm:ss"/"yyyy-MM-dd"
+	End   string `form:"end,optional"`   // 统计结束时间（不含），默认当前时间
+}
+
+type UasDetailStatsResp struct {
+	UasID           string       `json:"uasID"`
+	Start           string       `json:"start"` // 实际统计区间（RFC3339），未指定起点时为首次起飞时间
+	End             string       `json:"end"`
+	TotalFlights    int          `json:"totalFlights"`
+	TotalHours      float64      `json:"totalHours"`        // 单位：小时
+	TotalDistance   float64      `json:"totalDistance"`     // 单位：米（m）
+	TotalPayload    float64      `json:"totalPayload"`      // 单位：千克（kg）
+	TotalExpress    int          `json:"totalExpressCount"` // 单位：票
+	TotalEnergy     float64      `json:"totalEnergy"`       // 单位：kWh
+	EnergyPerKm     float64      `json:"energyPerKm"`       // 单位：kWh/km
+	EnergyPerKg     float64      `json:"energyPerKg"`       // 单位：kWh/kg（仅统计已录入载货量的架次）
+	SOCDrop         SOCDropStats `json:"socDrop"`           // 单架次 SOC 降幅分布（百分点）
+	UtilisationRate float64      `json:"utilisationRate"`   // 飞行时长 / 统计区间时长，0-1
+}
+
 type UasStatsResp struct {
 	Total  int `json:"total"`
 	Online int `json:"online"`
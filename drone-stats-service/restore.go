@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"drone-stats-service/internal/backup"
+	"drone-stats-service/internal/config"
+
+	"github.com/go-sql-driver/mysql"
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/zeromicro/go-zero/core/conf"
+)
+
+// runRestore 处理 restore 子命令，将备份（增量备份时连同其备份链）恢复到指定的目标库并按清单校验：
+//
+//	dronestats restore -f etc/dronestats.yaml -backup backup_20250101_030000_incr -mysql 'user:pass@tcp(host:3306)/drone_restore?parseTime=true' -bucket drone_restore
+//
+// 未指定 -mysql 与 -bucket 时只校验备份文件。结果以 JSON 输出，校验未通过时退出码为 1
+func runRestore(args []string) int {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	cfgFile := fs.String("f", "etc/dronestats.yaml", "the config file")
+	dir := fs.String("backup", "", "备份目录，或 BackupConf.BackupDir 下的备份名")
+	dsn := fs.String("mysql", "", "目标 MySQL DSN（恢复到其中指定的数据库），为空时不恢复 MySQL")
+	bucket := fs.String("bucket", "", "目标 Influx bucket（使用配置中的 Influx 连接），为空时不恢复 Influx")
+	from := fs.String("from", "", "只恢复该时间（含）之后的数据，格式 2006-01-02 15:04:05，按源库时区解释")
+	to := fs.String("to", "", "只恢复该时间之前的数据")
+	single := fs.Bool("single", false, "只恢复指定的备份，不恢复备份链中之前的备份")
+	_ = fs.Parse(args)
+
+	var c config.Config
+	conf.MustLoad(*cfgFile, &c)
+	if *dir == "" {
+		fmt.Fprintln(os.Stderr, "缺少 -backup")
+		return 2
+	}
+	path := *dir
+	if _, err := os.Stat(path); err != nil && c.BackupConf.BackupDir != "" {
+		path = filepath.Join(c.BackupConf.BackupDir, *dir)
+	}
+
+	opts := backup.RestoreOptions{Location: time.Local, Single: *single}
+	if cfg, err := mysql.ParseDSN(c.MySQL.DataSource); err == nil && cfg.Loc != nil {
+		opts.Location = cfg.Loc
+	}
+	var err error
+	for _, v := range []struct {
+		s string
+		t *time.Time
+	}{{*from, &opts.From}, {*to, &opts.To}} {
+		if v.s == "" {
+			continue
+		}
+		if *v.t, err = time.ParseInLocation("2006-01-02 15:04:05", v.s, opts.Location); err != nil {
+			fmt.Fprintln(os.Stderr, "时间格式无效:", v.s)
+			return 2
+		}
+	}
+	if *dsn != "" {
+		db, err := sql.Open("mysql", *dsn)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "连接目标 MySQL 失败:", err)
+			return 1
+		}
+		defer db.Close()
+		opts.MySQL = db
+	}
+	if *bucket != "" {
+		client := influxdb2.NewClient("http://"+c.InfluxDBConfig.Host+":"+c.InfluxDBConfig.Port, c.InfluxDBConfig.Token)
+		defer client.Close()
+		opts.InfluxClient, opts.InfluxOrg, opts.InfluxBucket = client, c.InfluxDBConfig.Org, *bucket
+	}
+
+	res, err := backup.Restore(context.Background(), path, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "恢复失败:", err)
+		return 1
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(res)
+	if !res.OK {
+		for _, p := range res.Problems() {
+			fmt.Fprintln(os.Stderr, "校验未通过:", p)
+		}
+		return 1
+	}
+	return 0
+}